@@ -4,12 +4,23 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"os"
 	"time"
+	_ "time/tzdata" // 会議室のタイムゾーンをOSに依存せず解決する
 
 	"github.com/gin-gonic/gin" // ★ Ginをインポート
 	"github.com/shuto.sawaki/elmo-project/internal/ai"
+	"github.com/shuto.sawaki/elmo-project/internal/analytics"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
 	"github.com/shuto.sawaki/elmo-project/internal/db"
+	"github.com/shuto.sawaki/elmo-project/internal/events"
+	"github.com/shuto.sawaki/elmo-project/internal/export"
 	"github.com/shuto.sawaki/elmo-project/internal/handlers"
+	"github.com/shuto.sawaki/elmo-project/internal/jobs"
+	"github.com/shuto.sawaki/elmo-project/internal/mail"
+	"github.com/shuto.sawaki/elmo-project/internal/ratelimit"
+	"github.com/shuto.sawaki/elmo-project/internal/slack"
+	"github.com/shuto.sawaki/elmo-project/internal/webhooks"
 	
 	// Swagger関連のインポート
 	_ "github.com/shuto.sawaki/elmo-project/docs"
@@ -30,14 +41,150 @@ func main() {
 		log.Fatalf("AIジェネレータの初期化に失敗しました: %v", err)
 	}
 
+	guestTokens, err := auth.NewGuestTokensFromEnv()
+	if err != nil {
+		log.Fatalf("ゲストトークンの設定に失敗しました: %v", err)
+	}
+
+	// サービスアカウントと API キーを管理する管理者トークン（未設定なら service-accounts:manage スコープのキーのみ）
+	adminToken, err := auth.NewAdminTokenFromEnv()
+	if err != nil {
+		log.Fatalf("管理者トークンの設定に失敗しました: %v", err)
+	}
+
+	// 保持期間を過ぎたゲストをバックグラウンドで整理
+	guestRetention, err := jobs.NewGuestRetentionFromEnv(database)
+	if err != nil {
+		log.Fatalf("ゲスト保持期間の設定に失敗しました: %v", err)
+	}
+	go guestRetention.Run(ctx)
+
+	limiter, err := ratelimit.NewFromEnv(database)
+	if err != nil {
+		log.Fatalf("レート制限の設定に失敗しました: %v", err)
+	}
+	if store, ok := limiter.Store().(*ratelimit.PostgresStore); ok {
+		go store.Run(ctx)
+	}
+
+	// 議事録の書き出し（PDFには EXPORT_PDF_FONT の日本語フォントを埋め込む）
+	exporter, err := export.NewExporterFromEnv()
+	if err != nil {
+		log.Fatalf("議事録の書き出しの設定に失敗しました: %v", err)
+	}
+
 	// 各ハンドラーを初期化
 	roomHandler := handlers.NewRoomHandler(database, aiGenerator)
+	roomHandler.SetRateLimiter(limiter)
 	userHandler := handlers.NewUserHandler(database)
 	participantHandler := handlers.NewParticipantHandler(database)
+	guestHandler := handlers.NewGuestHandler(database, guestTokens)
+	serviceAccountHandler := handlers.NewServiceAccountHandler(database)
+	reactionHandler := handlers.NewReactionHandler(database)
+	seriesHandler := handlers.NewSeriesHandler(database)
+	templateHandler := handlers.NewTemplateHandler(database)
+	searchHandler := handlers.NewSearchHandler(database)
+	exportHandler := handlers.NewExportHandler(database, exporter)
+	archiveHandler := handlers.NewArchiveHandler(database)
+	// カレンダーの予定に含める参加用のリンク（APP_BASE_URL が未設定ならリンクを含めない）
+	calendarHandler := handlers.NewCalendarHandler(database, os.Getenv("APP_BASE_URL"))
+	webhookHandler := handlers.NewWebhookHandler(database)
+
+	// 会議室の作成・開始・要約・結論・終了を購読先に送る（配信はバックグラウンドで行い、失敗したら再送する）
+	webhookNotifier := webhooks.NewNotifier(database)
+	roomHandler.AddNotifier(webhookNotifier)
+	go webhooks.NewDispatcher(database).Run(ctx)
+
+	// 会議の開始・AI の要約・結論を Slack / Mattermost に投稿する
+	chatNotifier := slack.NewNotifier(database, os.Getenv("APP_BASE_URL"))
+	roomHandler.AddNotifier(chatNotifier)
+	go chatNotifier.Run(ctx)
+	chatChannelHandler := handlers.NewChatChannelHandler(database, chatNotifier)
+
+	// 招待・開始前のリマインダー・会議後のまとめをメールで送る（SMTP_ADDR か MAIL_FILE_DIR が未設定なら送らない）
+	mailer, err := mail.NewMailerFromEnv(database, os.Getenv("APP_BASE_URL"))
+	if err != nil {
+		log.Fatalf("メールの設定に失敗しました: %v", err)
+	}
+	roomHandler.AddNotifier(mailer)
+	go mailer.Run(ctx)
+	notificationHandler := handlers.NewNotificationHandler(database)
+	invitationHandler := handlers.NewInvitationHandler(database, mailer)
+	actionItemHandler := handlers.NewActionItemHandler(database, aiGenerator)
+
+	// 会議の分析（ANALYTICS_REFRESH_INTERVAL を設定すると、週ごとの傾向は定期的に更新するビューから集計する）
+	analyticsRefresher, err := analytics.NewRefresherFromEnv(database)
+	if err != nil {
+		log.Fatalf("分析の設定に失敗しました: %v", err)
+	}
+	if analyticsRefresher != nil {
+		go analyticsRefresher.Run(ctx)
+	}
+	analyticsHandler := handlers.NewAnalyticsHandler(database, analyticsRefresher != nil)
+
+	// 会議室ごとのリアルタイム通知
+	hub := events.NewHub()
+	roomHandler.SetHub(hub)
+	pollHandler := handlers.NewPollHandler(database, hub)
+	eventHandler := handlers.NewEventHandler(database, hub)
+	agendaHandler := handlers.NewAgendaHandler(database, aiGenerator, hub)
+
+	// 定例会議の会議室の作成と、予定された会議室の自動開始・終了
+	scheduler := jobs.NewScheduler(database, aiGenerator, hub)
+	scheduler.AddNotifier(webhookNotifier)
+	scheduler.AddNotifier(chatNotifier)
+	scheduler.AddNotifier(mailer)
+	go scheduler.Run(ctx)
+
+	// サービスアカウント（APIキー）から呼び出せるルートと必要なスコープ
+	scopePolicy := auth.ScopePolicy{
+		"GET /rooms":                                       auth.ScopeRoomsRead,
+		"GET /rooms/:id":                                   auth.ScopeRoomsRead,
+		"POST /rooms":                                      auth.ScopeRoomsWrite,
+		"POST /rooms/:id/start":                            auth.ScopeRoomsWrite,
+		"PUT /rooms/:id/status":                            auth.ScopeRoomsWrite,
+		"GET /rooms/:id/result":                            auth.ScopeResultsRead,
+		"GET /rooms/:id/conclusion/history":                auth.ScopeResultsRead,
+		"GET /rooms/:id/export":                            auth.ScopeResultsRead,
+		"GET /rooms/:id/archive":                           auth.ScopeResultsRead,
+		"GET /rooms/:id/logs/export":                       auth.ScopeResultsRead,
+		"GET /logs/export":                                 auth.ScopeResultsRead,
+		"GET /rooms/:id/action-items":                      auth.ScopeResultsRead,
+		"GET /rooms/:id/analytics":                         auth.ScopeResultsRead,
+		"GET /analytics/trends":                            auth.ScopeResultsRead,
+		"GET /rooms/:id/calendar.ics":                      auth.ScopeRoomsRead,
+		"POST /rooms/:id/invitations":                      auth.ScopeRoomsWrite,
+		"POST /rooms/import":                               auth.ScopeRoomsWrite,
+		"POST /series":                                     auth.ScopeRoomsWrite,
+		"GET /series/:id":                                  auth.ScopeRoomsRead,
+		"POST /series/:id/stop":                            auth.ScopeRoomsWrite,
+		"GET /templates":                                   auth.ScopeRoomsRead,
+		"GET /templates/:id":                               auth.ScopeRoomsRead,
+		"POST /templates":                                  auth.ScopeTemplatesManage,
+		"PUT /templates/:id":                               auth.ScopeTemplatesManage,
+		"DELETE /templates/:id":                            auth.ScopeTemplatesManage,
+		"POST /webhooks":                                   auth.ScopeWebhooksManage,
+		"GET /webhooks":                                    auth.ScopeWebhooksManage,
+		"DELETE /webhooks/:id":                             auth.ScopeWebhooksManage,
+		"GET /webhooks/:id/deliveries":                     auth.ScopeWebhooksManage,
+		"POST /webhooks/:id/deliveries/:deliveryId/replay": auth.ScopeWebhooksManage,
+		"POST /chat-channels":                              auth.ScopeWebhooksManage,
+		"GET /chat-channels":                               auth.ScopeWebhooksManage,
+		"DELETE /chat-channels/:id":                        auth.ScopeWebhooksManage,
+		"POST /chat-channels/:id/test":                     auth.ScopeWebhooksManage,
+		"GET /search":                                      auth.ScopeResultsRead,
+		"POST /reaction-types":                             auth.ScopeReactionTypesManage,
+		"POST /service-accounts":                           auth.ScopeServiceAccountsManage,
+		"GET /service-accounts/:id/keys":                   auth.ScopeServiceAccountsManage,
+		"POST /service-accounts/:id/keys":                  auth.ScopeServiceAccountsManage,
+		"POST /service-accounts/:id/keys/:keyId/rotate":    auth.ScopeServiceAccountsManage,
+		"DELETE /service-accounts/:id/keys/:keyId":         auth.ScopeServiceAccountsManage,
+	}
 
 	// ★ Ginのルーターを初期化
 	// gin.Default()は、ロガーやリカバリーといった便利なミドルウェアが最初から組み込まれています。
 	router := gin.Default()
+	router.Use(auth.Middleware(guestTokens, auth.NewAPIKeys(database), adminToken, scopePolicy))
 
 	// Swagger UIのルートを追加
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -56,21 +203,107 @@ func main() {
 	// これにより、ハンドラー内のswitch文が不要になります。
 	router.GET("/rooms", roomHandler.GetRooms)
 	router.POST("/rooms", roomHandler.CreateRoom)
+	router.POST("/rooms/import", archiveHandler.ImportRoomArchive)
 
 	// URL内の可変部分を :id のようにコロンで指定できます。
 	// これを「URLパラメータ」と呼びます。
 	router.GET("/rooms/:id", roomHandler.GetRoomByID)
 	router.POST("/rooms/:id/start", roomHandler.StartRoom)
 	router.PUT("/rooms/:id/status", roomHandler.UpdateRoomStatus)
+	router.PUT("/rooms/:id/schedule", roomHandler.SetSchedule)
+	router.PUT("/rooms/:id/tags", roomHandler.SetRoomTags)
 	router.GET("/rooms/:id/result", roomHandler.GetRoomResult)
+	router.GET("/rooms/:id/export", exportHandler.ExportMinutes)
+	router.GET("/rooms/:id/archive", archiveHandler.GetRoomArchive)
+	router.GET("/rooms/:id/logs/export", roomHandler.ExportRoomLogs)
+	router.GET("/rooms/:id/calendar.ics", calendarHandler.GetRoomCalendar)
 	router.POST("/rooms/:id/conclusion", roomHandler.SaveConclusion)
+	router.GET("/rooms/:id/conclusion/history", roomHandler.GetConclusionHistory)
+	router.POST("/rooms/:id/conclusion/revert", roomHandler.RevertConclusion)
+	router.PUT("/rooms/:id/conclusion/approvers", roomHandler.SetConclusionApprovers)
+	router.POST("/rooms/:id/conclusion/approve", roomHandler.ApproveConclusion)
 	router.POST("/rooms/:id/sorena", roomHandler.HandleSorena)
 	router.POST("/rooms/:id/summary", roomHandler.CreateSummary)
+	router.POST("/rooms/:id/messages", roomHandler.PostMessage)
+	router.PUT("/rooms/:id/messages/:messageId/sorena", roomHandler.AddMessageSorena)
+	router.DELETE("/rooms/:id/messages/:messageId/sorena", roomHandler.RemoveMessageSorena)
+	router.PUT("/rooms/:id/messages/:messageId/reactions/:type", roomHandler.AddMessageReaction)
+	router.DELETE("/rooms/:id/messages/:messageId/reactions/:type", roomHandler.RemoveMessageReaction)
+	router.GET("/rooms/:id/reaction-types", reactionHandler.GetRoomReactionTypes)
+	router.PUT("/rooms/:id/reaction-types", reactionHandler.SetRoomReactionTypes)
+	router.POST("/rooms/:id/guests", guestHandler.JoinAsGuest)
+	router.POST("/rooms/:id/invitations", invitationHandler.InviteToRoom)
+	router.GET("/rooms/:id/events", eventHandler.StreamRoomEvents)
+
+	router.GET("/rooms/:id/action-items", actionItemHandler.ListRoomActionItems)
+	router.POST("/rooms/:id/action-items", actionItemHandler.CreateActionItem)
+	router.POST("/rooms/:id/action-items/extract", actionItemHandler.ExtractActionItems)
+	router.PATCH("/action-items/:id", actionItemHandler.UpdateActionItem)
+
+	router.GET("/rooms/:id/analytics", analyticsHandler.GetRoomAnalytics)
+	router.GET("/analytics/trends", analyticsHandler.GetAnalyticsTrends)
+
+	router.GET("/rooms/:id/polls", pollHandler.ListPolls)
+	router.POST("/rooms/:id/polls", pollHandler.CreatePoll)
+	router.GET("/rooms/:id/polls/:pollId", pollHandler.GetPoll)
+	router.POST("/rooms/:id/polls/:pollId/votes", pollHandler.Vote)
+	router.POST("/rooms/:id/polls/:pollId/close", pollHandler.ClosePoll)
+
+	router.GET("/rooms/:id/agenda", agendaHandler.GetAgenda)
+	router.POST("/rooms/:id/agenda", agendaHandler.AddAgendaItem)
+	router.PUT("/rooms/:id/agenda/order", agendaHandler.ReorderAgenda)
+	router.POST("/rooms/:id/agenda/advance", agendaHandler.AdvanceAgenda)
+	router.DELETE("/rooms/:id/agenda/:itemId", agendaHandler.DeleteAgendaItem)
+	router.PUT("/rooms/:id/agenda/:itemId/conclusion", agendaHandler.SaveAgendaConclusion)
+
+	router.POST("/series", seriesHandler.CreateSeries)
+	router.GET("/series/:id", seriesHandler.GetSeries)
+	router.POST("/series/:id/stop", seriesHandler.StopSeries)
+
+	router.GET("/search", searchHandler.Search)
+	router.GET("/logs/export", roomHandler.ExportLogs)
+
+	router.POST("/webhooks", webhookHandler.CreateWebhook)
+	router.GET("/webhooks", webhookHandler.ListWebhooks)
+	router.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+	router.GET("/webhooks/:id/deliveries", webhookHandler.ListWebhookDeliveries)
+	router.POST("/webhooks/:id/deliveries/:deliveryId/replay", webhookHandler.ReplayWebhookDelivery)
+
+	router.POST("/chat-channels", chatChannelHandler.CreateChatChannel)
+	router.GET("/chat-channels", chatChannelHandler.ListChatChannels)
+	router.DELETE("/chat-channels/:id", chatChannelHandler.DeleteChatChannel)
+	router.POST("/chat-channels/:id/test", chatChannelHandler.TestChatChannel)
+
+	router.GET("/templates", templateHandler.ListTemplates)
+	router.POST("/templates", templateHandler.CreateTemplate)
+	router.GET("/templates/:id", templateHandler.GetTemplate)
+	router.PUT("/templates/:id", templateHandler.UpdateTemplate)
+	router.DELETE("/templates/:id", templateHandler.DeleteTemplate)
 
 	router.POST("/users", userHandler.CreateUser)
+	router.GET("/users/:id/calendar.ics", calendarHandler.GetUserCalendar)
+	router.GET("/users/:id/action-items", actionItemHandler.ListUserActionItems)
+	router.POST("/users/:id/calendar-token", calendarHandler.IssueCalendarToken)
+	router.GET("/users/:id/notification-preferences", notificationHandler.GetNotificationPreferences)
+	router.PUT("/users/:id/notification-preferences", notificationHandler.UpdateNotificationPreferences)
+	router.GET("/unsubscribe", notificationHandler.ShowUnsubscribe)
+	router.POST("/unsubscribe", notificationHandler.Unsubscribe)
+
+	router.GET("/reaction-types", reactionHandler.ListReactionTypes)
+	router.POST("/reaction-types", reactionHandler.CreateReactionType)
+
+	router.POST("/service-accounts", serviceAccountHandler.CreateServiceAccount)
+	router.GET("/service-accounts/:id/keys", serviceAccountHandler.ListAPIKeys)
+	router.POST("/service-accounts/:id/keys", serviceAccountHandler.CreateAPIKey)
+	router.POST("/service-accounts/:id/keys/:keyId/rotate", serviceAccountHandler.RotateAPIKey)
+	router.DELETE("/service-accounts/:id/keys/:keyId", serviceAccountHandler.RevokeAPIKey)
 
 	router.GET("/participants", participantHandler.GetParticipants)
 	router.POST("/participants", participantHandler.AddParticipant)
+	router.DELETE("/rooms/:id/participants/:userId", participantHandler.RemoveParticipant)
+	router.POST("/rooms/:id/participants/:userId/heartbeat", participantHandler.Heartbeat)
+	router.POST("/rooms/:id/leave", participantHandler.LeaveRoom)
+	router.GET("/rooms/:id/waitlist", participantHandler.GetWaitlist)
 
 	log.Println("サーバー起動: http://localhost:8080")
 	log.Println("Swagger UI: http://localhost:8080/swagger/index.html")
@@ -0,0 +1,118 @@
+// Package actionitems は会議室をまたいで追跡するアクションアイテムの読み込みを提供します。
+package actionitems
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// アクションアイテムの状態と作成の方法
+const (
+	StatusOpen     = "open"
+	StatusDone     = "done"
+	StatusCanceled = "canceled"
+
+	SourceManual = "manual"
+	SourceAI     = "ai"
+)
+
+// MaxTitleLength はやることの最大長（文字数）です。
+const MaxTitleLength = 200
+
+// ValidStatus は状態が open / done / canceled のいずれかかどうかを返します。
+func ValidStatus(status string) bool {
+	return status == StatusOpen || status == StatusDone || status == StatusCanceled
+}
+
+// ValidDueDate は期限が YYYY-MM-DD の形の実在する日付かどうかを返します。
+func ValidDueDate(s string) bool {
+	_, err := time.Parse(time.DateOnly, s)
+	return err == nil
+}
+
+// OverdueCondition は期限を過ぎて未完了のアクションアイテム a に一致する条件です（rooms r を参照します）。
+// 期限切れは会議室のタイムゾーンの今日の日付で判定します（会議室が削除された場合は UTC）。
+const OverdueCondition = `(a.status = 'open' AND a.due_date < (NOW() AT TIME ZONE COALESCE(r.time_zone, 'UTC'))::date)`
+
+// selectQuery はアクションアイテムを作成元の会議室のタイトル・担当者の名前とともに読み込むクエリです。
+const selectQuery = `
+	SELECT a.id, a.room_id, r.title, a.message_id, a.title, a.assignee_id, u.user_name,
+		to_char(a.due_date, 'YYYY-MM-DD'), a.status, a.source, COALESCE(` + OverdueCondition + `, FALSE),
+		a.created_by, a.created_at, a.updated_at, a.completed_at
+	FROM action_items a
+	LEFT JOIN rooms r ON r.id = a.room_id
+	LEFT JOIN users u ON u.id = a.assignee_id`
+
+// List は condition（action_items a・rooms r・users u を参照できます）に一致するアクションアイテムを、
+// 期限の近い順（期限なしは最後）に返します。limit が 0 の場合はすべて返します。
+func List(ctx context.Context, db *sql.DB, condition string, limit int, args ...interface{}) ([]models.ActionItem, error) {
+	query := selectQuery + " WHERE " + condition + " ORDER BY a.due_date NULLS LAST, a.created_at, a.id"
+	if limit > 0 {
+		query += " LIMIT " + strconv.Itoa(limit)
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []models.ActionItem{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Get はアクションアイテムを一件返します。見つからない場合は sql.ErrNoRows を返します。
+func Get(ctx context.Context, db *sql.DB, id string) (models.ActionItem, error) {
+	return scan(db.QueryRowContext(ctx, selectQuery+" WHERE a.id = $1", id))
+}
+
+// OpenRelated は会議室・その前回の会議・同じ定例会議の会議室で作られた、未完了のアクションアイテムを返します。
+// 新しい会議室を開始したときに、これまでの会議から持ち越しているアイテムを示すために使います。
+func OpenRelated(ctx context.Context, db *sql.DB, roomID string, limit int) ([]models.ActionItem, error) {
+	return List(ctx, db, `a.status = 'open' AND a.room_id IN (
+		SELECT id FROM rooms WHERE id = $1
+		UNION SELECT previous_room_id FROM rooms WHERE id = $1
+		UNION SELECT o.id FROM rooms o JOIN rooms s ON s.series_id = o.series_id WHERE s.id = $1)`, limit, roomID)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(row scanner) (models.ActionItem, error) {
+	var item models.ActionItem
+	var roomID, roomTitle, messageID, assigneeID, assigneeName, dueDate, createdBy sql.NullString
+	var completedAt sql.NullTime
+	err := row.Scan(&item.ID, &roomID, &roomTitle, &messageID, &item.Title, &assigneeID, &assigneeName,
+		&dueDate, &item.Status, &item.Source, &item.Overdue, &createdBy, &item.CreatedAt, &item.UpdatedAt, &completedAt)
+	if err != nil {
+		return item, err
+	}
+	item.RoomID = nullString(roomID)
+	item.RoomTitle = nullString(roomTitle)
+	item.MessageID = nullString(messageID)
+	item.AssigneeID = nullString(assigneeID)
+	item.AssigneeName = nullString(assigneeName)
+	item.DueDate = nullString(dueDate)
+	item.CreatedBy = nullString(createdBy)
+	if completedAt.Valid {
+		item.CompletedAt = &completedAt.Time
+	}
+	return item, nil
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
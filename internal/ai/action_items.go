@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// parseActionItems は AI の応答から JSON の配列を取り出して読み込みます。
+// 応答はコードブロック（```json ... ```）で囲まれていることがあるため、最初の [ から最後の ] までを使います。
+// やることが空の要素は捨てます。
+func parseActionItems(text string) ([]models.ActionItemCandidate, error) {
+	start := strings.Index(text, "[")
+	end := strings.LastIndex(text, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no json array in action items response")
+	}
+	var parsed []models.ActionItemCandidate
+	if err := json.Unmarshal([]byte(text[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid action items response: %w", err)
+	}
+	items := []models.ActionItemCandidate{}
+	for _, item := range parsed {
+		item.Title = strings.TrimSpace(item.Title)
+		item.Assignee = strings.TrimSpace(item.Assignee)
+		item.Due = strings.TrimSpace(item.Due)
+		if item.Title != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
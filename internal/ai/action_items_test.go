@@ -0,0 +1,22 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseActionItems(t *testing.T) {
+	items, err := parseActionItems("```json\n[\n  {\"title\": \" テスト計画を共有する \", \"assignee\": \"田中\", \"due\": \"2024-01-31\"},\n  {\"title\": \"\", \"assignee\": \"佐藤\"}\n]\n```")
+	require.NoError(t, err)
+	assert.Equal(t, []models.ActionItemCandidate{{Title: "テスト計画を共有する", Assignee: "田中", Due: "2024-01-31"}}, items)
+
+	items, err = parseActionItems("[]")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	_, err = parseActionItems("アクションはありません")
+	assert.Error(t, err)
+}
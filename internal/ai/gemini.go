@@ -78,6 +78,28 @@ func (g *GeminiAIGenerator) SummarizeLogs(ctx context.Context, logs []models.Log
 	return "", fmt.Errorf("unexpected response format from gemini api for summarization")
 }
 
+// ExtractActionItems は、Gemini API で要約からアクションアイテムを JSON で抜き出します。
+func (g *GeminiAIGenerator) ExtractActionItems(ctx context.Context, summary string) ([]models.ActionItemCandidate, error) {
+	prompt := fmt.Sprintf("以下の会議の要約から、会議後に誰かが行うべきアクションを抜き出してください。"+
+		"JSON の配列だけを返し、各要素は {\"title\": やること, \"assignee\": 担当者の名前（不明なら空文字）, \"due\": 期限 YYYY-MM-DD（不明なら空文字）} としてください。"+
+		"アクションがなければ [] を返してください。\n\n要約:\n%s", summary)
+
+	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return nil, fmt.Errorf("gemini api call failed for action items: %w", err)
+	}
+
+	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("no valid response from gemini api for action items")
+	}
+
+	if text, ok := resp.Candidates[0].Content.Parts[0].(genai.Text); ok {
+		return parseActionItems(string(text))
+	}
+
+	return nil, fmt.Errorf("unexpected response format from gemini api for action items")
+}
+
 // Close はクライアント接続を閉じます。
 func (g *GeminiAIGenerator) Close() {
 	// genai.Clientには明示的なCloseメソッドがありません。
@@ -10,4 +10,6 @@ type AIGenerator interface {
 	// ★ GenerateInitialQuestion を再度追加
 	GenerateInitialQuestion(ctx context.Context, title, description string) (string, error)
 	SummarizeLogs(ctx context.Context, logs []models.LogEntry) (string, error)
+	// ExtractActionItems は AI の要約から、やること・担当者の名前・期限を抜き出します。
+	ExtractActionItems(ctx context.Context, summary string) ([]models.ActionItemCandidate, error)
 }
\ No newline at end of file
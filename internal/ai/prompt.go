@@ -0,0 +1,37 @@
+package ai
+
+import "strings"
+
+// 最初の問いかけのプロンプトの種類
+const (
+	PromptDefault       = "default"
+	PromptRetrospective = "retrospective"
+	PromptBrainstorming = "brainstorming"
+	PromptDecision      = "decision"
+	PromptOneOnOne      = "one_on_one"
+)
+
+// promptInstructions は種類ごとに説明に加える指示です。
+var promptInstructions = map[string]string{
+	PromptDefault:       "",
+	PromptRetrospective: "この会議はふりかえりです。うまくいったこと・うまくいかなかったことを率直に話せる問いかけにしてください。",
+	PromptBrainstorming: "この会議はブレインストーミングです。自由な発想を促し、たくさんのアイデアが出る問いかけにしてください。",
+	PromptDecision:      "この会議は意思決定の場です。判断の基準や選択肢をはっきりさせる問いかけにしてください。",
+	PromptOneOnOne:      "この会議は1on1です。相手が安心して近況や悩みを話せる、個人に寄り添った問いかけにしてください。",
+}
+
+// IsPromptVariant は対応しているプロンプトの種類かどうかを返します。
+func IsPromptVariant(variant string) bool {
+	_, ok := promptInstructions[variant]
+	return ok
+}
+
+// WithPromptVariant は会議の説明にプロンプトの種類に応じた指示を加えます。
+// GenerateInitialQuestion の description にそのまま渡せます。
+func WithPromptVariant(description, variant string) string {
+	instruction := promptInstructions[variant]
+	if instruction == "" {
+		return description
+	}
+	return strings.TrimSpace(description + "\n\n" + instruction)
+}
@@ -0,0 +1,92 @@
+// Package analytics は会議の分析（発言の偏り・結論が出るまでの時間・発言の頻度・週ごとの傾向）を集計します。
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// Gini は値のばらつきを表すジニ係数を返します。0 は全員が同じ、1 に近いほど一部に偏っています。
+// 値が二つ未満か合計が 0 の場合は 0 を返します。
+func Gini(values []int) float64 {
+	n := len(values)
+	if n < 2 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	var total, weighted float64
+	for i, v := range sorted {
+		total += float64(v)
+		weighted += float64(i+1) * float64(v)
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2*weighted/(float64(n)*total) - float64(n+1)/float64(n)
+}
+
+// RoomSummarySQL は開始した会議室ごとの、結論が初めて出た日時・最後に終了した日時・発言数・結論の有無です。
+// マテリアライズドビュー room_analytics と同じ列を返すため、ビューを使わない場合はこれを直接集計します。
+const RoomSummarySQL = `
+	SELECT r.id AS room_id, r.started_at,
+		(SELECT MIN(h.changed_at) FROM room_status_history h WHERE h.room_id = r.id AND h.status = 'concluded') AS concluded_at,
+		(SELECT MAX(h.changed_at) FROM room_status_history h WHERE h.room_id = r.id AND h.status = 'done') AS ended_at,
+		(SELECT COUNT(*) FROM chat_logs l WHERE l.room_id = r.id AND NOT l.is_summary) AS message_count,
+		COALESCE(r.conclusion, '') <> '' AS has_conclusion
+	FROM rooms r
+	WHERE r.started_at IS NOT NULL`
+
+// MaterializedView は RoomSummarySQL を保存したマテリアライズドビューの名前です。
+const MaterializedView = "room_analytics"
+
+// Refresher はマテリアライズドビュー room_analytics を定期的に更新します。
+type Refresher struct {
+	db       *sql.DB
+	interval time.Duration
+}
+
+func NewRefresher(db *sql.DB, interval time.Duration) *Refresher {
+	return &Refresher{db: db, interval: interval}
+}
+
+// NewRefresherFromEnv は ANALYTICS_REFRESH_INTERVAL（例: 15m）の間隔でビューを更新する Refresher を返します。
+// 未設定の場合は nil を返し、週ごとの傾向は毎回テーブルから集計します。
+func NewRefresherFromEnv(db *sql.DB) (*Refresher, error) {
+	v := os.Getenv("ANALYTICS_REFRESH_INTERVAL")
+	if v == "" {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(v)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("ANALYTICS_REFRESH_INTERVAL が不正です: %q", v)
+	}
+	return NewRefresher(db, interval), nil
+}
+
+// Refresh はビューを更新します。更新中も読み込めるよう CONCURRENTLY で更新します。
+func (r *Refresher) Refresh(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY `+MaterializedView)
+	return err
+}
+
+// Run は起動時と interval ごとに、ctx がキャンセルされるまでビューを更新します。
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("分析用のマテリアライズドビューの更新に失敗しました: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGini(t *testing.T) {
+	assert.Equal(t, 0.0, Gini(nil))
+	assert.Equal(t, 0.0, Gini([]int{5}))
+	assert.Equal(t, 0.0, Gini([]int{0, 0, 0}))
+	assert.InDelta(t, 0.0, Gini([]int{4, 4, 4, 4}), 1e-9)
+	// 一人だけが話した場合は (n-1)/n
+	assert.InDelta(t, 0.75, Gini([]int{0, 12, 0, 0}), 1e-9)
+	assert.InDelta(t, 0.25, Gini([]int{1, 3}), 1e-9)
+}
+
+func TestWeekStart(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	// 日曜日の UTC 20時は東京では月曜日
+	got := WeekStart(time.Date(2024, 1, 7, 20, 0, 0, 0, time.UTC), tokyo)
+	assert.Equal(t, time.Date(2024, 1, 8, 0, 0, 0, 0, tokyo), got)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), WeekStart(time.Date(2024, 1, 7, 20, 0, 0, 0, time.UTC), time.UTC))
+}
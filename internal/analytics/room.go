@@ -0,0 +1,145 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// Room は会議室の分析を返します。会議室が見つからない場合は sql.ErrNoRows を返します。
+// 発言の頻度は bucket ごとに数え、メッセージがない間隔も 0 件として含めます。
+func Room(ctx context.Context, db *sql.DB, roomID string, bucket time.Duration) (models.RoomAnalytics, error) {
+	result := models.RoomAnalytics{RoomID: roomID, MessageRate: []models.MessageRateBucket{}}
+	var anonymous bool
+	var startedAt, concludedAt, endedAt sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT r.status, r.anonymous, r.started_at,
+			(SELECT MIN(h.changed_at) FROM room_status_history h WHERE h.room_id = r.id AND h.status = 'concluded'),
+			(SELECT MAX(h.changed_at) FROM room_status_history h WHERE h.room_id = r.id AND h.status = 'done'),
+			(SELECT COUNT(*) FROM chat_logs l WHERE l.room_id = r.id AND NOT l.is_summary),
+			(SELECT COUNT(*) FROM message_reactions mr JOIN chat_logs l ON l.id = mr.message_id
+				WHERE l.room_id = r.id AND mr.reaction_type = 'sorena')
+		FROM rooms r WHERE r.id = $1`, roomID).
+		Scan(&result.Status, &anonymous, &startedAt, &concludedAt, &endedAt, &result.MessageCount, &result.SorenaCount)
+	if err != nil {
+		return result, err
+	}
+	if startedAt.Valid {
+		result.StartedAt = &startedAt.Time
+		result.ConcludedAt, result.TimeToConclusionMinutes = since(startedAt.Time, concludedAt)
+		result.EndedAt, result.DurationMinutes = since(startedAt.Time, endedAt)
+	}
+
+	participants, err := roomParticipants(ctx, db, roomID)
+	if err != nil {
+		return result, err
+	}
+	counts := make([]int, len(participants))
+	for i := range participants {
+		counts[i] = participants[i].MessageCount
+		if result.MessageCount > 0 {
+			participants[i].SpeakingShare = float64(participants[i].MessageCount) / float64(result.MessageCount)
+		}
+	}
+	result.Gini = Gini(counts)
+	// 匿名モードの会議室では誰がどれだけ発言したかを返さず、偏りの大きさだけを返す
+	if !anonymous {
+		result.Participants = participants
+	}
+
+	result.MessageRate, err = messageRate(ctx, db, roomID, bucket, startedAt)
+	return result, err
+}
+
+// since は開始から at までの時間（分）を返します。at が開始より前の場合（再開した会議室など）は時間を返しません。
+func since(start time.Time, at sql.NullTime) (*time.Time, *float64) {
+	if !at.Valid {
+		return nil, nil
+	}
+	if at.Time.Before(start) {
+		return &at.Time, nil
+	}
+	minutes := at.Time.Sub(start).Minutes()
+	return &at.Time, &minutes
+}
+
+// roomParticipants は参加者とメッセージを送ったユーザーごとの発言を、メッセージの多い順に返します。
+// 発言していない参加者も 0 件として含めます（偏りの計算に必要なため）。
+func roomParticipants(ctx context.Context, db *sql.DB, roomID string) ([]models.ParticipantActivity, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.user_id, u.user_name, COUNT(l.id), COALESCE(SUM(char_length(l.message)), 0), COALESCE(SUM(s.n), 0)
+		FROM (
+			SELECT user_id FROM participants WHERE room_id = $1
+			UNION SELECT user_id FROM chat_logs WHERE room_id = $1 AND user_id IS NOT NULL AND NOT is_summary
+		) m
+		JOIN users u ON u.id = m.user_id
+		LEFT JOIN chat_logs l ON l.room_id = $1 AND l.user_id = m.user_id AND NOT l.is_summary
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS n FROM message_reactions mr WHERE mr.message_id = l.id AND mr.reaction_type = 'sorena'
+		) s ON TRUE
+		GROUP BY m.user_id, u.user_name
+		ORDER BY COUNT(l.id) DESC, m.user_id`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	participants := []models.ParticipantActivity{}
+	for rows.Next() {
+		var p models.ParticipantActivity
+		if err := rows.Scan(&p.UserID, &p.UserName, &p.MessageCount, &p.CharacterCount, &p.SorenaReceived); err != nil {
+			return nil, err
+		}
+		participants = append(participants, p)
+	}
+	return participants, rows.Err()
+}
+
+// messageRate は bucket ごとのメッセージ数を、開始（開始前のメッセージがあればその時点）から最後のメッセージまで返します。
+func messageRate(ctx context.Context, db *sql.DB, roomID string, bucket time.Duration, startedAt sql.NullTime) ([]models.MessageRateBucket, error) {
+	seconds := int64(bucket / time.Second)
+	rows, err := db.QueryContext(ctx, `
+		SELECT to_timestamp(floor(extract(epoch FROM l.created_at) / $2) * $2) AS bucket, COUNT(*)
+		FROM chat_logs l
+		WHERE l.room_id = $1 AND NOT l.is_summary
+		GROUP BY bucket
+		ORDER BY bucket`, roomID, seconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[int64]int{}
+	var first, last int64
+	for rows.Next() {
+		var start time.Time
+		var n int
+		if err := rows.Scan(&start, &n); err != nil {
+			return nil, err
+		}
+		key := start.Unix()
+		if len(counts) == 0 || key < first {
+			first = key
+		}
+		if len(counts) == 0 || key > last {
+			last = key
+		}
+		counts[key] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	buckets := []models.MessageRateBucket{}
+	if len(counts) == 0 {
+		return buckets, nil
+	}
+	if startedAt.Valid {
+		if key := startedAt.Time.Unix() / seconds * seconds; key < first {
+			first = key
+		}
+	}
+	for key := first; key <= last; key += seconds {
+		buckets = append(buckets, models.MessageRateBucket{Start: time.Unix(key, 0).UTC(), MessageCount: counts[key]})
+	}
+	return buckets, nil
+}
@@ -0,0 +1,76 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// WeekStart は t を含む週の初め（loc の月曜日の 0 時）を返します。PostgreSQL の date_trunc('week', ...) と同じ区切りです。
+func WeekStart(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+}
+
+// Trends は from の週から weeks 週分の、週ごとの会議の傾向を古い順に返します。会議がない週も含めます。
+// condition は rooms r を絞り込む条件で、プレースホルダーは $3 から始めます（$1 はタイムゾーン、$2 は from）。
+// materialized が true の場合はマテリアライズドビューから集計します（最後に更新した時点の値になります）。
+func Trends(ctx context.Context, db *sql.DB, materialized bool, loc *time.Location, from time.Time, weeks int, condition string, args ...interface{}) ([]models.WeeklyTrend, error) {
+	source := RoomSummarySQL
+	if materialized {
+		source = `SELECT room_id, started_at, concluded_at, ended_at, message_count, has_conclusion FROM ` + MaterializedView
+	}
+	args = append([]interface{}{loc.String(), from}, args...)
+	rows, err := db.QueryContext(ctx, `
+		WITH s AS (`+source+`)
+		SELECT to_char(date_trunc('week', s.started_at AT TIME ZONE $1), 'YYYY-MM-DD') AS week,
+			COUNT(*),
+			(AVG(EXTRACT(EPOCH FROM s.ended_at - s.started_at) / 60) FILTER (WHERE s.ended_at > s.started_at))::float8,
+			AVG(s.has_conclusion::int)::float8,
+			(AVG(EXTRACT(EPOCH FROM s.concluded_at - s.started_at) / 60) FILTER (WHERE s.concluded_at >= s.started_at))::float8,
+			SUM(s.message_count)::bigint
+		FROM s JOIN rooms r ON r.id = s.room_id
+		WHERE s.started_at >= $2 AND `+condition+`
+		GROUP BY week
+		ORDER BY week`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	byWeek := map[string]models.WeeklyTrend{}
+	for rows.Next() {
+		var t models.WeeklyTrend
+		var duration, rate, toConclusion sql.NullFloat64
+		if err := rows.Scan(&t.WeekStart, &t.RoomsHeld, &duration, &rate, &toConclusion, &t.MessageCount); err != nil {
+			return nil, err
+		}
+		t.AvgDurationMinutes = nullFloat(duration)
+		t.ConclusionRate = nullFloat(rate)
+		t.AvgTimeToConclusionMins = nullFloat(toConclusion)
+		byWeek[t.WeekStart] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	trends := make([]models.WeeklyTrend, weeks)
+	for i := range trends {
+		week := from.AddDate(0, 0, 7*i).Format(time.DateOnly)
+		t, ok := byWeek[week]
+		if !ok {
+			t = models.WeeklyTrend{WeekStart: week}
+		}
+		trends[i] = t
+	}
+	return trends, nil
+}
+
+func nullFloat(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
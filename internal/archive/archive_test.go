@@ -0,0 +1,204 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleArchive() models.RoomArchive {
+	at := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	host, item := "u001", "item1"
+	return models.RoomArchive{
+		Version:    models.ArchiveVersion,
+		ExportedAt: at.Add(time.Hour),
+		Room: models.ArchiveRoom{
+			ID: "r001", Title: "週次ふりかえり", Status: "done", Conclusion: "隔週にする",
+			CreatedBy: &host, TimeZone: "Asia/Tokyo", PromptVariant: "default", Tags: []string{"定例"},
+			CurrentAgendaItemID: &item, CreatedAt: at,
+		},
+		Users: []models.ArchiveUser{{ID: "u001", Name: "田中太郎"}, {ID: "g001", Name: "ゲスト", IsGuest: true}},
+		Participants: []models.ArchiveParticipant{
+			{UserID: "u001", JoinedAt: at},
+			{UserID: "g001", JoinedAt: at},
+		},
+		ReactionTypes: []models.ArchiveReactionType{{Key: "sorena", Label: "それな", Emoji: "🙌"}},
+		Agenda:        []models.ArchiveAgendaItem{{ID: item, Position: 1, Title: "進め方"}},
+		Messages: []models.ArchiveMessage{
+			{ID: "log1", UserID: &host, Message: "進め方を変えたい", AgendaItemID: &item, CreatedAt: at},
+			{ID: "log2", Message: "隔週にする案が出ています", IsSummary: true, CreatedAt: at.Add(time.Minute)},
+		},
+		Reactions: []models.ArchiveReaction{{MessageID: "log1", UserID: "g001", Type: "sorena", CreatedAt: at}},
+	}
+}
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Encode(&buf, format, sampleArchive()))
+
+			decoded, err := Decode(&buf, format)
+			require.NoError(t, err)
+			assert.Equal(t, sampleArchive(), decoded)
+		})
+	}
+}
+
+func TestEncode_NDJSONOneRecordPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, FormatNDJSON, sampleArchive()))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// 会議室・ユーザー2・参加者2・リアクションの定義1・議題1・メッセージ2・リアクション1
+	assert.Len(t, lines, 10)
+	assert.True(t, strings.HasPrefix(lines[0], `{"type":"room","version":1,`))
+	assert.True(t, strings.HasPrefix(lines[9], `{"type":"reaction",`))
+}
+
+func TestDecode_Rejects(t *testing.T) {
+	_, err := Decode(strings.NewReader(`{"version":2,"room":{}}`), FormatJSON)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	_, err = Decode(strings.NewReader(`{"type":"user","data":{"id":"u001"}}`), FormatNDJSON)
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+
+	_, err = Decode(strings.NewReader(""), FormatNDJSON)
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+
+	_, err = Decode(strings.NewReader("{}"), "xml")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(sampleArchive()))
+
+	tests := map[string]func(a *models.RoomArchive){
+		"発言者がいない":      func(a *models.RoomArchive) { missing := "u999"; a.Messages[0].UserID = &missing },
+		"議題がない":        func(a *models.RoomArchive) { a.Agenda = nil },
+		"IDが長すぎる":      func(a *models.RoomArchive) { a.Room.ID = "abcdefg" },
+		"ステータスが不正":     func(a *models.RoomArchive) { a.Room.Status = "archived" },
+		"リアクションの定義がない": func(a *models.RoomArchive) { a.ReactionTypes = nil },
+		"メッセージIDの重複":   func(a *models.RoomArchive) { a.Messages[1].ID = "log1" },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := sampleArchive()
+			mutate(&a)
+			assert.ErrorIs(t, Validate(a), ErrInvalidArchive)
+		})
+	}
+}
+
+func TestImport_RemapsConflictingIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT created_by FROM rooms WHERE id = \$1 FOR UPDATE`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	// 同じ名前のユーザーはそのまま使い、IDが別の人に使われているゲストは新しいIDで作成する
+	mock.ExpectQuery(`SELECT user_name, is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"user_name", "is_guest"}).AddRow("田中太郎", false))
+	mock.ExpectQuery(`SELECT user_name, is_guest FROM users`).WithArgs("g001").
+		WillReturnRows(sqlmock.NewRows([]string{"user_name", "is_guest"}).AddRow("別の人", false))
+	mock.ExpectExec(`INSERT INTO users`).WithArgs(sqlmock.AnyArg(), "ゲスト", true).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO reaction_types`).WithArgs("sorena", "それな", "🙌").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO rooms`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO conclusion_versions`).WithArgs(sqlmock.AnyArg(), "隔週にする").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO room_tags`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO agenda_items`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE rooms SET current_agenda_item_id`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO participants`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO participants`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO chat_logs`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO chat_logs`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO message_reactions`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := Import(context.Background(), db, sampleArchive(), "", Importer{UserID: "u001"})
+	require.NoError(t, err)
+
+	assert.True(t, result.Remapped)
+	assert.NotEqual(t, "r001", result.RoomID)
+	assert.Len(t, result.RoomID, 6)
+	assert.Equal(t, "u001", result.UserIDs["u001"])
+	assert.NotEqual(t, "g001", result.UserIDs["g001"])
+	assert.Equal(t, 2, result.Participants)
+	assert.Equal(t, 2, result.Messages)
+	assert.Equal(t, 1, result.Reactions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImport_FailOnConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT created_by FROM rooms WHERE id = \$1 FOR UPDATE`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	mock.ExpectRollback()
+
+	_, err = Import(context.Background(), db, sampleArchive(), models.ArchiveConflictFail, Importer{UserID: "u001"})
+	assert.ErrorIs(t, err, ErrRoomExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = Import(context.Background(), db, sampleArchive(), "merge", Importer{UserID: "u001"})
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+}
+
+func TestImport_ReplaceOnlyByHost(t *testing.T) {
+	tests := map[string]struct {
+		by      Importer
+		replace bool
+	}{
+		"ホスト": {Importer{UserID: "u001"}, true},
+		"rooms:write のサービスアカウント": {Importer{UserID: "bot00001", ReplaceAny: true}, true},
+		"ホストでないユーザー":             {Importer{UserID: "u002"}, false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT created_by FROM rooms WHERE id = \$1 FOR UPDATE`).WithArgs("r001").
+				WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+			if tt.replace {
+				mock.ExpectExec(`DELETE FROM rooms WHERE id = \$1`).WithArgs("r001").WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			// 以降の書き込みは置き換えと振り直しで同じため、失敗させて打ち切る
+			mock.ExpectQuery(`SELECT user_name, is_guest FROM users`).WithArgs("u001").WillReturnError(errors.New("stop"))
+			mock.ExpectRollback()
+
+			_, err = Import(context.Background(), db, sampleArchive(), models.ArchiveConflictReplace, tt.by)
+			assert.EqualError(t, err, "stop")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	a := sampleArchive()
+	Redact(&a)
+
+	for _, m := range a.Messages {
+		assert.Nil(t, m.UserID, m.ID)
+	}
+	assert.Empty(t, a.Reactions)
+	assert.Len(t, a.Messages, 2)
+	assert.Len(t, a.Participants, 2)
+	// 伏せたアーカイブもそのまま取り込める
+	assert.NoError(t, Validate(a))
+}
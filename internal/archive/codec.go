@@ -0,0 +1,189 @@
+// Package archive は会議室を環境間で移したり保管したりするためのアーカイブを書き出し・取り込みます。
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// アーカイブの形式
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// NDJSON の各行の種類
+const (
+	recordRoom         = "room"
+	recordUser         = "user"
+	recordParticipant  = "participant"
+	recordReactionType = "reaction_type"
+	recordAgendaItem   = "agenda_item"
+	recordMessage      = "message"
+	recordReaction     = "reaction"
+)
+
+var (
+	// ErrUnknownFormat は対応していない形式を指定された場合のエラーです。
+	ErrUnknownFormat = errors.New("unknown archive format")
+	// ErrInvalidArchive はアーカイブの内容が壊れている場合のエラーです。
+	ErrInvalidArchive = errors.New("invalid archive")
+	// ErrUnsupportedVersion はこのサーバーが読めないバージョンのアーカイブの場合のエラーです。
+	ErrUnsupportedVersion = errors.New("unsupported archive version")
+)
+
+// record は NDJSON の一行です。最初の行は会議室で、バージョンと書き出した日時を持ちます。
+type record struct {
+	Type       string          `json:"type"`
+	Version    int             `json:"version,omitempty"`
+	ExportedAt *time.Time      `json:"exported_at,omitempty"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// ContentType は形式のContent-Typeを返します。
+func ContentType(format string) (string, error) {
+	switch format {
+	case FormatJSON:
+		return "application/json; charset=utf-8", nil
+	case FormatNDJSON:
+		return "application/x-ndjson", nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Encode はアーカイブを指定した形式で w に書き出します。
+// NDJSON は一行に一件ずつ書き出すため、メッセージの多い会議室でも行単位で処理できます。
+func Encode(w io.Writer, format string, a models.RoomArchive) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(a)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		write := func(typ string, v interface{}) error {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			return enc.Encode(record{Type: typ, Data: data})
+		}
+		room, err := json.Marshal(a.Room)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(record{Type: recordRoom, Version: a.Version, ExportedAt: &a.ExportedAt, Data: room}); err != nil {
+			return err
+		}
+		for _, u := range a.Users {
+			if err := write(recordUser, u); err != nil {
+				return err
+			}
+		}
+		for _, p := range a.Participants {
+			if err := write(recordParticipant, p); err != nil {
+				return err
+			}
+		}
+		for _, t := range a.ReactionTypes {
+			if err := write(recordReactionType, t); err != nil {
+				return err
+			}
+		}
+		for _, item := range a.Agenda {
+			if err := write(recordAgendaItem, item); err != nil {
+				return err
+			}
+		}
+		for _, m := range a.Messages {
+			if err := write(recordMessage, m); err != nil {
+				return err
+			}
+		}
+		for _, r := range a.Reactions {
+			if err := write(recordReaction, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return ErrUnknownFormat
+}
+
+// Decode は指定した形式のアーカイブを読み込みます。
+func Decode(r io.Reader, format string) (models.RoomArchive, error) {
+	var a models.RoomArchive
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&a); err != nil {
+			return a, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+	case FormatNDJSON:
+		if err := decodeNDJSON(r, &a); err != nil {
+			return a, err
+		}
+	default:
+		return a, ErrUnknownFormat
+	}
+	if a.Version != models.ArchiveVersion {
+		return a, fmt.Errorf("%w: %d", ErrUnsupportedVersion, a.Version)
+	}
+	return a, nil
+}
+
+func decodeNDJSON(r io.Reader, a *models.RoomArchive) error {
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var rec record
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) && line > 1 {
+				return nil
+			}
+			return fmt.Errorf("%w: %d行目: %w", ErrInvalidArchive, line, err)
+		}
+		if (line == 1) != (rec.Type == recordRoom) {
+			return fmt.Errorf("%w: 最初の行は会議室のみです（%d行目: %q）", ErrInvalidArchive, line, rec.Type)
+		}
+
+		var err error
+		switch rec.Type {
+		case recordRoom:
+			a.Version = rec.Version
+			if rec.ExportedAt != nil {
+				a.ExportedAt = *rec.ExportedAt
+			}
+			err = json.Unmarshal(rec.Data, &a.Room)
+		case recordUser:
+			err = appendRecord(rec.Data, &a.Users)
+		case recordParticipant:
+			err = appendRecord(rec.Data, &a.Participants)
+		case recordReactionType:
+			err = appendRecord(rec.Data, &a.ReactionTypes)
+		case recordAgendaItem:
+			err = appendRecord(rec.Data, &a.Agenda)
+		case recordMessage:
+			err = appendRecord(rec.Data, &a.Messages)
+		case recordReaction:
+			err = appendRecord(rec.Data, &a.Reactions)
+		default:
+			err = fmt.Errorf("不明な種類 %q", rec.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %d行目: %v", ErrInvalidArchive, line, err)
+		}
+	}
+}
+
+func appendRecord[T any](data json.RawMessage, list *[]T) error {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*list = append(*list, v)
+	return nil
+}
@@ -0,0 +1,351 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// ErrRoomExists は on_conflict=fail で同じIDの会議室があった場合のエラーです。
+var ErrRoomExists = errors.New("room already exists")
+
+// IDの長さの上限（rooms.id・users.id・agenda_items.id / chat_logs.id）
+const (
+	maxRoomIDLength = 6
+	maxUserIDLength = 10
+	maxItemIDLength = 21
+)
+
+var roomStatuses = map[string]bool{"not started": true, "inprogress": true, "concluded": true, "done": true}
+
+// Importer は取り込みを実行する主体です。既存の会議室を置き換えてよいかの判断に使います。
+type Importer struct {
+	UserID     string // 取り込むユーザーのID
+	ReplaceAny bool   // rooms:write スコープのサービスアカウントはどの会議室も置き換えられる
+}
+
+// Import はアーカイブを一つのトランザクションで取り込みます。
+//
+// 会議室IDが使われていない場合はアーカイブのIDのまま取り込みます。使われている場合は onConflict に従い、
+// remap では会議室・議題・メッセージに新しいIDを割り当て、replace では既存の会議室を削除して置き換え、fail では ErrRoomExists を返します。
+// replace は既存の会議室のホストか by.ReplaceAny の場合のみ行い、それ以外は他人の会議室を消さないよう remap として取り込みます。
+// ユーザーは同じIDで同じ名前のユーザーがいればそのまま使い、IDが別の人に使われている場合は新しいIDで作成します。
+func Import(ctx context.Context, db *sql.DB, a models.RoomArchive, onConflict string, by Importer) (models.ArchiveImportResult, error) {
+	result := models.ArchiveImportResult{OriginalRoomID: a.Room.ID, RoomID: a.Room.ID, UserIDs: make(map[string]string)}
+	if onConflict == "" {
+		onConflict = models.ArchiveConflictRemap
+	}
+	if onConflict != models.ArchiveConflictRemap && onConflict != models.ArchiveConflictFail && onConflict != models.ArchiveConflictReplace {
+		return result, fmt.Errorf("%w: on_conflict は remap / fail / replace のいずれかです", ErrInvalidArchive)
+	}
+	if err := Validate(a); err != nil {
+		return result, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	var existingHost sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT created_by FROM rooms WHERE id = $1 FOR UPDATE`, a.Room.ID).Scan(&existingHost)
+	if err == nil && onConflict == models.ArchiveConflictReplace {
+		ok, err := mayReplace(ctx, tx, a.Room.ID, existingHost, by)
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			onConflict = models.ArchiveConflictRemap
+		}
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return result, err
+	case onConflict == models.ArchiveConflictFail:
+		return result, ErrRoomExists
+	case onConflict == models.ArchiveConflictReplace:
+		if _, err := tx.ExecContext(ctx, `DELETE FROM rooms WHERE id = $1`, a.Room.ID); err != nil {
+			return result, err
+		}
+		result.Replaced = true
+	default:
+		if result.RoomID, err = gonanoid.Generate("0123456789abcdefghijklmnopqrstuvwxyz", 6); err != nil {
+			return result, err
+		}
+		result.Remapped = true
+	}
+
+	// 会議室を別のIDで取り込む場合は、同じアーカイブを取り込み済みでも衝突しないよう議題とメッセージのIDも付け直す
+	itemIDs := make(map[string]string)
+	mapID := func(id string) (string, error) {
+		if !result.Remapped {
+			return id, nil
+		}
+		if mapped, ok := itemIDs[id]; ok {
+			return mapped, nil
+		}
+		mapped, err := gonanoid.New()
+		if err != nil {
+			return "", err
+		}
+		itemIDs[id] = mapped
+		return mapped, nil
+	}
+
+	for _, u := range a.Users {
+		id, err := importUser(ctx, tx, u)
+		if err != nil {
+			return result, err
+		}
+		result.UserIDs[u.ID] = id
+	}
+	for _, t := range a.ReactionTypes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO reaction_types (key, label, emoji) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING`,
+			t.Key, t.Label, t.Emoji); err != nil {
+			return result, err
+		}
+	}
+
+	room := a.Room
+	var createdBy *string
+	if room.CreatedBy != nil {
+		id := result.UserIDs[*room.CreatedBy]
+		createdBy = &id
+	}
+	if room.TimeZone == "" {
+		room.TimeZone = "Asia/Tokyo"
+	}
+	if room.PromptVariant == "" {
+		room.PromptVariant = "default"
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rooms (id, title, description, conclusion, status, initial_question, max_participants, allow_guests, anonymous,
+		                   created_by, scheduled_start_at, duration_minutes, time_zone, prompt_variant, started_at, created_at, conclusion_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, CASE WHEN $4 <> '' THEN 1 ELSE 0 END)`,
+		result.RoomID, room.Title, room.Description, room.Conclusion, room.Status, room.InitialQuestion, room.MaxParticipants, room.AllowGuests, room.Anonymous,
+		createdBy, room.ScheduledStartAt, room.DurationMinutes, room.TimeZone, room.PromptVariant, room.StartedAt, room.CreatedAt)
+	if err != nil {
+		return result, err
+	}
+	// 結論の版の履歴は書き出さないため、取り込んだ結論を最初の版にする
+	if room.Conclusion != "" {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO conclusion_versions (room_id, version, conclusion) VALUES ($1, 1, $2)`, result.RoomID, room.Conclusion); err != nil {
+			return result, err
+		}
+	}
+	for _, tag := range room.Tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO room_tags (room_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING`, result.RoomID, tag); err != nil {
+			return result, err
+		}
+	}
+	for i, key := range room.ReactionTypes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO room_reaction_types (room_id, reaction_key, sort_order) VALUES ($1, $2, $3)`,
+			result.RoomID, key, (i+1)*10); err != nil {
+			return result, err
+		}
+	}
+
+	for _, item := range a.Agenda {
+		id, err := mapID(item.ID)
+		if err != nil {
+			return result, err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO agenda_items (id, room_id, position, title, description, timebox_minutes, initial_question, conclusion, started_at, ended_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10)`,
+			id, result.RoomID, item.Position, item.Title, item.Description, item.TimeboxMinutes, item.InitialQuestion, item.Conclusion, item.StartedAt, item.EndedAt)
+		if err != nil {
+			return result, err
+		}
+	}
+	if room.CurrentAgendaItemID != nil {
+		id, err := mapID(*room.CurrentAgendaItemID)
+		if err != nil {
+			return result, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE rooms SET current_agenda_item_id = $1 WHERE id = $2`, id, result.RoomID); err != nil {
+			return result, err
+		}
+	}
+
+	for _, p := range a.Participants {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO participants (room_id, user_id, joined_at, left_at, last_seen_at) VALUES ($1, $2, $3, $4, $5)`,
+			result.RoomID, result.UserIDs[p.UserID], p.JoinedAt, p.LeftAt, p.LastSeenAt)
+		if err != nil {
+			return result, err
+		}
+		result.Participants++
+	}
+	for _, m := range a.Messages {
+		id, err := mapID(m.ID)
+		if err != nil {
+			return result, err
+		}
+		var userID, agendaItemID *string
+		if m.UserID != nil {
+			mapped := result.UserIDs[*m.UserID]
+			userID = &mapped
+		}
+		if m.AgendaItemID != nil {
+			mapped, err := mapID(*m.AgendaItemID)
+			if err != nil {
+				return result, err
+			}
+			agendaItemID = &mapped
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO chat_logs (id, room_id, user_id, message, is_summary, agenda_item_id, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			id, result.RoomID, userID, m.Message, m.IsSummary, agendaItemID, m.CreatedAt)
+		if err != nil {
+			return result, err
+		}
+		result.Messages++
+	}
+	for _, r := range a.Reactions {
+		messageID, err := mapID(r.MessageID)
+		if err != nil {
+			return result, err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO message_reactions (message_id, user_id, reaction_type, created_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING`,
+			messageID, result.UserIDs[r.UserID], r.Type, r.CreatedAt)
+		if err != nil {
+			return result, err
+		}
+		result.Reactions++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// importUser はアーカイブのユーザーに対応する取り込み先のユーザーIDを返します。必要に応じてユーザーを作成します。
+func importUser(ctx context.Context, tx *sql.Tx, u models.ArchiveUser) (string, error) {
+	var name string
+	var isGuest bool
+	err := tx.QueryRowContext(ctx, `SELECT user_name, is_guest FROM users WHERE id = $1`, u.ID).Scan(&name, &isGuest)
+	switch {
+	case err == nil && name == u.Name && isGuest == u.IsGuest:
+		return u.ID, nil
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return "", err
+	}
+
+	id := u.ID
+	if err == nil {
+		// IDが別のユーザーに使われている
+		if id, err = gonanoid.Generate("0123456789abcdefghijklmnopqrstuvwxyz", 8); err != nil {
+			return "", err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO users (id, user_name, is_guest) VALUES ($1, $2, $3)`, id, u.Name, u.IsGuest); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Validate はアーカイブの中の参照がそろっているかを確かめます。
+func Validate(a models.RoomArchive) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidArchive, fmt.Sprintf(format, args...))
+	}
+	if a.Version != models.ArchiveVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, a.Version)
+	}
+	if a.Room.ID == "" || len(a.Room.ID) > maxRoomIDLength {
+		return invalid("会議室IDは1〜%d文字です", maxRoomIDLength)
+	}
+	if a.Room.Title == "" {
+		return invalid("会議室のタイトルがありません")
+	}
+	if !roomStatuses[a.Room.Status] {
+		return invalid("会議室のステータス %q は使えません", a.Room.Status)
+	}
+
+	users := make(map[string]bool)
+	for _, u := range a.Users {
+		if u.ID == "" || len(u.ID) > maxUserIDLength || users[u.ID] {
+			return invalid("ユーザーID %q が不正か重複しています", u.ID)
+		}
+		users[u.ID] = true
+	}
+	if a.Room.CreatedBy != nil && !users[*a.Room.CreatedBy] {
+		return invalid("ホスト %q が users にありません", *a.Room.CreatedBy)
+	}
+	for _, p := range a.Participants {
+		if !users[p.UserID] {
+			return invalid("参加者 %q が users にありません", p.UserID)
+		}
+	}
+
+	types := make(map[string]bool)
+	for _, t := range a.ReactionTypes {
+		types[t.Key] = true
+	}
+	for _, key := range a.Room.ReactionTypes {
+		if !types[key] {
+			return invalid("リアクション %q が reaction_types にありません", key)
+		}
+	}
+
+	items := make(map[string]bool)
+	for _, item := range a.Agenda {
+		if item.ID == "" || len(item.ID) > maxItemIDLength || items[item.ID] {
+			return invalid("議題ID %q が不正か重複しています", item.ID)
+		}
+		items[item.ID] = true
+	}
+	if a.Room.CurrentAgendaItemID != nil && !items[*a.Room.CurrentAgendaItemID] {
+		return invalid("現在の議題 %q が agenda にありません", *a.Room.CurrentAgendaItemID)
+	}
+
+	messages := make(map[string]bool)
+	for _, m := range a.Messages {
+		if m.ID == "" || len(m.ID) > maxItemIDLength || messages[m.ID] || items[m.ID] {
+			return invalid("メッセージID %q が不正か重複しています", m.ID)
+		}
+		if m.UserID != nil && !users[*m.UserID] {
+			return invalid("発言者 %q が users にありません", *m.UserID)
+		}
+		if m.AgendaItemID != nil && !items[*m.AgendaItemID] {
+			return invalid("議題 %q が agenda にありません", *m.AgendaItemID)
+		}
+		messages[m.ID] = true
+	}
+	for _, r := range a.Reactions {
+		if !messages[r.MessageID] || !users[r.UserID] || !types[r.Type] {
+			return invalid("リアクション（%s / %s / %s）の参照先がありません", r.MessageID, r.UserID, r.Type)
+		}
+	}
+	return nil
+}
+
+// mayReplace は by が既存の会議室を置き換えてよいかどうかを返します。
+// ホストの判断は requireHost と同じく、ホストがいない会議室では参加中のユーザーをホストとみなします。
+func mayReplace(ctx context.Context, tx *sql.Tx, roomID string, host sql.NullString, by Importer) (bool, error) {
+	if by.ReplaceAny {
+		return true, nil
+	}
+	if by.UserID == "" {
+		return false, nil
+	}
+	if host.Valid {
+		return host.String == by.UserID, nil
+	}
+	var ok bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM participants WHERE room_id = $1 AND user_id = $2 AND left_at IS NULL)`,
+		roomID, by.UserID).Scan(&ok)
+	return ok, err
+}
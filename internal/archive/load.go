@@ -0,0 +1,255 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// Load は会議室とその参加者・議題・チャットログ・リアクションをアーカイブとして読み込みます。
+// 発言者とリアクションしたユーザーもそのまま含めるため、匿名モードの会議室では呼び出し側で Redact を通してください。
+// 会議室が見つからない場合は sql.ErrNoRows を返します。
+func Load(ctx context.Context, db *sql.DB, roomID string, now time.Time) (models.RoomArchive, error) {
+	a := models.RoomArchive{Version: models.ArchiveVersion, ExportedAt: now}
+	if err := loadRoom(ctx, db, roomID, &a.Room); err != nil {
+		return a, err
+	}
+
+	var err error
+	if a.Room.Tags, err = queryStrings(ctx, db, `SELECT tag FROM room_tags WHERE room_id = $1 ORDER BY tag`, roomID); err != nil {
+		return a, err
+	}
+	if a.Room.ReactionTypes, err = queryStrings(ctx, db, `SELECT reaction_key FROM room_reaction_types WHERE room_id = $1 ORDER BY sort_order, reaction_key`, roomID); err != nil {
+		return a, err
+	}
+	if err := loadUsers(ctx, db, roomID, &a); err != nil {
+		return a, err
+	}
+	if err := loadParticipants(ctx, db, roomID, &a); err != nil {
+		return a, err
+	}
+	if err := loadReactionTypes(ctx, db, roomID, &a); err != nil {
+		return a, err
+	}
+	if err := loadAgenda(ctx, db, roomID, &a); err != nil {
+		return a, err
+	}
+	if err := loadMessages(ctx, db, roomID, &a); err != nil {
+		return a, err
+	}
+	if err := loadReactions(ctx, db, roomID, &a); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// Redact は匿名モードの会議室のアーカイブから、発言やリアクションを個人に結び付ける情報を取り除きます。
+// リアクションは誰が付けたかを外すと取り込めないため、件数ごと含めません。
+func Redact(a *models.RoomArchive) {
+	for i := range a.Messages {
+		a.Messages[i].UserID = nil
+	}
+	a.Reactions = nil
+}
+
+func loadRoom(ctx context.Context, db *sql.DB, roomID string, room *models.ArchiveRoom) error {
+	var conclusion, initialQuestion, createdBy, currentItem sql.NullString
+	var maxParticipants, durationMinutes sql.NullInt64
+	var scheduledStartAt, startedAt sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT id, title, description, conclusion, status, initial_question, max_participants, allow_guests, anonymous,
+		       created_by, scheduled_start_at, duration_minutes, time_zone, prompt_variant, current_agenda_item_id, started_at, created_at
+		FROM rooms WHERE id = $1`, roomID).Scan(
+		&room.ID, &room.Title, &room.Description, &conclusion, &room.Status, &initialQuestion, &maxParticipants, &room.AllowGuests, &room.Anonymous,
+		&createdBy, &scheduledStartAt, &durationMinutes, &room.TimeZone, &room.PromptVariant, &currentItem, &startedAt, &room.CreatedAt)
+	if err != nil {
+		return err
+	}
+	room.Conclusion = conclusion.String
+	room.InitialQuestion = initialQuestion.String
+	room.MaxParticipants = nullInt(maxParticipants)
+	room.DurationMinutes = nullInt(durationMinutes)
+	room.CreatedBy = nullString(createdBy)
+	room.CurrentAgendaItemID = nullString(currentItem)
+	room.ScheduledStartAt = nullTime(scheduledStartAt)
+	room.StartedAt = nullTime(startedAt)
+	return nil
+}
+
+// loadUsers は参加者・発言者・リアクションしたユーザー・ホストを読み込みます。
+func loadUsers(ctx context.Context, db *sql.DB, roomID string, a *models.RoomArchive) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, user_name, is_guest FROM users
+		WHERE id IN (
+			SELECT user_id FROM participants WHERE room_id = $1
+			UNION SELECT user_id FROM chat_logs WHERE room_id = $1 AND user_id IS NOT NULL
+			UNION SELECT r.user_id FROM message_reactions r JOIN chat_logs l ON l.id = r.message_id WHERE l.room_id = $1
+			UNION SELECT created_by FROM rooms WHERE id = $1 AND created_by IS NOT NULL
+		)
+		ORDER BY id`, roomID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u models.ArchiveUser
+		if err := rows.Scan(&u.ID, &u.Name, &u.IsGuest); err != nil {
+			return err
+		}
+		a.Users = append(a.Users, u)
+	}
+	return rows.Err()
+}
+
+func loadParticipants(ctx context.Context, db *sql.DB, roomID string, a *models.RoomArchive) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT user_id, joined_at, left_at, last_seen_at FROM participants
+		WHERE room_id = $1 ORDER BY joined_at, user_id`, roomID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.ArchiveParticipant
+		var leftAt, lastSeenAt sql.NullTime
+		if err := rows.Scan(&p.UserID, &p.JoinedAt, &leftAt, &lastSeenAt); err != nil {
+			return err
+		}
+		p.LeftAt = nullTime(leftAt)
+		p.LastSeenAt = nullTime(lastSeenAt)
+		a.Participants = append(a.Participants, p)
+	}
+	return rows.Err()
+}
+
+// loadReactionTypes は会議室で使うリアクションと、使われたリアクションの定義を読み込みます。
+func loadReactionTypes(ctx context.Context, db *sql.DB, roomID string, a *models.RoomArchive) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT key, label, emoji FROM reaction_types
+		WHERE key IN (
+			SELECT reaction_key FROM room_reaction_types WHERE room_id = $1
+			UNION SELECT r.reaction_type FROM message_reactions r JOIN chat_logs l ON l.id = r.message_id WHERE l.room_id = $1
+		)
+		ORDER BY sort_order, key`, roomID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t models.ArchiveReactionType
+		if err := rows.Scan(&t.Key, &t.Label, &t.Emoji); err != nil {
+			return err
+		}
+		a.ReactionTypes = append(a.ReactionTypes, t)
+	}
+	return rows.Err()
+}
+
+func loadAgenda(ctx context.Context, db *sql.DB, roomID string, a *models.RoomArchive) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, position, title, description, timebox_minutes, initial_question, conclusion, started_at, ended_at
+		FROM agenda_items WHERE room_id = $1 ORDER BY position`, roomID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item models.ArchiveAgendaItem
+		var timebox sql.NullInt64
+		var question, conclusion sql.NullString
+		var startedAt, endedAt sql.NullTime
+		if err := rows.Scan(&item.ID, &item.Position, &item.Title, &item.Description, &timebox, &question, &conclusion, &startedAt, &endedAt); err != nil {
+			return err
+		}
+		item.TimeboxMinutes = nullInt(timebox)
+		item.InitialQuestion = question.String
+		item.Conclusion = conclusion.String
+		item.StartedAt = nullTime(startedAt)
+		item.EndedAt = nullTime(endedAt)
+		a.Agenda = append(a.Agenda, item)
+	}
+	return rows.Err()
+}
+
+func loadMessages(ctx context.Context, db *sql.DB, roomID string, a *models.RoomArchive) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, user_id, message, is_summary, agenda_item_id, created_at
+		FROM chat_logs WHERE room_id = $1 ORDER BY created_at, id`, roomID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m models.ArchiveMessage
+		var userID, agendaItemID sql.NullString
+		if err := rows.Scan(&m.ID, &userID, &m.Message, &m.IsSummary, &agendaItemID, &m.CreatedAt); err != nil {
+			return err
+		}
+		m.UserID = nullString(userID)
+		m.AgendaItemID = nullString(agendaItemID)
+		a.Messages = append(a.Messages, m)
+	}
+	return rows.Err()
+}
+
+func loadReactions(ctx context.Context, db *sql.DB, roomID string, a *models.RoomArchive) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT r.message_id, r.user_id, r.reaction_type, r.created_at
+		FROM message_reactions r
+		JOIN chat_logs l ON l.id = r.message_id
+		WHERE l.room_id = $1
+		ORDER BY r.created_at, r.message_id, r.user_id`, roomID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r models.ArchiveReaction
+		if err := rows.Scan(&r.MessageID, &r.UserID, &r.Type, &r.CreatedAt); err != nil {
+			return err
+		}
+		a.Reactions = append(a.Reactions, r)
+	}
+	return rows.Err()
+}
+
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+func nullString(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+)
+
+// 管理者トークンの最短の長さ（推測されにくい値を求める）
+const minAdminTokenLength = 32
+
+// AdminToken はサービスアカウントと API キーを管理するための管理者トークンです。
+// 最初の API キーを発行するために使い、以降は service-accounts:manage スコープのキーでも管理できます。
+type AdminToken struct {
+	hash [sha256.Size]byte
+}
+
+func NewAdminToken(token string) *AdminToken {
+	return &AdminToken{hash: sha256.Sum256([]byte(token))}
+}
+
+// NewAdminTokenFromEnv は ADMIN_API_TOKEN から管理者トークンを読み込みます。
+// 未設定の場合は nil を返し、管理者トークンでは認証しません。
+func NewAdminTokenFromEnv() (*AdminToken, error) {
+	token := os.Getenv("ADMIN_API_TOKEN")
+	if token == "" {
+		return nil, nil
+	}
+	if len(token) < minAdminTokenLength {
+		return nil, fmt.Errorf("ADMIN_API_TOKEN must be at least %d characters", minAdminTokenLength)
+	}
+	return NewAdminToken(token), nil
+}
+
+// Verify はトークンが管理者トークンと一致するかどうかを返します。
+func (a *AdminToken) Verify(token string) bool {
+	if a == nil {
+		return false
+	}
+	sum := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare(sum[:], a.hash[:]) == 1
+}
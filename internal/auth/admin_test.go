@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminToken_Verify(t *testing.T) {
+	admin := NewAdminToken("admin-token-0123456789abcdefghijklmnop")
+	assert.True(t, admin.Verify("admin-token-0123456789abcdefghijklmnop"))
+	assert.False(t, admin.Verify("admin-token-0123456789abcdefghijklmnoq"))
+	assert.False(t, admin.Verify(""))
+
+	// 未設定の場合は何とも一致しない
+	var unset *AdminToken
+	assert.False(t, unset.Verify(""))
+}
+
+func TestNewAdminTokenFromEnv(t *testing.T) {
+	t.Setenv("ADMIN_API_TOKEN", "")
+	admin, err := NewAdminTokenFromEnv()
+	require.NoError(t, err)
+	assert.Nil(t, admin)
+
+	t.Setenv("ADMIN_API_TOKEN", "short")
+	_, err = NewAdminTokenFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("ADMIN_API_TOKEN", "admin-token-0123456789abcdefghijklmnop")
+	admin, err = NewAdminTokenFromEnv()
+	require.NoError(t, err)
+	assert.True(t, admin.Verify("admin-token-0123456789abcdefghijklmnop"))
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+const apiKeyPrefix = "elmo"
+
+// APIキーに付与できるスコープ
+const (
+	ScopeRoomsRead      = "rooms:read"
+	ScopeRoomsWrite     = "rooms:write"
+	ScopeResultsRead    = "results:read"
+	ScopeWebhooksManage = "webhooks:manage" // Webhook の購読の管理
+
+	ScopeReactionTypesManage   = "reaction-types:manage"   // すべての会議室で使えるリアクションの種類の追加
+	ScopeTemplatesManage       = "templates:manage"        // すべてのテンプレートの追加・変更・削除
+	ScopeServiceAccountsManage = "service-accounts:manage" // サービスアカウントと API キーの管理
+)
+
+// AllScopes は付与可能なスコープの一覧です。
+var AllScopes = []string{
+	ScopeRoomsRead, ScopeRoomsWrite, ScopeResultsRead, ScopeWebhooksManage, ScopeReactionTypesManage, ScopeTemplatesManage,
+	ScopeServiceAccountsManage,
+}
+
+var ErrRevokedKey = errors.New("api key revoked")
+
+// ValidScope はスコープが付与可能なものかどうかを返します。
+func ValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAPIKey は新しいAPIキーを生成します。平文のキーは呼び出し元に一度だけ返し、保存するのはハッシュのみです。
+// キーは "elmo_<キーID>_<シークレット>" の形式です。
+func NewAPIKey() (keyID, plaintext, hash string, err error) {
+	keyID, err = gonanoid.Generate("0123456789abcdefghijklmnopqrstuvwxyz", 12)
+	if err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return keyID, apiKeyPrefix + "_" + keyID + "_" + encoded, hashSecret(encoded), nil
+}
+
+// IsAPIKey はトークンがAPIキーの形式かどうかを返します。
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix+"_")
+}
+
+func parseAPIKey(token string) (keyID, secret string, ok bool) {
+	parts := strings.SplitN(token, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeys はデータベースに保存されたAPIキーでリクエストを認証します。
+type APIKeys struct {
+	db *sql.DB
+}
+
+func NewAPIKeys(db *sql.DB) *APIKeys {
+	return &APIKeys{db: db}
+}
+
+// Authenticate はAPIキーを検証し、対応するサービスアカウントを返します。最終使用日時も更新します。
+func (k *APIKeys) Authenticate(ctx context.Context, token string) (Principal, error) {
+	keyID, secret, ok := parseAPIKey(token)
+	if !ok {
+		return Principal{}, ErrInvalidToken
+	}
+
+	var userID, keyHash, scopes string
+	var revoked bool
+	err := k.db.QueryRowContext(ctx, `
+		SELECT k.user_id, k.key_hash, k.scopes, k.revoked_at IS NOT NULL
+		FROM api_keys k
+		JOIN users u ON k.user_id = u.id
+		WHERE k.id = $1 AND u.is_service_account`, keyID).Scan(&userID, &keyHash, &scopes, &revoked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Principal{}, ErrInvalidToken
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(keyHash), []byte(hashSecret(secret))) != 1 {
+		return Principal{}, ErrInvalidToken
+	}
+	if revoked {
+		return Principal{}, ErrRevokedKey
+	}
+
+	// 書き込みを減らすため、最終使用日時は1分単位で更新する
+	_, err = k.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, keyID)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	return Principal{
+		Kind:   KindServiceAccount,
+		UserID: userID,
+		KeyID:  keyID,
+		Scopes: strings.Fields(scopes),
+	}, nil
+}
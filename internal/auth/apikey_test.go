@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIKey_RoundTrip(t *testing.T) {
+	keyID, plaintext, hash, err := NewAPIKey()
+	require.NoError(t, err)
+	assert.True(t, IsAPIKey(plaintext))
+
+	parsedID, secret, ok := parseAPIKey(plaintext)
+	require.True(t, ok)
+	assert.Equal(t, keyID, parsedID)
+	assert.Equal(t, hash, hashSecret(secret))
+}
+
+func newKeyRouter(t *testing.T, scopes string) (*gin.Engine, string, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	keyID, plaintext, hash, err := NewAPIKey()
+	require.NoError(t, err)
+	mock.ExpectQuery(`FROM api_keys k`).
+		WithArgs(keyID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "key_hash", "scopes", "revoked"}).
+			AddRow("bot00001", hash, scopes, false))
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at`).
+		WithArgs(keyID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	policy := ScopePolicy{"GET /rooms/:id/result": ScopeResultsRead}
+	router.Use(Middleware(NewGuestTokens([]byte("secret"), 0), NewAPIKeys(db), nil, policy))
+	ok := func(c *gin.Context) {
+		p, _ := PrincipalFrom(c)
+		c.String(http.StatusOK, p.UserID)
+	}
+	router.GET("/rooms/:id/result", ok)
+	router.POST("/rooms/:id/messages", ok)
+	return router, plaintext, mock
+}
+
+func TestMiddleware_APIKeyWithScope(t *testing.T) {
+	router, key, mock := newKeyRouter(t, "rooms:read results:read")
+
+	req := httptest.NewRequest(http.MethodGet, "/rooms/r001/result", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bot00001", w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMiddleware_APIKeyWithoutScopeIsForbidden(t *testing.T) {
+	router, key, _ := newKeyRouter(t, "rooms:read")
+
+	req := httptest.NewRequest(http.MethodGet, "/rooms/r001/result", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestMiddleware_APIKeyOnRouteOutsidePolicyIsForbidden(t *testing.T) {
+	router, key, _ := newKeyRouter(t, "rooms:read rooms:write results:read")
+
+	req := httptest.NewRequest(http.MethodPost, "/rooms/r001/messages", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
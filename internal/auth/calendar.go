@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+)
+
+const calendarTokenPrefix = "cal"
+
+// NewCalendarToken はカレンダーの購読用のトークンを生成します。
+// カレンダーアプリは Authorization ヘッダーを送れないため URL のクエリで渡します。保存するのはハッシュのみです。
+func NewCalendarToken() (plaintext, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	plaintext = calendarTokenPrefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return plaintext, hashSecret(plaintext), nil
+}
+
+// VerifyCalendarToken はトークンが保存されたハッシュと一致するかどうかを返します。
+func VerifyCalendarToken(token, hash string) bool {
+	if !strings.HasPrefix(token, calendarTokenPrefix+"_") || hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashSecret(token)), []byte(hash)) == 1
+}
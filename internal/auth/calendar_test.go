@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarToken(t *testing.T) {
+	token, hash, err := NewCalendarToken()
+	require.NoError(t, err)
+
+	assert.True(t, VerifyCalendarToken(token, hash))
+	assert.False(t, VerifyCalendarToken(token+"x", hash))
+	assert.False(t, VerifyCalendarToken(token, ""))
+	assert.False(t, VerifyCalendarToken("", hash))
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const guestTokenPrefix = "g1"
+
+// ゲストトークンの既定の有効期間
+const DefaultGuestTokenTTL = 12 * time.Hour
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// GuestClaims はゲストトークンに含まれる情報です。トークンは発行された一つの部屋でのみ有効です。
+type GuestClaims struct {
+	UserID    string    `json:"uid"`
+	RoomID    string    `json:"rid"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// GuestTokens はHMAC-SHA256で署名された短命のゲストトークンを発行・検証します。
+type GuestTokens struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewGuestTokens(secret []byte, ttl time.Duration) *GuestTokens {
+	return &GuestTokens{secret: secret, ttl: ttl, now: time.Now}
+}
+
+// NewGuestTokensFromEnv は GUEST_TOKEN_SECRET と GUEST_TOKEN_TTL からゲストトークンの設定を読み込みます。
+// シークレットが未設定の場合は起動ごとにランダムな値を使うため、再起動で既存のトークンは無効になります。
+func NewGuestTokensFromEnv() (*GuestTokens, error) {
+	ttl := DefaultGuestTokenTTL
+	if v := os.Getenv("GUEST_TOKEN_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GUEST_TOKEN_TTL: %w", err)
+		}
+		ttl = d
+	}
+
+	secret := []byte(os.Getenv("GUEST_TOKEN_SECRET"))
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate guest token secret: %w", err)
+		}
+		log.Println("GUEST_TOKEN_SECRET is not set; using a random secret for this process")
+	}
+	return NewGuestTokens(secret, ttl), nil
+}
+
+// Issue は指定されたゲストユーザーと部屋に対するトークンを発行します。
+func (g *GuestTokens) Issue(userID, roomID string) (string, time.Time, error) {
+	claims := GuestClaims{
+		UserID:    userID,
+		RoomID:    roomID,
+		ExpiresAt: g.now().Add(g.ttl).UTC().Truncate(time.Second),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	body := guestTokenPrefix + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(g.sign(body)), claims.ExpiresAt, nil
+}
+
+// Verify はトークンの署名と有効期限を検証し、含まれる情報を返します。
+func (g *GuestTokens) Verify(token string) (GuestClaims, error) {
+	var claims GuestClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != guestTokenPrefix {
+		return claims, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, g.sign(parts[0]+"."+parts[1])) {
+		return claims, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(payload, &claims) != nil {
+		return claims, ErrInvalidToken
+	}
+	if !g.now().Before(claims.ExpiresAt) {
+		return claims, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+// IsGuestToken はトークンがゲストトークンの形式かどうかを返します。
+func IsGuestToken(token string) bool {
+	return strings.HasPrefix(token, guestTokenPrefix+".")
+}
+
+func (g *GuestTokens) sign(body string) []byte {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
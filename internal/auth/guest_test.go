@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuestTokens_IssueAndVerify(t *testing.T) {
+	tokens := NewGuestTokens([]byte("secret"), time.Hour)
+
+	token, expiresAt, err := tokens.Issue("g0000001", "r001")
+	require.NoError(t, err)
+	assert.True(t, IsGuestToken(token))
+
+	claims, err := tokens.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "g0000001", claims.UserID)
+	assert.Equal(t, "r001", claims.RoomID)
+	assert.True(t, claims.ExpiresAt.Equal(expiresAt))
+}
+
+func TestGuestTokens_RejectsTamperedToken(t *testing.T) {
+	tokens := NewGuestTokens([]byte("secret"), time.Hour)
+	token, _, err := tokens.Issue("g0000001", "r001")
+	require.NoError(t, err)
+
+	other, _, err := tokens.Issue("g0000001", "r002")
+	require.NoError(t, err)
+	parts := strings.Split(token, ".")
+	otherParts := strings.Split(other, ".")
+	forged := parts[0] + "." + otherParts[1] + "." + parts[2]
+
+	_, err = tokens.Verify(forged)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = NewGuestTokens([]byte("another"), time.Hour).Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestGuestTokens_RejectsExpiredToken(t *testing.T) {
+	tokens := NewGuestTokens([]byte("secret"), time.Minute)
+	token, _, err := tokens.Issue("g0000001", "r001")
+	require.NoError(t, err)
+
+	tokens.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = tokens.Verify(token)
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScopePolicy はサービスアカウントが呼び出せるルートと必要なスコープの対応です。
+// キーは "GET /rooms/:id" のように HTTP メソッドとルートパターンを空白で繋げたものです。
+// ここにないルートはサービスアカウントからは呼び出せません。
+type ScopePolicy map[string]string
+
+// bearerToken は Authorization ヘッダーから Bearer トークンを取り出します。
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
+
+// Middleware は Authorization: Bearer で送られたゲストトークン・APIキー・管理者トークンを検証し、
+// 認証済みの主体をコンテキストに格納します。ヘッダーのないリクエストはそのまま通します。
+// サービスアカウントは policy に登録されたルートを、必要なスコープを持つ場合にのみ呼び出せます。
+// 管理者トークンは policy で service-accounts:manage が必要なルートのみ呼び出せます。
+func Middleware(guests *GuestTokens, keys *APIKeys, admin *AdminToken, policy ScopePolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		switch {
+		case admin.Verify(token):
+			if policy[c.Request.Method+" "+c.FullPath()] != ScopeServiceAccountsManage {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "管理者トークンではこの操作はできません"})
+				return
+			}
+			c.Set(principalContextKey, Principal{Kind: KindAdmin})
+
+		case IsGuestToken(token):
+			claims, err := guests.Verify(token)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "ゲストトークンが無効です"})
+				return
+			}
+			c.Set(principalContextKey, Principal{Kind: KindGuest, UserID: claims.UserID, RoomID: claims.RoomID})
+
+		case IsAPIKey(token) && keys != nil:
+			principal, err := keys.Authenticate(c.Request.Context(), token)
+			if err != nil {
+				if !errors.Is(err, ErrInvalidToken) && !errors.Is(err, ErrRevokedKey) {
+					log.Printf("APIキーの検証に失敗しました: %v", err)
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "APIキーが無効です"})
+				return
+			}
+			scope, ok := policy[c.Request.Method+" "+c.FullPath()]
+			if !ok || !principal.HasScope(scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "APIキーにこの操作の権限がありません"})
+				return
+			}
+			c.Set(principalContextKey, principal)
+
+		default:
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "認証トークンが無効です"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import "github.com/gin-gonic/gin"
+
+// 認証済みリクエストの主体の種類
+const (
+	KindGuest          = "guest"
+	KindServiceAccount = "service_account"
+	KindAdmin          = "admin" // 管理者トークン。サービスアカウントの管理のみ
+)
+
+const principalContextKey = "auth.principal"
+
+// Principal は Authorization ヘッダーで認証されたリクエストの主体です。
+// ヘッダーのない通常のユーザーリクエストには Principal はありません。
+type Principal struct {
+	Kind   string
+	UserID string
+	RoomID string   // ゲストの場合のみ。トークンが有効な部屋
+	KeyID  string   // サービスアカウントの場合のみ
+	Scopes []string // サービスアカウントの場合のみ
+}
+
+// HasScope は主体が指定されたスコープを持っているかどうかを返します。
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFrom はリクエストの認証済み主体を返します。
+func PrincipalFrom(c *gin.Context) (Principal, bool) {
+	v, ok := c.Get(principalContextKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := v.(Principal)
+	return p, ok
+}
+
+// GuestFrom はリクエストがゲストトークンで認証されている場合にその情報を返します。
+func GuestFrom(c *gin.Context) (GuestClaims, bool) {
+	p, ok := PrincipalFrom(c)
+	if !ok || p.Kind != KindGuest {
+		return GuestClaims{}, false
+	}
+	return GuestClaims{UserID: p.UserID, RoomID: p.RoomID}, true
+}
@@ -0,0 +1,150 @@
+// Package calendar は会議室の予定を iCalendar（RFC 5545）形式で書き出します。
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType は iCalendar のメディアタイプです。
+const ContentType = "text/calendar; charset=utf-8"
+
+const (
+	prodID = "-//elmo-project//Elmo//JA"
+	// UIDDomain は予定の UID の @ 以降です。UID は会議室ごとに変わらないため、カレンダーアプリは同じ予定として更新します。
+	UIDDomain = "elmo-project"
+	// 一行の長さの上限（オクテット）。超える行は折り返す
+	maxLineOctets = 75
+)
+
+// Attendee は予定の参加者です。メールアドレスを持たないため、ユーザーIDの URN で表します。
+type Attendee struct {
+	UserID string
+	Name   string
+}
+
+// Event は会議室一つ分の予定です。
+type Event struct {
+	RoomID      string
+	Sequence    int // 日時などを変更するたびに増やす
+	Title       string
+	Description string
+	Start       time.Time
+	Duration    time.Duration // 0 の場合は終了日時を含めない
+	TimeZone    string
+	JoinURL     string
+	Organizer   *Attendee
+	Attendees   []Attendee
+}
+
+// UID は会議室の予定の UID です。
+func UID(roomID string) string {
+	return "room-" + roomID + "@" + UIDDomain
+}
+
+// Write は予定を VCALENDAR として書き出します。name はカレンダーアプリに表示する名前です。
+func Write(w io.Writer, name string, events []Event, now time.Time) error {
+	b := bufio.NewWriter(w)
+	line := func(s string) { writeFolded(b, s) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:" + prodID)
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if name != "" {
+		line("X-WR-CALNAME:" + escapeText(name))
+	}
+	stamp := formatUTC(now)
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + UID(e.RoomID))
+		line("SEQUENCE:" + strconv.Itoa(e.Sequence))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART:" + formatUTC(e.Start))
+		if e.Duration > 0 {
+			line("DTEND:" + formatUTC(e.Start.Add(e.Duration)))
+		}
+		line("SUMMARY:" + escapeText(e.Title))
+		if description := e.description(); description != "" {
+			line("DESCRIPTION:" + escapeText(description))
+		}
+		if e.JoinURL != "" {
+			line("URL:" + e.JoinURL)
+		}
+		if e.Organizer != nil {
+			line("ORGANIZER;CN=" + quoteParam(e.Organizer.Name) + ":" + userURN(e.Organizer.UserID))
+		}
+		for _, a := range e.Attendees {
+			line("ATTENDEE;CN=" + quoteParam(a.Name) + ";ROLE=REQ-PARTICIPANT:" + userURN(a.UserID))
+		}
+		line("STATUS:CONFIRMED")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.Flush()
+}
+
+// description は説明の後ろに会議室のタイムゾーンでの開始日時と参加用のリンクを付けたものです。
+func (e Event) description() string {
+	var parts []string
+	if e.Description != "" {
+		parts = append(parts, e.Description)
+	}
+	if e.TimeZone != "" {
+		if loc, err := time.LoadLocation(e.TimeZone); err == nil {
+			parts = append(parts, "開始: "+e.Start.In(loc).Format("2006-01-02 15:04")+"（"+e.TimeZone+"）")
+		}
+	}
+	if e.JoinURL != "" {
+		parts = append(parts, "参加: "+e.JoinURL)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func userURN(userID string) string {
+	return "urn:elmo:user:" + userID
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeText は TEXT の値の \ ; , 改行をエスケープします。
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// quoteParam はパラメーターの値を二重引用符で囲みます。値に二重引用符は使えないため取り除きます。
+func quoteParam(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '"' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, s)
+	return `"` + s + `"`
+}
+
+// writeFolded は 75 オクテットを超える行を、UTF-8 の文字の途中で切らないように折り返して CRLF で書き出します。
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1 // 続きの行は先頭の空白の分だけ短い
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
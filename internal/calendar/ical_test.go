@@ -0,0 +1,60 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	start := time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC)
+	event := Event{
+		RoomID:      "r001",
+		Sequence:    2,
+		Title:       "週次ふりかえり; 3月",
+		Description: "進め方を見直します,\n必ず参加してください",
+		Start:       start,
+		Duration:    30 * time.Minute,
+		TimeZone:    "Asia/Tokyo",
+		JoinURL:     "https://elmo.example.com/rooms/r001",
+		Organizer:   &Attendee{UserID: "u001", Name: "田中太郎"},
+		Attendees:   []Attendee{{UserID: "u002", Name: `佐藤"花子"`}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "Elmo", []Event{event}, start.Add(-time.Hour)))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	// 折り返しを戻してから中身を確かめる
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	for _, want := range []string{
+		"UID:room-r001@elmo-project\r\n",
+		"SEQUENCE:2\r\n",
+		"DTSTAMP:20240301T000000Z\r\n",
+		"DTSTART:20240301T010000Z\r\n",
+		"DTEND:20240301T013000Z\r\n",
+		`SUMMARY:週次ふりかえり\; 3月` + "\r\n",
+		`DESCRIPTION:進め方を見直します\,\n必ず参加してください\n\n開始: 2024-03-01 10:00（Asia/Tokyo）\n\n参加: https://elmo.example.com/rooms/r001` + "\r\n",
+		"URL:https://elmo.example.com/rooms/r001\r\n",
+		`ORGANIZER;CN="田中太郎":urn:elmo:user:u001` + "\r\n",
+		`ATTENDEE;CN="佐藤花子";ROLE=REQ-PARTICIPANT:urn:elmo:user:u002` + "\r\n",
+	} {
+		assert.Contains(t, unfolded, want)
+	}
+}
+
+func TestWriteFolded(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, strings.Repeat("あ", 60), nil, time.Now()))
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), maxLineOctets)
+		assert.True(t, strings.ToValidUTF8(line, "?") == line, "文字の途中で折り返している: %q", line)
+	}
+}
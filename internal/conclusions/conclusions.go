@@ -0,0 +1,43 @@
+// Package conclusions は会議の結論の版の記録と、結論の承認を扱います。
+package conclusions
+
+import (
+	"context"
+	"database/sql"
+)
+
+// PendingApprovals は会議室 rooms に、最新の版の結論をまだ承認していない承認者がいる場合に一致する条件です。
+// 承認者を登録していない会議室には一致しません。結論がない会議室は承認者がいれば一致します。
+const PendingApprovals = `EXISTS (
+	SELECT 1 FROM conclusion_approvers ca
+	WHERE ca.room_id = rooms.id AND ca.approved_version IS DISTINCT FROM rooms.conclusion_version)`
+
+// Save は結論を新しい版として記録し、会議室の結論を置き換えてステータスを concluded にします。
+// 現在の結論と同じ場合は何もせず、changed に false を返します。
+// revertedFrom は以前の版に戻す場合の戻し元の版です。会議室が見つからない場合は sql.ErrNoRows を返します。
+// 承認は版ごとに行うため、新しい版を保存すると承認者はもう一度承認する必要があります。
+func Save(ctx context.Context, tx *sql.Tx, roomID string, authorID *string, conclusion string, revertedFrom *int) (version int, changed bool, err error) {
+	var current string
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(conclusion, ''), conclusion_version FROM rooms WHERE id = $1 FOR UPDATE`, roomID).Scan(&current, &version)
+	if err != nil {
+		return 0, false, err
+	}
+	if current == conclusion && version > 0 {
+		return version, false, nil
+	}
+
+	version++
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO conclusion_versions (room_id, version, conclusion, author_id, reverted_from)
+		VALUES ($1, $2, $3, $4, $5)`, roomID, version, conclusion, authorID, revertedFrom)
+	if err != nil {
+		return 0, false, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rooms SET conclusion = $2, conclusion_version = $3, status = 'concluded' WHERE id = $1`, roomID, conclusion, version)
+	if err != nil {
+		return 0, false, err
+	}
+	return version, true, nil
+}
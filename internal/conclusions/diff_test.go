@@ -0,0 +1,21 @@
+package conclusions
+
+import (
+	"testing"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	assert.Equal(t, []models.DiffLine{
+		{Op: DiffEqual, Text: "リリースを延期する"},
+		{Op: DiffDelete, Text: "期限は来週"},
+		{Op: DiffInsert, Text: "期限は再来週"},
+		{Op: DiffEqual, Text: "田中さんが告知する"},
+		{Op: DiffInsert, Text: "佐藤さんが確認する"},
+	}, Diff("リリースを延期する\n期限は来週\n田中さんが告知する", "リリースを延期する\r\n期限は再来週\r\n田中さんが告知する\r\n佐藤さんが確認する"))
+
+	assert.Equal(t, []models.DiffLine{{Op: DiffInsert, Text: "最初の結論"}}, Diff("", "最初の結論"))
+	assert.Empty(t, Diff("", ""))
+}
@@ -0,0 +1,77 @@
+// Package events は会議室ごとのリアルタイム通知を配信します。
+package events
+
+import "sync"
+
+// 購読者ごとのバッファ。溢れた通知は捨てる（遅いクライアントで配信全体を止めない）
+const subscriberBuffer = 16
+
+// 会議室に配信される通知の種類
+const (
+	TypePollCreated   = "poll.created"
+	TypePollUpdated   = "poll.updated"
+	TypePollClosed    = "poll.closed"
+	TypeAgendaUpdated = "agenda.updated"
+	TypeRoomStarted   = "room.started"
+	TypeRoomEnding    = "room.ending"
+	TypeRoomEnded     = "room.ended"
+
+	// 問いかけの準備が間に合わずに開始した会議室で、問いかけを用意できたとき
+	TypeRoomQuestionReady = "room.question_ready"
+)
+
+// Event は会議室に配信される通知一件です。
+type Event struct {
+	Type string
+	Data any
+}
+
+// Hub は会議室ごとの購読者に通知を配信します。
+// 状態はプロセス内に保持するため、同じサーバーに接続した購読者にのみ届きます。
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[string]map[chan Event]struct{})}
+}
+
+// Subscribe は会議室の通知を購読します。不要になったら返された関数を呼んでください。
+func (h *Hub) Subscribe(roomID string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subs[roomID] == nil {
+		h.subs[roomID] = make(map[chan Event]struct{})
+	}
+	h.subs[roomID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[roomID], ch)
+			if len(h.subs[roomID]) == 0 {
+				delete(h.subs, roomID)
+			}
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Publish は会議室の購読者全員に通知を送ります。nil の Hub では何もしません。
+func (h *Hub) Publish(roomID string, e Event) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[roomID] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_PublishesToRoomSubscribersOnly(t *testing.T) {
+	hub := NewHub()
+	r1, cancel1 := hub.Subscribe("r001")
+	defer cancel1()
+	r2, cancel2 := hub.Subscribe("r002")
+	defer cancel2()
+
+	hub.Publish("r001", Event{Type: "poll.updated", Data: "p1"})
+
+	require.Len(t, r1, 1)
+	assert.Equal(t, Event{Type: "poll.updated", Data: "p1"}, <-r1)
+	assert.Len(t, r2, 0)
+}
+
+func TestHub_SlowSubscriberDoesNotBlock(t *testing.T) {
+	hub := NewHub()
+	ch, cancel := hub.Subscribe("r001")
+
+	for i := 0; i < subscriberBuffer+5; i++ {
+		hub.Publish("r001", Event{Type: "poll.updated"})
+	}
+	assert.Len(t, ch, subscriberBuffer)
+
+	cancel()
+	cancel()
+	hub.Publish("r001", Event{Type: "poll.updated"})
+	assert.Empty(t, hub.subs)
+}
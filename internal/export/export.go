@@ -0,0 +1,101 @@
+// Package export は会議の議事録を Markdown・HTML・PDF に書き出します。
+package export
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	texttemplate "text/template"
+)
+
+// 議事録の出力形式
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+)
+
+// ErrUnknownFormat は対応していない出力形式を指定された場合のエラーです。
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// ErrNoPDFFont はPDFに埋め込む日本語フォントが設定されていない場合のエラーです。
+var ErrNoPDFFont = errors.New("pdf font is not configured")
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	markdownTemplate = texttemplate.Must(texttemplate.New("minutes.md.tmpl").
+				Funcs(texttemplate.FuncMap{"mditem": markdownItem, "mdquote": markdownQuote}).
+				ParseFS(templateFS, "templates/minutes.md.tmpl"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/minutes.html.tmpl"))
+)
+
+// Exporter は議事録を各形式に書き出します。
+// PDFには日本語を含むTrueTypeフォントが必要で、使う文字だけを埋め込みます。
+type Exporter struct {
+	pdfFont []byte
+}
+
+// NewExporter は pdfFont（TrueTypeフォントのデータ）を使うExporterを作成します。
+// pdfFont が空の場合、PDFへの書き出しは ErrNoPDFFont を返します。
+func NewExporter(pdfFont []byte) *Exporter {
+	return &Exporter{pdfFont: pdfFont}
+}
+
+// NewExporterFromEnv は環境変数 EXPORT_PDF_FONT に指定されたフォントファイルを読み込んでExporterを作成します。
+// 未設定の場合はPDF以外の形式だけを書き出せます。
+func NewExporterFromEnv() (*Exporter, error) {
+	path := os.Getenv("EXPORT_PDF_FONT")
+	if path == "" {
+		return NewExporter(nil), nil
+	}
+	font, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("EXPORT_PDF_FONT を読み込めません: %w", err)
+	}
+	return NewExporter(font), nil
+}
+
+// ContentType は出力形式のContent-Typeを返します。
+func ContentType(format string) (string, error) {
+	switch format {
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8", nil
+	case FormatHTML:
+		return "text/html; charset=utf-8", nil
+	case FormatPDF:
+		return "application/pdf", nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Render は議事録を指定した形式で w に書き出します。
+func (e *Exporter) Render(w io.Writer, format string, m Minutes) error {
+	switch format {
+	case FormatMarkdown:
+		return markdownTemplate.Execute(w, m)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, m)
+	case FormatPDF:
+		if len(e.pdfFont) == 0 {
+			return ErrNoPDFFont
+		}
+		return renderPDF(w, m, e.pdfFont)
+	}
+	return ErrUnknownFormat
+}
+
+// Bytes は議事録を指定した形式で書き出した内容を返します。
+// 途中で失敗した場合に書きかけの内容をレスポンスに送らないために使います。
+func (e *Exporter) Bytes(format string, m Minutes) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := e.Render(&buf, format, m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,108 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleMinutes(anonymous bool) Minutes {
+	start := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	u1, u2 := "u001", "u002"
+	timebox := 10
+	room := models.Room{
+		ID:              "r001",
+		Title:           "週次ふりかえり",
+		Description:     "今週の良かった点と改善点",
+		InitialQuestion: "今週一番うまくいったことは？",
+		Conclusion:      "レビューを毎朝行う\n担当は持ち回り",
+		Status:          "done",
+		Anonymous:       anonymous,
+		TimeZone:        "Asia/Tokyo",
+	}
+	result := models.RoomResultResponse{
+		RoomInfo: models.ResultRoomInfo{RoomID: "r001", Title: room.Title, Anonymous: anonymous},
+		ChatLogs: []models.ChatLog{
+			{LogID: "log1", UserID: &u1, Message: "レビューが遅い<script>", Timestamp: start, SorenaCount: 2},
+			{LogID: "log2", UserID: &u2, Message: "朝にまとめてやりたい", Timestamp: start.Add(3 * time.Minute)},
+			{LogID: "log3", Message: "レビューの時間を決める案が出ています", IsSummary: true, Timestamp: start.Add(5 * time.Minute)},
+		},
+		SorenaSummary: models.SorenaSummary{
+			TotalCount:  2,
+			TopMessages: []models.SorenaMessage{{LogID: "log1", UserID: &u1, Message: "レビューが遅い<script>", Count: 2}},
+		},
+		Agenda: []models.AgendaItem{{Position: 1, Title: "レビュー", TimeboxMinutes: &timebox, Conclusion: "毎朝行う", Overtime: true}},
+		Polls:  []models.Poll{{Question: "次回はいつ？", Options: []models.PollOption{{Label: "月曜", Votes: 2}, {Label: "金曜"}}}},
+	}
+	names := map[string]string{"u001": "田中太郎", "u002": "佐藤花子"}
+	return NewMinutes(room, result, names, []string{"u001", "u002"}, start.Add(time.Hour))
+}
+
+func TestNewMinutes_AnonymousRoomDropsNames(t *testing.T) {
+	m := sampleMinutes(true)
+
+	assert.Empty(t, m.Participants)
+	assert.Equal(t, 2, m.ParticipantCount)
+	require.Len(t, m.Timeline, 3)
+	assert.Equal(t, "匿名", m.Timeline[0].SpeakerLabel())
+	assert.Equal(t, "AI要約", m.Timeline[2].SpeakerLabel())
+	assert.Equal(t, "匿名", m.Ranking[0].SpeakerLabel())
+}
+
+func TestRender_Markdown(t *testing.T) {
+	out, err := NewExporter(nil).Bytes(FormatMarkdown, sampleMinutes(false))
+	require.NoError(t, err)
+	md := string(out)
+
+	assert.Contains(t, md, "# 週次ふりかえり\n")
+	assert.Contains(t, md, "- ステータス: 終了\n")
+	assert.Contains(t, md, "- 出力日時: 2024/01/01 11:00\n")
+	assert.Contains(t, md, "## 最初の問いかけ\n\n> 今週一番うまくいったことは？\n")
+	assert.Contains(t, md, "- 田中太郎\n- 佐藤花子\n")
+	assert.Contains(t, md, "### 1. レビュー\n\n- 割り当て時間: 10分（超過）\n- 結論: 毎朝行う\n")
+	assert.Contains(t, md, "- 10:00 **田中太郎**（それな 2）: レビューが遅い<script>\n")
+	assert.Contains(t, md, "- 10:05 **AI要約**: レビューの時間を決める案が出ています\n")
+	assert.Contains(t, md, "1. レビューが遅い<script>（田中太郎、それな 2）\n")
+	assert.Contains(t, md, "- 月曜: 2票\n- 金曜: 0票\n")
+	assert.True(t, bytes.HasSuffix(out, []byte("## 結論\n\n> レビューを毎朝行う  \n> 担当は持ち回り\n")), md)
+}
+
+func TestRender_HTMLEscapesMessages(t *testing.T) {
+	out, err := NewExporter(nil).Bytes(FormatHTML, sampleMinutes(true))
+	require.NoError(t, err)
+	html := string(out)
+
+	assert.Contains(t, html, "<title>週次ふりかえり - 議事録</title>")
+	assert.Contains(t, html, "レビューが遅い&lt;script&gt;")
+	assert.NotContains(t, html, "<script>")
+	assert.Contains(t, html, "匿名モードのため人数のみ記載します（2名）。")
+	assert.NotContains(t, html, "田中太郎")
+	assert.Contains(t, html, `<li class="summary">`)
+}
+
+func TestRender_PDFRequiresFont(t *testing.T) {
+	_, err := NewExporter(nil).Bytes(FormatPDF, sampleMinutes(false))
+	assert.ErrorIs(t, err, ErrNoPDFFont)
+
+	_, err = NewExporter(nil).Bytes("docx", sampleMinutes(false))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestRender_PDF(t *testing.T) {
+	path := os.Getenv("EXPORT_PDF_FONT")
+	if path == "" {
+		t.Skip("EXPORT_PDF_FONT が設定されていないためスキップします")
+	}
+	font, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	out, err := NewExporter(font).Bytes(FormatPDF, sampleMinutes(false))
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-")))
+	assert.Contains(t, string(out), "/FontFile2")
+}
@@ -0,0 +1,161 @@
+package export
+
+import (
+	"strings"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// 議事録に載せる「それな」ランキングの件数
+const rankingSize = 5
+
+// Minutes は議事録として出力する会議の内容です。
+// 匿名モードの会議室では発言者の名前を持たせず、参加者は人数だけを載せます。
+type Minutes struct {
+	RoomID           string
+	Title            string
+	Description      string
+	InitialQuestion  string
+	Conclusion       string
+	Status           string
+	Anonymous        bool
+	Location         *time.Location
+	Participants     []string
+	ParticipantCount int
+	Agenda           []models.AgendaItem
+	Timeline         []TimelineEntry
+	Ranking          []RankedMessage
+	Polls            []models.Poll
+	GeneratedAt      time.Time
+}
+
+// TimelineEntry は時系列に並べた発言またはAIの要約です。
+type TimelineEntry struct {
+	Time      time.Time
+	Speaker   string
+	Message   string
+	IsSummary bool
+	Sorena    int
+}
+
+// RankedMessage は「それな」が多かった発言です。
+type RankedMessage struct {
+	Rank    int
+	Speaker string
+	Message string
+	Count   int
+}
+
+// NewMinutes は会議室・リザルトの集計・ユーザー名から議事録を組み立てます。
+// names はユーザーIDから表示名への対応で、participants は参加者のユーザーIDです（参加順）。
+func NewMinutes(room models.Room, result models.RoomResultResponse, names map[string]string, participants []string, now time.Time) Minutes {
+	loc, err := time.LoadLocation(room.TimeZone)
+	if err != nil || room.TimeZone == "" {
+		loc = time.UTC
+	}
+	anonymous := room.Anonymous || result.RoomInfo.Anonymous
+	m := Minutes{
+		RoomID:           room.ID,
+		Title:            room.Title,
+		Description:      room.Description,
+		InitialQuestion:  room.InitialQuestion,
+		Conclusion:       room.Conclusion,
+		Status:           room.Status,
+		Anonymous:        anonymous,
+		Location:         loc,
+		ParticipantCount: len(participants),
+		Agenda:           result.Agenda,
+		Polls:            result.Polls,
+		GeneratedAt:      now,
+	}
+	speaker := func(userID *string) string {
+		if anonymous || userID == nil {
+			return ""
+		}
+		if name, ok := names[*userID]; ok {
+			return name
+		}
+		return *userID
+	}
+	if !anonymous {
+		for _, id := range participants {
+			m.Participants = append(m.Participants, speaker(&id))
+		}
+	}
+	for _, log := range result.ChatLogs {
+		entry := TimelineEntry{Time: log.Timestamp, Message: log.Message, IsSummary: log.IsSummary, Sorena: log.SorenaCount}
+		if !log.IsSummary {
+			entry.Speaker = speaker(log.UserID)
+		}
+		m.Timeline = append(m.Timeline, entry)
+	}
+	for i, msg := range result.SorenaSummary.TopMessages {
+		if i == rankingSize {
+			break
+		}
+		m.Ranking = append(m.Ranking, RankedMessage{Rank: i + 1, Speaker: speaker(msg.UserID), Message: msg.Message, Count: msg.Count})
+	}
+	return m
+}
+
+// FormatTime は日時を会議室のタイムゾーンで表示します。
+func (m Minutes) FormatTime(t time.Time) string {
+	return t.In(m.location()).Format("2006/01/02 15:04")
+}
+
+// FormatClock は時刻だけを会議室のタイムゾーンで表示します。
+func (m Minutes) FormatClock(t time.Time) string {
+	return t.In(m.location()).Format("15:04")
+}
+
+// StatusLabel は会議室のステータスの表示名を返します。
+func (m Minutes) StatusLabel() string {
+	switch m.Status {
+	case "not started":
+		return "未開始"
+	case "inprogress":
+		return "進行中"
+	case "concluded":
+		return "結論済み"
+	case "done":
+		return "終了"
+	}
+	return m.Status
+}
+
+// SpeakerLabel は発言者の表示名を返します。要約はAI、匿名モードの発言は「匿名」と表示します。
+func (e TimelineEntry) SpeakerLabel() string {
+	if e.IsSummary {
+		return "AI要約"
+	}
+	if e.Speaker == "" {
+		return "匿名"
+	}
+	return e.Speaker
+}
+
+// SpeakerLabel は発言者の表示名を返します。匿名モードでは「匿名」と表示します。
+func (r RankedMessage) SpeakerLabel() string {
+	if r.Speaker == "" {
+		return "匿名"
+	}
+	return r.Speaker
+}
+
+func (m Minutes) location() *time.Location {
+	if m.Location == nil {
+		return time.UTC
+	}
+	return m.Location
+}
+
+// markdownItem は複数行のテキストをMarkdownのリスト項目の中で改行として扱われるようにします。
+func markdownItem(s string) string {
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", "  \n  ")
+}
+
+// markdownQuote は複数行のテキストをMarkdownの引用にします。
+func markdownQuote(s string) string {
+	return "> " + strings.ReplaceAll(strings.TrimSpace(s), "\n", "  \n> ")
+}
@@ -0,0 +1,185 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// PDFに埋め込むフォントのファミリー名
+const pdfFontFamily = "minutes"
+
+// PDFのレイアウト（単位はmm・pt）
+const (
+	pdfMargin     = 18.0
+	pdfLineHeight = 6.0
+	pdfBodySize   = 10.5
+	pdfSmallSize  = 9.0
+)
+
+// renderPDF は議事録をA4のPDFに書き出します。
+// 日本語は単語の区切りに空白がないため、MultiCell が文字単位で折り返します。
+func renderPDF(w io.Writer, m Minutes, font []byte) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "", font)
+	pdf.SetTitle(m.Title, true)
+	pdf.SetCreationDate(m.GeneratedAt)
+	pdf.SetModificationDate(m.GeneratedAt)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin + 4)
+		pdf.SetFont(pdfFontFamily, "", pdfSmallSize)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(0, pdfLineHeight, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	p := pdfWriter{pdf: pdf, m: m}
+	p.title()
+	if m.Description != "" {
+		p.heading("説明")
+		p.paragraph(m.Description)
+	}
+	if m.InitialQuestion != "" {
+		p.heading("最初の問いかけ")
+		p.paragraph(m.InitialQuestion)
+	}
+
+	p.heading("参加者")
+	switch {
+	case m.Anonymous:
+		p.paragraph(fmt.Sprintf("匿名モードのため人数のみ記載します（%d名）。", m.ParticipantCount))
+	case len(m.Participants) == 0:
+		p.paragraph("なし")
+	default:
+		p.paragraph(strings.Join(m.Participants, "、"))
+	}
+
+	if len(m.Agenda) > 0 {
+		p.heading("議題")
+		for _, item := range m.Agenda {
+			p.subheading(fmt.Sprintf("%d. %s", item.Position, item.Title))
+			if item.TimeboxMinutes != nil {
+				timebox := fmt.Sprintf("割り当て時間: %d分", *item.TimeboxMinutes)
+				if item.Overtime {
+					timebox += "（超過）"
+				}
+				p.bullet(timebox)
+			}
+			if item.InitialQuestion != "" {
+				p.bullet("問いかけ: " + item.InitialQuestion)
+			}
+			conclusion := item.Conclusion
+			if conclusion == "" {
+				conclusion = "なし"
+			}
+			p.bullet("結論: " + conclusion)
+		}
+	}
+
+	p.heading("タイムライン")
+	if len(m.Timeline) == 0 {
+		p.paragraph("発言はありません。")
+	}
+	for _, entry := range m.Timeline {
+		p.timelineEntry(entry)
+	}
+
+	p.heading("それなランキング")
+	if len(m.Ranking) == 0 {
+		p.paragraph("「それな」はありません。")
+	}
+	for _, r := range m.Ranking {
+		p.paragraph(fmt.Sprintf("%d. %s（%s、それな %d）", r.Rank, r.Message, r.SpeakerLabel(), r.Count))
+	}
+
+	if len(m.Polls) > 0 {
+		p.heading("投票結果")
+		for _, poll := range m.Polls {
+			p.subheading(poll.Question)
+			for _, o := range poll.Options {
+				p.bullet(fmt.Sprintf("%s: %d票", o.Label, o.Votes))
+			}
+		}
+	}
+
+	p.heading("結論")
+	if m.Conclusion != "" {
+		p.paragraph(m.Conclusion)
+	} else {
+		p.paragraph("結論はまだありません。")
+	}
+
+	return pdf.Output(w)
+}
+
+// pdfWriter は議事録の見出しや段落をPDFに描画します。
+type pdfWriter struct {
+	pdf *fpdf.Fpdf
+	m   Minutes
+}
+
+func (p pdfWriter) title() {
+	p.pdf.SetFont(pdfFontFamily, "", 18)
+	p.pdf.SetTextColor(34, 34, 34)
+	p.pdf.MultiCell(0, 9, p.m.Title, "", "L", false)
+	p.pdf.SetFont(pdfFontFamily, "", pdfSmallSize)
+	p.pdf.SetTextColor(102, 102, 102)
+	p.pdf.MultiCell(0, pdfLineHeight, fmt.Sprintf("会議室ID: %s / ステータス: %s / 出力日時: %s",
+		p.m.RoomID, p.m.StatusLabel(), p.m.FormatTime(p.m.GeneratedAt)), "", "L", false)
+	left, _, right, _ := p.pdf.GetMargins()
+	width, _ := p.pdf.GetPageSize()
+	p.pdf.SetDrawColor(51, 51, 51)
+	p.pdf.Line(left, p.pdf.GetY()+1, width-right, p.pdf.GetY()+1)
+	p.pdf.Ln(3)
+}
+
+func (p pdfWriter) heading(text string) {
+	p.pdf.Ln(4)
+	p.pdf.SetFont(pdfFontFamily, "", 14)
+	p.pdf.SetTextColor(74, 123, 208)
+	p.pdf.MultiCell(0, 8, text, "", "L", false)
+	p.pdf.Ln(1)
+}
+
+func (p pdfWriter) subheading(text string) {
+	p.pdf.Ln(1)
+	p.pdf.SetFont(pdfFontFamily, "", 12)
+	p.pdf.SetTextColor(34, 34, 34)
+	p.pdf.MultiCell(0, 7, text, "", "L", false)
+}
+
+func (p pdfWriter) paragraph(text string) {
+	p.pdf.SetFont(pdfFontFamily, "", pdfBodySize)
+	p.pdf.SetTextColor(34, 34, 34)
+	p.pdf.MultiCell(0, pdfLineHeight, strings.TrimSpace(text), "", "L", false)
+}
+
+func (p pdfWriter) bullet(text string) {
+	p.pdf.SetFont(pdfFontFamily, "", pdfBodySize)
+	p.pdf.SetTextColor(34, 34, 34)
+	left, _, _, _ := p.pdf.GetMargins()
+	p.pdf.SetX(left + 2)
+	p.pdf.CellFormat(4, pdfLineHeight, "・", "", 0, "L", false, 0, "")
+	p.pdf.MultiCell(0, pdfLineHeight, strings.TrimSpace(text), "", "L", false)
+}
+
+// timelineEntry は発言を「時刻 発言者」の行と本文の2段で描画します。AIの要約は背景色で区別します。
+func (p pdfWriter) timelineEntry(e TimelineEntry) {
+	header := p.m.FormatClock(e.Time) + "  " + e.SpeakerLabel()
+	if e.Sorena > 0 {
+		header += fmt.Sprintf("  それな %d", e.Sorena)
+	}
+	p.pdf.SetFont(pdfFontFamily, "", pdfSmallSize)
+	p.pdf.SetTextColor(136, 136, 136)
+	p.pdf.MultiCell(0, 5, header, "", "L", false)
+	p.pdf.SetFont(pdfFontFamily, "", pdfBodySize)
+	p.pdf.SetTextColor(34, 34, 34)
+	p.pdf.SetFillColor(255, 248, 229)
+	p.pdf.MultiCell(0, pdfLineHeight, strings.TrimSpace(e.Message), "", "L", e.IsSummary)
+	p.pdf.Ln(1.5)
+}
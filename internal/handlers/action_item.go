@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/actionitems"
+	"github.com/shuto.sawaki/elmo-project/internal/ai"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+type ActionItemHandler struct {
+	db          *sql.DB
+	aiGenerator ai.AIGenerator
+}
+
+func NewActionItemHandler(db *sql.DB, aiGen ai.AIGenerator) *ActionItemHandler {
+	return &ActionItemHandler{db: db, aiGenerator: aiGen}
+}
+
+// requireRoomMember はユーザーが会議室のホストか参加者（退出したユーザーを含む）であることを確認します。
+// 会議が終わってからもアクションアイテムを追加できるよう、退出したユーザーも認めます。
+// 拒否した場合はレスポンスを書き込んで false を返します。
+func requireRoomMember(c *gin.Context, db *sql.DB, roomID, userID string) bool {
+	var ok bool
+	err := db.QueryRowContext(c.Request.Context(), `
+		SELECT COALESCE(r.created_by = $2, FALSE) OR EXISTS (SELECT 1 FROM participants p WHERE p.room_id = r.id AND p.user_id = $2)
+		FROM rooms r WHERE r.id = $1`, roomID, userID).Scan(&ok)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "この会議室の参加者ではありません"})
+		return false
+	}
+	return true
+}
+
+// assignableUser は担当者にできるユーザー（ゲスト・サービスアカウント以外）かどうかを返します。
+func assignableUser(ctx context.Context, db *sql.DB, userID string) (bool, error) {
+	var ok bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND NOT is_guest AND NOT is_service_account)`, userID).Scan(&ok)
+	return ok, err
+}
+
+// truncateRunes は s を n 文字までに切り詰めます。
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// ListRoomActionItems godoc
+// @Summary      会議室のアクションアイテムを取得
+// @Description  会議室で作られたアクションアイテムを期限の近い順に取得します。会議室のホスト・参加者が取得できます
+// @Tags         action-items
+// @Produce      json
+// @Param        id       path      string  true   "会議室ID"
+// @Param        user_id  query     string  false  "操作するユーザーのID（認証情報がない場合は必須）"
+// @Param        status   query     string  false  "状態で絞り込む（open / done / canceled）"
+// @Success      200      {object}  models.ActionItemList
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/action-items [get]
+func (h *ActionItemHandler) ListRoomActionItems(c *gin.Context) {
+	roomID := c.Param("id")
+	status := c.Query("status")
+	if status != "" && !actionitems.ValidStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "statusは open / done / canceled のいずれかを指定してください"})
+		return
+	}
+	scope, ok := resolveRoomScope(c, h.db)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	found, err := scope.includes(ctx, h.db, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		return
+	}
+
+	items, err := actionitems.List(ctx, h.db, "a.room_id = $1 AND ($2 = '' OR a.status = $2)", 0, roomID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "アクションアイテムの取得に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusOK, models.ActionItemList{Items: items})
+}
+
+// CreateActionItem godoc
+// @Summary      アクションアイテムを作成
+// @Description  会議室のアクションアイテムを作成します。message_id を指定するとメッセージから作成します（title を省略するとメッセージの本文）。会議室のホスト・参加者が作成できます
+// @Tags         action-items
+// @Accept       json
+// @Produce      json
+// @Param        id           path      string                    true  "会議室ID"
+// @Param        action_item  body      models.ActionItemRequest  true  "アクションアイテム"
+// @Success      201          {object}  models.ActionItem
+// @Failure      400          {object}  map[string]interface{}
+// @Failure      403          {object}  map[string]interface{}
+// @Failure      404          {object}  map[string]interface{}
+// @Failure      500          {object}  map[string]interface{}
+// @Router       /rooms/{id}/action-items [post]
+func (h *ActionItemHandler) CreateActionItem(c *gin.Context) {
+	roomID := c.Param("id")
+	var req models.ActionItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" && req.MessageID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "titleは必須です"})
+		return
+	}
+	if utf8.RuneCountInString(title) > actionitems.MaxTitleLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "titleは200文字以内で指定してください"})
+		return
+	}
+	if req.DueDate != nil && !actionitems.ValidDueDate(*req.DueDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "due_dateは YYYY-MM-DD の形で指定してください"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireRoomMember(c, h.db, roomID, userID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.MessageID != nil {
+		var message string
+		err := h.db.QueryRowContext(ctx, `SELECT message FROM chat_logs WHERE id = $1 AND room_id = $2`, *req.MessageID, roomID).Scan(&message)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "message_idのメッセージがこの会議室にありません"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			}
+			return
+		}
+		if title == "" {
+			title = truncateRunes(strings.TrimSpace(message), actionitems.MaxTitleLength)
+		}
+	}
+	if req.AssigneeID != nil {
+		ok, err := assignableUser(ctx, h.db, *req.AssigneeID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "assignee_idのユーザーが見つかりません"})
+			return
+		}
+	}
+
+	id, err := gonanoid.New()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "アクションアイテムIDの生成に失敗しました"})
+		return
+	}
+	_, err = h.db.ExecContext(ctx, `
+		INSERT INTO action_items (id, room_id, message_id, title, assignee_id, due_date, source, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		id, roomID, req.MessageID, title, req.AssigneeID, req.DueDate, actionitems.SourceManual, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "アクションアイテムの作成に失敗しました"})
+		return
+	}
+	item, err := actionitems.Get(ctx, h.db, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "作成したアクションアイテムの取得に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+// ExtractActionItems godoc
+// @Summary      AI の要約からアクションアイテムを抽出
+// @Description  AI の要約からやること・担当者・期限を抜き出してアクションアイテムを作成し、その要約から作られたアクションアイテムを返します。担当者は会議室のホスト・参加者の名前と完全に一致する場合のみ設定します（匿名モードの会議室では設定しません）。同じ会議室にすでにあるやることは作成しません。ホストのみ実行できます
+// @Tags         action-items
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                            true  "会議室ID"
+// @Param        request  body      models.ExtractActionItemsRequest  true  "抽出元の要約"
+// @Success      200      {object}  models.ActionItemList
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/action-items/extract [post]
+func (h *ActionItemHandler) ExtractActionItems(c *gin.Context) {
+	roomID := c.Param("id")
+	var req models.ExtractActionItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var summaryID, summary string
+	messageID := ""
+	if req.MessageID != nil {
+		messageID = *req.MessageID
+	}
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, message FROM chat_logs
+		WHERE room_id = $1 AND is_summary AND ($2 = '' OR id = $2)
+		ORDER BY created_at DESC LIMIT 1`, roomID, messageID).Scan(&summaryID, &summary)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "AI の要約が見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+
+	candidates, err := h.aiGenerator.ExtractActionItems(ctx, summary)
+	if err != nil {
+		log.Printf("アクションアイテムの抽出に失敗しました: room=%s, err=%v", roomID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "AI API呼び出しエラー"})
+		return
+	}
+	members, err := h.memberIDsByName(ctx, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+	for _, candidate := range candidates {
+		id, err := gonanoid.New()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "アクションアイテムIDの生成に失敗しました"})
+			return
+		}
+		var assigneeID, dueDate *string
+		if memberID, ok := members[candidate.Assignee]; ok && memberID != "" {
+			assigneeID = &memberID
+		}
+		if actionitems.ValidDueDate(candidate.Due) {
+			dueDate = &candidate.Due
+		}
+		title := truncateRunes(candidate.Title, actionitems.MaxTitleLength)
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO action_items (id, room_id, message_id, title, assignee_id, due_date, source, created_by)
+			SELECT $1, $2, $3, $4, $5, $6, $7, $8
+			WHERE NOT EXISTS (SELECT 1 FROM action_items WHERE room_id = $2 AND title = $4)`,
+			id, roomID, summaryID, title, assigneeID, dueDate, actionitems.SourceAI, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "アクションアイテムの作成に失敗しました"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "アクションアイテムの作成に失敗しました"})
+		return
+	}
+
+	items, err := actionitems.List(ctx, h.db, "a.room_id = $1 AND a.message_id = $2", 0, roomID, summaryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "アクションアイテムの取得に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusOK, models.ActionItemList{Items: items})
+}
+
+// memberIDsByName は会議室のホストと参加者（ゲストを除く）の名前からユーザーIDを引く表を返します。
+// 同じ名前のユーザーが複数いる場合は担当者を決められないため、空文字にします。
+// 匿名モードの会議室では名前から参加者を特定しないよう、空の表を返します。
+func (h *ActionItemHandler) memberIDsByName(ctx context.Context, roomID string) (map[string]string, error) {
+	members := make(map[string]string)
+	anonymous, err := roomIsAnonymous(ctx, h.db, roomID)
+	if err != nil || anonymous {
+		return members, err
+	}
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT u.id, u.user_name FROM users u
+		WHERE NOT u.is_guest AND NOT u.is_service_account AND u.id IN (
+			SELECT created_by FROM rooms WHERE id = $1
+			UNION SELECT user_id FROM participants WHERE room_id = $1)`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		if _, dup := members[name]; dup {
+			members[name] = ""
+		} else {
+			members[name] = id
+		}
+	}
+	return members, rows.Err()
+}
+
+// UpdateActionItem godoc
+// @Summary      アクションアイテムを更新
+// @Description  やること・担当者・期限・状態を更新します。省略した項目は変更しません。作成したユーザー・担当者・会議室のホストが更新できます
+// @Tags         action-items
+// @Accept       json
+// @Produce      json
+// @Param        id           path      string                          true  "アクションアイテムID"
+// @Param        action_item  body      models.ActionItemUpdateRequest  true  "更新する項目"
+// @Success      200          {object}  models.ActionItem
+// @Failure      400          {object}  map[string]interface{}
+// @Failure      403          {object}  map[string]interface{}
+// @Failure      404          {object}  map[string]interface{}
+// @Failure      500          {object}  map[string]interface{}
+// @Router       /action-items/{id} [patch]
+func (h *ActionItemHandler) UpdateActionItem(c *gin.Context) {
+	itemID := c.Param("id")
+	var req models.ActionItemUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	var title *string
+	if req.Title != nil {
+		trimmed := strings.TrimSpace(*req.Title)
+		if trimmed == "" || utf8.RuneCountInString(trimmed) > actionitems.MaxTitleLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "titleは1〜200文字で指定してください"})
+			return
+		}
+		title = &trimmed
+	}
+	if req.Status != nil && !actionitems.ValidStatus(*req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "statusは open / done / canceled のいずれかを指定してください"})
+		return
+	}
+	var assigneeID, dueDate interface{}
+	if req.AssigneeID != nil && *req.AssigneeID != "" {
+		assigneeID = *req.AssigneeID
+	}
+	if req.DueDate != nil && *req.DueDate != "" {
+		if !actionitems.ValidDueDate(*req.DueDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "due_dateは YYYY-MM-DD の形で指定してください"})
+			return
+		}
+		dueDate = *req.DueDate
+	}
+
+	ctx := c.Request.Context()
+	var roomID, createdBy, currentAssignee, hostID sql.NullString
+	err := h.db.QueryRowContext(ctx, `
+		SELECT a.room_id, a.created_by, a.assignee_id, r.created_by
+		FROM action_items a LEFT JOIN rooms r ON r.id = a.room_id
+		WHERE a.id = $1`, itemID).Scan(&roomID, &createdBy, &currentAssignee, &hostID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定されたアクションアイテムは見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID.String, req.UserID)
+	if !ok {
+		return
+	}
+	if userID != createdBy.String && userID != currentAssignee.String && userID != hostID.String {
+		c.JSON(http.StatusForbidden, gin.H{"error": "作成したユーザー・担当者・会議室のホストのみ更新できます"})
+		return
+	}
+	if assigneeID != nil {
+		ok, err := assignableUser(ctx, h.db, *req.AssigneeID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "assignee_idのユーザーが見つかりません"})
+			return
+		}
+	}
+
+	// 期限を変えた場合は、新しい期限を過ぎたときにもう一度リマインダーを送る
+	_, err = h.db.ExecContext(ctx, `
+		UPDATE action_items SET
+			title = COALESCE($2, title),
+			assignee_id = CASE WHEN $3 THEN $4 ELSE assignee_id END,
+			due_date = CASE WHEN $5 THEN $6::date ELSE due_date END,
+			completed_at = CASE
+				WHEN $7::text IS NULL OR $7 = status THEN completed_at
+				WHEN $7 = 'open' THEN NULL
+				ELSE NOW() END,
+			status = COALESCE($7, status),
+			reminded_at = CASE WHEN $5 OR $7 = 'open' THEN NULL ELSE reminded_at END,
+			updated_at = NOW()
+		WHERE id = $1`,
+		itemID, title, req.AssigneeID != nil, assigneeID, req.DueDate != nil, dueDate, req.Status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "アクションアイテムの更新に失敗しました"})
+		return
+	}
+	item, err := actionitems.Get(ctx, h.db, itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新したアクションアイテムの取得に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// ListUserActionItems godoc
+// @Summary      担当しているアクションアイテムを取得
+// @Description  すべての会議室をまたいで、ユーザーが担当しているアクションアイテムを期限の近い順に取得します。本人のみ取得できます
+// @Tags         action-items
+// @Produce      json
+// @Param        id       path      string  true   "ユーザーID"
+// @Param        user_id  query     string  false  "操作するユーザーのID（認証情報がない場合は必須。id と同じ）"
+// @Param        status   query     string  false  "状態で絞り込む（open / done / canceled）"
+// @Param        overdue  query     bool    false  "true なら期限を過ぎて未完了のものだけ"
+// @Success      200      {object}  models.ActionItemList
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /users/{id}/action-items [get]
+func (h *ActionItemHandler) ListUserActionItems(c *gin.Context) {
+	userID := c.Param("id")
+	status := c.Query("status")
+	if status != "" && !actionitems.ValidStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "statusは open / done / canceled のいずれかを指定してください"})
+		return
+	}
+	overdue := c.Query("overdue")
+	if overdue != "" && overdue != "true" && overdue != "false" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "overdueは true か false で指定してください"})
+		return
+	}
+	actor, ok := actingUser(c, h.db, "", c.Query("user_id"))
+	if !ok {
+		return
+	}
+	if actor != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "本人のみアクションアイテムの一覧を取得できます"})
+		return
+	}
+
+	condition := "a.assignee_id = $1 AND ($2 = '' OR a.status = $2)"
+	if overdue == "true" {
+		condition += " AND " + actionitems.OverdueCondition
+	}
+	items, err := actionitems.List(c.Request.Context(), h.db, condition, 0, userID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "アクションアイテムの取得に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusOK, models.ActionItemList{Items: items})
+}
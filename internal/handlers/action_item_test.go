@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func actionItemRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "room_id", "room_title", "message_id", "title", "assignee_id", "user_name",
+		"due_date", "status", "source", "overdue", "created_by", "created_at", "updated_at", "completed_at"})
+}
+
+func TestCreateActionItem_FromMessage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT COALESCE\(r.created_by = \$2, FALSE\) OR EXISTS`).WithArgs("r001", "u001").
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+	mock.ExpectQuery(`SELECT message FROM chat_logs WHERE id = \$1 AND room_id = \$2`).WithArgs("m001", "r001").
+		WillReturnRows(sqlmock.NewRows([]string{"message"}).AddRow(" テスト計画を共有します "))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM users WHERE id = \$1 AND NOT is_guest`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec(`INSERT INTO action_items`).
+		WithArgs(sqlmock.AnyArg(), "r001", "m001", "テスト計画を共有します", "u002", "2024-01-31", "manual", "u001").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`FROM action_items a .* WHERE a.id = \$1`).
+		WillReturnRows(actionItemRows().AddRow("a001", "r001", "週次定例", "m001", "テスト計画を共有します", "u002", "佐藤",
+			"2024-01-31", "open", "manual", false, "u001", now, now, nil))
+
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/action-items",
+		`{"user_id":"u001","message_id":"m001","assignee_id":"u002","due_date":"2024-01-31"}`)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewActionItemHandler(db, &stubAIGenerator{}).CreateActionItem(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var item models.ActionItem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &item))
+	assert.Equal(t, "テスト計画を共有します", item.Title)
+	assert.Equal(t, "佐藤", *item.AssigneeName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateActionItem_Rejects(t *testing.T) {
+	tests := map[string]string{
+		"タイトルなし": `{"user_id":"u001"}`,
+		"期限が不正":  `{"user_id":"u001","title":"共有する","due_date":"2024-02-30"}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			c, w := newJSONContext(http.MethodPost, "/rooms/r001/action-items", body)
+			c.Params = gin.Params{{Key: "id", Value: "r001"}}
+			NewActionItemHandler(db, &stubAIGenerator{}).CreateActionItem(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestExtractActionItems(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	mock.ExpectQuery(`SELECT id, message FROM chat_logs`).WithArgs("r001", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message"}).AddRow("s001", "田中さんが来週までにテスト計画を共有する"))
+	mock.ExpectQuery(`SELECT anonymous FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"anonymous"}).AddRow(false))
+	mock.ExpectQuery(`SELECT u.id, u.user_name FROM users u`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_name"}).
+			AddRow("u002", "田中").AddRow("u003", "佐藤").AddRow("u004", "佐藤"))
+	mock.ExpectBegin()
+	// 名前が一致する参加者を担当者にし、同じ名前が複数いる場合と不正な期限は設定しない
+	mock.ExpectExec(`INSERT INTO action_items`).
+		WithArgs(sqlmock.AnyArg(), "r001", "s001", "テスト計画を共有する", "u002", "2024-01-08", "ai", "u001").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO action_items`).
+		WithArgs(sqlmock.AnyArg(), "r001", "s001", "見積もりを出す", nil, nil, "ai", "u001").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`FROM action_items a .* WHERE a.room_id = \$1 AND a.message_id = \$2`).WithArgs("r001", "s001").
+		WillReturnRows(actionItemRows())
+
+	ai := &stubAIGenerator{actionItems: []models.ActionItemCandidate{
+		{Title: "テスト計画を共有する", Assignee: "田中", Due: "2024-01-08"},
+		{Title: "見積もりを出す", Assignee: "佐藤", Due: "来週"},
+	}}
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/action-items/extract", `{"user_id":"u001"}`)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewActionItemHandler(db, ai).ExtractActionItems(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"items":[]}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateActionItem_Forbidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT a.room_id, a.created_by, a.assignee_id, r.created_by`).WithArgs("a001").
+		WillReturnRows(sqlmock.NewRows([]string{"room_id", "created_by", "assignee_id", "host"}).AddRow("r001", "u001", "u002", "u001"))
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u009").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+
+	c, w := newJSONContext(http.MethodPatch, "/action-items/a001", `{"user_id":"u009","status":"done"}`)
+	c.Params = gin.Params{{Key: "id", Value: "a001"}}
+	NewActionItemHandler(db, &stubAIGenerator{}).UpdateActionItem(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListUserActionItems_Overdue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`WHERE a.assignee_id = \$1 AND \(\$2 = '' OR a.status = \$2\) AND \(a.status = 'open' AND a.due_date <`).
+		WithArgs("u002", "").
+		WillReturnRows(actionItemRows().AddRow("a001", nil, nil, nil, "テスト計画を共有する", "u002", "佐藤",
+			"2024-01-31", "open", "ai", true, nil, now, now, nil))
+
+	c, w := newJSONContext(http.MethodGet, "/users/u002/action-items?user_id=u002&overdue=true", "")
+	c.Params = gin.Params{{Key: "id", Value: "u002"}}
+	NewActionItemHandler(db, &stubAIGenerator{}).ListUserActionItems(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list models.ActionItemList
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Items, 1)
+	assert.True(t, list.Items[0].Overdue)
+	assert.Nil(t, list.Items[0].RoomID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/ai"
+	"github.com/shuto.sawaki/elmo-project/internal/events"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// 議題のタイトルの最大長と割り当て時間の上限（分）
+const (
+	maxAgendaTitleLength = 100
+	maxTimeboxMinutes    = 480
+)
+
+type AgendaHandler struct {
+	db          *sql.DB
+	aiGenerator ai.AIGenerator
+	hub         *events.Hub
+}
+
+func NewAgendaHandler(db *sql.DB, aiGen ai.AIGenerator, hub *events.Hub) *AgendaHandler {
+	return &AgendaHandler{db: db, aiGenerator: aiGen, hub: hub}
+}
+
+// loadAgendaItems は会議室の議題を順番順に返します。currentID は現在の議題のIDです。
+func loadAgendaItems(ctx context.Context, db *sql.DB, roomID, currentID string, now time.Time) ([]models.AgendaItem, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, title, description, timebox_minutes, initial_question, conclusion, started_at, ended_at
+		FROM agenda_items
+		WHERE room_id = $1
+		ORDER BY position ASC, created_at ASC`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.AgendaItem{}
+	for rows.Next() {
+		var item models.AgendaItem
+		var timebox sql.NullInt64
+		var question, conclusion sql.NullString
+		var startedAt, endedAt sql.NullTime
+		if err := rows.Scan(&item.ID, &item.Title, &item.Description, &timebox, &question, &conclusion, &startedAt, &endedAt); err != nil {
+			return nil, err
+		}
+		item.Position = len(items) + 1
+		item.InitialQuestion = question.String
+		item.Conclusion = conclusion.String
+		item.Current = item.ID == currentID
+		if timebox.Valid {
+			minutes := int(timebox.Int64)
+			item.TimeboxMinutes = &minutes
+		}
+		if startedAt.Valid {
+			item.StartedAt = &startedAt.Time
+		}
+		if endedAt.Valid {
+			item.EndedAt = &endedAt.Time
+		}
+		applyTimebox(&item, now)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// applyTimebox は開始日時と割り当て時間から終了予定日時と超過を求めます。
+// 終えた議題は終了日時で、進行中の議題は now で超過を判定します。
+func applyTimebox(item *models.AgendaItem, now time.Time) {
+	if item.TimeboxMinutes == nil || item.StartedAt == nil {
+		return
+	}
+	deadline := item.StartedAt.Add(time.Duration(*item.TimeboxMinutes) * time.Minute)
+	item.DeadlineAt = &deadline
+	until := now
+	if item.EndedAt != nil {
+		until = *item.EndedAt
+	}
+	item.Overtime = until.After(deadline)
+}
+
+// loadAgenda は会議室の現在の議題と議題の一覧を返します。
+func loadAgenda(ctx context.Context, db *sql.DB, roomID string) (models.AgendaResponse, error) {
+	var current sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT current_agenda_item_id FROM rooms WHERE id = $1`, roomID).Scan(&current)
+	if err != nil {
+		return models.AgendaResponse{}, err
+	}
+	items, err := loadAgendaItems(ctx, db, roomID, current.String, time.Now())
+	if err != nil {
+		return models.AgendaResponse{}, err
+	}
+	response := models.AgendaResponse{RoomID: roomID, Items: items}
+	if current.Valid {
+		response.CurrentItemID = &current.String
+	}
+	return response, nil
+}
+
+// respondAgenda は議題の最新の状態を返し、変更があった場合は購読者にも配信します。
+func (h *AgendaHandler) respondAgenda(c *gin.Context, status int, roomID string, changed bool) {
+	agenda, err := loadAgenda(c.Request.Context(), h.db, roomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	if changed {
+		h.hub.Publish(roomID, events.Event{Type: events.TypeAgendaUpdated, Data: agenda})
+	}
+	c.JSON(status, agenda)
+}
+
+// GetAgenda godoc
+// @Summary      議題一覧を取得
+// @Description  会議室の議題を順番順に、現在の議題と割り当て時間の状況とともに取得します
+// @Tags         agenda
+// @Produce      json
+// @Param        id   path      string  true  "会議室ID"
+// @Success      200  {object}  models.AgendaResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /rooms/{id}/agenda [get]
+func (h *AgendaHandler) GetAgenda(c *gin.Context) {
+	h.respondAgenda(c, http.StatusOK, c.Param("id"), false)
+}
+
+// AddAgendaItem godoc
+// @Summary      議題を追加
+// @Description  会議室のホストが議題を末尾に追加します
+// @Tags         agenda
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                    true  "会議室ID"
+// @Param        item  body      models.AgendaItemRequest  true  "議題"
+// @Success      201   {object}  models.AgendaResponse
+// @Failure      400   {object}  map[string]interface{}
+// @Failure      403   {object}  map[string]interface{}
+// @Failure      404   {object}  map[string]interface{}
+// @Failure      500   {object}  map[string]interface{}
+// @Router       /rooms/{id}/agenda [post]
+func (h *AgendaHandler) AddAgendaItem(c *gin.Context) {
+	roomID := c.Param("id")
+
+	var req models.AgendaItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || utf8.RuneCountInString(req.Title) > maxAgendaTitleLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "タイトルは1〜100文字で指定してください"})
+		return
+	}
+	if req.TimeboxMinutes != nil && (*req.TimeboxMinutes <= 0 || *req.TimeboxMinutes > maxTimeboxMinutes) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timebox_minutesは1〜480で指定してください"})
+		return
+	}
+
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	itemID, err := gonanoid.New()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "IDの生成に失敗しました"})
+		return
+	}
+	_, err = h.db.ExecContext(c.Request.Context(), `
+		INSERT INTO agenda_items (id, room_id, position, title, description, timebox_minutes)
+		SELECT $1, $2, COALESCE(MAX(position), 0) + 1, $3, $4, $5 FROM agenda_items WHERE room_id = $2`,
+		itemID, roomID, req.Title, req.Description, req.TimeboxMinutes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "議題の追加に失敗しました"})
+		return
+	}
+	h.respondAgenda(c, http.StatusCreated, roomID, true)
+}
+
+// ReorderAgenda godoc
+// @Summary      議題を並べ替え
+// @Description  会議室のホストが議題の順番を変更します。すべての議題のIDを指定してください
+// @Tags         agenda
+// @Accept       json
+// @Produce      json
+// @Param        id     path      string                     true  "会議室ID"
+// @Param        order  body      models.AgendaOrderRequest  true  "新しい順番"
+// @Success      200    {object}  models.AgendaResponse
+// @Failure      400    {object}  map[string]interface{}
+// @Failure      403    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]interface{}
+// @Failure      500    {object}  map[string]interface{}
+// @Router       /rooms/{id}/agenda/order [put]
+func (h *AgendaHandler) ReorderAgenda(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req models.AgendaOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM agenda_items WHERE room_id = $1 FOR UPDATE`, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		existing[id] = true
+	}
+	rows.Close()
+
+	if len(req.ItemIDs) != len(existing) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "すべての議題のIDを一度ずつ指定してください"})
+		return
+	}
+	seen := make(map[string]bool, len(req.ItemIDs))
+	for _, id := range req.ItemIDs {
+		if !existing[id] || seen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "すべての議題のIDを一度ずつ指定してください"})
+			return
+		}
+		seen[id] = true
+	}
+
+	for i, id := range req.ItemIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE agenda_items SET position = $1 WHERE id = $2`, i+1, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "議題の並べ替えに失敗しました"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "議題の並べ替えに失敗しました"})
+		return
+	}
+	h.respondAgenda(c, http.StatusOK, roomID, true)
+}
+
+// DeleteAgendaItem godoc
+// @Summary      議題を削除
+// @Description  会議室のホストがまだ始めていない議題を削除します
+// @Tags         agenda
+// @Produce      json
+// @Param        id       path      string  true  "会議室ID"
+// @Param        itemId   path      string  true  "議題ID"
+// @Param        user_id  query     string  false "削除するユーザー（ホスト）のID"
+// @Success      200      {object}  models.AgendaResponse
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/agenda/{itemId} [delete]
+func (h *AgendaHandler) DeleteAgendaItem(c *gin.Context) {
+	roomID, itemID := c.Param("id"), c.Param("itemId")
+	ctx := c.Request.Context()
+
+	userID, ok := actingUser(c, h.db, roomID, c.Query("user_id"))
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	var started bool
+	err := h.db.QueryRowContext(ctx, `
+		SELECT started_at IS NOT NULL FROM agenda_items WHERE id = $1 AND room_id = $2`, itemID, roomID).Scan(&started)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された議題は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	// 始めた議題にはメッセージや結論が紐付いているため削除しない
+	if started {
+		c.JSON(http.StatusConflict, gin.H{"error": "始めた議題は削除できません"})
+		return
+	}
+	if _, err := h.db.ExecContext(ctx, `DELETE FROM agenda_items WHERE id = $1 AND started_at IS NULL`, itemID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "議題の削除に失敗しました"})
+		return
+	}
+	h.respondAgenda(c, http.StatusOK, roomID, true)
+}
+
+// AdvanceAgenda godoc
+// @Summary      議題を進める
+// @Description  会議室のホストが現在の議題を終え、次の議題（または指定した議題）に移ります。最後の議題を終えると現在の議題はなくなります。初めて始める議題にはAIが最初の問いかけを生成します
+// @Tags         agenda
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                       true  "会議室ID"
+// @Param        advance  body      models.AdvanceAgendaRequest  true  "進行"
+// @Success      200      {object}  models.AgendaResponse
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/agenda/advance [post]
+func (h *AgendaHandler) AdvanceAgenda(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req models.AdvanceAgendaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	// 同時に進められても議題が飛ばないよう、部屋の行をロックする
+	var roomTitle string
+	var current sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT title, current_agenda_item_id FROM rooms WHERE id = $1 FOR UPDATE`, roomID).Scan(&roomTitle, &current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+
+	next := sql.NullString{String: req.ItemID, Valid: req.ItemID != ""}
+	if next.Valid {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM agenda_items WHERE id = $1 AND room_id = $2)`, req.ItemID, roomID).Scan(&exists)
+		if err == nil && !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された議題は見つかりません"})
+			return
+		}
+	} else if current.Valid {
+		err = tx.QueryRowContext(ctx, `
+			SELECT id FROM agenda_items
+			WHERE room_id = $1 AND position > (SELECT position FROM agenda_items WHERE id = $2)
+			ORDER BY position ASC, created_at ASC
+			LIMIT 1`, roomID, current.String).Scan(&next.String)
+		next.Valid = err == nil
+	} else {
+		err = tx.QueryRowContext(ctx, `
+			SELECT id FROM agenda_items
+			WHERE room_id = $1 AND ended_at IS NULL
+			ORDER BY position ASC, created_at ASC
+			LIMIT 1`, roomID).Scan(&next.String)
+		next.Valid = err == nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+
+	if current.Valid && current != next {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE agenda_items SET ended_at = NOW() WHERE id = $1 AND ended_at IS NULL`, current.String)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "議題の進行に失敗しました"})
+			return
+		}
+	}
+	var title, description string
+	var question sql.NullString
+	if next.Valid {
+		err := tx.QueryRowContext(ctx, `
+			UPDATE agenda_items SET started_at = COALESCE(started_at, NOW()), ended_at = NULL
+			WHERE id = $1
+			RETURNING title, description, initial_question`, next.String).Scan(&title, &description, &question)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "議題の進行に失敗しました"})
+			return
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE rooms SET current_agenda_item_id = $1 WHERE id = $2`, next, roomID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "議題の進行に失敗しました"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "議題の進行に失敗しました"})
+		return
+	}
+
+	// 問いかけの生成に失敗しても進行は止めない
+	if next.Valid && !question.Valid {
+		topic := strings.TrimSpace("会議「" + roomTitle + "」の議題です。\n" + description)
+		generated, err := h.aiGenerator.GenerateInitialQuestion(ctx, title, topic)
+		if err != nil {
+			log.Printf("議題の問いかけの生成に失敗しました: room=%s, item=%s, err=%v", roomID, next.String, err)
+		} else if _, err := h.db.ExecContext(ctx, `
+			UPDATE agenda_items SET initial_question = $1 WHERE id = $2 AND initial_question IS NULL`,
+			generated, next.String); err != nil {
+			log.Printf("議題の問いかけの保存に失敗しました: room=%s, item=%s, err=%v", roomID, next.String, err)
+		}
+	}
+	h.respondAgenda(c, http.StatusOK, roomID, true)
+}
+
+// SaveAgendaConclusion godoc
+// @Summary      議題の結論を保存
+// @Description  会議室のホストが議題ごとの結論を保存します
+// @Tags         agenda
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string                          true  "会議室ID"
+// @Param        itemId      path      string                          true  "議題ID"
+// @Param        conclusion  body      models.AgendaConclusionRequest  true  "結論"
+// @Success      200         {object}  models.AgendaResponse
+// @Failure      400         {object}  map[string]interface{}
+// @Failure      403         {object}  map[string]interface{}
+// @Failure      404         {object}  map[string]interface{}
+// @Failure      500         {object}  map[string]interface{}
+// @Router       /rooms/{id}/agenda/{itemId}/conclusion [put]
+func (h *AgendaHandler) SaveAgendaConclusion(c *gin.Context) {
+	roomID, itemID := c.Param("id"), c.Param("itemId")
+
+	var req models.AgendaConclusionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	req.Conclusion = strings.TrimSpace(req.Conclusion)
+	if req.Conclusion == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "結論は必須です"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		UPDATE agenda_items SET conclusion = $1 WHERE id = $2 AND room_id = $3`, req.Conclusion, itemID, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースの更新に失敗しました"})
+		return
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された議題は見つかりません"})
+		return
+	}
+	h.respondAgenda(c, http.StatusOK, roomID, true)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubAIGenerator struct {
+	question    string
+	titles      []string
+	actionItems []models.ActionItemCandidate
+}
+
+func (s *stubAIGenerator) GenerateInitialQuestion(_ context.Context, title, _ string) (string, error) {
+	s.titles = append(s.titles, title)
+	return s.question, nil
+}
+
+func (s *stubAIGenerator) SummarizeLogs(context.Context, []models.LogEntry) (string, error) {
+	return "", nil
+}
+
+func (s *stubAIGenerator) ExtractActionItems(context.Context, string) ([]models.ActionItemCandidate, error) {
+	return s.actionItems, nil
+}
+
+func TestAdvanceAgenda_StartsNextItemWithGeneratedQuestion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT is_guest FROM users`).
+		WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM rooms`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT title, current_agenda_item_id FROM rooms WHERE id = \$1 FOR UPDATE`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "current_agenda_item_id"}).AddRow("週次定例", "item1"))
+	mock.ExpectQuery(`SELECT id FROM agenda_items\s+WHERE room_id = \$1 AND position >`).
+		WithArgs("r001", "item1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("item2"))
+	mock.ExpectExec(`UPDATE agenda_items SET ended_at = NOW\(\)`).
+		WithArgs("item1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE agenda_items SET started_at`).
+		WithArgs("item2").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "description", "initial_question"}).AddRow("採用", "", nil))
+	mock.ExpectExec(`UPDATE rooms SET current_agenda_item_id`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`UPDATE agenda_items SET initial_question`).
+		WithArgs("採用で困っていることは？", "item2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT current_agenda_item_id FROM rooms`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"current_agenda_item_id"}).AddRow("item2"))
+	mock.ExpectQuery(`FROM agenda_items`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "timebox_minutes", "initial_question", "conclusion", "started_at", "ended_at"}).
+			AddRow("item1", "進め方", "", nil, "q1", nil, now.Add(-20*time.Minute), now).
+			AddRow("item2", "採用", "", 15, "採用で困っていることは？", nil, now, nil))
+
+	ai := &stubAIGenerator{question: "採用で困っていることは？"}
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/agenda/advance", `{"user_id":"u001"}`)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewAgendaHandler(db, ai, nil).AdvanceAgenda(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"採用"}, ai.titles)
+	assert.Contains(t, w.Body.String(), `"current_item_id":"item2"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyTimebox(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	minutes := 15
+	item := models.AgendaItem{TimeboxMinutes: &minutes, StartedAt: &start}
+
+	applyTimebox(&item, start.Add(10*time.Minute))
+	require.NotNil(t, item.DeadlineAt)
+	assert.Equal(t, start.Add(15*time.Minute), *item.DeadlineAt)
+	assert.False(t, item.Overtime)
+
+	applyTimebox(&item, start.Add(20*time.Minute))
+	assert.True(t, item.Overtime)
+
+	// 終えた議題は終了日時で判定する
+	ended := start.Add(14 * time.Minute)
+	item.EndedAt = &ended
+	applyTimebox(&item, start.Add(time.Hour))
+	assert.False(t, item.Overtime)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/analytics"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// 発言の頻度の間隔（分）と週ごとの傾向の週数
+const (
+	defaultAnalyticsBucketMinutes = 5
+	maxAnalyticsBucketMinutes     = 60
+	defaultAnalyticsWeeks         = 12
+	maxAnalyticsWeeks             = 52
+)
+
+type AnalyticsHandler struct {
+	db           *sql.DB
+	materialized bool
+	now          func() time.Time
+}
+
+// NewAnalyticsHandler は materialized が true の場合、週ごとの傾向をマテリアライズドビュー room_analytics から集計します。
+// ビューは analytics.Refresher で定期的に更新してください。
+func NewAnalyticsHandler(db *sql.DB, materialized bool) *AnalyticsHandler {
+	return &AnalyticsHandler{db: db, materialized: materialized, now: time.Now}
+}
+
+// GetRoomAnalytics godoc
+// @Summary      会議室の分析を取得
+// @Description  参加者ごとのメッセージ数・文字数・受け取ったそれなの数と発言の割合、発言の偏り（ジニ係数）、開始から結論が出るまで・終了までの時間、一定の間隔ごとのメッセージ数を返します。匿名モードの会議室では参加者ごとの値を含みません。ユーザーはホストか参加したことのある会議室、ゲストはトークンの会議室、サービスアカウントはすべての会議室を取得できます
+// @Tags         analytics
+// @Produce      json
+// @Param        id       path      string  true   "会議室ID"
+// @Param        bucket   query     int     false  "発言の頻度を数える間隔（分。1〜60、既定は5）"
+// @Param        user_id  query     string  false  "取得するユーザーのID（認証情報がない場合は必須）"
+// @Success      200      {object}  models.RoomAnalytics
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/analytics [get]
+func (h *AnalyticsHandler) GetRoomAnalytics(c *gin.Context) {
+	roomID := c.Param("id")
+	bucket := defaultAnalyticsBucketMinutes
+	if v := c.Query("bucket"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxAnalyticsBucketMinutes {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bucketは1〜60で指定してください"})
+			return
+		}
+		bucket = n
+	}
+	scope, ok := resolveRoomScope(c, h.db)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	found, err := scope.includes(ctx, h.db, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		return
+	}
+
+	result, err := analytics.Room(ctx, h.db, roomID, time.Duration(bucket)*time.Minute)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+			return
+		}
+		log.Printf("会議室の分析の集計に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetAnalyticsTrends godoc
+// @Summary      週ごとの会議の傾向を取得
+// @Description  開始した会議の数・終了した会議の平均の長さ・結論が出た割合・結論が出るまでの平均時間・メッセージ数を週（月曜日から）ごとに古い順で返します。ユーザーはホストか参加したことのある会議室、ゲストはトークンの会議室、サービスアカウントはすべての会議室を集計します。ANALYTICS_REFRESH_INTERVAL を設定したサーバーでは、最後にビューを更新した時点の値になります
+// @Tags         analytics
+// @Produce      json
+// @Param        weeks      query     int     false  "集計する週の数（今週を含む。1〜52、既定は12）"
+// @Param        time_zone  query     string  false  "週の区切りに使うタイムゾーン（既定は Asia/Tokyo）"
+// @Param        user_id    query     string  false  "取得するユーザーのID（認証情報がない場合は必須）"
+// @Success      200        {object}  models.AnalyticsTrends
+// @Failure      400        {object}  map[string]interface{}
+// @Failure      500        {object}  map[string]interface{}
+// @Router       /analytics/trends [get]
+func (h *AnalyticsHandler) GetAnalyticsTrends(c *gin.Context) {
+	weeks := defaultAnalyticsWeeks
+	if v := c.Query("weeks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxAnalyticsWeeks {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weeksは1〜52で指定してください"})
+			return
+		}
+		weeks = n
+	}
+	timeZone := c.DefaultQuery("time_zone", defaultRoomTimeZone)
+	loc, err := time.LoadLocation(timeZone)
+	// "" と "Local" はサーバーのタイムゾーンになり、データベースでは使えないため受け付けない
+	if err != nil || timeZone == "" || timeZone == "Local" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "time_zoneが不正です"})
+		return
+	}
+	scope, ok := resolveRoomScope(c, h.db)
+	if !ok {
+		return
+	}
+
+	// $1 はタイムゾーン、$2 は集計の開始日時
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args)+2)
+	}
+	condition := scope.condition(arg)
+	from := analytics.WeekStart(h.now(), loc).AddDate(0, 0, -7*(weeks-1))
+	trends, err := analytics.Trends(c.Request.Context(), h.db, h.materialized, loc, from, weeks, condition, args...)
+	if err != nil {
+		log.Printf("週ごとの会議の傾向の集計に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.JSON(http.StatusOK, models.AnalyticsTrends{TimeZone: loc.String(), Weeks: trends})
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func expectRoomAnalytics(mock sqlmock.Sqlmock, anonymous bool) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT r.id FROM rooms r WHERE r.id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("r001"))
+	mock.ExpectQuery(`SELECT r.status, r.anonymous, r.started_at`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "anonymous", "started_at", "concluded_at", "ended_at", "messages", "sorena"}).
+			AddRow("done", anonymous, start, start.Add(40*time.Minute), start.Add(time.Hour), 4, 3))
+	mock.ExpectQuery(`FROM participants WHERE room_id = \$1`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "user_name", "messages", "characters", "sorena"}).
+			AddRow("u001", "田中", 3, 120, 3).
+			AddRow("u002", "佐藤", 1, 20, 0).
+			AddRow("u003", "鈴木", 0, 0, 0))
+	mock.ExpectQuery(`SELECT to_timestamp\(floor\(extract\(epoch FROM l.created_at\) / \$2\) \* \$2\)`).WithArgs("r001", int64(300)).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).
+			AddRow(start, 2).
+			AddRow(start.Add(10*time.Minute), 2))
+}
+
+func TestGetRoomAnalytics(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	expectRoomAnalytics(mock, false)
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/analytics?user_id=u001", "")
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewAnalyticsHandler(db, false).GetRoomAnalytics(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result models.RoomAnalytics
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 40.0, *result.TimeToConclusionMinutes)
+	assert.Equal(t, 60.0, *result.DurationMinutes)
+	assert.InDelta(t, 0.5, result.Gini, 1e-9)
+	require.Len(t, result.Participants, 3)
+	assert.Equal(t, 0.75, result.Participants[0].SpeakingShare)
+	assert.Equal(t, 3, result.Participants[0].SorenaReceived)
+	// メッセージがない間隔も 0 件として含める
+	require.Len(t, result.MessageRate, 3)
+	assert.Equal(t, []int{2, 0, 2}, []int{result.MessageRate[0].MessageCount, result.MessageRate[1].MessageCount, result.MessageRate[2].MessageCount})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRoomAnalytics_AnonymousOmitsParticipants(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	expectRoomAnalytics(mock, true)
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/analytics?user_id=u001", "")
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewAnalyticsHandler(db, false).GetRoomAnalytics(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.NotContains(t, result, "participants")
+	assert.InDelta(t, 0.5, result["gini"], 1e-9)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRoomAnalytics_InvalidBucket(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/analytics?user_id=u001&bucket=90", "")
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewAnalyticsHandler(db, false).GetRoomAnalytics(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAnalyticsTrends(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`FROM room_analytics\).* WHERE s.started_at >= \$2 AND \(r.created_by = \$3 OR`).
+		WithArgs("Asia/Tokyo", time.Date(2024, 1, 1, 0, 0, 0, 0, tokyo), "u001").
+		WillReturnRows(sqlmock.NewRows([]string{"week", "rooms", "duration", "rate", "to_conclusion", "messages"}).
+			AddRow("2024-01-08", 2, 45.0, 0.5, nil, 80))
+
+	c, w := newJSONContext(http.MethodGet, "/analytics/trends?user_id=u001&weeks=2", "")
+	h := NewAnalyticsHandler(db, true)
+	h.now = func() time.Time { return time.Date(2024, 1, 10, 12, 0, 0, 0, tokyo) }
+	h.GetAnalyticsTrends(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"time_zone":"Asia/Tokyo","weeks":[
+		{"week_start":"2024-01-01","rooms_held":0,"message_count":0},
+		{"week_start":"2024-01-08","rooms_held":2,"avg_duration_minutes":45,"conclusion_rate":0.5,"message_count":80}
+	]}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// roomIsAnonymous は部屋が匿名モードかどうかを返します。
+func roomIsAnonymous(ctx context.Context, db *sql.DB, roomID string) (bool, error) {
+	var anonymous bool
+	err := db.QueryRowContext(ctx, `SELECT anonymous FROM rooms WHERE id = $1`, roomID).Scan(&anonymous)
+	return anonymous, err
+}
+
+// redactChatLogs は匿名モードの部屋のチャットログから発言者を特定できる情報を取り除きます。
+// 発言やリアクションを個人に結び付けて返すエンドポイントは、匿名モードの部屋では必ずこれを通してください。
+func redactChatLogs(logs []models.ChatLog) {
+	for i := range logs {
+		logs[i].UserID = nil
+		logs[i].IsGuest = false
+	}
+}
+
+// redactRoomResult は匿名モードの部屋のリザルトを集計値のみに絞り込みます。
+func redactRoomResult(result *models.RoomResultResponse) {
+	result.SorenaSummary.Participants = nil
+	for i := range result.SorenaSummary.TopMessages {
+		result.SorenaSummary.TopMessages[i].UserID = nil
+	}
+	for i := range result.Reactions {
+		result.Reactions[i].Participants = nil
+		for j := range result.Reactions[i].TopMessages {
+			result.Reactions[i].TopMessages[j].UserID = nil
+		}
+	}
+	redactChatLogs(result.ChatLogs)
+	redactPolls(result.Polls)
+}
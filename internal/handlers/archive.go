@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/archive"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+)
+
+// 取り込むアーカイブの大きさの上限
+const maxArchiveBytes = 32 << 20
+
+type ArchiveHandler struct {
+	db *sql.DB
+}
+
+func NewArchiveHandler(db *sql.DB) *ArchiveHandler {
+	return &ArchiveHandler{db: db}
+}
+
+// GetRoomArchive godoc
+// @Summary      会議室のアーカイブを書き出す
+// @Description  会議室と参加者・議題・チャットログ（AIの要約を含む）・リアクション・結論を、環境間の移行や保管のためのバージョン付きのアーカイブとして書き出します。匿名モードの会議室では、サービスアカウント以外には発言者を伏せ、リアクションを含めません。ホストか、results:read スコープのサービスアカウントのみ実行できます
+// @Tags         rooms
+// @Produce      json
+// @Produce      application/x-ndjson
+// @Param        id       path      string  true   "会議室のID"
+// @Param        format   query     string  false  "形式（json / ndjson、既定 json）"
+// @Param        user_id  query     string  false  "ホストのユーザーID（認証情報がない場合は必須）"
+// @Success      200      {object}  models.RoomArchive
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/archive [get]
+func (h *ArchiveHandler) GetRoomArchive(c *gin.Context) {
+	ctx := c.Request.Context()
+	roomID := c.Param("id")
+	format := c.DefaultQuery("format", archive.FormatJSON)
+	contentType, err := archive.ContentType(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format は json / ndjson のいずれかを指定してください"})
+		return
+	}
+	principal, ok := auth.PrincipalFrom(c)
+	serviceAccount := ok && principal.Kind == auth.KindServiceAccount
+	if !serviceAccount {
+		userID, ok := actingUser(c, h.db, roomID, c.Query("user_id"))
+		if !ok || !requireHost(c, h.db, roomID, userID) {
+			return
+		}
+	}
+
+	a, err := archive.Load(ctx, h.db, roomID, time.Now())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+			return
+		}
+		log.Printf("会議室のアーカイブの読み込みに失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	// 匿名モードの会議室の発言者を含めるのは移行に使うサービスアカウントのみ。ホストにも参加者を特定させない
+	if a.Room.Anonymous && !serviceAccount {
+		archive.Redact(&a)
+	}
+	var buf bytes.Buffer
+	if err := archive.Encode(&buf, format, a); err != nil {
+		log.Printf("会議室のアーカイブの書き出しに失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": "room-" + roomID + "." + format,
+	}))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// ImportRoomArchive godoc
+// @Summary      会議室のアーカイブを取り込む
+// @Description  GET /rooms/{id}/archive で書き出したアーカイブから会議室を復元します。会議室IDが使われている場合、on_conflict=remap（既定）は会議室・議題・メッセージに新しいIDを割り当て、replace は既存の会議室を置き換え、fail は 409 を返します。replace は既存の会議室のホストか rooms:write スコープのサービスアカウントのみ行え、それ以外は remap として取り込みます。ユーザーは同じIDで同じ名前のユーザーがいればそのまま使い、IDが別の人に使われている場合は新しいIDで作成します。ゲストは実行できません
+// @Tags         rooms
+// @Accept       json
+// @Accept       application/x-ndjson
+// @Produce      json
+// @Param        archive      body      models.RoomArchive  true   "アーカイブ（NDJSON の場合は Content-Type: application/x-ndjson）"
+// @Param        format       query     string              false  "形式（json / ndjson。省略時は Content-Type で判断）"
+// @Param        on_conflict  query     string              false  "会議室IDが使われている場合の扱い（remap / fail / replace、既定 remap）"
+// @Param        user_id      query     string              false  "取り込むユーザーのID（認証情報がない場合は必須）"
+// @Success      201          {object}  models.ArchiveImportResult
+// @Failure      400          {object}  map[string]interface{}
+// @Failure      403          {object}  map[string]interface{}
+// @Failure      409          {object}  map[string]interface{}
+// @Failure      413          {object}  map[string]interface{}
+// @Failure      500          {object}  map[string]interface{}
+// @Router       /rooms/import [post]
+func (h *ArchiveHandler) ImportRoomArchive(c *gin.Context) {
+	// 既存の会議室を置き換えられるのは、そのホストか rooms:write スコープのサービスアカウントのみ
+	var by archive.Importer
+	if principal, ok := auth.PrincipalFrom(c); ok && principal.Kind == auth.KindServiceAccount {
+		by = archive.Importer{UserID: principal.UserID, ReplaceAny: principal.HasScope(auth.ScopeRoomsWrite)}
+	} else {
+		userID, ok := actingUser(c, h.db, "", c.Query("user_id"))
+		if !ok {
+			return
+		}
+		by.UserID = userID
+	}
+	format := c.Query("format")
+	if format == "" {
+		format = archive.FormatJSON
+		if strings.HasPrefix(c.ContentType(), "application/x-ndjson") {
+			format = archive.FormatNDJSON
+		}
+	}
+
+	a, err := archive.Decode(http.MaxBytesReader(c.Writer, c.Request.Body, maxArchiveBytes), format)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "アーカイブが大きすぎます"})
+		case errors.Is(err, archive.ErrUnknownFormat):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format は json / ndjson のいずれかを指定してください"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	result, err := archive.Import(c.Request.Context(), h.db, a, c.Query("on_conflict"), by)
+	if err != nil {
+		switch {
+		case errors.Is(err, archive.ErrRoomExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "同じIDの会議室があります", "room_id": a.Room.ID})
+		case errors.Is(err, archive.ErrInvalidArchive), errors.Is(err, archive.ErrUnsupportedVersion):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("会議室のアーカイブの取り込みに失敗しました: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
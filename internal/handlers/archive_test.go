@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const archiveBody = `{
+  "version": 1,
+  "exported_at": "2024-01-01T12:00:00Z",
+  "room": {"id": "r001", "title": "週次ふりかえり", "description": "", "status": "done", "allow_guests": false, "anonymous": false,
+           "time_zone": "Asia/Tokyo", "prompt_variant": "default", "created_at": "2024-01-01T10:00:00Z"},
+  "users": [], "participants": [], "reaction_types": [], "agenda": [], "messages": [], "reactions": []
+}`
+
+func TestGetRoomArchive_HostOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/archive?user_id=u002", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewArchiveHandler(db).GetRoomArchive(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportRoomArchive_Conflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT created_by FROM rooms WHERE id = \$1 FOR UPDATE`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	mock.ExpectRollback()
+
+	c, w := newJSONContext(http.MethodPost, "/rooms/import?user_id=u001&on_conflict=fail", archiveBody)
+	NewArchiveHandler(db).ImportRoomArchive(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"room_id":"r001"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportRoomArchive_RejectsInvalidArchive(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := map[string]string{
+		"壊れたJSON":   `{"version": 1, "room": `,
+		"未対応のバージョン": `{"version": 99, "room": {"id": "r001"}}`,
+		"発言者の参照がない": `{"version": 1, "room": {"id": "r001", "title": "t", "status": "done"},
+			"messages": [{"id": "log1", "user_id": "u999", "message": "こんにちは", "created_at": "2024-01-01T10:00:00Z"}]}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+				WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+
+			c, w := newJSONContext(http.MethodPost, "/rooms/import?user_id=u001", body)
+			NewArchiveHandler(db).ImportRoomArchive(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportRoomArchive_ReplaceByNonHostIsRemapped(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// ホストでないユーザーの replace は既存の会議室を消さずに新しいIDで取り込む
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT created_by FROM rooms WHERE id = \$1 FOR UPDATE`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	mock.ExpectExec(`INSERT INTO rooms`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	c, w := newJSONContext(http.MethodPost, "/rooms/import?user_id=u002&on_conflict=replace", archiveBody)
+	NewArchiveHandler(db).ImportRoomArchive(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"remapped":true`)
+	assert.NotContains(t, w.Body.String(), `"room_id":"r001"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRoomArchive_AnonymousRoomIsRedactedForHost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	mock.ExpectQuery(`FROM rooms WHERE id = \$1`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "conclusion", "status", "initial_question", "max_participants",
+			"allow_guests", "anonymous", "created_by", "scheduled_start_at", "duration_minutes", "time_zone", "prompt_variant",
+			"current_agenda_item_id", "started_at", "created_at"}).
+			AddRow("r001", "週次ふりかえり", "", nil, "done", nil, nil, false, true, "u001", nil, nil, "Asia/Tokyo", "default", nil, nil, at))
+	mock.ExpectQuery(`SELECT tag FROM room_tags`).WillReturnRows(sqlmock.NewRows([]string{"tag"}))
+	mock.ExpectQuery(`SELECT reaction_key FROM room_reaction_types`).WillReturnRows(sqlmock.NewRows([]string{"reaction_key"}))
+	mock.ExpectQuery(`SELECT id, user_name, is_guest FROM users`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_name", "is_guest"}).AddRow("u001", "田中太郎", false).AddRow("u002", "鈴木花子", false))
+	mock.ExpectQuery(`FROM participants`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "joined_at", "left_at", "last_seen_at"}).AddRow("u001", at, nil, nil).AddRow("u002", at, nil, nil))
+	mock.ExpectQuery(`SELECT key, label, emoji FROM reaction_types`).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "label", "emoji"}).AddRow("sorena", "それな", "🙌"))
+	mock.ExpectQuery(`FROM agenda_items`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`FROM chat_logs`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "message", "is_summary", "agenda_item_id", "created_at"}).
+			AddRow("log1", "u002", "進め方を変えたい", false, nil, at))
+	mock.ExpectQuery(`FROM message_reactions r`).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "user_id", "reaction_type", "created_at"}).AddRow("log1", "u001", "sorena", at))
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/archive?user_id=u001", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewArchiveHandler(db).GetRoomArchive(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var a models.RoomArchive
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &a))
+	require.Len(t, a.Messages, 1)
+	assert.Nil(t, a.Messages[0].UserID)
+	assert.Empty(t, a.Reactions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
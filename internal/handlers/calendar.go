@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/calendar"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// カレンダーの購読に含める過去の会議の日数
+const calendarFeedPastDays = 90
+
+type CalendarHandler struct {
+	db      *sql.DB
+	baseURL string
+	now     func() time.Time
+}
+
+// NewCalendarHandler は baseURL（例: https://elmo.example.com）を参加用のリンクに使います。空の場合はリンクを含めません。
+func NewCalendarHandler(db *sql.DB, baseURL string) *CalendarHandler {
+	return &CalendarHandler{db: db, baseURL: strings.TrimRight(baseURL, "/"), now: time.Now}
+}
+
+func (h *CalendarHandler) joinURL(roomID string) string {
+	if h.baseURL == "" {
+		return ""
+	}
+	return h.baseURL + "/rooms/" + url.PathEscape(roomID)
+}
+
+// loadCalendarEvents は開始予定日時（なければ開始日時）のある会議室を予定として読み込みます。
+// where は rooms r を絞り込む条件で、arg で値をプレースホルダーに置き換えたものです。
+// 匿名モードの会議室には参加者を含めません。
+func (h *CalendarHandler) loadCalendarEvents(ctx context.Context, where string, args []interface{}) ([]calendar.Event, error) {
+	query := `
+		SELECT r.id, r.title, r.description, COALESCE(r.scheduled_start_at, r.started_at), r.duration_minutes, r.time_zone,
+			r.calendar_sequence, r.created_by, COALESCE(host.user_name, ''),
+			CASE WHEN r.anonymous THEN '[]'::json ELSE COALESCE((
+				SELECT json_agg(json_build_object('id', u.id, 'name', u.user_name) ORDER BY p.joined_at, u.id)
+				FROM participants p JOIN users u ON u.id = p.user_id
+				WHERE p.room_id = r.id AND NOT u.is_service_account), '[]'::json) END
+		FROM rooms r
+		LEFT JOIN users host ON host.id = r.created_by
+		WHERE COALESCE(r.scheduled_start_at, r.started_at) IS NOT NULL AND ` + where + `
+		ORDER BY 4, r.id`
+	rows, err := h.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []calendar.Event{}
+	for rows.Next() {
+		var e calendar.Event
+		var duration sql.NullInt64
+		var hostID sql.NullString
+		var hostName string
+		var attendees []byte
+		if err := rows.Scan(&e.RoomID, &e.Title, &e.Description, &e.Start, &duration, &e.TimeZone,
+			&e.Sequence, &hostID, &hostName, &attendees); err != nil {
+			return nil, err
+		}
+		if duration.Valid {
+			e.Duration = time.Duration(duration.Int64) * time.Minute
+		}
+		if hostID.Valid {
+			e.Organizer = &calendar.Attendee{UserID: hostID.String, Name: hostName}
+		}
+		var members []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(attendees, &members); err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			e.Attendees = append(e.Attendees, calendar.Attendee{UserID: m.ID, Name: m.Name})
+		}
+		e.JoinURL = h.joinURL(e.RoomID)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (h *CalendarHandler) writeCalendar(c *gin.Context, filename, name string, events []calendar.Event) {
+	c.Header("Content-Type", calendar.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Status(http.StatusOK)
+	if err := calendar.Write(c.Writer, name, events, h.now()); err != nil {
+		log.Printf("カレンダーの書き出しに失敗しました: %v", err)
+	}
+}
+
+// GetRoomCalendar godoc
+// @Summary      会議室の予定をiCalendarで取得
+// @Description  会議室の予定を .ics ファイルとして返します。説明・参加用のリンク・参加者（匿名モードの会議室を除く）を含みます。UIDは会議室ごとに変わらず、予定を変更するたびにSEQUENCEが増えるため、再度取り込むとカレンダーの予定が更新されます。ユーザーはホストか参加したことのある会議室、ゲストはトークンの会議室、サービスアカウントはすべての会議室を取得できます
+// @Tags         calendar
+// @Produce      text/calendar
+// @Param        id       path      string  true   "会議室のID"
+// @Param        user_id  query     string  false  "取得するユーザーのID（認証情報がない場合は必須）"
+// @Success      200      {file}    file
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/calendar.ics [get]
+func (h *CalendarHandler) GetRoomCalendar(c *gin.Context) {
+	roomID := c.Param("id")
+	scope, ok := resolveRoomScope(c, h.db)
+	if !ok {
+		return
+	}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where := "r.id = " + arg(roomID) + " AND " + scope.condition(arg)
+	events, err := h.loadCalendarEvents(c.Request.Context(), where, args)
+	if err != nil {
+		log.Printf("会議室の予定の取得に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if len(events) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋が見つからないか、予定が設定されていません"})
+		return
+	}
+	h.writeCalendar(c, "room-"+roomID+".ics", events[0].Title, events)
+}
+
+// GetUserCalendar godoc
+// @Summary      ユーザーの予定をiCalendarで購読
+// @Description  ユーザーがホストか参加者の会議室の予定（過去90日以降）をカレンダーアプリで購読できる .ics として返します。カレンダーアプリは認証ヘッダーを送れないため、POST /users/{id}/calendar-token で発行したトークンをクエリで渡します
+// @Tags         calendar
+// @Produce      text/calendar
+// @Param        id     path      string  true  "ユーザーID"
+// @Param        token  query     string  true  "購読用のトークン"
+// @Success      200    {file}    file
+// @Failure      401    {object}  map[string]interface{}
+// @Failure      500    {object}  map[string]interface{}
+// @Router       /users/{id}/calendar.ics [get]
+func (h *CalendarHandler) GetUserCalendar(c *gin.Context) {
+	userID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var userName string
+	var tokenHash sql.NullString
+	err := h.db.QueryRowContext(ctx, `SELECT user_name, calendar_token_hash FROM users WHERE id = $1`, userID).Scan(&userName, &tokenHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	// ユーザーの有無を知られないよう、存在しない場合もトークンが違う場合と同じ応答にする
+	if errors.Is(err, sql.ErrNoRows) || !auth.VerifyCalendarToken(c.Query("token"), tokenHash.String) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "カレンダーのトークンが無効です"})
+		return
+	}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	since := h.now().AddDate(0, 0, -calendarFeedPastDays)
+	where := roomScope{userID: userID}.condition(arg) + " AND COALESCE(r.scheduled_start_at, r.started_at) >= " + arg(since)
+	events, err := h.loadCalendarEvents(ctx, where, args)
+	if err != nil {
+		log.Printf("ユーザーの予定の取得に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	h.writeCalendar(c, "elmo-"+userID+".ics", "Elmo（"+userName+"）", events)
+}
+
+// IssueCalendarToken godoc
+// @Summary      カレンダーの購読用トークンを発行
+// @Description  GET /users/{id}/calendar.ics の購読用トークンを発行します。トークンは一度だけ返し、再発行すると以前のトークンは使えなくなります。本人のみ発行できます
+// @Tags         calendar
+// @Produce      json
+// @Param        id       path      string  true   "ユーザーID"
+// @Param        user_id  query     string  false  "操作するユーザーのID（認証情報がない場合は必須。id と同じ）"
+// @Success      201      {object}  models.CalendarTokenResponse
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /users/{id}/calendar-token [post]
+func (h *CalendarHandler) IssueCalendarToken(c *gin.Context) {
+	userID := c.Param("id")
+	actor, ok := actingUser(c, h.db, "", c.Query("user_id"))
+	if !ok {
+		return
+	}
+	if actor != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "本人のみトークンを発行できます"})
+		return
+	}
+
+	token, hash, err := auth.NewCalendarToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		UPDATE users SET calendar_token_hash = $1 WHERE id = $2 AND NOT is_guest AND NOT is_service_account`, hash, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "トークンの発行に失敗しました"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定されたユーザーは見つかりません"})
+		return
+	}
+
+	path := "/users/" + url.PathEscape(userID) + "/calendar.ics?token=" + url.QueryEscape(token)
+	c.JSON(http.StatusCreated, models.CalendarTokenResponse{Token: token, URL: h.baseURL + path})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var calendarEventColumns = []string{"id", "title", "description", "start", "duration_minutes", "time_zone", "calendar_sequence", "created_by", "host_name", "attendees"}
+
+func TestGetRoomCalendar(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`FROM rooms r\s+LEFT JOIN users host`).WithArgs("r001", "u001").
+		WillReturnRows(sqlmock.NewRows(calendarEventColumns).
+			AddRow("r001", "週次ふりかえり", "", start, 30, "Asia/Tokyo", 3, "u001", "田中太郎", []byte(`[{"id": "u002", "name": "佐藤花子"}]`)))
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/calendar.ics?user_id=u001", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewCalendarHandler(db, "https://elmo.example.com/").GetRoomCalendar(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	body := strings.ReplaceAll(w.Body.String(), "\r\n ", "")
+	assert.Contains(t, body, "UID:room-r001@elmo-project\r\n")
+	assert.Contains(t, body, "SEQUENCE:3\r\n")
+	assert.Contains(t, body, "URL:https://elmo.example.com/rooms/r001\r\n")
+	assert.Contains(t, body, `ATTENDEE;CN="佐藤花子";ROLE=REQ-PARTICIPANT:urn:elmo:user:u002`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRoomCalendar_NotScheduled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`FROM rooms r`).WithArgs("r001", "u001").
+		WillReturnRows(sqlmock.NewRows(calendarEventColumns))
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/calendar.ics?user_id=u001", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewCalendarHandler(db, "").GetRoomCalendar(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserCalendar(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	token, hash, err := auth.NewCalendarToken()
+	require.NoError(t, err)
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	h := NewCalendarHandler(db, "")
+	h.now = func() time.Time { return now }
+
+	mock.ExpectQuery(`SELECT user_name, calendar_token_hash FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"user_name", "calendar_token_hash"}).AddRow("田中太郎", hash))
+	mock.ExpectQuery(`FROM rooms r`).WithArgs("u001", now.AddDate(0, 0, -calendarFeedPastDays)).
+		WillReturnRows(sqlmock.NewRows(calendarEventColumns).
+			AddRow("r001", "週次ふりかえり", "", now, nil, "Asia/Tokyo", 0, "u001", "田中太郎", []byte(`[]`)).
+			AddRow("r002", "1on1", "", now.Add(time.Hour), 30, "Asia/Tokyo", 1, nil, "", []byte(`[]`)))
+
+	c, w := newJSONContext(http.MethodGet, "/users/u001/calendar.ics?token="+token, "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "u001"}}
+	h.GetUserCalendar(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 2, strings.Count(w.Body.String(), "BEGIN:VEVENT"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserCalendar_InvalidToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, hash, err := auth.NewCalendarToken()
+	require.NoError(t, err)
+	mock.ExpectQuery(`SELECT user_name, calendar_token_hash FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"user_name", "calendar_token_hash"}).AddRow("田中太郎", hash))
+	mock.ExpectQuery(`SELECT user_name, calendar_token_hash FROM users`).WithArgs("u999").
+		WillReturnRows(sqlmock.NewRows([]string{"user_name", "calendar_token_hash"}))
+
+	for _, userID := range []string{"u001", "u999"} {
+		c, w := newJSONContext(http.MethodGet, "/users/"+userID+"/calendar.ics?token=cal_wrong", "")
+		c.Params = gin.Params{gin.Param{Key: "id", Value: userID}}
+		NewCalendarHandler(db, "").GetUserCalendar(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code, userID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIssueCalendarToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectExec(`UPDATE users SET calendar_token_hash`).WithArgs(sqlmock.AnyArg(), "u001").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, w := newJSONContext(http.MethodPost, "/users/u001/calendar-token?user_id=u001", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "u001"}}
+	NewCalendarHandler(db, "https://elmo.example.com").IssueCalendarToken(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"url":"https://elmo.example.com/users/u001/calendar.ics?token=cal_`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// 他のユーザーのトークンは発行できない
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	c, w = newJSONContext(http.MethodPost, "/users/u001/calendar-token?user_id=u002", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "u001"}}
+	NewCalendarHandler(db, "").IssueCalendarToken(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
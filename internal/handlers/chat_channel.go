@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/shuto.sawaki/elmo-project/internal/slack"
+	"github.com/shuto.sawaki/elmo-project/internal/webhooks"
+)
+
+type ChatChannelHandler struct {
+	db       *sql.DB
+	notifier *slack.Notifier
+}
+
+// NewChatChannelHandler はテスト投稿の送信に notifier を使います。
+func NewChatChannelHandler(db *sql.DB, notifier *slack.Notifier) *ChatChannelHandler {
+	return &ChatChannelHandler{db: db, notifier: notifier}
+}
+
+// urlHost は投稿先のURLのホスト名です。URL 全体は投稿の権限そのものなので返しません。
+func urlHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// CreateChatChannel godoc
+// @Summary      チャットツールへの投稿先を登録
+// @Description  会議の開始（最初の問いかけ）・AI の要約・結論（結果へのリンク付き）を Slack / Mattermost の Incoming Webhook に投稿します。範囲は一つの会議室（ホストのみ）か、自分がホストの会議室すべてです
+// @Tags         chat-channels
+// @Accept       json
+// @Produce      json
+// @Param        channel  body      models.ChatChannelRequest  true  "投稿先"
+// @Success      201      {object}  models.ChatChannel
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /chat-channels [post]
+func (h *ChatChannelHandler) CreateChatChannel(c *gin.Context) {
+	var req models.ChatChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	if req.Kind != slack.KindSlack && req.Kind != slack.KindMattermost {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kindは slack / mattermost のいずれかを指定してください"})
+		return
+	}
+	dest, ok := bindDestination(c, h.db, destinationRequest{URL: req.URL, Events: req.Events, Scope: req.Scope, RoomID: req.RoomID, UserID: req.UserID},
+		slack.Types, []string{webhooks.ScopeRoom, webhooks.ScopeCreator})
+	if !ok {
+		return
+	}
+	channel := models.ChatChannel{Kind: req.Kind, URLHost: urlHost(req.URL), Scope: req.Scope, Events: dest.events, RoomID: dest.roomID, CreatorID: dest.creatorID, CreatedBy: dest.userID}
+
+	id, err := gonanoid.New()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	channel.ID = id
+	err = h.db.QueryRowContext(c.Request.Context(), `
+		INSERT INTO chat_channels (id, kind, url, scope, room_id, creator_id, events, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at`,
+		channel.ID, channel.Kind, req.URL, channel.Scope, channel.RoomID, channel.CreatorID, strings.Join(channel.Events, " "), channel.CreatedBy).
+		Scan(&channel.CreatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーが見つかりません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "投稿先の登録に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusCreated, channel)
+}
+
+// ListChatChannels godoc
+// @Summary      チャットツールへの投稿先の一覧
+// @Description  自分が登録した投稿先を、最後の投稿の結果とともに返します
+// @Tags         chat-channels
+// @Produce      json
+// @Param        user_id  query     string  false  "ユーザーID（認証情報がない場合は必須）"
+// @Success      200      {array}   models.ChatChannel
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /chat-channels [get]
+func (h *ChatChannelHandler) ListChatChannels(c *gin.Context) {
+	userID, ok := actingUser(c, h.db, "", c.Query("user_id"))
+	if !ok {
+		return
+	}
+	rows, err := h.db.QueryContext(c.Request.Context(), `
+		SELECT id, kind, url, scope, room_id, creator_id, events, created_by, created_at, last_posted_at, last_error, last_error_at
+		FROM chat_channels WHERE created_by = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer rows.Close()
+
+	list := []models.ChatChannel{}
+	for rows.Next() {
+		var ch models.ChatChannel
+		var rawURL, events string
+		var roomID, creatorID, lastError sql.NullString
+		var lastPostedAt, lastErrorAt sql.NullTime
+		if err := rows.Scan(&ch.ID, &ch.Kind, &rawURL, &ch.Scope, &roomID, &creatorID, &events, &ch.CreatedBy, &ch.CreatedAt,
+			&lastPostedAt, &lastError, &lastErrorAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		ch.URLHost = urlHost(rawURL)
+		ch.Events = strings.Fields(events)
+		if roomID.Valid {
+			ch.RoomID = &roomID.String
+		}
+		if creatorID.Valid {
+			ch.CreatorID = &creatorID.String
+		}
+		if lastPostedAt.Valid {
+			ch.LastPostedAt = &lastPostedAt.Time
+		}
+		if lastError.Valid {
+			ch.LastError = &lastError.String
+		}
+		if lastErrorAt.Valid {
+			ch.LastErrorAt = &lastErrorAt.Time
+		}
+		list = append(list, ch)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteChatChannel godoc
+// @Summary      チャットツールへの投稿先を削除
+// @Tags         chat-channels
+// @Param        id       path  string  true   "投稿先のID"
+// @Param        user_id  query string  false  "ユーザーID（認証情報がない場合は必須）"
+// @Success      204
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /chat-channels/{id} [delete]
+func (h *ChatChannelHandler) DeleteChatChannel(c *gin.Context) {
+	userID, ok := actingUser(c, h.db, "", c.Query("user_id"))
+	if !ok {
+		return
+	}
+	result, err := h.db.ExecContext(c.Request.Context(),
+		`DELETE FROM chat_channels WHERE id = $1 AND created_by = $2`, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "投稿先の削除に失敗しました"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された投稿先は見つかりません"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TestChatChannel godoc
+// @Summary      チャットツールにテスト投稿
+// @Description  設定を確かめるため、投稿先にテストのメッセージを一回だけ送ります。投稿先が受け付けなかった場合は 502 とその理由を返します
+// @Tags         chat-channels
+// @Produce      json
+// @Param        id       path  string  true   "投稿先のID"
+// @Param        user_id  query string  false  "ユーザーID（認証情報がない場合は必須）"
+// @Success      204
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      502      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /chat-channels/{id}/test [post]
+func (h *ChatChannelHandler) TestChatChannel(c *gin.Context) {
+	userID, ok := actingUser(c, h.db, "", c.Query("user_id"))
+	if !ok {
+		return
+	}
+	var rawURL string
+	err := h.db.QueryRowContext(c.Request.Context(),
+		`SELECT url FROM chat_channels WHERE id = $1 AND created_by = $2`, c.Param("id"), userID).Scan(&rawURL)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された投稿先は見つかりません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if err := h.notifier.Send(c.Request.Context(), rawURL, slack.Message{Text: "Elmo からのテスト投稿です"}); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "投稿に失敗しました: " + err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateChatChannel_Creator(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`INSERT INTO chat_channels`).
+		WithArgs(sqlmock.AnyArg(), "slack", "https://hooks.slack.com/services/T0/B0/secret", "creator", nil, "u001", "conclusion.saved", "u001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+
+	body := `{"user_id":"u001","kind":"slack","url":"https://hooks.slack.com/services/T0/B0/secret","scope":"creator","events":["conclusion.saved"]}`
+	c, w := newJSONContext(http.MethodPost, "/chat-channels", body)
+	NewChatChannelHandler(db, slack.NewNotifier(db, "")).CreateChatChannel(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"url_host":"hooks.slack.com"`)
+	assert.Contains(t, w.Body.String(), `"creator_id":"u001"`)
+	// Incoming Webhook のURLはそれだけで投稿できるため返さない
+	assert.NotContains(t, w.Body.String(), "secret")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateChatChannel_Rejects(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := map[string]string{
+		"種類が不正":      `{"user_id":"u001","kind":"teams","url":"https://example.com","scope":"creator"}`,
+		"投稿しない出来事":   `{"user_id":"u001","kind":"slack","url":"https://example.com","scope":"creator","events":["room.created"]}`,
+		"globalは不可":  `{"user_id":"u001","kind":"slack","url":"https://example.com","scope":"global"}`,
+		"room_idがない": `{"user_id":"u001","kind":"mattermost","url":"https://example.com","scope":"room"}`,
+		"URLが不正":     `{"user_id":"u001","kind":"slack","url":"hooks.slack.com","scope":"creator"}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, w := newJSONContext(http.MethodPost, "/chat-channels", body)
+			NewChatChannelHandler(db, slack.NewNotifier(db, "")).CreateChatChannel(c)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTestChatChannel(t *testing.T) {
+	tests := map[string]struct {
+		status int
+		want   int
+	}{
+		"成功":         {http.StatusOK, http.StatusNoContent},
+		"投稿先が受け付けない": {http.StatusNotFound, http.StatusBadGateway},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var received bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = true
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+				WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+			mock.ExpectQuery(`SELECT url FROM chat_channels`).WithArgs("ch1", "u001").
+				WillReturnRows(sqlmock.NewRows([]string{"url"}).AddRow(server.URL))
+
+			c, w := newJSONContext(http.MethodPost, "/chat-channels/ch1/test?user_id=u001", "")
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: "ch1"})
+			NewChatChannelHandler(db, slack.NewNotifier(db, "").WithClient(server.Client())).TestChatChannel(c)
+
+			assert.Equal(t, tt.want, c.Writer.Status(), w.Body.String())
+			assert.True(t, received)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
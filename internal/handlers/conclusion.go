@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/conclusions"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+)
+
+// 結論の承認者の上限
+const maxConclusionApprovers = 20
+
+// saveConclusionVersion は結論を新しい版として記録し、変わった場合は結論の保存を通知します。
+// 失敗した場合はレスポンスを書き込んで false を返します。
+func (h *RoomHandler) saveConclusionVersion(c *gin.Context, roomID, userID, conclusion string, revertedFrom *int) bool {
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースの更新に失敗しました"})
+		return false
+	}
+	defer tx.Rollback()
+
+	_, changed, err := conclusions.Save(ctx, tx, roomID, &userID, conclusion, revertedFrom)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースの更新に失敗しました"})
+		}
+		return false
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースの更新に失敗しました"})
+		return false
+	}
+	if changed {
+		h.notifiers.Publish(ctx, h.db, notify.TypeConclusionSaved, roomID, notify.ConclusionSaved{Conclusion: conclusion})
+	}
+	return true
+}
+
+// loadConclusionApproval は結論の承認者と、それぞれが最新の版（current）を承認したかを返します。
+func loadConclusionApproval(ctx context.Context, db *sql.DB, roomID string, current int) (models.ConclusionApproval, error) {
+	approval := models.ConclusionApproval{Approved: true, Approvers: []models.ConclusionApprover{}}
+	rows, err := db.QueryContext(ctx, `
+		SELECT ca.user_id, u.user_name, ca.approved_version, ca.approved_at
+		FROM conclusion_approvers ca JOIN users u ON u.id = ca.user_id
+		WHERE ca.room_id = $1
+		ORDER BY u.user_name, ca.user_id`, roomID)
+	if err != nil {
+		return approval, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var approver models.ConclusionApprover
+		var version sql.NullInt64
+		var approvedAt sql.NullTime
+		if err := rows.Scan(&approver.UserID, &approver.UserName, &version, &approvedAt); err != nil {
+			return approval, err
+		}
+		if version.Valid {
+			v := int(version.Int64)
+			approver.ApprovedVersion = &v
+			approver.Approved = v == current
+		}
+		if approvedAt.Valid {
+			approver.ApprovedAt = &approvedAt.Time
+		}
+		approval.Required = true
+		approval.Approved = approval.Approved && approver.Approved
+		approval.Approvers = append(approval.Approvers, approver)
+	}
+	return approval, rows.Err()
+}
+
+// loadConclusionHistory は結論の版を新しい順に、一つ前の版からの差分と承認の状況とともに返します。
+// 匿名モードの会議室では保存したユーザーを含めません。
+func loadConclusionHistory(ctx context.Context, db *sql.DB, roomID string) (models.ConclusionHistory, error) {
+	history := models.ConclusionHistory{RoomID: roomID, Versions: []models.ConclusionVersion{}}
+	var anonymous bool
+	err := db.QueryRowContext(ctx, `SELECT conclusion_version, anonymous FROM rooms WHERE id = $1`, roomID).
+		Scan(&history.CurrentVersion, &anonymous)
+	if err != nil {
+		return history, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT v.version, v.conclusion, v.author_id, u.user_name, v.reverted_from, v.created_at
+		FROM conclusion_versions v LEFT JOIN users u ON u.id = v.author_id
+		WHERE v.room_id = $1
+		ORDER BY v.version`, roomID)
+	if err != nil {
+		return history, err
+	}
+	defer rows.Close()
+	previous := ""
+	for rows.Next() {
+		var v models.ConclusionVersion
+		var authorID, authorName sql.NullString
+		var revertedFrom sql.NullInt64
+		if err := rows.Scan(&v.Version, &v.Conclusion, &authorID, &authorName, &revertedFrom, &v.CreatedAt); err != nil {
+			return history, err
+		}
+		if authorID.Valid && !anonymous {
+			v.AuthorID = &authorID.String
+			v.AuthorName = &authorName.String
+		}
+		if revertedFrom.Valid {
+			from := int(revertedFrom.Int64)
+			v.RevertedFrom = &from
+		}
+		v.Diff = conclusions.Diff(previous, v.Conclusion)
+		previous = v.Conclusion
+		history.Versions = append(history.Versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return history, err
+	}
+	for i, j := 0, len(history.Versions)-1; i < j; i, j = i+1, j-1 {
+		history.Versions[i], history.Versions[j] = history.Versions[j], history.Versions[i]
+	}
+
+	history.Approval, err = loadConclusionApproval(ctx, db, roomID, history.CurrentVersion)
+	return history, err
+}
+
+func (h *RoomHandler) respondConclusionHistory(c *gin.Context, roomID string) {
+	history, err := loadConclusionHistory(c.Request.Context(), h.db, roomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "結論の履歴の取得に失敗しました"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// GetConclusionHistory godoc
+// @Summary      結論の履歴を取得
+// @Description  会議の結論の版を新しい順に、保存したユーザー・日時・一つ前の版からの差分とともに取得します。承認の状況も返します。匿名モードの会議室では保存したユーザーを含みません
+// @Tags         conclusion
+// @Produce      json
+// @Param        id       path      string  true   "会議室ID"
+// @Param        user_id  query     string  false  "操作するユーザーのID（認証情報がない場合は必須）"
+// @Success      200      {object}  models.ConclusionHistory
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/conclusion/history [get]
+func (h *RoomHandler) GetConclusionHistory(c *gin.Context) {
+	roomID := c.Param("id")
+	scope, ok := resolveRoomScope(c, h.db)
+	if !ok {
+		return
+	}
+	found, err := scope.includes(c.Request.Context(), h.db, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		return
+	}
+	h.respondConclusionHistory(c, roomID)
+}
+
+// RevertConclusion godoc
+// @Summary      結論を以前の版に戻す
+// @Description  以前の版の結論を新しい版として保存します（履歴は消えません）。承認者は戻した版をもう一度承認する必要があります。ホストのみ実行できます
+// @Tags         conclusion
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                          true  "会議室ID"
+// @Param        request  body      models.ConclusionRevertRequest  true  "戻す版"
+// @Success      200      {object}  models.ConclusionHistory
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/conclusion/revert [post]
+func (h *RoomHandler) RevertConclusion(c *gin.Context) {
+	roomID := c.Param("id")
+	var req models.ConclusionRevertRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Version <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "versionは1以上で指定してください"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	var conclusion string
+	err := h.db.QueryRowContext(c.Request.Context(), `
+		SELECT conclusion FROM conclusion_versions WHERE room_id = $1 AND version = $2`, roomID, req.Version).Scan(&conclusion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された版は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	if !h.saveConclusionVersion(c, roomID, userID, conclusion, &req.Version) {
+		return
+	}
+	h.respondConclusionHistory(c, roomID)
+}
+
+// SetConclusionApprovers godoc
+// @Summary      結論の承認者を設定
+// @Description  結論の承認者を置き換えます。承認者がいる会議室は、全員が最新の版を承認するまで done にできません（予定の時間が過ぎても自動では終了しません）。引き続き承認者になったユーザーの承認は残ります。ホストのみ実行できます
+// @Tags         conclusion
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                             true  "会議室ID"
+// @Param        request  body      models.ConclusionApproversRequest  true  "承認者"
+// @Success      200      {object}  models.ConclusionApproval
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/conclusion/approvers [put]
+func (h *RoomHandler) SetConclusionApprovers(c *gin.Context) {
+	roomID := c.Param("id")
+	var req models.ConclusionApproversRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	if len(req.ApproverIDs) > maxConclusionApprovers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "approver_idsは20人以内で指定してください"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	wanted := make(map[string]bool)
+	for _, id := range req.ApproverIDs {
+		if wanted[id] {
+			continue
+		}
+		wanted[id] = true
+		// 承認者はホスト・参加者・招待されたユーザーから選ぶ（ゲストは後で削除されるため選べない）
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO conclusion_approvers (room_id, user_id)
+			SELECT $1, u.id FROM users u
+			WHERE u.id = $2 AND NOT u.is_guest AND NOT u.is_service_account AND u.id IN (
+				SELECT created_by FROM rooms WHERE id = $1
+				UNION SELECT user_id FROM participants WHERE room_id = $1
+				UNION SELECT user_id FROM room_invitations WHERE room_id = $1)
+			ON CONFLICT (room_id, user_id) DO NOTHING`, roomID, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "承認者の設定に失敗しました"})
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			var exists bool
+			err := tx.QueryRowContext(ctx, `
+				SELECT EXISTS (SELECT 1 FROM conclusion_approvers WHERE room_id = $1 AND user_id = $2)`, roomID, id).Scan(&exists)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "承認者の設定に失敗しました"})
+				return
+			}
+			if !exists {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "会議室のホスト・参加者・招待されたユーザーではありません: " + id})
+				return
+			}
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT user_id FROM conclusion_approvers WHERE room_id = $1`, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "承認者の設定に失敗しました"})
+		return
+	}
+	var removed []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "承認者の設定に失敗しました"})
+			return
+		}
+		if !wanted[id] {
+			removed = append(removed, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "承認者の設定に失敗しました"})
+		return
+	}
+	for _, id := range removed {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM conclusion_approvers WHERE room_id = $1 AND user_id = $2`, roomID, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "承認者の設定に失敗しました"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "承認者の設定に失敗しました"})
+		return
+	}
+	h.respondConclusionApproval(c, roomID)
+}
+
+func (h *RoomHandler) respondConclusionApproval(c *gin.Context, roomID string) {
+	ctx := c.Request.Context()
+	var current int
+	err := h.db.QueryRowContext(ctx, `SELECT conclusion_version FROM rooms WHERE id = $1`, roomID).Scan(&current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	approval, err := loadConclusionApproval(ctx, h.db, roomID, current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "承認の状況の取得に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusOK, approval)
+}
+
+// ApproveConclusion godoc
+// @Summary      結論を承認
+// @Description  承認者が結論の版を承認します。承認後に結論が変わった場合はもう一度承認する必要があります。最新の版でない場合は 409 を返します
+// @Tags         conclusion
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                           true  "会議室ID"
+// @Param        request  body      models.ConclusionApproveRequest  true  "承認する版"
+// @Success      200      {object}  models.ConclusionApproval
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/conclusion/approve [post]
+func (h *RoomHandler) ApproveConclusion(c *gin.Context) {
+	roomID := c.Param("id")
+	var req models.ConclusionApproveRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Version <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "versionは1以上で指定してください"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var current int
+	var isApprover bool
+	err := h.db.QueryRowContext(ctx, `
+		SELECT r.conclusion_version, EXISTS (SELECT 1 FROM conclusion_approvers WHERE room_id = r.id AND user_id = $2)
+		FROM rooms r WHERE r.id = $1`, roomID, userID).Scan(&current, &isApprover)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	if !isApprover {
+		c.JSON(http.StatusForbidden, gin.H{"error": "この会議室の結論の承認者ではありません"})
+		return
+	}
+	if req.Version != current {
+		c.JSON(http.StatusConflict, gin.H{"error": "最新の版の結論ではありません", "current_version": current})
+		return
+	}
+
+	// 承認の間に新しい版が保存されても、承認した版が最新でなければ承認済みにはならない
+	_, err = h.db.ExecContext(ctx, `
+		UPDATE conclusion_approvers SET approved_version = $3, approved_at = NOW()
+		WHERE room_id = $1 AND user_id = $2`, roomID, userID, req.Version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "結論の承認に失敗しました"})
+		return
+	}
+	h.respondConclusionApproval(c, roomID)
+}
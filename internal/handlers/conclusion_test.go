@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveConclusion_RecordsVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT COALESCE\(r.created_by = \$2, FALSE\) OR EXISTS`).WithArgs("r001", "u001").
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COALESCE\(conclusion, ''\), conclusion_version FROM rooms WHERE id = \$1 FOR UPDATE`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"conclusion", "conclusion_version"}).AddRow("リリースする", 1))
+	mock.ExpectExec(`INSERT INTO conclusion_versions`).WithArgs("r001", 2, "リリースを延期する", "u001", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE rooms SET conclusion = \$2, conclusion_version = \$3, status = 'concluded'`).WithArgs("r001", "リリースを延期する", 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT id, title, description, conclusion, status FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "conclusion", "status"}).
+			AddRow("r001", "週次定例", "", "リリースを延期する", "concluded"))
+
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/conclusion", `{"user_id":"u001","conclusion":"リリースを延期する"}`)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewRoomHandler(db, nil).SaveConclusion(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"id":"r001","title":"週次定例","description":"","conclusion":"リリースを延期する","status":"concluded"}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetConclusionHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT r.id FROM rooms r WHERE r.id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("r001"))
+	mock.ExpectQuery(`SELECT conclusion_version, anonymous FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"conclusion_version", "anonymous"}).AddRow(3, true))
+	mock.ExpectQuery(`FROM conclusion_versions v`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "conclusion", "author_id", "user_name", "reverted_from", "created_at"}).
+			AddRow(1, "リリースする", "u001", "田中", nil, at).
+			AddRow(2, "リリースを延期する", "u002", "佐藤", nil, at).
+			AddRow(3, "リリースする", "u001", "田中", 1, at))
+	mock.ExpectQuery(`FROM conclusion_approvers ca JOIN users u`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "user_name", "approved_version", "approved_at"}).
+			AddRow("u002", "佐藤", 2, at).
+			AddRow("u003", "鈴木", 3, at))
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/conclusion/history?user_id=u001", "")
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewRoomHandler(db, nil).GetConclusionHistory(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var history models.ConclusionHistory
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	require.Len(t, history.Versions, 3)
+	// 新しい順に並べ、匿名モードの会議室では保存したユーザーを含めない
+	latest := history.Versions[0]
+	assert.Equal(t, 3, latest.Version)
+	assert.Equal(t, 1, *latest.RevertedFrom)
+	assert.Nil(t, latest.AuthorID)
+	assert.Equal(t, []models.DiffLine{{Op: "delete", Text: "リリースを延期する"}, {Op: "insert", Text: "リリースする"}}, latest.Diff)
+	assert.Equal(t, []models.DiffLine{{Op: "insert", Text: "リリースする"}}, history.Versions[2].Diff)
+	// 古い版を承認しただけの承認者がいるため、承認は済んでいない
+	assert.True(t, history.Approval.Required)
+	assert.False(t, history.Approval.Approved)
+	assert.False(t, history.Approval.Approvers[0].Approved)
+	assert.True(t, history.Approval.Approvers[1].Approved)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApproveConclusion_StaleVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT r.conclusion_version, EXISTS`).WithArgs("r001", "u002").
+		WillReturnRows(sqlmock.NewRows([]string{"conclusion_version", "is_approver"}).AddRow(3, true))
+
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/conclusion/approve", `{"user_id":"u002","version":2}`)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewRoomHandler(db, nil).ApproveConclusion(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateRoomStatus_PendingApproval(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE rooms SET status = \$1 WHERE id = \$2 AND status <> \$1 AND NOT EXISTS`).WithArgs("done", "r001").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT status, EXISTS`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "pending"}).AddRow("concluded", true))
+
+	c, w := newJSONContext(http.MethodPut, "/rooms/r001/status", `{"status":"done"}`)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewRoomHandler(db, nil).UpdateRoomStatus(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
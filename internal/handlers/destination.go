@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+	"github.com/shuto.sawaki/elmo-project/internal/webhooks"
+)
+
+// destinationRequest は Webhook の購読とチャットツールの投稿先に共通する登録の内容です。
+type destinationRequest struct {
+	URL    string
+	Events []string
+	Scope  string
+	RoomID *string
+	UserID string
+}
+
+// destination は確かめた送り先です。範囲が room なら roomID、creator なら creatorID が入ります。
+type destination struct {
+	userID    string
+	events    []string
+	roomID    *string
+	creatorID *string
+}
+
+// bindDestination は送り先の URL・出来事の種類・範囲を確かめ、登録するユーザーと範囲の会議室または作成者を決めます。
+// 出来事の種類は重複を除き、types を指定した場合はそのうちの種類だけを受け付けます。scopes は受け付ける範囲です。
+// room の範囲はホストのみ、global の範囲は webhooks:manage スコープの API キーのみ登録できます。
+// 不正な場合はレスポンスを書き込んで false を返します。
+func bindDestination(c *gin.Context, db *sql.DB, req destinationRequest, types, scopes []string) (destination, bool) {
+	if notify.ValidateURL(req.URL) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "urlには内部のネットワーク以外を指すhttpまたはhttpsのURLを指定してください"})
+		return destination{}, false
+	}
+	dest := destination{events: []string{}}
+	for _, e := range req.Events {
+		if !notify.ValidType(e) || (types != nil && !slices.Contains(types, e)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "eventsが不正です: " + e})
+			return destination{}, false
+		}
+		if !slices.Contains(dest.events, e) {
+			dest.events = append(dest.events, e)
+		}
+	}
+	if !slices.Contains(scopes, req.Scope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scopeは " + strings.Join(scopes, " / ") + " のいずれかを指定してください"})
+		return destination{}, false
+	}
+	roomID := ""
+	if req.RoomID != nil {
+		roomID = *req.RoomID
+	}
+	if req.Scope == webhooks.ScopeRoom && roomID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scopeがroomの場合はroom_idが必須です"})
+		return destination{}, false
+	}
+	userID, ok := actingUser(c, db, "", req.UserID)
+	if !ok {
+		return destination{}, false
+	}
+	dest.userID = userID
+
+	switch req.Scope {
+	case webhooks.ScopeRoom:
+		if !requireHost(c, db, roomID, userID) {
+			return destination{}, false
+		}
+		dest.roomID = &roomID
+	case webhooks.ScopeCreator:
+		dest.creatorID = &userID
+	case webhooks.ScopeGlobal:
+		// すべての会議室の出来事を受け取れるのは、管理用のスコープを持つサービスアカウントのみ
+		if principal, ok := auth.PrincipalFrom(c); !ok || !principal.HasScope(auth.ScopeWebhooksManage) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "globalの購読は webhooks:manage スコープのAPIキーでのみ作成できます"})
+			return destination{}, false
+		}
+	}
+	return dest, true
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/events"
+)
+
+// 接続を維持するためのコメント行を送る間隔
+const eventKeepAliveInterval = 25 * time.Second
+
+type EventHandler struct {
+	db  *sql.DB
+	hub *events.Hub
+}
+
+func NewEventHandler(db *sql.DB, hub *events.Hub) *EventHandler {
+	return &EventHandler{db: db, hub: hub}
+}
+
+// StreamRoomEvents godoc
+// @Summary      会議室の通知を購読
+// @Description  投票の作成・集計の更新・締め切りなどをServer-Sent Eventsで配信します。ユーザーはホストか参加したことのある会議室、ゲストはトークンの会議室のみ購読できます
+// @Tags         events
+// @Produce      text/event-stream
+// @Param        id       path      string  true   "会議室ID"
+// @Param        user_id  query     string  false  "ユーザーID（認証情報がない場合は必須）"
+// @Success      200      {string}  string  "イベントストリーム"
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/events [get]
+func (h *EventHandler) StreamRoomEvents(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	// 範囲外の会議室は存在を明かさない
+	scope, ok := resolveRoomScope(c, h.db)
+	if !ok {
+		return
+	}
+	if found, err := scope.includes(ctx, h.db, roomID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	} else if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		return
+	}
+
+	stream, cancel := h.hub.Subscribe(roomID)
+	defer cancel()
+	ticker := time.NewTicker(eventKeepAliveInterval)
+	defer ticker.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case e := <-stream:
+			c.SSEvent(e.Type, e.Data)
+			return true
+		case <-ticker.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamRoomEvents_OutsideScope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h := NewEventHandler(db, events.NewHub())
+
+	// ホストでも参加者でもないユーザー。範囲外の会議室は存在を明かさない
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u009").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT r.id FROM rooms r WHERE r.id = \$1 AND \(r.created_by = \$2`).WithArgs("r001", "u009").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/events?user_id=u009", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	h.StreamRoomEvents(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "指定された部屋は見つかりません")
+
+	// 別の会議室のゲストトークン
+	mock.ExpectQuery(`SELECT r.id FROM rooms r WHERE r.id = \$1 AND r.id = \$2`).WithArgs("r001", "r999").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	c, w = newJSONContext(http.MethodGet, "/rooms/r001/events", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	authenticateGuest(t, c, "g0000001", "r999")
+	h.StreamRoomEvents(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 認証情報も user_id もない
+	c, w = newJSONContext(http.MethodGet, "/rooms/r001/events", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	h.StreamRoomEvents(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/export"
+)
+
+type ExportHandler struct {
+	db       *sql.DB
+	exporter *export.Exporter
+}
+
+func NewExportHandler(db *sql.DB, exporter *export.Exporter) *ExportHandler {
+	return &ExportHandler{db: db, exporter: exporter}
+}
+
+// ExportMinutes godoc
+// @Summary      議事録を書き出す
+// @Description  会議のタイトル・説明・最初の問いかけ・参加者・発言とAI要約のタイムライン・議題・「それな」ランキング・投票結果・結論を議事録として書き出します。匿名モードの会議室では発言者の名前を含めず、参加者は人数のみ記載します。PDFにはサーバーに設定された日本語フォントを埋め込みます
+// @Tags         rooms
+// @Produce      text/markdown
+// @Produce      html
+// @Produce      application/pdf
+// @Param        id       path      string  true   "会議室のID"
+// @Param        format   query     string  false  "出力形式（md / html / pdf、既定 md）"
+// @Param        user_id  query     string  false  "ユーザーID（認証情報がない場合は必須。ホストか参加者の会議室のみ書き出せます）"
+// @Success      200      {file}    file
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Failure      503      {object}  map[string]interface{}
+// @Router       /rooms/{id}/export [get]
+func (h *ExportHandler) ExportMinutes(c *gin.Context) {
+	ctx := c.Request.Context()
+	roomID := c.Param("id")
+	format := c.DefaultQuery("format", export.FormatMarkdown)
+	contentType, err := export.ContentType(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format は md / html / pdf のいずれかを指定してください"})
+		return
+	}
+	// 書き出せるのは呼び出し元が見られる会議室のみ。範囲外の会議室は存在を明かさない
+	scope, ok := resolveRoomScope(c, h.db)
+	if !ok {
+		return
+	}
+	found, err := scope.includes(ctx, h.db, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		return
+	}
+
+	room, err := loadRoom(ctx, h.db, roomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+			return
+		}
+		log.Printf("議事録の会議室の取得に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	result, err := loadRoomResult(ctx, h.db, roomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+			return
+		}
+		log.Printf("議事録の集計の取得に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	names, participants, err := minutesMembers(ctx, h.db, roomID)
+	if err != nil {
+		log.Printf("議事録の参加者の取得に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+
+	minutes := export.NewMinutes(room, result, names, participants, time.Now())
+	body, err := h.exporter.Bytes(format, minutes)
+	if err != nil {
+		if errors.Is(err, export.ErrNoPDFFont) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "PDFの書き出しは設定されていません"})
+			return
+		}
+		log.Printf("議事録の書き出しに失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": room.Title + "." + format,
+	}))
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// minutesMembers は会議室の参加者（参加順。退出した人も含む）と、発言者を含むユーザーの表示名を読み込みます。
+func minutesMembers(ctx context.Context, db *sql.DB, roomID string) (map[string]string, []string, error) {
+	query := `
+		SELECT u.id, u.user_name, p.user_id IS NOT NULL
+		FROM users u
+		LEFT JOIN participants p ON p.user_id = u.id AND p.room_id = $1
+		WHERE p.user_id IS NOT NULL
+		   OR u.id IN (SELECT user_id FROM chat_logs WHERE room_id = $1 AND user_id IS NOT NULL)
+		ORDER BY p.joined_at ASC NULLS LAST, u.id`
+	rows, err := db.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[string]string)
+	var participants []string
+	for rows.Next() {
+		var id, name string
+		var participant bool
+		if err := rows.Scan(&id, &name, &participant); err != nil {
+			return nil, nil, err
+		}
+		names[id] = name
+		if participant {
+			participants = append(participants, id)
+		}
+	}
+	return names, participants, rows.Err()
+}
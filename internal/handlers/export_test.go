@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectMinutesQueries は議事録の書き出しで読み込むクエリを登録します。
+func expectMinutesQueries(mock sqlmock.Sqlmock, anonymous bool) {
+	now := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT r.id FROM rooms r WHERE r.id = \$1 AND \(r.created_by = \$2`).WithArgs("r001", "u001").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("r001"))
+	mock.ExpectQuery(`SELECT id, title, description, conclusion, status, initial_question, .* FROM rooms WHERE id = \$1`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "conclusion", "status", "initial_question", "max_participants", "allow_guests", "anonymous", "created_by", "scheduled_start_at", "duration_minutes", "time_zone", "series_id", "previous_room_id", "template_id", "prompt_variant"}).
+			AddRow("r001", "週次ふりかえり", "", "隔週にする", "done", "何を変えたいですか？", nil, false, anonymous, "u001", nil, nil, "Asia/Tokyo", nil, nil, nil, "default"))
+	mock.ExpectQuery(`SELECT tag FROM room_tags`).WithArgs("r001").WillReturnRows(sqlmock.NewRows([]string{"tag"}))
+	mock.ExpectQuery(`SELECT title, anonymous, current_agenda_item_id FROM rooms`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "anonymous", "current_agenda_item_id"}).AddRow("週次ふりかえり", anonymous, nil))
+	mock.ExpectQuery(`FROM agenda_items`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "timebox_minutes", "initial_question", "conclusion", "started_at", "ended_at"}))
+	mock.ExpectQuery(`SELECT t.key, t.label, t.emoji, t.built_in\s+FROM reaction_types`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "label", "emoji", "built_in"}).AddRow("sorena", "それな", "🙌", true))
+	mock.ExpectQuery(`SELECT r.reaction_type, u.id`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"reaction_type", "id", "user_name", "is_guest", "count"}).AddRow("sorena", "u001", "田中太郎", false, 2))
+	mock.ExpectQuery(`SELECT r.message_id, r.reaction_type, COUNT`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "reaction_type", "count"}).AddRow("log1", "sorena", 2))
+	mock.ExpectQuery(`SELECT l.id, l.user_id, l.message`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "message", "is_summary", "created_at", "is_guest", "agenda_item_id"}).
+			AddRow("log1", "u001", "進め方を変えたい", false, now, false, nil).
+			AddRow("log2", nil, "隔週にする案が出ています", true, now.Add(time.Minute), false, nil))
+	mock.ExpectQuery(`FROM polls p\s+WHERE p.room_id = \$1`).
+		WithArgs("r001", "", true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "question", "multiple", "anonymous", "created_by", "closes_at", "created_at", "closed", "total_voters"}))
+	mock.ExpectQuery(`SELECT u.id, u.user_name, p.user_id IS NOT NULL`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_name", "participant"}).
+			AddRow("u001", "田中太郎", true).
+			AddRow("u002", "佐藤花子", true))
+}
+
+func TestExportMinutes_Markdown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+	expectMinutesQueries(mock, false)
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/export?user_id=u001", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewExportHandler(db, export.NewExporter(nil)).ExportMinutes(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/markdown; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment; filename*=utf-8''")
+	body := w.Body.String()
+	assert.Contains(t, body, "# 週次ふりかえり\n")
+	assert.Contains(t, body, "- 田中太郎\n- 佐藤花子\n")
+	assert.Contains(t, body, "- 10:00 **田中太郎**（それな 2）: 進め方を変えたい\n")
+	assert.Contains(t, body, "- 10:01 **AI要約**: 隔週にする案が出ています\n")
+	assert.Contains(t, body, "1. 進め方を変えたい（田中太郎、それな 2）\n")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportMinutes_AnonymousRoomHidesNames(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+	expectMinutesQueries(mock, true)
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/export?format=html&user_id=u001", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewExportHandler(db, export.NewExporter(nil)).ExportMinutes(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "（2名）")
+	assert.NotContains(t, w.Body.String(), "田中太郎")
+	assert.NotContains(t, w.Body.String(), "佐藤花子")
+}
+
+func TestExportMinutes_PDFWithoutFont(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+	expectMinutesQueries(mock, false)
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/export?format=pdf&user_id=u001", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewExportHandler(db, export.NewExporter(nil)).ExportMinutes(c)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestExportMinutes_Rejects(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/export?format=docx", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewExportHandler(db, export.NewExporter(nil)).ExportMinutes(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 別の会議室のゲストトークン。範囲外の会議室は存在を明かさない
+	tokens := auth.NewGuestTokens([]byte("secret"), time.Hour)
+	token, _, err := tokens.Issue("g001", "r999")
+	require.NoError(t, err)
+	c, w = newJSONContext(http.MethodGet, "/rooms/r001/export", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	c.Request.Header.Set("Authorization", "Bearer "+token)
+	auth.Middleware(tokens, nil, nil, nil)(c)
+	mock.ExpectQuery(`SELECT r.id FROM rooms r WHERE r.id = \$1 AND r.id = \$2`).WithArgs("r001", "r999").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	NewExportHandler(db, export.NewExporter(nil)).ExportMinutes(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "指定された部屋は見つかりません")
+
+	// ホストでも参加者でもないユーザー
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u009").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT r.id FROM rooms r WHERE r.id = \$1 AND \(r.created_by = \$2`).WithArgs("r001", "u009").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	c, w = newJSONContext(http.MethodGet, "/rooms/r001/export?user_id=u009", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewExportHandler(db, export.NewExporter(nil)).ExportMinutes(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// users.user_name の最大長
+const maxNicknameLength = 20
+
+type GuestHandler struct {
+	db     *sql.DB
+	tokens *auth.GuestTokens
+}
+
+func NewGuestHandler(db *sql.DB, tokens *auth.GuestTokens) *GuestHandler {
+	return &GuestHandler{db: db, tokens: tokens}
+}
+
+// JoinAsGuest godoc
+// @Summary      ゲストとして参加
+// @Description  アカウントを作成せずにニックネームだけで会議室に参加し、その会議室でのみ有効な短命のトークンを受け取ります
+// @Tags         participants
+// @Accept       json
+// @Produce      json
+// @Param        id     path      string                   true  "会議室ID"
+// @Param        guest  body      models.GuestJoinRequest  true  "ゲスト情報"
+// @Success      201    {object}  models.GuestJoinResponse
+// @Success      202    {object}  models.GuestJoinResponse
+// @Failure      400    {object}  map[string]interface{}
+// @Failure      403    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]interface{}
+// @Failure      500    {object}  map[string]interface{}
+// @Router       /rooms/{id}/guests [post]
+func (h *GuestHandler) JoinAsGuest(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req models.GuestJoinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	req.Nickname = strings.TrimSpace(req.Nickname)
+	if req.Nickname == "" || utf8.RuneCountInString(req.Nickname) > maxNicknameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ニックネームは1〜20文字で指定してください"})
+		return
+	}
+
+	var allowGuests bool
+	err := h.db.QueryRowContext(ctx, `SELECT allow_guests FROM rooms WHERE id = $1`, roomID).Scan(&allowGuests)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	if !allowGuests {
+		c.JSON(http.StatusForbidden, gin.H{"error": "この会議室はゲスト参加を許可していません"})
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	var userID string
+	const maxRetries = 10
+	for i := 0; i < maxRetries && userID == ""; i++ {
+		newID, err := gonanoid.Generate("0123456789abcdefghijklmnopqrstuvwxyz", 8)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO users (id, user_name, is_guest) VALUES ($1, $2, TRUE)
+			ON CONFLICT (id) DO NOTHING`, newID, req.Nickname)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			userID = newID
+		}
+	}
+	if userID == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部で問題が発生しました。"})
+		return
+	}
+
+	result, err := joinRoom(ctx, tx, roomID, userID)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if errors.Is(err, errRoomNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ゲスト参加に失敗しました"})
+		return
+	}
+
+	token, expiresAt, err := h.tokens.Issue(userID, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "トークンの発行に失敗しました"})
+		return
+	}
+
+	response := models.GuestJoinResponse{
+		RoomID:           roomID,
+		UserID:           userID,
+		Nickname:         req.Nickname,
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		Status:           result.Status,
+		WaitlistPosition: result.Position,
+	}
+	if result.Status == models.JoinStatusWaitlisted {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// actingUser はリクエストを実行するユーザーを決定します。
+// ゲストトークンがある場合はトークンの部屋・ユーザーに限定され、それ以外の操作は拒否します。
+// APIキーの場合はサービスアカウント自身として扱います。
+// トークンを持たないリクエストでゲストユーザーを名乗ることもできません。
+// 拒否した場合はレスポンスを書き込んで false を返します。
+func actingUser(c *gin.Context, db *sql.DB, roomID, requestedUserID string) (string, bool) {
+	if principal, ok := auth.PrincipalFrom(c); ok {
+		if (principal.Kind == auth.KindGuest && principal.RoomID != roomID) ||
+			(requestedUserID != "" && requestedUserID != principal.UserID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "この認証情報はこの操作に使用できません"})
+			return "", false
+		}
+		return principal.UserID, true
+	}
+
+	if requestedUserID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_idは必須です"})
+		return "", false
+	}
+	var isGuest bool
+	err := db.QueryRowContext(c.Request.Context(), `SELECT is_guest FROM users WHERE id = $1`, requestedUserID).Scan(&isGuest)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return "", false
+	}
+	if isGuest {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "ゲストはゲストトークンが必要です"})
+		return "", false
+	}
+	return requestedUserID, true
+}
+
+// isForeignKeyViolation は外部キー制約違反かどうかを返します。
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
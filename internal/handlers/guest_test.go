@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostMessage_GuestTokenIsScopedToOneRoom(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	tokens := auth.NewGuestTokens([]byte("secret"), time.Hour)
+	token, _, err := tokens.Issue("g0000001", "r001")
+	require.NoError(t, err)
+
+	c, w := newJSONContext(http.MethodPost, "/rooms/r002/messages", `{"message":"こんにちは"}`)
+	c.Request.Header.Set("Authorization", "Bearer "+token)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r002"}}
+
+	auth.Middleware(tokens, nil, nil, nil)(c)
+	NewRoomHandler(db, nil).PostMessage(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleSorena_GuestWithoutTokenIsRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users WHERE id = \$1`).
+		WithArgs("g0000001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(true))
+
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/sorena", `{"user_id":"g0000001","message_id":"log1"}`)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewRoomHandler(db, nil).HandleSorena(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isActiveParticipant はユーザーが会議室に参加中かどうかを返します。
+func isActiveParticipant(ctx context.Context, db *sql.DB, roomID, userID string) (bool, error) {
+	var ok bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM participants WHERE room_id = $1 AND user_id = $2 AND left_at IS NULL)`,
+		roomID, userID).Scan(&ok)
+	return ok, err
+}
+
+// requireHost はユーザーが会議室のホストとして操作できることを確認します。
+// ホスト（rooms.created_by）が記録されていない部屋では、参加中のユーザーなら誰でも操作できます。
+// 拒否した場合はレスポンスを書き込んで false を返します。
+func requireHost(c *gin.Context, db *sql.DB, roomID, userID string) bool {
+	ctx := c.Request.Context()
+	var createdBy sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT created_by FROM rooms WHERE id = $1`, roomID).Scan(&createdBy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return false
+	}
+	if createdBy.Valid {
+		if createdBy.String != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "この操作は会議室のホストのみ実行できます"})
+			return false
+		}
+		return true
+	}
+
+	ok, err := isActiveParticipant(ctx, db, roomID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "この会議室の参加者ではありません"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/mail"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// 一度に招待できる人数
+const maxInvitations = 50
+
+type InvitationHandler struct {
+	db     *sql.DB
+	mailer *mail.Mailer
+}
+
+func NewInvitationHandler(db *sql.DB, mailer *mail.Mailer) *InvitationHandler {
+	return &InvitationHandler{db: db, mailer: mailer}
+}
+
+// InviteToRoom godoc
+// @Summary      会議室にユーザーを招待
+// @Description  ユーザーを会議室に招待し、招待のメールを受け取る設定のユーザーにメールを送ります。招待したユーザーには開始前のリマインダーと会議後のまとめも送ります。招待済みのユーザーを指定するとメールを送り直します。ホストのみ実行できます
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string                    true  "会議室ID"
+// @Param        invitation  body      models.InvitationRequest  true  "招待するユーザー"
+// @Success      201         {object}  models.InvitationResponse
+// @Failure      400         {object}  map[string]interface{}
+// @Failure      403         {object}  map[string]interface{}
+// @Failure      404         {object}  map[string]interface{}
+// @Failure      500         {object}  map[string]interface{}
+// @Router       /rooms/{id}/invitations [post]
+func (h *InvitationHandler) InviteToRoom(c *gin.Context) {
+	roomID := c.Param("id")
+	var req models.InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	if len(req.UserIDs) == 0 || len(req.UserIDs) > maxInvitations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_idsは1〜50人で指定してください"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	invited := []string{}
+	seen := make(map[string]bool)
+	for _, id := range req.UserIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO room_invitations (room_id, user_id, invited_by)
+			SELECT $1, id, $3 FROM users WHERE id = $2 AND NOT is_guest AND NOT is_service_account
+			ON CONFLICT (room_id, user_id) DO UPDATE SET invited_by = EXCLUDED.invited_by, invited_at = NOW()`,
+			roomID, id, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "招待に失敗しました"})
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーが見つかりません: " + id})
+			return
+		}
+		invited = append(invited, id)
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "招待に失敗しました"})
+		return
+	}
+
+	// 招待は記録できているため、メールを積めなくても失敗にはしない
+	emailed, err := h.mailer.Invite(ctx, roomID, userID, invited)
+	if err != nil {
+		log.Printf("招待のメールの送信に失敗しました: room=%s, err=%v", roomID, err)
+	}
+	c.JSON(http.StatusCreated, models.InvitationResponse{Invited: invited, Emailed: emailed})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteToRoom(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO room_invitations`).WithArgs("r001", "u002", "u001").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO room_invitations`).WithArgs("r001", "u003", "u001").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// メールの送信先が設定されていない場合は招待だけを記録する
+	mailer, err := mail.NewMailer(db, nil, "", "")
+	require.NoError(t, err)
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/invitations", `{"user_id":"u001","user_ids":["u002","u003","u002"]}`)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewInvitationHandler(db, mailer).InviteToRoom(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"invited":["u002","u003"],"emailed":0}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInviteToRoom_UnknownUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO room_invitations`).WithArgs("r001", "nobody", "u001").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	mailer, err := mail.NewMailer(db, nil, "", "")
+	require.NoError(t, err)
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/invitations", `{"user_id":"u001","user_ids":["nobody"]}`)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewInvitationHandler(db, mailer).InviteToRoom(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
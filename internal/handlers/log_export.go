@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 書き出したデータをクライアントに送る間隔（行数）
+const logExportFlushRows = 500
+
+// logExportColumns はチャットログの書き出しの列です。この後にリアクションの種類ごとの列が続きます。
+var logExportColumns = []string{
+	"room_id", "room_title", "message_id", "created_at", "time_zone",
+	"user_id", "user_name", "is_guest", "is_summary", "agenda_item", "message", "reaction_total",
+}
+
+// logExportFormat はチャットログの書き出しの形式です。
+type logExportFormat struct {
+	ext         string
+	contentType string
+	comma       rune
+	bom         bool
+}
+
+// parseLogExportFormat は format（csv / tsv）と bom のクエリを読み取ります。
+func parseLogExportFormat(c *gin.Context) (logExportFormat, bool) {
+	var f logExportFormat
+	switch c.DefaultQuery("format", "csv") {
+	case "csv":
+		f = logExportFormat{ext: "csv", contentType: "text/csv; charset=utf-8", comma: ','}
+	case "tsv":
+		f = logExportFormat{ext: "tsv", contentType: "text/tab-separated-values; charset=utf-8", comma: '\t'}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format は csv / tsv のいずれかを指定してください"})
+		return f, false
+	}
+	if v := c.Query("bom"); v != "" {
+		bom, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bom には true / false を指定してください"})
+			return f, false
+		}
+		f.bom = bom
+	}
+	return f, true
+}
+
+// buildLogExportQuery は範囲内の会議室のチャットログを、メッセージごとのリアクションの数と一緒に読み込むクエリを組み立てます。
+// roomID を指定した場合はその会議室だけ、そうでなければ会議の日時（開始日時、なければ開始予定日時か作成日時）が [from, to) の会議室を対象にします。
+func buildLogExportQuery(scope roomScope, roomID string, from, to time.Time) (string, []interface{}) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conds := []string{scope.condition(arg)}
+	if roomID != "" {
+		conds = append(conds, "r.id = "+arg(roomID))
+	} else {
+		conds = append(conds,
+			"COALESCE(r.started_at, r.scheduled_start_at, r.created_at) >= "+arg(from),
+			"COALESCE(r.started_at, r.scheduled_start_at, r.created_at) < "+arg(to))
+	}
+
+	query := `
+		WITH exported AS (
+			SELECT r.id, r.title, r.anonymous, r.time_zone, COALESCE(r.started_at, r.scheduled_start_at, r.created_at) AS held_at
+			FROM rooms r WHERE ` + strings.Join(conds, " AND ") + `
+		)
+		SELECT e.id, e.title, e.anonymous, e.time_zone, l.id, l.created_at, l.user_id, u.user_name, COALESCE(u.is_guest, FALSE),
+		       l.is_summary, COALESCE(ai.title, ''), l.message,
+		       COALESCE((SELECT json_object_agg(c.reaction_type, c.n) FROM (
+		           SELECT reaction_type, COUNT(*) AS n FROM message_reactions WHERE message_id = l.id GROUP BY reaction_type
+		       ) c), '{}')
+		FROM chat_logs l
+		JOIN exported e ON e.id = l.room_id
+		LEFT JOIN users u ON u.id = l.user_id
+		LEFT JOIN agenda_items ai ON ai.id = l.agenda_item_id
+		ORDER BY e.held_at, e.id, l.created_at, l.id`
+	return query, args
+}
+
+// ExportRoomLogs godoc
+// @Summary      会議室のチャットログをCSV・TSVで書き出す
+// @Description  会議室のチャットログ（AIの要約を含む）を、投稿日時（会議室のタイムゾーン）・発言者・メッセージ・リアクションの種類ごとの数の列を持つCSVまたはTSVで書き出します。一行ずつ送るため大きな会議室でもメモリに溜めません。匿名モードの会議室では発言者の列を空にします。ユーザーはホストか参加したことのある会議室、ゲストはトークンの会議室、サービスアカウントはすべての会議室を書き出せます
+// @Tags         rooms
+// @Produce      text/csv
+// @Produce      text/tab-separated-values
+// @Param        id       path      string  true   "会議室のID"
+// @Param        format   query     string  false  "形式（csv / tsv、既定 csv）"
+// @Param        bom      query     bool    false  "先頭にUTF-8のBOMを付ける（Excelで開く場合）"
+// @Param        user_id  query     string  false  "書き出すユーザーのID（認証情報がない場合は必須）"
+// @Success      200      {file}    file
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/logs/export [get]
+func (h *RoomHandler) ExportRoomLogs(c *gin.Context) {
+	roomID := c.Param("id")
+	format, ok := parseLogExportFormat(c)
+	if !ok {
+		return
+	}
+	scope, ok := resolveRoomScope(c, h.db)
+	if !ok {
+		return
+	}
+
+	// 書き出しを始めると状態コードを変えられないため、先に会議室を確かめる
+	found, err := scope.includes(c.Request.Context(), h.db, roomID)
+	if err != nil {
+		log.Printf("チャットログの書き出しの会議室の確認に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		return
+	}
+
+	query, args := buildLogExportQuery(scope, roomID, time.Time{}, time.Time{})
+	h.streamLogs(c, format, "chat-logs-"+roomID, query, args)
+}
+
+// ExportLogs godoc
+// @Summary      期間内の会議室のチャットログをCSV・TSVで書き出す
+// @Description  会議の日時（開始日時、なければ開始予定日時か作成日時）が期間内の会議室のチャットログを、会議室ごと・投稿順にCSVまたはTSVで書き出します。列と書き出せる会議室は GET /rooms/{id}/logs/export と同じです
+// @Tags         rooms
+// @Produce      text/csv
+// @Produce      text/tab-separated-values
+// @Param        from     query     string  true   "会議の日時の下限（RFC 3339）"
+// @Param        to       query     string  true   "会議の日時の上限（RFC 3339、この日時を含まない）"
+// @Param        format   query     string  false  "形式（csv / tsv、既定 csv）"
+// @Param        bom      query     bool    false  "先頭にUTF-8のBOMを付ける（Excelで開く場合）"
+// @Param        user_id  query     string  false  "書き出すユーザーのID（認証情報がない場合は必須）"
+// @Success      200      {file}    file
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /logs/export [get]
+func (h *RoomHandler) ExportLogs(c *gin.Context) {
+	format, ok := parseLogExportFormat(c)
+	if !ok {
+		return
+	}
+	from, errFrom := time.Parse(time.RFC3339, c.Query("from"))
+	to, errTo := time.Parse(time.RFC3339, c.Query("to"))
+	if errFrom != nil || errTo != nil || !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from と to にはRFC 3339形式の日時を from < to となるように指定してください"})
+		return
+	}
+	scope, ok := resolveRoomScope(c, h.db)
+	if !ok {
+		return
+	}
+
+	query, args := buildLogExportQuery(scope, "", from, to)
+	h.streamLogs(c, format, "chat-logs-"+from.Format("20060102")+"-"+to.Format("20060102"), query, args)
+}
+
+// streamLogs はクエリの結果を一行ずつCSV・TSVとして書き出します。
+func (h *RoomHandler) streamLogs(c *gin.Context, format logExportFormat, filename, query string, args []interface{}) {
+	ctx := c.Request.Context()
+	reactionKeys, err := allReactionKeys(ctx, h.db)
+	if err != nil {
+		log.Printf("チャットログの書き出しのリアクションの取得に失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	rows, err := h.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("チャットログの書き出しに失敗しました: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", format.contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + "." + format.ext}))
+	c.Status(http.StatusOK)
+	if format.bom {
+		c.Writer.WriteString("\uFEFF")
+	}
+	w := csv.NewWriter(c.Writer)
+	w.Comma = format.comma
+
+	header := append([]string{}, logExportColumns...)
+	for _, key := range reactionKeys {
+		header = append(header, "reaction_"+key)
+	}
+	w.Write(header)
+
+	locations := make(map[string]*time.Location)
+	record := make([]string, len(header))
+	for n := 1; rows.Next(); n++ {
+		var roomID, title, timeZone, messageID, message, agendaItem string
+		var anonymous, isGuest, isSummary bool
+		var createdAt time.Time
+		var userID, userName sql.NullString
+		var reactions []byte
+		if err := rows.Scan(&roomID, &title, &anonymous, &timeZone, &messageID, &createdAt, &userID, &userName, &isGuest,
+			&isSummary, &agendaItem, &message, &reactions); err != nil {
+			log.Printf("チャットログの書き出し中に失敗しました: %v", err)
+			return
+		}
+		counts := make(map[string]int)
+		if err := json.Unmarshal(reactions, &counts); err != nil {
+			log.Printf("チャットログの書き出し中に失敗しました: %v", err)
+			return
+		}
+
+		loc, ok := locations[timeZone]
+		if !ok {
+			if loc, err = time.LoadLocation(timeZone); err != nil {
+				loc = time.UTC
+			}
+			locations[timeZone] = loc
+		}
+		total := 0
+		for _, count := range counts {
+			total += count
+		}
+		record = record[:0]
+		record = append(record, roomID, spreadsheetText(title), messageID, createdAt.In(loc).Format("2006-01-02 15:04:05"), loc.String())
+		// 匿名モードの会議室では発言者を含めない
+		if anonymous {
+			record = append(record, "", "", "")
+		} else {
+			record = append(record, userID.String, spreadsheetText(userName.String), strconv.FormatBool(isGuest && userID.Valid))
+		}
+		record = append(record, strconv.FormatBool(isSummary), spreadsheetText(agendaItem), spreadsheetText(message), strconv.Itoa(total))
+		for _, key := range reactionKeys {
+			record = append(record, strconv.Itoa(counts[key]))
+		}
+		if err := w.Write(record); err != nil {
+			log.Printf("チャットログの書き出し中に失敗しました: %v", err)
+			return
+		}
+		if n%logExportFlushRows == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("チャットログの書き出し中に失敗しました: %v", err)
+		return
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Printf("チャットログの書き出し中に失敗しました: %v", err)
+	}
+}
+
+// allReactionKeys はリアクションの種類のキーを表示順に返します（書き出しの列に使います）。
+func allReactionKeys(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT key FROM reaction_types ORDER BY sort_order, key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// spreadsheetText は表計算ソフトで数式として解釈される文字で始まるテキストの先頭に ' を付けます。
+func spreadsheetText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
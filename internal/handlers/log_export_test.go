@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var logExportRowColumns = []string{"id", "title", "anonymous", "time_zone", "message_id", "created_at", "user_id", "user_name", "is_guest", "is_summary", "agenda_item", "message", "reactions"}
+
+func expectLogExportQueries(mock sqlmock.Sqlmock, anonymous bool) {
+	at := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT key FROM reaction_types`).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("sorena").AddRow("agree"))
+	mock.ExpectQuery(`WITH exported AS`).
+		WithArgs("u001", "r001").
+		WillReturnRows(sqlmock.NewRows(logExportRowColumns).
+			AddRow("r001", "週次ふりかえり", anonymous, "Asia/Tokyo", "log1", at, "u001", "田中太郎", false, false, "進め方", "進め方を変えたい, 隔週で", []byte(`{"sorena": 2, "agree": 1}`)).
+			AddRow("r001", "週次ふりかえり", anonymous, "Asia/Tokyo", "log2", at.Add(time.Minute), nil, nil, false, true, "", "=SUM(A1)", []byte(`{}`)))
+}
+
+func TestExportRoomLogs_CSV(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT r.id FROM rooms r WHERE r.id = \$1 AND \(r.created_by = \$2`).
+		WithArgs("r001", "u001").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("r001"))
+	expectLogExportQueries(mock, false)
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/logs/export?user_id=u001&bom=true", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewRoomHandler(db, nil).ExportRoomLogs(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=chat-logs-r001.csv`, w.Header().Get("Content-Disposition"))
+	body := w.Body.String()
+	require.True(t, strings.HasPrefix(body, "\uFEFF"))
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(body, "\uFEFF"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"room_id", "room_title", "message_id", "created_at", "time_zone", "user_id", "user_name", "is_guest", "is_summary",
+		"agenda_item", "message", "reaction_total", "reaction_sorena", "reaction_agree"}, records[0])
+	assert.Equal(t, []string{"r001", "週次ふりかえり", "log1", "2024-01-01 10:00:00", "Asia/Tokyo", "u001", "田中太郎", "false", "false",
+		"進め方", "進め方を変えたい, 隔週で", "3", "2", "1"}, records[1])
+	// 要約には発言者がなく、数式として解釈される文字で始まるメッセージは ' を付ける
+	assert.Equal(t, []string{"r001", "週次ふりかえり", "log2", "2024-01-01 10:01:00", "Asia/Tokyo", "", "", "false", "true",
+		"", "'=SUM(A1)", "0", "0", "0"}, records[2])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportRoomLogs_AnonymousTSV(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT r.id FROM rooms r`).
+		WithArgs("r001", "u001").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("r001"))
+	expectLogExportQueries(mock, true)
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/logs/export?user_id=u001&format=tsv", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewRoomHandler(db, nil).ExportRoomLogs(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/tab-separated-values; charset=utf-8", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "r001\t週次ふりかえり\tlog1\t2024-01-01 10:00:00\tAsia/Tokyo\t\t\t\tfalse\t進め方\t進め方を変えたい, 隔週で\t3\t2\t1", lines[1])
+	assert.NotContains(t, w.Body.String(), "田中太郎")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportRoomLogs_NotAccessible(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT r.id FROM rooms r`).
+		WithArgs("r001", "u002").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/logs/export?user_id=u002", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewRoomHandler(db, nil).ExportRoomLogs(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportLogs_RequiresRange(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, target := range []string{
+		"/logs/export?user_id=u001",
+		"/logs/export?user_id=u001&from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z",
+		"/logs/export?user_id=u001&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&format=xlsx",
+		"/logs/export?user_id=u001&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&bom=yes",
+	} {
+		c, w := newJSONContext(http.MethodGet, target, "")
+		NewRoomHandler(db, nil).ExportLogs(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestBuildLogExportQuery_DateRange(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	query, args := buildLogExportQuery(roomScope{allRooms: true}, "", from, to)
+
+	assert.Contains(t, query, "WHERE TRUE AND COALESCE(r.started_at, r.scheduled_start_at, r.created_at) >= $1 AND COALESCE(r.started_at, r.scheduled_start_at, r.created_at) < $2")
+	assert.Equal(t, []interface{}{from, to}, args)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/shuto.sawaki/elmo-project/internal/ratelimit"
+)
+
+// POST /rooms/:id/messages
+func (h *RoomHandler) PostMessage(c *gin.Context) {
+	roomID := c.Param("id")
+
+	var req models.MessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "メッセージは必須です"})
+		return
+	}
+
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !allowAction(c, h.limiter, ratelimit.ActionMessage, roomID, userID) {
+		return
+	}
+	// 二重送信などで同じ内容が続けて届いた場合は保存しない
+	if isDuplicate(c, h.limiter, "message:"+roomID+":"+userID+":"+req.Message, duplicateMessageWindow) {
+		c.JSON(http.StatusConflict, gin.H{"error": "同じメッセージが続けて投稿されました"})
+		return
+	}
+
+	logID, err := gonanoid.New()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "IDの生成に失敗しました"})
+		return
+	}
+
+	// 参加中のユーザーのみ投稿できる。メッセージには投稿時の議題を記録する
+	chatLog := models.ChatLog{LogID: logID, UserID: &userID, Message: req.Message}
+	var agendaItemID sql.NullString
+	err = h.db.QueryRowContext(c.Request.Context(), `
+		INSERT INTO chat_logs (id, room_id, user_id, message, is_summary, agenda_item_id)
+		SELECT $1, $2, $3, $4, FALSE, (SELECT current_agenda_item_id FROM rooms WHERE id = $2)
+		WHERE EXISTS (SELECT 1 FROM participants WHERE room_id = $2 AND user_id = $3 AND left_at IS NULL)
+		RETURNING created_at, (SELECT is_guest FROM users WHERE id = $3), agenda_item_id`,
+		logID, roomID, userID, req.Message).Scan(&chatLog.Timestamp, &chatLog.IsGuest, &agendaItemID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusForbidden, gin.H{"error": "この会議室の参加者ではありません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースへの保存に失敗しました"})
+		return
+	}
+	if agendaItemID.Valid {
+		chatLog.AgendaItemID = &agendaItemID.String
+	}
+	c.JSON(http.StatusCreated, chatLog)
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	htmltemplate "html/template"
+	"net/http"
+	netmail "net/mail"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/mail"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+type NotificationHandler struct {
+	db *sql.DB
+}
+
+func NewNotificationHandler(db *sql.DB) *NotificationHandler {
+	return &NotificationHandler{db: db}
+}
+
+// validEmail はメールアドレスが "user@example.com" の形かどうかを返します（名前付きの形は受け付けません）。
+func validEmail(s string) bool {
+	addr, err := netmail.ParseAddress(s)
+	return err == nil && addr.Address == s && len(s) <= 254
+}
+
+// GetNotificationPreferences godoc
+// @Summary      メールの通知の設定を取得
+// @Description  本人のみ取得できます。設定したことがない場合は既定（メールアドレスなし・すべて受け取る）を返します
+// @Tags         notifications
+// @Produce      json
+// @Param        id       path      string  true   "ユーザーID"
+// @Param        user_id  query     string  false  "操作するユーザーのID（認証情報がない場合は必須。id と同じ）"
+// @Success      200      {object}  models.NotificationPreferences
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /users/{id}/notification-preferences [get]
+func (h *NotificationHandler) GetNotificationPreferences(c *gin.Context) {
+	userID := c.Param("id")
+	actor, ok := actingUser(c, h.db, "", c.Query("user_id"))
+	if !ok {
+		return
+	}
+	if actor != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "本人のみ通知の設定を操作できます"})
+		return
+	}
+
+	prefs := models.NotificationPreferences{Invitations: true, Reminders: true, Digests: true}
+	var email sql.NullString
+	err := h.db.QueryRowContext(c.Request.Context(), `
+		SELECT email, invitations, reminders, digests FROM notification_preferences WHERE user_id = $1`, userID).
+		Scan(&email, &prefs.Invitations, &prefs.Reminders, &prefs.Digests)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if email.Valid {
+		prefs.Email = &email.String
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateNotificationPreferences godoc
+// @Summary      メールの通知の設定を更新
+// @Description  通知を送るメールアドレスと、受け取るメールの種類（招待・開始前のリマインダー・会議後のまとめ）を設定します。省略した項目は変更しません。本人のみ更新できます
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        id           path      string                                 true   "ユーザーID"
+// @Param        user_id      query     string                                 false  "操作するユーザーのID（認証情報がない場合は必須。id と同じ）"
+// @Param        preferences  body      models.NotificationPreferencesRequest  true   "通知の設定"
+// @Success      200          {object}  models.NotificationPreferences
+// @Failure      400          {object}  map[string]interface{}
+// @Failure      403          {object}  map[string]interface{}
+// @Failure      404          {object}  map[string]interface{}
+// @Failure      500          {object}  map[string]interface{}
+// @Router       /users/{id}/notification-preferences [put]
+func (h *NotificationHandler) UpdateNotificationPreferences(c *gin.Context) {
+	userID := c.Param("id")
+	var req models.NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	var email *string
+	if req.Email != nil {
+		if trimmed := strings.TrimSpace(*req.Email); trimmed != "" {
+			if !validEmail(trimmed) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "emailが不正です"})
+				return
+			}
+			email = &trimmed
+		}
+	}
+	actor, ok := actingUser(c, h.db, "", c.Query("user_id"))
+	if !ok {
+		return
+	}
+	if actor != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "本人のみ通知の設定を操作できます"})
+		return
+	}
+
+	token, err := mail.NewUnsubscribeToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	var prefs models.NotificationPreferences
+	var saved sql.NullString
+	err = h.db.QueryRowContext(c.Request.Context(), `
+		INSERT INTO notification_preferences (user_id, email, invitations, reminders, digests, unsubscribe_token)
+		SELECT id, $2, COALESCE($3, TRUE), COALESCE($4, TRUE), COALESCE($5, TRUE), $6
+		FROM users WHERE id = $1 AND NOT is_guest AND NOT is_service_account
+		ON CONFLICT (user_id) DO UPDATE SET
+			email = CASE WHEN $7 THEN EXCLUDED.email ELSE notification_preferences.email END,
+			invitations = COALESCE($3, notification_preferences.invitations),
+			reminders = COALESCE($4, notification_preferences.reminders),
+			digests = COALESCE($5, notification_preferences.digests),
+			updated_at = NOW()
+		RETURNING email, invitations, reminders, digests`,
+		userID, email, req.Invitations, req.Reminders, req.Digests, token, req.Email != nil).
+		Scan(&saved, &prefs.Invitations, &prefs.Reminders, &prefs.Digests)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定されたユーザーは見つかりません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通知の設定の更新に失敗しました"})
+		return
+	}
+	if saved.Valid {
+		prefs.Email = &saved.String
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// 配信停止のページ。メールのリンクから開くため JSON ではなく HTML を返す
+var unsubscribePage = htmltemplate.Must(htmltemplate.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head><meta charset="UTF-8"><title>配信停止 - Elmo</title></head>
+<body style="font-family:sans-serif;max-width:480px;margin:48px auto;padding:0 16px;">
+{{if .Done}}<p>{{.Label}}の配信を停止しました。</p>
+{{else if .NotFound}}<p>このリンクは無効です。</p>
+{{else}}<p>{{.Label}}の配信を停止しますか？</p>
+<form method="post"><button type="submit">配信を停止する</button></form>
+{{end}}</body>
+</html>
+`))
+
+// unsubscribeLabel は配信停止の種類の表示名です。種類が不正な場合は false を返します。
+func unsubscribeLabel(kind string) (string, bool) {
+	switch kind {
+	case "":
+		return "すべてのメール", true
+	case mail.KindInvitation:
+		return "招待のメール", true
+	case mail.KindReminder:
+		return "開始前のリマインダー", true
+	case mail.KindDigest:
+		return "会議後のまとめ", true
+	}
+	return "", false
+}
+
+func renderUnsubscribePage(c *gin.Context, status int, data gin.H) {
+	var buf bytes.Buffer
+	if err := unsubscribePage.Execute(&buf, data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// ShowUnsubscribe godoc
+// @Summary      メールの配信停止の確認
+// @Description  メールの配信停止のリンクから開くページです。メールのリンクを先に開くセキュリティ製品があるため、開いただけでは停止せず、確認のボタンで停止します
+// @Tags         notifications
+// @Produce      html
+// @Param        token  query  string  true   "メールに含まれる配信停止のトークン"
+// @Param        type   query  string  false  "停止する種類（invitations / reminders / digests。省略するとすべて）"
+// @Success      200
+// @Failure      400
+// @Failure      404
+// @Router       /unsubscribe [get]
+func (h *NotificationHandler) ShowUnsubscribe(c *gin.Context) {
+	label, ok := unsubscribeLabel(c.Query("type"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "typeは invitations / reminders / digests のいずれかを指定してください"})
+		return
+	}
+	var exists bool
+	err := h.db.QueryRowContext(c.Request.Context(),
+		`SELECT EXISTS (SELECT 1 FROM notification_preferences WHERE unsubscribe_token = $1)`, c.Query("token")).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if !exists {
+		renderUnsubscribePage(c, http.StatusNotFound, gin.H{"NotFound": true})
+		return
+	}
+	renderUnsubscribePage(c, http.StatusOK, gin.H{"Label": label})
+}
+
+// Unsubscribe godoc
+// @Summary      メールの配信を停止
+// @Description  確認のページのボタンと、メールクライアントのワンクリックでの配信停止（RFC 8058 の List-Unsubscribe-Post）から呼ばれます
+// @Tags         notifications
+// @Produce      html
+// @Param        token  query  string  true   "メールに含まれる配信停止のトークン"
+// @Param        type   query  string  false  "停止する種類（invitations / reminders / digests。省略するとすべて）"
+// @Success      200
+// @Failure      400
+// @Failure      404
+// @Router       /unsubscribe [post]
+func (h *NotificationHandler) Unsubscribe(c *gin.Context) {
+	kind := c.Query("type")
+	label, ok := unsubscribeLabel(kind)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "typeは invitations / reminders / digests のいずれかを指定してください"})
+		return
+	}
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		UPDATE notification_preferences SET
+			invitations = invitations AND $2 NOT IN ('', 'invitations'),
+			reminders = reminders AND $2 NOT IN ('', 'reminders'),
+			digests = digests AND $2 NOT IN ('', 'digests'),
+			updated_at = NOW()
+		WHERE unsubscribe_token = $1`, c.Query("token"), kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "配信の停止に失敗しました"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		renderUnsubscribePage(c, http.StatusNotFound, gin.H{"NotFound": true})
+		return
+	}
+	renderUnsubscribePage(c, http.StatusOK, gin.H{"Done": true, "Label": label})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateNotificationPreferences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	// メールアドレスを指定し、まとめだけ受け取らない。省略した項目は変更しない
+	mock.ExpectQuery(`INSERT INTO notification_preferences`).
+		WithArgs("u001", "tanaka@example.com", nil, nil, false, sqlmock.AnyArg(), true).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "invitations", "reminders", "digests"}).
+			AddRow("tanaka@example.com", true, true, false))
+
+	c, w := newJSONContext(http.MethodPut, "/users/u001/notification-preferences?user_id=u001",
+		`{"email":" tanaka@example.com ","digests":false}`)
+	c.Params = gin.Params{{Key: "id", Value: "u001"}}
+	NewNotificationHandler(db).UpdateNotificationPreferences(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"email":"tanaka@example.com","invitations":true,"reminders":true,"digests":false}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateNotificationPreferences_Rejects(t *testing.T) {
+	tests := map[string]struct {
+		target string
+		body   string
+		status int
+	}{
+		"メールアドレスが不正": {"/users/u001/notification-preferences?user_id=u001", `{"email":"田中 <tanaka@example.com>"}`, http.StatusBadRequest},
+		"本人以外":       {"/users/u001/notification-preferences?user_id=u002", `{"digests":false}`, http.StatusForbidden},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if tt.status == http.StatusForbidden {
+				mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+					WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+			}
+
+			c, w := newJSONContext(http.MethodPut, tt.target, tt.body)
+			c.Params = gin.Params{{Key: "id", Value: "u001"}}
+			NewNotificationHandler(db).UpdateNotificationPreferences(c)
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE notification_preferences SET`).WithArgs("tok1", "digests").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE notification_preferences SET`).WithArgs("unknown", "").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	c, w := newJSONContext(http.MethodPost, "/unsubscribe?token=tok1&type=digests", "List-Unsubscribe=One-Click")
+	NewNotificationHandler(db).Unsubscribe(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "会議後のまとめの配信を停止しました")
+
+	c, w = newJSONContext(http.MethodPost, "/unsubscribe?token=unknown", "")
+	NewNotificationHandler(db).Unsubscribe(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c, w = newJSONContext(http.MethodPost, "/unsubscribe?token=tok1&type=all", "")
+	NewNotificationHandler(db).Unsubscribe(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"github.com/shuto.sawaki/elmo-project/internal/events"
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+)
+
+// AddNotifier は会議室の作成・開始・要約・結論・終了を知らせる通知先を追加します。
+// 追加しない場合は通知しません。
+func (h *RoomHandler) AddNotifier(n notify.Notifier) {
+	h.notifiers = append(h.notifiers, n)
+}
+
+// SetHub は会議室の開始・終了を購読者にリアルタイムで配信する Hub を設定します。
+// 設定しない場合は配信しません。
+func (h *RoomHandler) SetHub(hub *events.Hub) {
+	h.hub = hub
+}
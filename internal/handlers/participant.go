@@ -2,12 +2,21 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shuto.sawaki/elmo-project/internal/models"
 )
 
+// ハートビートが途絶えてからプレゼンスを切り替えるまでの時間
+const (
+	presenceOnlineWindow = 30 * time.Second
+	presenceAwayWindow   = 5 * time.Minute
+)
+
 type ParticipantHandler struct {
 	db *sql.DB
 }
@@ -16,13 +25,30 @@ func NewParticipantHandler(db *sql.DB) *ParticipantHandler {
 	return &ParticipantHandler{db: db}
 }
 
+// presenceOf は最終ハートビート時刻からプレゼンス状態を判定します。
+func presenceOf(lastSeen sql.NullTime, now time.Time) string {
+	if !lastSeen.Valid {
+		return models.PresenceOffline
+	}
+	elapsed := now.Sub(lastSeen.Time)
+	switch {
+	case elapsed <= presenceOnlineWindow:
+		return models.PresenceOnline
+	case elapsed <= presenceAwayWindow:
+		return models.PresenceAway
+	default:
+		return models.PresenceOffline
+	}
+}
+
 // GetParticipants godoc
 // @Summary      参加者一覧を取得
-// @Description  指定された会議室の参加者一覧を取得します
+// @Description  指定された会議室の参加者一覧をプレゼンス状態とともに取得します
 // @Tags         participants
 // @Accept       json
 // @Produce      json
-// @Param        room_id  query     string  true  "会議室ID"
+// @Param        room_id       query     string  true   "会議室ID"
+// @Param        include_left  query     bool    false  "退出済みの参加者も含めるか"
 // @Success      200      {object}  models.ParticipantsResponse
 // @Failure      400      {object}  map[string]interface{}
 // @Failure      500      {object}  map[string]interface{}
@@ -34,20 +60,40 @@ func (h *ParticipantHandler) GetParticipants(c *gin.Context) {
 		return
 	}
 
-	rows, err := h.db.Query("SELECT u.id, u.user_name FROM participants p JOIN users u ON p.user_id = u.id WHERE p.room_id = $1", roomID)
+	query := `
+		SELECT u.id, u.user_name, u.is_guest, p.joined_at, p.left_at, p.last_seen_at
+		FROM participants p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.room_id = $1`
+	if c.Query("include_left") != "true" {
+		query += " AND p.left_at IS NULL"
+	}
+	query += " ORDER BY p.joined_at ASC"
+
+	rows, err := h.db.Query(query, roomID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
 		return
 	}
 	defer rows.Close()
 
+	now := time.Now()
 	var users []models.ParticipantUser
 	for rows.Next() {
 		var user models.ParticipantUser
-		if err := rows.Scan(&user.ID, &user.Name); err != nil {
+		var joinedAt time.Time
+		var leftAt, lastSeen sql.NullTime
+		if err := rows.Scan(&user.ID, &user.Name, &user.IsGuest, &joinedAt, &leftAt, &lastSeen); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
 			return
 		}
+		user.JoinedAt = &joinedAt
+		if leftAt.Valid {
+			user.LeftAt = &leftAt.Time
+			user.Presence = models.PresenceOffline
+		} else {
+			user.Presence = presenceOf(lastSeen, now)
+		}
 		users = append(users, user)
 	}
 
@@ -60,13 +106,16 @@ func (h *ParticipantHandler) GetParticipants(c *gin.Context) {
 
 // AddParticipant godoc
 // @Summary      参加者を追加
-// @Description  指定された会議室に参加者を追加します
+// @Description  指定された会議室に参加者を追加します。既に参加済みの場合は何もせず200を返します。参加者が上限に達している場合はキャンセル待ちに登録し202を返します
 // @Tags         participants
 // @Accept       json
 // @Produce      json
 // @Param        participant  body      models.ParticipantRequest  true  "参加者情報"
-// @Success      201          "Created"
+// @Success      200          {object}  models.JoinResponse
+// @Success      201          {object}  models.JoinResponse
+// @Success      202          {object}  models.JoinResponse
 // @Failure      400          {object}  map[string]interface{}
+// @Failure      404          {object}  map[string]interface{}
 // @Failure      500          {object}  map[string]interface{}
 // @Router       /participants [post]
 func (h *ParticipantHandler) AddParticipant(c *gin.Context) {
@@ -81,10 +130,143 @@ func (h *ParticipantHandler) AddParticipant(c *gin.Context) {
 		return
 	}
 
-	_, err := h.db.Exec("INSERT INTO participants (room_id, user_id) VALUES ($1, $2)", req.RoomID, req.UserID)
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTx(ctx, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "参加者の追加に失敗しました"})
 		return
 	}
-	c.Status(http.StatusCreated)
-}
\ No newline at end of file
+	defer tx.Rollback()
+
+	result, err := joinRoom(ctx, tx, req.RoomID, req.UserID)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if errors.Is(err, errRoomNotFound) || isForeignKeyViolation(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された会議室またはユーザーが見つかりません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "参加者の追加に失敗しました"})
+		return
+	}
+
+	response := models.JoinResponse{
+		RoomID:           req.RoomID,
+		UserID:           req.UserID,
+		Status:           result.Status,
+		WaitlistPosition: result.Position,
+	}
+	switch {
+	case result.Status == models.JoinStatusWaitlisted:
+		c.JSON(http.StatusAccepted, response)
+	case result.Created:
+		c.JSON(http.StatusCreated, response)
+	default:
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// RemoveParticipant godoc
+// @Summary      参加者を削除
+// @Description  指定された会議室から参加者を外します（退出日時を記録します）。空いた枠にはキャンセル待ちの先頭が繰り上がります。ホストのみ実行できます
+// @Tags         participants
+// @Produce      json
+// @Param        id       path      string  true   "会議室ID"
+// @Param        userId   path      string  true   "外すユーザーのID"
+// @Param        user_id  query     string  false  "ホストのユーザーID（認証情報がない場合は必須）"
+// @Success      204      "No Content"
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /rooms/{id}/participants/{userId} [delete]
+func (h *ParticipantHandler) RemoveParticipant(c *gin.Context) {
+	roomID := c.Param("id")
+	hostID, ok := actingUser(c, h.db, roomID, c.Query("user_id"))
+	if !ok || !requireHost(c, h.db, roomID, hostID) {
+		return
+	}
+	h.markLeft(c, roomID, c.Param("userId"))
+}
+
+// LeaveRoom godoc
+// @Summary      会議室から退出
+// @Description  参加者自身が会議室から退出します。キャンセル待ち中の場合はキャンセル待ちから外れます。他のユーザーを退出させることはできません
+// @Tags         participants
+// @Accept       json
+// @Produce      json
+// @Param        id     path      string                   true  "会議室ID"
+// @Param        leave  body      models.LeaveRoomRequest  true  "退出するユーザー"
+// @Success      204    "No Content"
+// @Failure      400    {object}  map[string]interface{}
+// @Failure      403    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]interface{}
+// @Failure      500    {object}  map[string]interface{}
+// @Router       /rooms/{id}/leave [post]
+func (h *ParticipantHandler) LeaveRoom(c *gin.Context) {
+	roomID := c.Param("id")
+	var req models.LeaveRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	h.markLeft(c, roomID, userID)
+}
+
+func (h *ParticipantHandler) markLeft(c *gin.Context, roomID, userID string) {
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	found, promoted, err := leaveRoom(ctx, tx, roomID, userID)
+	if err == nil && found {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された参加者は見つかりません"})
+		return
+	}
+	for _, id := range promoted {
+		log.Printf("キャンセル待ちから繰り上げました: room=%s, user=%s", roomID, id)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Heartbeat godoc
+// @Summary      プレゼンスのハートビート
+// @Description  参加者がオンラインであることを通知します。定期的に呼び出してください
+// @Tags         participants
+// @Produce      json
+// @Param        id      path      string  true  "会議室ID"
+// @Param        userId  path      string  true  "ユーザーID"
+// @Success      204     "No Content"
+// @Failure      404     {object}  map[string]interface{}
+// @Failure      500     {object}  map[string]interface{}
+// @Router       /rooms/{id}/participants/{userId}/heartbeat [post]
+func (h *ParticipantHandler) Heartbeat(c *gin.Context) {
+	result, err := h.db.Exec(`
+		UPDATE participants SET last_seen_at = NOW()
+		WHERE room_id = $1 AND user_id = $2 AND left_at IS NULL`, c.Param("id"), c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された参加者は見つかりません"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJSONContext(method, target, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, w
+}
+
+func TestAddParticipant_DuplicateJoinIsIdempotent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_participants FROM rooms WHERE id = \$1 FOR UPDATE`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"max_participants"}).AddRow(nil))
+	mock.ExpectQuery(`SELECT left_at FROM participants`).
+		WithArgs("r001", "u001").
+		WillReturnRows(sqlmock.NewRows([]string{"left_at"}).AddRow(nil))
+	mock.ExpectCommit()
+
+	c, w := newJSONContext(http.MethodPost, "/participants", `{"room_id":"r001","user_id":"u001"}`)
+	NewParticipantHandler(db).AddParticipant(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"room_id":"r001","user_id":"u001","status":"joined"}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddParticipant_FullRoomGoesToWaitlist(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_participants FROM rooms WHERE id = \$1 FOR UPDATE`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"max_participants"}).AddRow(2))
+	mock.ExpectQuery(`SELECT left_at FROM participants`).
+		WithArgs("r001", "u003").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM participants`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectExec(`INSERT INTO room_waitlist`).
+		WithArgs("r001", "u003").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// 繰り上げと同じ (created_at, user_id) の順で数える
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM room_waitlist .*\(w.created_at, w.user_id\) <= \(me.created_at, me.user_id\)`).
+		WithArgs("r001", "u003").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+
+	c, w := newJSONContext(http.MethodPost, "/participants", `{"room_id":"r001","user_id":"u003"}`)
+	NewParticipantHandler(db).AddParticipant(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"room_id":"r001","user_id":"u003","status":"waitlisted","waitlist_position":1}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLeaveRoom_PromotesNextWaitlistedUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_participants FROM rooms WHERE id = \$1 FOR UPDATE`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"max_participants"}).AddRow(2))
+	mock.ExpectExec(`UPDATE participants SET left_at = NOW\(\)`).
+		WithArgs("r001", "u001").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM participants`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT user_id FROM room_waitlist`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("u003"))
+	mock.ExpectExec(`INSERT INTO participants`).
+		WithArgs("r001", "u003").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM room_waitlist`).
+		WithArgs("r001", "u003").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM participants`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectCommit()
+
+	c, _ := newJSONContext(http.MethodPost, "/rooms/r001/leave", `{"user_id":"u001"}`)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewParticipantHandler(db).LeaveRoom(c)
+
+	assert.Equal(t, http.StatusNoContent, c.Writer.Status())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLeaveRoom_CannotRemoveOthers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/leave", `{"user_id":"u002"}`)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	authenticateGuest(t, c, "g0000001", "r001")
+	NewParticipantHandler(db).LeaveRoom(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveParticipant_NonHostIsForbidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+
+	c, w := newJSONContext(http.MethodDelete, "/rooms/r001/participants/u003?user_id=u002", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}, gin.Param{Key: "userId", Value: "u003"}}
+	NewParticipantHandler(db).RemoveParticipant(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddParticipant_ForeignKeyViolationIsNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_participants FROM rooms WHERE id = \$1 FOR UPDATE`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"max_participants"}).AddRow(nil))
+	mock.ExpectQuery(`SELECT left_at FROM participants`).
+		WithArgs("r001", "nope").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec(`INSERT INTO participants`).
+		WithArgs("r001", "nope").
+		WillReturnError(&pgconn.PgError{Code: "23503"})
+	mock.ExpectRollback()
+
+	c, w := newJSONContext(http.MethodPost, "/participants", `{"room_id":"r001","user_id":"nope"}`)
+	NewParticipantHandler(db).AddParticipant(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPresenceOf(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		lastSeen sql.NullTime
+		want     string
+	}{
+		{"ハートビートなし", sql.NullTime{}, models.PresenceOffline},
+		{"直近", sql.NullTime{Time: now.Add(-10 * time.Second), Valid: true}, models.PresenceOnline},
+		{"少し前", sql.NullTime{Time: now.Add(-2 * time.Minute), Valid: true}, models.PresenceAway},
+		{"途絶", sql.NullTime{Time: now.Add(-time.Hour), Valid: true}, models.PresenceOffline},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, presenceOf(tc.lastSeen, now))
+		})
+	}
+}
@@ -0,0 +1,519 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/events"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// 投票の選択肢の数と長さの上限
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 10
+	maxPollOptionLength = 100
+)
+
+// pollClosedCondition は締め切り済みの投票を表す条件です（polls の別名は p）。
+const pollClosedCondition = `(p.closed_at IS NOT NULL OR p.closes_at <= NOW())`
+
+type PollHandler struct {
+	db  *sql.DB
+	hub *events.Hub
+}
+
+func NewPollHandler(db *sql.DB, hub *events.Hub) *PollHandler {
+	return &PollHandler{db: db, hub: hub}
+}
+
+// loadPolls は会議室の投票を選択肢ごとの集計とともに作成順に返します。
+// pollID を指定した場合はその投票のみ、closedOnly の場合は締め切り済みのもののみを返します。
+// 匿名投票の投票者は読み込みません。
+func loadPolls(ctx context.Context, db *sql.DB, roomID, pollID string, closedOnly bool) ([]models.Poll, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT p.id, p.question, p.multiple, p.anonymous, p.created_by, p.closes_at, p.created_at,
+		       `+pollClosedCondition+`,
+		       (SELECT COUNT(DISTINCT v.user_id) FROM poll_votes v WHERE v.poll_id = p.id)
+		FROM polls p
+		WHERE p.room_id = $1 AND ($2 = '' OR p.id = $2) AND (NOT $3 OR `+pollClosedCondition+`)
+		ORDER BY p.created_at ASC, p.id ASC`, roomID, pollID, closedOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	polls := []models.Poll{}
+	index := make(map[string]int)
+	for rows.Next() {
+		p := models.Poll{RoomID: roomID, Options: []models.PollOption{}}
+		var createdBy sql.NullString
+		var closesAt sql.NullTime
+		if err := rows.Scan(&p.ID, &p.Question, &p.Multiple, &p.Anonymous, &createdBy, &closesAt, &p.CreatedAt, &p.Closed, &p.TotalVoters); err != nil {
+			return nil, err
+		}
+		if createdBy.Valid {
+			p.CreatedBy = &createdBy.String
+		}
+		if closesAt.Valid {
+			p.ClosesAt = &closesAt.Time
+		}
+		index[p.ID] = len(polls)
+		polls = append(polls, p)
+	}
+	if err := rows.Err(); err != nil || len(polls) == 0 {
+		return polls, err
+	}
+
+	optionRows, err := db.QueryContext(ctx, `
+		SELECT o.poll_id, o.id, o.label, COUNT(v.user_id)
+		FROM poll_options o
+		JOIN polls p ON o.poll_id = p.id
+		LEFT JOIN poll_votes v ON v.option_id = o.id
+		WHERE p.room_id = $1 AND ($2 = '' OR p.id = $2)
+		GROUP BY o.poll_id, o.id, o.label, o.position
+		ORDER BY o.position ASC`, roomID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer optionRows.Close()
+
+	type optionRef struct{ poll, option int }
+	options := make(map[int64]optionRef)
+	for optionRows.Next() {
+		var id string
+		var o models.PollOption
+		if err := optionRows.Scan(&id, &o.ID, &o.Label, &o.Votes); err != nil {
+			return nil, err
+		}
+		i, ok := index[id]
+		if !ok {
+			continue
+		}
+		options[o.ID] = optionRef{poll: i, option: len(polls[i].Options)}
+		polls[i].Options = append(polls[i].Options, o)
+	}
+	if err := optionRows.Err(); err != nil {
+		return nil, err
+	}
+
+	voterRows, err := db.QueryContext(ctx, `
+		SELECT v.option_id, v.user_id
+		FROM poll_votes v
+		JOIN polls p ON v.poll_id = p.id
+		WHERE p.room_id = $1 AND ($2 = '' OR p.id = $2) AND NOT p.anonymous
+		ORDER BY v.created_at ASC, v.user_id ASC`, roomID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer voterRows.Close()
+
+	for voterRows.Next() {
+		var optionID int64
+		var userID string
+		if err := voterRows.Scan(&optionID, &userID); err != nil {
+			return nil, err
+		}
+		if ref, ok := options[optionID]; ok {
+			o := &polls[ref.poll].Options[ref.option]
+			o.Voters = append(o.Voters, userID)
+		}
+	}
+	return polls, voterRows.Err()
+}
+
+// redactPolls は匿名モードの部屋の投票から作成者と投票者を取り除きます。
+func redactPolls(polls []models.Poll) {
+	for i := range polls {
+		polls[i].CreatedBy = nil
+		for j := range polls[i].Options {
+			polls[i].Options[j].Voters = nil
+		}
+	}
+}
+
+// pollLogEntries は締め切られた投票の結果を、AIの要約に渡せる形に整えます。
+func pollLogEntries(polls []models.Poll) []models.LogEntry {
+	entries := make([]models.LogEntry, 0, len(polls))
+	for _, p := range polls {
+		if !p.Closed {
+			continue
+		}
+		results := make([]string, 0, len(p.Options))
+		for _, o := range p.Options {
+			results = append(results, fmt.Sprintf("%s: %d票", o.Label, o.Votes))
+		}
+		entries = append(entries, models.LogEntry{
+			Content: fmt.Sprintf("【投票結果】%s（%d人が投票）%s", p.Question, p.TotalVoters, strings.Join(results, "、")),
+		})
+	}
+	return entries
+}
+
+// findPoll は投票を一件読み込み、匿名モードの部屋では投票者を取り除きます。
+func (h *PollHandler) findPoll(ctx context.Context, roomID, pollID string) (models.Poll, error) {
+	anonymous, err := roomIsAnonymous(ctx, h.db, roomID)
+	if err != nil {
+		return models.Poll{}, err
+	}
+	polls, err := loadPolls(ctx, h.db, roomID, pollID, false)
+	if err != nil {
+		return models.Poll{}, err
+	}
+	if len(polls) == 0 {
+		return models.Poll{}, sql.ErrNoRows
+	}
+	if anonymous {
+		redactPolls(polls)
+	}
+	return polls[0], nil
+}
+
+// publishPoll は投票の最新の状態を会議室の購読者に配信します。
+func (h *PollHandler) publishPoll(ctx context.Context, roomID, pollID, eventType string) {
+	poll, err := h.findPoll(ctx, roomID, pollID)
+	if err != nil {
+		log.Printf("投票の通知に失敗しました: room=%s, poll=%s, err=%v", roomID, pollID, err)
+		return
+	}
+	h.hub.Publish(roomID, events.Event{Type: eventType, Data: poll})
+}
+
+// respondPoll は投票の最新の状態を返し、購読者にも配信します。
+func (h *PollHandler) respondPoll(c *gin.Context, status int, roomID, pollID, eventType string) {
+	poll, err := h.findPoll(c.Request.Context(), roomID, pollID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	h.hub.Publish(roomID, events.Event{Type: eventType, Data: poll})
+	c.JSON(status, poll)
+}
+
+// CreatePoll godoc
+// @Summary      投票を作成
+// @Description  会議室のホストが投票を作成します。作成・投票・締め切りは /rooms/{id}/events で配信されます
+// @Tags         polls
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string              true  "会議室ID"
+// @Param        poll  body      models.PollRequest  true  "投票の内容"
+// @Success      201   {object}  models.Poll
+// @Failure      400   {object}  map[string]interface{}
+// @Failure      403   {object}  map[string]interface{}
+// @Failure      404   {object}  map[string]interface{}
+// @Failure      500   {object}  map[string]interface{}
+// @Router       /rooms/{id}/polls [post]
+func (h *PollHandler) CreatePoll(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req models.PollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "質問は必須です"})
+		return
+	}
+	if len(req.Options) < minPollOptions || len(req.Options) > maxPollOptions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "選択肢は2〜10個で指定してください"})
+		return
+	}
+	seen := make(map[string]bool, len(req.Options))
+	for i, o := range req.Options {
+		o = strings.TrimSpace(o)
+		if o == "" || utf8.RuneCountInString(o) > maxPollOptionLength || seen[o] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "選択肢は重複のない1〜100文字で指定してください"})
+			return
+		}
+		seen[o] = true
+		req.Options[i] = o
+	}
+	if req.ClosesAt != nil && !req.ClosesAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "closes_atには未来の日時を指定してください"})
+		return
+	}
+
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	pollID, err := gonanoid.New()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "IDの生成に失敗しました"})
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO polls (id, room_id, created_by, question, multiple, anonymous, closes_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		pollID, roomID, userID, req.Question, req.Multiple, req.Anonymous, req.ClosesAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "投票の作成に失敗しました"})
+		return
+	}
+	for i, o := range req.Options {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO poll_options (poll_id, position, label) VALUES ($1, $2, $3)`, pollID, i, o)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "投票の作成に失敗しました"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "投票の作成に失敗しました"})
+		return
+	}
+
+	// 締め切り日時になったら購読者に知らせる（集計は締め切りの判定を含めて読み込み時に行う）
+	if req.ClosesAt != nil {
+		time.AfterFunc(time.Until(*req.ClosesAt), func() {
+			h.publishPoll(context.Background(), roomID, pollID, events.TypePollClosed)
+		})
+	}
+	h.respondPoll(c, http.StatusCreated, roomID, pollID, events.TypePollCreated)
+}
+
+// ListPolls godoc
+// @Summary      投票一覧を取得
+// @Description  会議室の投票を現在の集計とともに作成順に取得します
+// @Tags         polls
+// @Produce      json
+// @Param        id   path      string  true  "会議室ID"
+// @Success      200  {array}   models.Poll
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /rooms/{id}/polls [get]
+func (h *PollHandler) ListPolls(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	anonymous, err := roomIsAnonymous(ctx, h.db, roomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	polls, err := loadPolls(ctx, h.db, roomID, "", false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if anonymous {
+		redactPolls(polls)
+	}
+	c.JSON(http.StatusOK, polls)
+}
+
+// GetPoll godoc
+// @Summary      投票を取得
+// @Description  投票を現在の集計とともに取得します
+// @Tags         polls
+// @Produce      json
+// @Param        id      path      string  true  "会議室ID"
+// @Param        pollId  path      string  true  "投票ID"
+// @Success      200     {object}  models.Poll
+// @Failure      404     {object}  map[string]interface{}
+// @Failure      500     {object}  map[string]interface{}
+// @Router       /rooms/{id}/polls/{pollId} [get]
+func (h *PollHandler) GetPoll(c *gin.Context) {
+	poll, err := h.findPoll(c.Request.Context(), c.Param("id"), c.Param("pollId"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された投票は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, poll)
+}
+
+// Vote godoc
+// @Summary      投票する
+// @Description  参加中のユーザーが投票します。再度投票すると前回の投票を置き換えます
+// @Tags         polls
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string              true  "会議室ID"
+// @Param        pollId  path      string              true  "投票ID"
+// @Param        vote    body      models.VoteRequest  true  "選ぶ選択肢"
+// @Success      200     {object}  models.Poll
+// @Failure      400     {object}  map[string]interface{}
+// @Failure      403     {object}  map[string]interface{}
+// @Failure      404     {object}  map[string]interface{}
+// @Failure      409     {object}  map[string]interface{}
+// @Failure      500     {object}  map[string]interface{}
+// @Router       /rooms/{id}/polls/{pollId}/votes [post]
+func (h *PollHandler) Vote(c *gin.Context) {
+	roomID, pollID := c.Param("id"), c.Param("pollId")
+	ctx := c.Request.Context()
+
+	var req models.VoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	// 締め切りと同時に投票されても締め切り後の票が入らないよう、投票の行をロックする
+	var multiple, closed, participating bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT p.multiple, `+pollClosedCondition+`,
+		       EXISTS (SELECT 1 FROM participants WHERE room_id = p.room_id AND user_id = $3 AND left_at IS NULL)
+		FROM polls p
+		WHERE p.id = $1 AND p.room_id = $2
+		FOR UPDATE`, pollID, roomID, userID).Scan(&multiple, &closed, &participating)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された投票は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	if !participating {
+		c.JSON(http.StatusForbidden, gin.H{"error": "この会議室の参加者ではありません"})
+		return
+	}
+	if closed {
+		c.JSON(http.StatusConflict, gin.H{"error": "この投票は締め切られています"})
+		return
+	}
+	if len(req.OptionIDs) == 0 || (!multiple && len(req.OptionIDs) > 1) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "選択肢の数が不正です"})
+		return
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM poll_options WHERE poll_id = $1`, pollID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	valid := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		valid[id] = true
+	}
+	rows.Close()
+	chosen := make(map[int64]bool, len(req.OptionIDs))
+	for _, id := range req.OptionIDs {
+		if !valid[id] || chosen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "存在しない、または重複した選択肢が指定されています"})
+			return
+		}
+		chosen[id] = true
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM poll_votes WHERE poll_id = $1 AND user_id = $2`, pollID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "投票に失敗しました"})
+		return
+	}
+	for _, id := range req.OptionIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO poll_votes (poll_id, option_id, user_id) VALUES ($1, $2, $3)`, pollID, id, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "投票に失敗しました"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "投票に失敗しました"})
+		return
+	}
+	h.respondPoll(c, http.StatusOK, roomID, pollID, events.TypePollUpdated)
+}
+
+// ClosePoll godoc
+// @Summary      投票を締め切る
+// @Description  会議室のホストが投票を締め切ります。締め切った投票はリザルトに含まれます
+// @Tags         polls
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string                   true  "会議室ID"
+// @Param        pollId  path      string                   true  "投票ID"
+// @Param        close   body      models.ClosePollRequest  true  "締め切るユーザー"
+// @Success      200     {object}  models.Poll
+// @Failure      403     {object}  map[string]interface{}
+// @Failure      404     {object}  map[string]interface{}
+// @Failure      500     {object}  map[string]interface{}
+// @Router       /rooms/{id}/polls/{pollId}/close [post]
+func (h *PollHandler) ClosePoll(c *gin.Context) {
+	roomID, pollID := c.Param("id"), c.Param("pollId")
+	ctx := c.Request.Context()
+
+	var req models.ClosePollRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+			return
+		}
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	// 既に締め切られている場合は締め切り日時を変えずにそのまま返す
+	var exists bool
+	err := h.db.QueryRowContext(ctx, `
+		WITH closed AS (
+			UPDATE polls SET closed_at = NOW()
+			WHERE id = $1 AND room_id = $2 AND closed_at IS NULL
+			RETURNING id
+		)
+		SELECT EXISTS (SELECT 1 FROM polls WHERE id = $1 AND room_id = $2)`, pollID, roomID).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された投票は見つかりません"})
+		return
+	}
+	h.respondPoll(c, http.StatusOK, roomID, pollID, events.TypePollClosed)
+}
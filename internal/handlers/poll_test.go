@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVote_ClosedPollIsRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).
+		WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM polls p\s+WHERE p.id = \$1 AND p.room_id = \$2\s+FOR UPDATE`).
+		WithArgs("poll1", "r001", "u002").
+		WillReturnRows(sqlmock.NewRows([]string{"multiple", "closed", "participating"}).AddRow(false, true, true))
+	mock.ExpectRollback()
+
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/polls/poll1/votes", `{"user_id":"u002","option_ids":[1]}`)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}, {Key: "pollId", Value: "poll1"}}
+	NewPollHandler(db, nil).Vote(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePoll_OnlyHostCanCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).
+		WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM rooms`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/polls",
+		`{"user_id":"u002","question":"次回は？","options":["月曜","金曜"]}`)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewPollHandler(db, nil).CreatePoll(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPollLogEntries(t *testing.T) {
+	polls := []models.Poll{
+		{Question: "次回は？", Closed: true, TotalVoters: 3, Options: []models.PollOption{
+			{Label: "月曜", Votes: 2},
+			{Label: "金曜", Votes: 1},
+		}},
+		{Question: "受付中", Closed: false},
+	}
+
+	entries := pollLogEntries(polls)
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, "【投票結果】次回は？（3人が投票）月曜: 2票、金曜: 1票", entries[0].Content)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/ratelimit"
+)
+
+// 同じ内容のリクエストを重複とみなす時間
+const (
+	duplicateMessageWindow = 10 * time.Second
+	duplicateSummaryWindow = time.Minute
+)
+
+// SetRateLimiter はリアクション・メッセージ投稿・要約のレート制限を設定します。
+// 設定しない場合は制限しません。
+func (h *RoomHandler) SetRateLimiter(l *ratelimit.Limiter) {
+	h.limiter = l
+}
+
+// allowAction は会議室・ユーザーごとのレート制限を確認します。
+// 上限を超えている場合は 429 と Retry-After を書き込んで false を返します。
+// 制限の保存先に障害があっても会議を止めないよう、その場合は許可します。
+func allowAction(c *gin.Context, limiter *ratelimit.Limiter, action, roomID, userID string) bool {
+	ok, retryAfter, err := limiter.Allow(c.Request.Context(), action, roomID+":"+userID)
+	if err != nil {
+		log.Printf("レート制限の確認に失敗しました: action=%s, room=%s, err=%v", action, roomID, err)
+		return true
+	}
+	if ok {
+		return true
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "リクエストが多すぎます。しばらく待ってから再度お試しください"})
+	return false
+}
+
+// isDuplicate は同じ内容のリクエストが window 以内に既にあったかどうかを返します。
+// 内容はハッシュ化して保存します。
+func isDuplicate(c *gin.Context, limiter *ratelimit.Limiter, content string, window time.Duration) bool {
+	sum := sha256.Sum256([]byte(content))
+	dup, err := limiter.Duplicate(c.Request.Context(), hex.EncodeToString(sum[:]), window)
+	if err != nil {
+		log.Printf("重複リクエストの確認に失敗しました: %v", err)
+		return false
+	}
+	return dup
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostMessage_RateLimitedReturnsRetryAfter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).
+		WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), map[string]ratelimit.Rule{
+		ratelimit.ActionMessage: {Burst: 1, Interval: 5 * time.Second},
+	})
+	ok, _, err := limiter.Allow(context.Background(), ratelimit.ActionMessage, "r001:u001")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	h := NewRoomHandler(db, nil)
+	h.SetRateLimiter(limiter)
+	c, w := newJSONContext(http.MethodPost, "/rooms/r001/messages", `{"user_id":"u001","message":"連投"}`)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	h.PostMessage(c)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleSorena_RejectsInvalidCount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, body := range []string{
+		`{"user_id":"u001","message_id":"log1","count":-5}`,
+		`{"user_id":"u001","message_id":"log1","count":1000}`,
+	} {
+		c, w := newJSONContext(http.MethodPost, "/rooms/r001/sorena", body)
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+		NewRoomHandler(db, nil).HandleSorena(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
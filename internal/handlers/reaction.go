@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/shuto.sawaki/elmo-project/internal/ratelimit"
+)
+
+// リザルトに含めるリアクションごとのランキングの件数
+const topReactionMessages = 5
+
+var (
+	errMessageNotFound  = errors.New("message not found")
+	errNotParticipant   = errors.New("not a participant")
+	errReactionDisabled = errors.New("reaction type not enabled")
+)
+
+// リアクションの種類のキーに使える文字列
+var reactionKeyPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+type ReactionHandler struct {
+	db *sql.DB
+}
+
+func NewReactionHandler(db *sql.DB) *ReactionHandler {
+	return &ReactionHandler{db: db}
+}
+
+// roomReactionTypes は部屋で使えるリアクションの種類を表示順に返します。
+// 部屋ごとの設定がない場合は既定のセットを返します。
+// includeUsed が true の場合は、設定から外されたがその部屋で既に使われている種類も含めます。
+func roomReactionTypes(ctx context.Context, db *sql.DB, roomID string, includeUsed bool) ([]models.ReactionType, error) {
+	query := `
+		SELECT t.key, t.label, t.emoji, t.built_in
+		FROM reaction_types t
+		LEFT JOIN room_reaction_types rt ON rt.room_id = $1 AND rt.reaction_key = t.key
+		WHERE (rt.room_id IS NOT NULL
+		       OR (t.is_default AND NOT EXISTS (SELECT 1 FROM room_reaction_types x WHERE x.room_id = $1)))`
+	if includeUsed {
+		query += `
+		   OR EXISTS (SELECT 1 FROM message_reactions r JOIN chat_logs l ON r.message_id = l.id
+		              WHERE l.room_id = $1 AND r.reaction_type = t.key)`
+	}
+	query += `
+		ORDER BY COALESCE(rt.sort_order, t.sort_order), t.key`
+
+	rows, err := db.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := []models.ReactionType{}
+	for rows.Next() {
+		var t models.ReactionType
+		if err := rows.Scan(&t.Key, &t.Label, &t.Emoji, &t.BuiltIn); err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
+
+// checkReactionTarget はメッセージがその部屋のものであり、ユーザーが参加中で、
+// リアクションの種類がその部屋で有効であることを確認します。
+func checkReactionTarget(ctx context.Context, db *sql.DB, roomID, messageID, userID, reactionType string) error {
+	var inRoom, participating, enabled bool
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM chat_logs WHERE id = $1 AND room_id = $2),
+			EXISTS (SELECT 1 FROM participants WHERE room_id = $2 AND user_id = $3 AND left_at IS NULL),
+			EXISTS (
+				SELECT 1 FROM reaction_types t
+				LEFT JOIN room_reaction_types rt ON rt.room_id = $2 AND rt.reaction_key = t.key
+				WHERE t.key = $4
+				  AND (rt.room_id IS NOT NULL
+				       OR (t.is_default AND NOT EXISTS (SELECT 1 FROM room_reaction_types x WHERE x.room_id = $2))))`,
+		messageID, roomID, userID, reactionType).Scan(&inRoom, &participating, &enabled)
+	if err != nil {
+		return err
+	}
+	if !inRoom {
+		return errMessageNotFound
+	}
+	if !participating {
+		return errNotParticipant
+	}
+	if !enabled {
+		return errReactionDisabled
+	}
+	return nil
+}
+
+// setReaction はメッセージへのリアクションを付ける（on=true）か外します。何度呼んでも結果は同じです。
+// 戻り値はそのメッセージのその種類のリアクション数です。
+func setReaction(ctx context.Context, db *sql.DB, messageID, userID, reactionType string, on bool) (int, error) {
+	var err error
+	if on {
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO message_reactions (message_id, user_id, reaction_type) VALUES ($1, $2, $3)
+			ON CONFLICT (message_id, user_id, reaction_type) DO NOTHING`, messageID, userID, reactionType)
+	} else {
+		_, err = db.ExecContext(ctx, `
+			DELETE FROM message_reactions
+			WHERE message_id = $1 AND user_id = $2 AND reaction_type = $3`, messageID, userID, reactionType)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM message_reactions WHERE message_id = $1 AND reaction_type = $2`,
+		messageID, reactionType).Scan(&count)
+	return count, err
+}
+
+// respondReactionError は checkReactionTarget / setReaction のエラーをレスポンスに変換します。
+func respondReactionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定されたメッセージは見つかりません"})
+	case errors.Is(err, errNotParticipant):
+		c.JSON(http.StatusForbidden, gin.H{"error": "この会議室の参加者ではありません"})
+	case errors.Is(err, errReactionDisabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "このリアクションはこの会議室では使用できません"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+	}
+}
+
+// PUT /rooms/:id/messages/:messageId/reactions/:type
+func (h *RoomHandler) AddMessageReaction(c *gin.Context) {
+	h.respondToggleReaction(c, true)
+}
+
+// DELETE /rooms/:id/messages/:messageId/reactions/:type
+func (h *RoomHandler) RemoveMessageReaction(c *gin.Context) {
+	h.respondToggleReaction(c, false)
+}
+
+func (h *RoomHandler) respondToggleReaction(c *gin.Context, on bool) {
+	reactionType := c.Param("type")
+	count, ok := h.toggleReaction(c, reactionType, on)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, models.ReactionToggleResponse{
+		MessageID: c.Param("messageId"),
+		Type:      reactionType,
+		Active:    on,
+		Count:     count,
+	})
+}
+
+// toggleReaction はURLの部屋・メッセージに対してリアクションを付け外しします。
+// 失敗した場合はレスポンスを書き込んで false を返します。
+func (h *RoomHandler) toggleReaction(c *gin.Context, reactionType string, on bool) (int, bool) {
+	roomID, messageID := c.Param("id"), c.Param("messageId")
+	ctx := c.Request.Context()
+
+	// DELETE ではボディを省略できる
+	var req models.ReactionToggleRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+			return 0, false
+		}
+	} else {
+		req.UserID = c.Query("user_id")
+	}
+
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return 0, false
+	}
+	if !allowAction(c, h.limiter, ratelimit.ActionReaction, roomID, userID) {
+		return 0, false
+	}
+	if err := checkReactionTarget(ctx, h.db, roomID, messageID, userID, reactionType); err != nil {
+		respondReactionError(c, err)
+		return 0, false
+	}
+	count, err := setReaction(ctx, h.db, messageID, userID, reactionType, on)
+	if err != nil {
+		respondReactionError(c, err)
+		return 0, false
+	}
+	return count, true
+}
+
+// ListReactionTypes godoc
+// @Summary      リアクションの種類一覧を取得
+// @Description  登録されているすべてのリアクションの種類を取得します
+// @Tags         reactions
+// @Produce      json
+// @Success      200  {array}   models.ReactionType
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /reaction-types [get]
+func (h *ReactionHandler) ListReactionTypes(c *gin.Context) {
+	rows, err := h.db.QueryContext(c.Request.Context(), `
+		SELECT key, label, emoji, built_in FROM reaction_types ORDER BY sort_order, key`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer rows.Close()
+
+	types := []models.ReactionType{}
+	for rows.Next() {
+		var t models.ReactionType
+		if err := rows.Scan(&t.Key, &t.Label, &t.Emoji, &t.BuiltIn); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		types = append(types, t)
+	}
+	c.JSON(http.StatusOK, types)
+}
+
+// CreateReactionType godoc
+// @Summary      リアクションの種類を追加
+// @Description  絵文字などのカスタムリアクションを追加します。追加した種類は会議室ごとの設定で有効にできます。すべての会議室から見えるため、reaction-types:manage スコープのサービスアカウントのみ実行できます
+// @Tags         reactions
+// @Accept       json
+// @Produce      json
+// @Param        reaction  body      models.ReactionType  true  "リアクションの種類"
+// @Success      201       {object}  models.ReactionType
+// @Failure      400       {object}  map[string]interface{}
+// @Failure      401       {object}  map[string]interface{}
+// @Failure      403       {object}  map[string]interface{}
+// @Failure      409       {object}  map[string]interface{}
+// @Failure      500       {object}  map[string]interface{}
+// @Router       /reaction-types [post]
+func (h *ReactionHandler) CreateReactionType(c *gin.Context) {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "reaction-types:manage スコープのAPIキーが必要です"})
+		return
+	}
+	if !principal.HasScope(auth.ScopeReactionTypesManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "リアクションの種類を追加する権限がありません"})
+		return
+	}
+
+	var req models.ReactionType
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	req.Label = strings.TrimSpace(req.Label)
+	req.Emoji = strings.TrimSpace(req.Emoji)
+	if !reactionKeyPattern.MatchString(req.Key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyは英小文字・数字・_の1〜32文字で指定してください"})
+		return
+	}
+	if req.Label == "" || utf8.RuneCountInString(req.Label) > 50 || utf8.RuneCountInString(req.Emoji) > 16 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "labelは1〜50文字、emojiは16文字以内で指定してください"})
+		return
+	}
+
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		INSERT INTO reaction_types (key, label, emoji, sort_order)
+		VALUES ($1, $2, $3, (SELECT COALESCE(MAX(sort_order), 0) + 10 FROM reaction_types))
+		ON CONFLICT (key) DO NOTHING`, req.Key, req.Label, req.Emoji)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "同じkeyのリアクションが既に存在します"})
+		return
+	}
+	req.BuiltIn = false
+	c.JSON(http.StatusCreated, req)
+}
+
+// GetRoomReactionTypes godoc
+// @Summary      会議室のリアクションの種類を取得
+// @Description  会議室で使えるリアクションの種類を表示順に取得します。設定がない場合は既定のセットを返します
+// @Tags         reactions
+// @Produce      json
+// @Param        id   path      string  true  "会議室ID"
+// @Success      200  {array}   models.ReactionType
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /rooms/{id}/reaction-types [get]
+func (h *ReactionHandler) GetRoomReactionTypes(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	if found, err := roomExists(ctx, h.db, roomID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	} else if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		return
+	}
+
+	types, err := roomReactionTypes(ctx, h.db, roomID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.JSON(http.StatusOK, types)
+}
+
+// SetRoomReactionTypes godoc
+// @Summary      会議室のリアクションの種類を設定
+// @Description  会議室で使うリアクションの種類と表示順を設定します。空のリストを指定すると既定のセットに戻ります。既に付いたリアクションは削除されません。ホストのみ実行でき、ゲストは実行できません
+// @Tags         reactions
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string                           true  "会議室ID"
+// @Param        reactions  body      models.RoomReactionTypesRequest  true  "使用するリアクション"
+// @Success      200        {array}   models.ReactionType
+// @Failure      400        {object}  map[string]interface{}
+// @Failure      403        {object}  map[string]interface{}
+// @Failure      404        {object}  map[string]interface{}
+// @Failure      500        {object}  map[string]interface{}
+// @Router       /rooms/{id}/reaction-types [put]
+func (h *ReactionHandler) SetRoomReactionTypes(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req models.RoomReactionTypesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	seen := make(map[string]bool, len(req.Keys))
+	for _, key := range req.Keys {
+		if seen[key] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "keysに重複があります"})
+			return
+		}
+		seen[key] = true
+	}
+	// ホストのいない会議室では参加中のユーザーをホストとみなすため、ゲストは先に断る
+	if principal, ok := auth.PrincipalFrom(c); ok && principal.Kind == auth.KindGuest {
+		c.JSON(http.StatusForbidden, gin.H{"error": "ゲストはリアクションの種類を変更できません"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok || !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	var locked string
+	err = tx.QueryRowContext(ctx, `SELECT id FROM rooms WHERE id = $1 FOR UPDATE`, roomID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM room_reaction_types WHERE room_id = $1`, roomID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	for i, key := range req.Keys {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO room_reaction_types (room_id, reaction_key, sort_order) VALUES ($1, $2, $3)`,
+			roomID, key, (i+1)*10)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "存在しないリアクションが指定されています: " + key})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			}
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+
+	types, err := roomReactionTypes(ctx, h.db, roomID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.JSON(http.StatusOK, types)
+}
+
+// roomExists は部屋が存在するかどうかを返します。
+func roomExists(ctx context.Context, db *sql.DB, roomID string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM rooms WHERE id = $1)`, roomID).Scan(&exists)
+	return exists, err
+}
+
+// rankReactionMessages は指定した種類のリアクションが多い順に上位のメッセージを返します。
+func rankReactionMessages(logs []models.ChatLog, reactionType string, limit int) []models.SorenaMessage {
+	ranked := []models.SorenaMessage{}
+	for _, l := range logs {
+		count := l.Reactions[reactionType]
+		if count == 0 {
+			continue
+		}
+		ranked = append(ranked, models.SorenaMessage{
+			LogID:   l.LogID,
+			UserID:  l.UserID,
+			Message: l.Message,
+			Count:   count,
+		})
+	}
+	// 同数の場合は先に発言されたものを上位にする（logs は時系列順）
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Count > ranked[j].Count })
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// buildReactionSummaries はリアクションの種類ごとの集計を組み立てます。
+// authors は種類ごとの発言者別集計、logs はメッセージごとの Reactions を設定済みのチャットログです。
+func buildReactionSummaries(types []models.ReactionType, authors map[string][]models.SorenaParticipant, logs []models.ChatLog) []models.ReactionSummary {
+	summaries := make([]models.ReactionSummary, 0, len(types))
+	for _, t := range types {
+		s := models.ReactionSummary{
+			Type:         t.Key,
+			Label:        t.Label,
+			Emoji:        t.Emoji,
+			Participants: authors[t.Key],
+			TopMessages:  rankReactionMessages(logs, t.Key, topReactionMessages),
+		}
+		// 総数はメッセージ単位の集計から求める（要約メッセージへのリアクションも含む）
+		for _, l := range logs {
+			s.TotalCount += l.Reactions[t.Key]
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authenticateGuest はゲストトークンを発行し、認証のミドルウェアを通して c にゲストを格納します。
+func authenticateGuest(t *testing.T, c *gin.Context, userID, roomID string) {
+	tokens := auth.NewGuestTokens([]byte("secret"), time.Hour)
+	token, _, err := tokens.Issue(userID, roomID)
+	require.NoError(t, err)
+	c.Request.Header.Set("Authorization", "Bearer "+token)
+	auth.Middleware(tokens, nil, nil, nil)(c)
+}
+
+func TestSetRoomReactionTypes_NonHostIsForbidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+
+	c, w := newJSONContext(http.MethodPut, "/rooms/r001/reaction-types", `{"user_id":"u002","keys":["agree"]}`)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewReactionHandler(db).SetRoomReactionTypes(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetRoomReactionTypes_GuestIsForbidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	c, w := newJSONContext(http.MethodPut, "/rooms/r001/reaction-types", `{"keys":["agree"]}`)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	authenticateGuest(t, c, "g0000001", "r001")
+	NewReactionHandler(db).SetRoomReactionTypes(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateReactionType_RequiresManageScope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	body := `{"key":"party","label":"お祝い","emoji":"🎉"}`
+	c, w := newJSONContext(http.MethodPost, "/reaction-types", body)
+	NewReactionHandler(db).CreateReactionType(c)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	c, w = newJSONContext(http.MethodPost, "/reaction-types", body)
+	authenticateGuest(t, c, "g0000001", "r001")
+	NewReactionHandler(db).CreateReactionType(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
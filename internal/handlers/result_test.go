@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRoomResult_AnonymousRoomHidesAttribution(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	// 集計は並行して取得されるため順序は問わない
+	mock.MatchExpectationsInOrder(false)
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT title, anonymous, current_agenda_item_id FROM rooms`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "anonymous", "current_agenda_item_id"}).AddRow("ふりかえり", true, nil))
+	mock.ExpectQuery(`FROM agenda_items`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "timebox_minutes", "initial_question", "conclusion", "started_at", "ended_at"}).
+			AddRow("item1", "進め方", "", 10, "何を変えたいですか？", "隔週にする", now, now.Add(12*time.Minute)))
+	mock.ExpectQuery(`SELECT t.key, t.label, t.emoji, t.built_in\s+FROM reaction_types`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "label", "emoji", "built_in"}).
+			AddRow("sorena", "それな", "🙌", true).
+			AddRow("question", "質問", "❓", true))
+	mock.ExpectQuery(`SELECT r.reaction_type, u.id`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"reaction_type", "id", "user_name", "is_guest", "count"}).
+			AddRow("sorena", "u001", "田中太郎", false, 2).
+			AddRow("sorena", "u002", "佐藤花子", false, 1).
+			AddRow("question", "u001", "田中太郎", false, 1))
+	mock.ExpectQuery(`SELECT r.message_id, r.reaction_type, COUNT`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "reaction_type", "count"}).
+			AddRow("log1", "sorena", 2).
+			AddRow("log1", "question", 1).
+			AddRow("log2", "sorena", 1))
+	mock.ExpectQuery(`SELECT l.id, l.user_id, l.message`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "message", "is_summary", "created_at", "is_guest", "agenda_item_id"}).
+			AddRow("log1", "u001", "進め方を変えたい", false, now, false, "item1").
+			AddRow("log2", "u002", "賛成です", false, now.Add(time.Minute), false, "item1"))
+	mock.ExpectQuery(`FROM polls p\s+WHERE p.room_id = \$1`).
+		WithArgs("r001", "", true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "question", "multiple", "anonymous", "created_by", "closes_at", "created_at", "closed", "total_voters"}).
+			AddRow("poll1", "次回はいつにしますか？", false, false, "u001", nil, now, true, 1))
+	mock.ExpectQuery(`FROM poll_options o`).
+		WithArgs("r001", "").
+		WillReturnRows(sqlmock.NewRows([]string{"poll_id", "id", "label", "votes"}).
+			AddRow("poll1", 1, "月曜", 1).
+			AddRow("poll1", 2, "金曜", 0))
+	mock.ExpectQuery(`SELECT v.option_id, v.user_id`).
+		WithArgs("r001", "").
+		WillReturnRows(sqlmock.NewRows([]string{"option_id", "user_id"}).AddRow(1, "u002"))
+
+	c, w := newJSONContext(http.MethodGet, "/rooms/r001/result", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "r001"}}
+	NewRoomHandler(db, nil).GetRoomResult(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response models.RoomResultResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	assert.True(t, response.RoomInfo.Anonymous)
+	assert.Equal(t, 3, response.SorenaSummary.TotalCount)
+	assert.Empty(t, response.SorenaSummary.Participants)
+	require.Len(t, response.SorenaSummary.TopMessages, 2)
+	assert.Equal(t, "log1", response.SorenaSummary.TopMessages[0].LogID)
+	assert.Nil(t, response.SorenaSummary.TopMessages[0].UserID)
+	require.Len(t, response.Reactions, 2)
+	assert.Equal(t, "question", response.Reactions[1].Type)
+	assert.Equal(t, 1, response.Reactions[1].TotalCount)
+	assert.Empty(t, response.Reactions[1].Participants)
+	assert.Nil(t, response.Reactions[1].TopMessages[0].UserID)
+	require.Len(t, response.ChatLogs, 2)
+	assert.Nil(t, response.ChatLogs[0].UserID)
+	assert.Equal(t, map[string]int{"sorena": 2, "question": 1}, response.ChatLogs[0].Reactions)
+	require.Len(t, response.Agenda, 1)
+	assert.Equal(t, "隔週にする", response.Agenda[0].Conclusion)
+	assert.True(t, response.Agenda[0].Overtime)
+	require.Len(t, response.Polls, 1)
+	assert.Equal(t, 1, response.Polls[0].Options[0].Votes)
+	assert.Nil(t, response.Polls[0].Options[0].Voters)
+	assert.NotContains(t, w.Body.String(), "u001")
+	assert.NotContains(t, w.Body.String(), "u002")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRankReactionMessages(t *testing.T) {
+	logs := []models.ChatLog{
+		{LogID: "a", Message: "最初", Reactions: map[string]int{"sorena": 1}},
+		{LogID: "b", Message: "反応なし"},
+		{LogID: "c", Message: "人気", Reactions: map[string]int{"sorena": 3}},
+		{LogID: "d", Message: "同数だが後", Reactions: map[string]int{"sorena": 1, "agree": 5}},
+	}
+
+	ranked := rankReactionMessages(logs, "sorena", 2)
+
+	require.Len(t, ranked, 2)
+	assert.Equal(t, "c", ranked[0].LogID)
+	assert.Equal(t, "a", ranked[1].LogID)
+}
@@ -1,22 +1,37 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
 	"sync"
-	"errors"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/actionitems"
 	"github.com/shuto.sawaki/elmo-project/internal/ai"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/conclusions"
+	"github.com/shuto.sawaki/elmo-project/internal/events"
 	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+	"github.com/shuto.sawaki/elmo-project/internal/ratelimit"
+	"github.com/shuto.sawaki/elmo-project/internal/series"
 )
 
+// 会議室の開始時に返す未完了のアクションアイテムの上限
+const maxOpenActionItemsOnStart = 20
+
 type RoomHandler struct {
 	db          *sql.DB
 	aiGenerator ai.AIGenerator
+	limiter     *ratelimit.Limiter
+	notifiers   notify.Notifiers
+	hub         *events.Hub
 }
 
 func NewRoomHandler(db *sql.DB, aiGen ai.AIGenerator) *RoomHandler {
@@ -26,27 +41,6 @@ func NewRoomHandler(db *sql.DB, aiGen ai.AIGenerator) *RoomHandler {
 	}
 }
 
-// GET /rooms
-func (h *RoomHandler) GetRooms(c *gin.Context) {
-	rows, err := h.db.Query("SELECT id, title, description FROM rooms ORDER BY id ASC")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
-		return
-	}
-	defer rows.Close()
-
-	var rooms []models.Room
-	for rows.Next() {
-		var room models.Room
-		if err := rows.Scan(&room.ID, &room.Title, &room.Description); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
-			return
-		}
-		rooms = append(rooms, room)
-	}
-	c.JSON(http.StatusOK, rooms)
-}
-
 // POST /rooms
 func (h *RoomHandler) CreateRoom(c *gin.Context) {
 	var newRoom models.Room
@@ -55,11 +49,56 @@ func (h *RoomHandler) CreateRoom(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+	if newRoom.TimeZone == "" {
+		newRoom.TimeZone = defaultRoomTimeZone
+	}
+
+	// テンプレートを指定した場合は、指定されなかった項目をテンプレートで補う
+	var template *models.RoomTemplate
+	if newRoom.TemplateID != nil {
+		t, err := loadTemplate(ctx, h.db, *newRoom.TemplateID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "指定されたテンプレートは見つかりません"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			}
+			return
+		}
+		applyTemplate(&newRoom, t, time.Now())
+		template = &t
+	}
+	if newRoom.PromptVariant == "" {
+		newRoom.PromptVariant = ai.PromptDefault
+	}
+
 	if newRoom.Title == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "タイトルは必須です"})
 		return
 	}
 
+	if newRoom.MaxParticipants != nil && *newRoom.MaxParticipants <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_participantsは1以上を指定してください"})
+		return
+	}
+
+	tags, msg := normalizeTags(newRoom.Tags)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	newRoom.Tags = tags
+
+	if !ai.IsPromptVariant(newRoom.PromptVariant) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prompt_variantが不正です"})
+		return
+	}
+	if msg := validateSchedule(newRoom.ScheduledStartAt, newRoom.DurationMinutes, newRoom.TimeZone, time.Now()); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	newId, err := gonanoid.Generate("0123456789abcdefghijklmnopqrstuvwxyz", 6)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
@@ -67,21 +106,49 @@ func (h *RoomHandler) CreateRoom(c *gin.Context) {
 	}
 	newRoom.ID = newId
 
-	sqlStatement := `INSERT INTO rooms (id, title, description) VALUES ($1, $2, $3)`
-	_, err = h.db.Exec(sqlStatement, newRoom.ID, newRoom.Title, newRoom.Description)
+	// APIキーで作成した場合はサービスアカウントをホストにする
+	if principal, ok := auth.PrincipalFrom(c); ok {
+		if principal.Kind != auth.KindServiceAccount {
+			c.JSON(http.StatusForbidden, gin.H{"error": "この認証情報はこの操作に使用できません"})
+			return
+		}
+		newRoom.CreatedBy = &principal.UserID
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
 		return
 	}
+	defer tx.Rollback()
+
+	sqlStatement := `INSERT INTO rooms (id, title, description, max_participants, allow_guests, anonymous, created_by, scheduled_start_at, duration_minutes, time_zone, template_id, prompt_variant) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	_, err = tx.ExecContext(ctx, sqlStatement, newRoom.ID, newRoom.Title, newRoom.Description, newRoom.MaxParticipants, newRoom.AllowGuests, newRoom.Anonymous, newRoom.CreatedBy, newRoom.ScheduledStartAt, newRoom.DurationMinutes, newRoom.TimeZone, newRoom.TemplateID, newRoom.PromptVariant)
+	if err == nil && template != nil {
+		err = insertTemplateRoomContents(ctx, tx, newRoom.ID, *template)
+	}
+	if err == nil && len(newRoom.Tags) > 0 {
+		err = replaceRoomTags(ctx, tx, newRoom.ID, newRoom.Tags)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "created_byのユーザーが見つかりません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	h.notifiers.Publish(ctx, h.db, notify.TypeRoomCreated, newRoom.ID, nil)
+	applyRoomTimeZone(&newRoom)
 	c.JSON(http.StatusCreated, newRoom)
 }
 
 // GET /rooms/:id
 func (h *RoomHandler) GetRoomByID(c *gin.Context) {
-	id := c.Param("id")
-	var room models.Room
-	sqlStatement := `SELECT id, title, description, conclusion, status, initial_question FROM rooms WHERE id = $1`
-	err := h.db.QueryRow(sqlStatement, id).Scan(&room.ID, &room.Title, &room.Description, &room.Conclusion, &room.Status)
+	room, err := loadRoom(c.Request.Context(), h.db, c.Param("id"))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
@@ -93,7 +160,51 @@ func (h *RoomHandler) GetRoomByID(c *gin.Context) {
 	c.JSON(http.StatusOK, room)
 }
 
+// loadRoom は会議室を一件読み込みます。予定日時は会議室のタイムゾーンで返します。
+func loadRoom(ctx context.Context, db *sql.DB, id string) (models.Room, error) {
+	var room models.Room
+	var conclusion, initialQuestion, createdBy, seriesID, previousRoomID, templateID sql.NullString
+	var maxParticipants, durationMinutes sql.NullInt64
+	var scheduledStartAt sql.NullTime
+	sqlStatement := `SELECT id, title, description, conclusion, status, initial_question, max_participants, allow_guests, anonymous, created_by, scheduled_start_at, duration_minutes, time_zone, series_id, previous_room_id, template_id, prompt_variant FROM rooms WHERE id = $1`
+	err := db.QueryRowContext(ctx, sqlStatement, id).Scan(&room.ID, &room.Title, &room.Description, &conclusion, &room.Status, &initialQuestion, &maxParticipants, &room.AllowGuests, &room.Anonymous, &createdBy, &scheduledStartAt, &durationMinutes, &room.TimeZone, &seriesID, &previousRoomID, &templateID, &room.PromptVariant)
+	if err != nil {
+		return room, err
+	}
+	room.Conclusion = conclusion.String
+	room.InitialQuestion = initialQuestion.String
+	if maxParticipants.Valid {
+		max := int(maxParticipants.Int64)
+		room.MaxParticipants = &max
+	}
+	if createdBy.Valid {
+		room.CreatedBy = &createdBy.String
+	}
+	if scheduledStartAt.Valid {
+		room.ScheduledStartAt = &scheduledStartAt.Time
+	}
+	if durationMinutes.Valid {
+		minutes := int(durationMinutes.Int64)
+		room.DurationMinutes = &minutes
+	}
+	if seriesID.Valid {
+		room.SeriesID = &seriesID.String
+	}
+	if previousRoomID.Valid {
+		room.PreviousRoomID = &previousRoomID.String
+	}
+	if templateID.Valid {
+		room.TemplateID = &templateID.String
+	}
+	if room.Tags, err = roomTags(ctx, db, id); err != nil {
+		return room, err
+	}
+	applyRoomTimeZone(&room)
+	return room, nil
+}
+
 // POST /rooms/:id/conclusion
+// 結論は新しい版として記録し、以前の版は GET /rooms/:id/conclusion/history で確認できる
 func (h *RoomHandler) SaveConclusion(c *gin.Context) {
 	roomID := c.Param("id")
 	
@@ -107,17 +218,22 @@ func (h *RoomHandler) SaveConclusion(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "結論は必須です"})
 		return
 	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireRoomMember(c, h.db, roomID, userID) {
+		return
+	}
 
-	// データベースを更新し、ステータスを'concluded'（結論が出た）に変更
-	_, err := h.db.Exec("UPDATE rooms SET conclusion = $1, status = 'concluded' WHERE id = $2", req.Conclusion, roomID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースの更新に失敗しました"})
+	// 新しい版を記録し、ステータスを'concluded'（結論が出た）に変更
+	if !h.saveConclusionVersion(c, roomID, userID, req.Conclusion, nil) {
 		return
 	}
 
 	// 更新後の部屋情報を取得して返す
 	var room models.Room
-	err = h.db.QueryRow("SELECT id, title, description, conclusion, status, initial_question FROM rooms WHERE id = $1", roomID).
+	err := h.db.QueryRow("SELECT id, title, description, conclusion, status FROM rooms WHERE id = $1", roomID).
 		Scan(&room.ID, &room.Title, &room.Description, &room.Conclusion, &room.Status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新後の部屋情報の取得に失敗しました"})
@@ -131,7 +247,8 @@ func (h *RoomHandler) StartRoom(c *gin.Context) {
 	roomID := c.Param("id")
 
 	var room models.Room
-	err := h.db.QueryRow("SELECT id, title, description, status FROM rooms WHERE id = $1", roomID).Scan(&room.ID, &room.Title, &room.Description, &room.Status)
+	var preparedQuestion, previousRoomID sql.NullString
+	err := h.db.QueryRow("SELECT id, title, description, status, initial_question, previous_room_id, prompt_variant FROM rooms WHERE id = $1", roomID).Scan(&room.ID, &room.Title, &room.Description, &room.Status, &preparedQuestion, &previousRoomID, &room.PromptVariant)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
 		return
@@ -142,19 +259,38 @@ func (h *RoomHandler) StartRoom(c *gin.Context) {
 		return
 	}
 
-	initialQuestion, err := h.aiGenerator.GenerateInitialQuestion(c.Request.Context(), room.Title, room.Description)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "AI API呼び出しエラー"})
-		return
+	// 予定された会議室ではスケジューラーが事前に問いかけを用意している
+	initialQuestion := preparedQuestion.String
+	if initialQuestion == "" {
+		// 定例会議では前回の結論と未完了のアクションを問いかけに引き継ぐ
+		topic, err := series.QuestionTopic(c.Request.Context(), h.db, previousRoomID, room.Description)
+		if err != nil {
+			log.Printf("前回の会議からの引き継ぎの読み込みに失敗しました: room=%s, err=%v", roomID, err)
+		}
+		initialQuestion, err = h.aiGenerator.GenerateInitialQuestion(c.Request.Context(), room.Title, ai.WithPromptVariant(topic, room.PromptVariant))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "AI API呼び出しエラー"})
+			return
+		}
 	}
 
-	_, err = h.db.Exec("UPDATE rooms SET status = $1, initial_question = $2 WHERE id = $3", "inprogress", initialQuestion, roomID)
+	// 問いかけを生成している間にスケジューラーが自動で開始した場合は、開始日時と問いかけを上書きせず通知も送らない
+	result, err := h.db.Exec("UPDATE rooms SET status = $1, initial_question = $2, started_at = NOW() WHERE id = $3 AND status = 'not started'", "inprogress", initialQuestion, roomID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
 		return
 	}
+	if n, err := result.RowsAffected(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	} else if n == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "部屋は既に開始されています"})
+		return
+	}
+	h.notifiers.Publish(c.Request.Context(), h.db, notify.TypeRoomStarted, roomID, notify.RoomStarted{InitialQuestion: initialQuestion})
+	h.hub.Publish(roomID, events.Event{Type: events.TypeRoomStarted, Data: map[string]string{"room_id": roomID, "initial_question": initialQuestion}})
 
-	rows, err := h.db.Query(`SELECT u.id, u.user_name FROM participants p JOIN users u ON p.user_id = u.id WHERE p.room_id = $1`, roomID)
+	rows, err := h.db.Query(`SELECT u.id, u.user_name FROM participants p JOIN users u ON p.user_id = u.id WHERE p.room_id = $1 AND p.left_at IS NULL`, roomID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
 		return
@@ -171,6 +307,13 @@ func (h *RoomHandler) StartRoom(c *gin.Context) {
 		participants = append(participants, p)
 	}
 
+	// これまでの会議から持ち越しているアクションアイテムを示す。読み込めなくても開始は失敗にしない
+	openActionItems, err := actionitems.OpenRelated(c.Request.Context(), h.db, roomID, maxOpenActionItemsOnStart)
+	if err != nil {
+		log.Printf("未完了のアクションアイテムの読み込みに失敗しました: room=%s, err=%v", roomID, err)
+		openActionItems = []models.ActionItem{}
+	}
+
 	response := models.StartRoomResponse{
 		InitialQuestion: initialQuestion,
 		RoomInfo: models.RoomInfo{
@@ -179,6 +322,7 @@ func (h *RoomHandler) StartRoom(c *gin.Context) {
 			Status: "inprogress",
 		},
 		Participants: participants,
+		OpenActionItems: openActionItems,
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -192,17 +336,32 @@ func (h *RoomHandler) HandleSorena(c *gin.Context) {
 		return
 	}
 
-	// ... (元のsorena.goにあったバリデーションを追加しても良い) ...
+	if req.MessageID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message_idは必須です"})
+		return
+	}
+	// count は非推奨で無視されるが、古いクライアントが送る 1 以外の値は不正として扱う
+	if req.Count < 0 || req.Count > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "countは0または1のみ指定できます"})
+		return
+	}
+
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !allowAction(c, h.limiter, ratelimit.ActionReaction, roomID, userID) {
+		return
+	}
 
-	sqlStatement := `
-		INSERT INTO sorena_counts (room_id, user_id, count)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (room_id, user_id)
-		DO UPDATE SET count = sorena_counts.count + EXCLUDED.count
-	`
-	_, err := h.db.Exec(sqlStatement, roomID, req.UserID, req.Count)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+	// 「それな」は一人一メッセージにつき一回。何度送っても増えない
+	ctx := c.Request.Context()
+	if err := checkReactionTarget(ctx, h.db, roomID, req.MessageID, userID, models.ReactionSorena); err != nil {
+		respondReactionError(c, err)
+		return
+	}
+	if _, err := setReaction(ctx, h.db, req.MessageID, userID, models.ReactionSorena, true); err != nil {
+		respondReactionError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -226,8 +385,34 @@ func (h *RoomHandler) CreateSummary(c *gin.Context) {
 		return
 	}
 
+	// 要約リクエストには送信者がないため、会議室単位で制限する
+	if !allowAction(c, h.limiter, ratelimit.ActionSummary, roomID, "") {
+		return
+	}
+	// 同じログの要約が短時間に繰り返し届いた場合はAIを呼ばずに終える
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	if isDuplicate(c, h.limiter, "summary:"+roomID+":"+string(reqJSON), duplicateSummaryWindow) {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	// 締め切られた投票の結果もログとしてAIに渡す
+	logs := req.Logs
+	if req.IncludePolls {
+		polls, err := loadPolls(c.Request.Context(), h.db, roomID, "", true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "投票結果の取得に失敗しました"})
+			return
+		}
+		logs = append(logs, pollLogEntries(polls)...)
+	}
+
 	// 3. AIに要約を依頼
-	summary, err := h.aiGenerator.SummarizeLogs(c.Request.Context(), req.Logs)
+	summary, err := h.aiGenerator.SummarizeLogs(c.Request.Context(), logs)
 	if err != nil {
 		// ここではエラーをログに出力するだけにして、クライアントにはエラーを返さないことも考えられます。
 		// 定期実行のバックグラウンド処理的な側面が強いため。今回はサーバーエラーとして返します。
@@ -243,14 +428,15 @@ func (h *RoomHandler) CreateSummary(c *gin.Context) {
 	}
 
 	sqlStatement := `
-		INSERT INTO chat_logs (id, room_id, message, is_summary)
-		VALUES ($1, $2, $3, TRUE)
+		INSERT INTO chat_logs (id, room_id, message, is_summary, agenda_item_id)
+		VALUES ($1, $2, $3, TRUE, (SELECT current_agenda_item_id FROM rooms WHERE id = $2))
 	`
 	_, err = h.db.Exec(sqlStatement, logID, roomID, summary)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースへの保存に失敗しました"})
 		return
 	}
+	h.notifiers.Publish(c.Request.Context(), h.db, notify.TypeSummaryCreated, roomID, notify.SummaryCreated{MessageID: logID, Summary: summary})
 
 	// 5. 成功したが返すコンテンツはない、というステータスを返す
 	c.Status(http.StatusNoContent)
@@ -274,8 +460,9 @@ func (h *RoomHandler) UpdateRoomStatus(c *gin.Context) {
 		return
 	}
 
-	// データベースを更新するSQL
-	sqlStatement := `UPDATE rooms SET status = $1 WHERE id = $2`
+	// データベースを更新するSQL。すでに終了している場合は更新せず、終了の通知も重ねて送らない
+	// 結論の承認者がいる場合は、全員が最新の版を承認するまで終了できない
+	sqlStatement := `UPDATE rooms SET status = $1 WHERE id = $2 AND status <> $1 AND NOT ` + conclusions.PendingApprovals
 	result, err := h.db.ExecContext(c.Request.Context(), sqlStatement, req.Status, roomID)
 	if err != nil {
 		log.Printf("failed to update room status: %v", err)
@@ -291,8 +478,25 @@ func (h *RoomHandler) UpdateRoomStatus(c *gin.Context) {
 		return
 	}
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "room not found"})
-		return
+		var status string
+		var pending bool
+		err := h.db.QueryRowContext(c.Request.Context(), `SELECT status, `+conclusions.PendingApprovals+` FROM rooms WHERE id = $1`, roomID).Scan(&status, &pending)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "room not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("failed to check room: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		if status != req.Status && pending {
+			c.JSON(http.StatusConflict, gin.H{"error": "the conclusion has not been approved by all approvers"})
+			return
+		}
+	} else {
+		h.notifiers.Publish(c.Request.Context(), h.db, notify.TypeRoomDone, roomID, nil)
+		h.hub.Publish(roomID, events.Event{Type: events.TypeRoomEnded, Data: map[string]string{"room_id": roomID}})
 	}
 
 	// 成功時は 204 No Content を返す
@@ -301,72 +505,125 @@ func (h *RoomHandler) UpdateRoomStatus(c *gin.Context) {
 
 // GET /rooms/:id/result
 func (h *RoomHandler) GetRoomResult(c *gin.Context) {
-	roomID := c.Param("id")
-	ctx := c.Request.Context()
+	response, err := loadRoomResult(c.Request.Context(), h.db, c.Param("id"))
+	if err != nil {
+		log.Printf("Error fetching room result: %v", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "room not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch room result data"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
 
+// loadRoomResult はリザルト画面の集計をまとめて読み込みます。匿名モードの部屋では発言者を取り除いて返します。
+// 部屋が見つからない場合は sql.ErrNoRows を含むエラーを返します。
+func loadRoomResult(ctx context.Context, db *sql.DB, roomID string) (models.RoomResultResponse, error) {
 	var wg sync.WaitGroup
 	var roomInfo models.ResultRoomInfo
-	var sorenaSummary models.SorenaSummary
+	var reactionTypes []models.ReactionType
+	authors := make(map[string][]models.SorenaParticipant)
+	messageCounts := make(map[string]map[string]int)
 	var chatLogs []models.ChatLog // ★ LogEntryからChatLogに統一
-	var errRoom, errSorena, errLogs error
+	var polls []models.Poll
+	var agenda []models.AgendaItem
+	var errAgenda error
+	var errRoom, errTypes, errAuthors, errCounts, errLogs, errPolls error
 
-	wg.Add(3)
+	wg.Add(6)
 
 	// Goroutine 1: 部屋情報を取得
 	go func() {
 		defer wg.Done()
 		var title string
-		err := h.db.QueryRowContext(ctx, "SELECT title FROM rooms WHERE id = $1", roomID).Scan(&title)
+		var anonymous bool
+		var currentItem sql.NullString
+		err := db.QueryRowContext(ctx, "SELECT title, anonymous, current_agenda_item_id FROM rooms WHERE id = $1", roomID).Scan(&title, &anonymous, &currentItem)
 		if err != nil {
 			errRoom = err
 			return
 		}
-		roomInfo = models.ResultRoomInfo{RoomID: roomID, Title: title}
+		roomInfo = models.ResultRoomInfo{RoomID: roomID, Title: title, Anonymous: anonymous}
+		agenda, errAgenda = loadAgendaItems(ctx, db, roomID, currentItem.String, time.Now())
 	}()
 
-	// Goroutine 2: 「それな」の集計
+	// Goroutine 2: この部屋のリアクションの種類（設定から外されたが使われたものも含む）
+	go func() {
+		defer wg.Done()
+		reactionTypes, errTypes = roomReactionTypes(ctx, db, roomID, true)
+	}()
+
+	// Goroutine 3: 種類ごと・発言者ごとのリアクションの集計
 	go func() {
 		defer wg.Done()
 		query := `
-            SELECT u.id, u.user_name, COUNT(sc.id) as count
-            FROM sorena_counts sc
-            JOIN users u ON sc.user_id = u.id
-            WHERE sc.room_id = $1
-            GROUP BY u.id, u.user_name
+            SELECT r.reaction_type, u.id, u.user_name, u.is_guest, COUNT(*) as count
+            FROM message_reactions r
+            JOIN chat_logs l ON r.message_id = l.id
+            JOIN users u ON l.user_id = u.id
+            WHERE l.room_id = $1
+            GROUP BY r.reaction_type, u.id, u.user_name, u.is_guest
             ORDER BY count DESC`
-		rows, err := h.db.QueryContext(ctx, query, roomID)
+		rows, err := db.QueryContext(ctx, query, roomID)
 		if err != nil {
-			errSorena = err
+			errAuthors = err
 			return
 		}
 		defer rows.Close()
 
-		var participants []models.SorenaParticipant
-		totalCount := 0
 		for rows.Next() {
+			var reactionType string
 			var p models.SorenaParticipant
-			if err := rows.Scan(&p.UserID, &p.UserName, &p.Count); err != nil {
-				errSorena = err
+			if err := rows.Scan(&reactionType, &p.UserID, &p.UserName, &p.IsGuest, &p.Count); err != nil {
+				errAuthors = err
 				return
 			}
-			participants = append(participants, p)
-			totalCount += p.Count
+			authors[reactionType] = append(authors[reactionType], p)
 		}
-		sorenaSummary = models.SorenaSummary{
-			TotalCount:   totalCount,
-			Participants: participants,
+	}()
+
+	// Goroutine 4: メッセージごと・種類ごとのリアクション数
+	go func() {
+		defer wg.Done()
+		query := `
+			SELECT r.message_id, r.reaction_type, COUNT(*)
+			FROM message_reactions r
+			JOIN chat_logs l ON r.message_id = l.id
+			WHERE l.room_id = $1
+			GROUP BY r.message_id, r.reaction_type`
+		rows, err := db.QueryContext(ctx, query, roomID)
+		if err != nil {
+			errCounts = err
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var messageID, reactionType string
+			var count int
+			if err := rows.Scan(&messageID, &reactionType, &count); err != nil {
+				errCounts = err
+				return
+			}
+			if messageCounts[messageID] == nil {
+				messageCounts[messageID] = make(map[string]int)
+			}
+			messageCounts[messageID][reactionType] = count
 		}
 	}()
 
-	// Goroutine 3: チャットログを取得
+	// Goroutine 5: チャットログを取得
 	go func() {
 		defer wg.Done()
 		query := `
-			SELECT id, user_id, content, is_summary, created_at
-			FROM chat_logs
-			WHERE room_id = $1
-			ORDER BY created_at ASC`
-		rows, err := h.db.QueryContext(ctx, query, roomID)
+			SELECT l.id, l.user_id, l.message, l.is_summary, l.created_at, COALESCE(u.is_guest, FALSE), l.agenda_item_id
+			FROM chat_logs l
+			LEFT JOIN users u ON l.user_id = u.id
+			WHERE l.room_id = $1
+			ORDER BY l.created_at ASC`
+		rows, err := db.QueryContext(ctx, query, roomID)
 		if err != nil {
 			errLogs = err
 			return
@@ -375,36 +632,62 @@ func (h *RoomHandler) GetRoomResult(c *gin.Context) {
 
 		for rows.Next() {
 			var log models.ChatLog
-			var userID sql.NullString
+			var userID, agendaItemID sql.NullString
 			// ★ ScanするフィールドをChatLogのフィールド名に合わせる
-			if err := rows.Scan(&log.LogID, &userID, &log.Message, &log.IsSummary, &log.Timestamp); err != nil {
+			if err := rows.Scan(&log.LogID, &userID, &log.Message, &log.IsSummary, &log.Timestamp, &log.IsGuest, &agendaItemID); err != nil {
 				errLogs = err
 				return
 			}
 			if userID.Valid {
 				log.UserID = &userID.String
 			}
+			if agendaItemID.Valid {
+				log.AgendaItemID = &agendaItemID.String
+			}
 			chatLogs = append(chatLogs, log)
 		}
 	}()
 
+	// Goroutine 6: 締め切られた投票の結果
+	go func() {
+		defer wg.Done()
+		polls, errPolls = loadPolls(ctx, db, roomID, "", true)
+	}()
+
 	wg.Wait()
 
-	if errRoom != nil || errSorena != nil || errLogs != nil {
-		log.Printf("Error fetching room result: roomErr=%v, sorenaErr=%v, logErr=%v", errRoom, errSorena, errLogs)
-		if errors.Is(errRoom, sql.ErrNoRows) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "room not found"})
-			return
+	if err := errors.Join(errRoom, errTypes, errAuthors, errCounts, errLogs, errPolls, errAgenda); err != nil {
+		return models.RoomResultResponse{}, err
+	}
+
+	for i := range chatLogs {
+		chatLogs[i].Reactions = messageCounts[chatLogs[i].LogID]
+		chatLogs[i].SorenaCount = chatLogs[i].Reactions[models.ReactionSorena]
+	}
+	reactions := buildReactionSummaries(reactionTypes, authors, chatLogs)
+
+	// sorena_summary は従来のクライアント向けに「それな」の集計をそのまま返す
+	sorenaSummary := models.SorenaSummary{TopMessages: []models.SorenaMessage{}}
+	for _, r := range reactions {
+		if r.Type == models.ReactionSorena {
+			sorenaSummary = models.SorenaSummary{
+				TotalCount:   r.TotalCount,
+				Participants: r.Participants,
+				TopMessages:  r.TopMessages,
+			}
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch room result data"})
-		return
 	}
 
 	response := models.RoomResultResponse{
 		RoomInfo:      roomInfo,
 		SorenaSummary: sorenaSummary,
+		Reactions:     reactions,
 		ChatLogs:      chatLogs, // ★ 変換処理が不要になった
+		Polls:         polls,
+		Agenda:        agenda,
 	}
-
-	c.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+	if roomInfo.Anonymous {
+		redactRoomResult(&response)
+	}
+	return response, nil
+}
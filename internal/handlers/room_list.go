@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// 会議室一覧の件数とタグの上限
+const (
+	defaultRoomListLimit = 20
+	maxRoomListLimit     = 100
+	maxRoomTags          = 10
+	maxRoomTagLength     = 50
+)
+
+// roomSortKeys は並び順ごとの並べ替えのキーです（rooms の別名は r）。いずれも新しい順に並べます。
+var roomSortKeys = map[string]string{
+	"created":  `r.created_at`,
+	"started":  `COALESCE(r.started_at, 'epoch'::timestamptz)`,
+	"activity": `COALESCE((SELECT MAX(l.created_at) FROM chat_logs l WHERE l.room_id = r.id), r.started_at, r.created_at)`,
+}
+
+var roomStatuses = map[string]bool{"not started": true, "inprogress": true, "concluded": true, "done": true}
+
+// roomListFilter は会議室一覧の絞り込み条件です。
+type roomListFilter struct {
+	Statuses    []string
+	CreatedBy   string
+	Participant string
+	Tags        []string
+	From, To    *time.Time
+	Query       string
+	Sort        string
+	Limit       int
+	Cursor      *roomCursor
+}
+
+// roomCursor は前のページの最後の会議室の位置です。
+type roomCursor struct {
+	Sort string    `json:"s"`
+	Key  time.Time `json:"k"`
+	ID   string    `json:"id"`
+}
+
+func (c roomCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeRoomCursor(s string) (*roomCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c roomCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// parseRoomListFilter はクエリパラメータから絞り込み条件を読み取り、不正な場合はエラーメッセージを返します。
+func parseRoomListFilter(c *gin.Context) (roomListFilter, string) {
+	f := roomListFilter{
+		CreatedBy:   c.Query("created_by"),
+		Participant: c.Query("participant"),
+		Query:       strings.TrimSpace(c.Query("q")),
+		Sort:        c.DefaultQuery("sort", "created"),
+		Limit:       defaultRoomListLimit,
+	}
+	for _, status := range splitQueryList(c.QueryArray("status")) {
+		if !roomStatuses[status] {
+			return f, "statusが不正です: " + status
+		}
+		f.Statuses = append(f.Statuses, status)
+	}
+	f.Tags = splitQueryList(c.QueryArray("tag"))
+	if _, ok := roomSortKeys[f.Sort]; !ok {
+		return f, "sortにはcreated・started・activityのいずれかを指定してください"
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxRoomListLimit {
+			return f, fmt.Sprintf("limitは1〜%dで指定してください", maxRoomListLimit)
+		}
+		f.Limit = n
+	}
+	for name, dst := range map[string]**time.Time{"from": &f.From, "to": &f.To} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, name + "にはRFC 3339形式の日時を指定してください"
+			}
+			*dst = &t
+		}
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := decodeRoomCursor(v)
+		if err != nil || cursor.Sort != f.Sort {
+			return f, "cursorが不正です"
+		}
+		f.Cursor = cursor
+	}
+
+	// participant=me は認証情報のユーザーに置き換える
+	if f.Participant == "me" {
+		principal, ok := auth.PrincipalFrom(c)
+		if !ok {
+			return f, "participant=meには認証が必要です"
+		}
+		f.Participant = principal.UserID
+	}
+	return f, ""
+}
+
+// splitQueryList は繰り返し指定とカンマ区切りの両方に対応して値を取り出します。
+func splitQueryList(values []string) []string {
+	var list []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// buildRoomListQuery は絞り込み条件から会議室一覧のクエリを組み立てます。
+// 日付の範囲は会議の日時（開始日時、開始予定日時、作成日時の順に最初にあるもの）で絞り込みます。
+func buildRoomListQuery(f roomListFilter) (string, []interface{}) {
+	sortKey := roomSortKeys[f.Sort]
+	var conds []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if len(f.Statuses) > 0 {
+		placeholders := make([]string, len(f.Statuses))
+		for i, status := range f.Statuses {
+			placeholders[i] = arg(status)
+		}
+		conds = append(conds, "r.status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if f.CreatedBy != "" {
+		conds = append(conds, "r.created_by = "+arg(f.CreatedBy))
+	}
+	if f.Participant != "" {
+		conds = append(conds, "EXISTS (SELECT 1 FROM participants p WHERE p.room_id = r.id AND p.user_id = "+arg(f.Participant)+")")
+	}
+	for _, tag := range f.Tags {
+		conds = append(conds, "EXISTS (SELECT 1 FROM room_tags t WHERE t.room_id = r.id AND t.tag = "+arg(tag)+")")
+	}
+	if f.From != nil {
+		conds = append(conds, "COALESCE(r.started_at, r.scheduled_start_at, r.created_at) >= "+arg(*f.From))
+	}
+	if f.To != nil {
+		conds = append(conds, "COALESCE(r.started_at, r.scheduled_start_at, r.created_at) < "+arg(*f.To))
+	}
+	if f.Query != "" {
+		pattern := arg("%" + escapeLike(f.Query) + "%")
+		conds = append(conds, "(r.title ILIKE "+pattern+" OR r.description ILIKE "+pattern+")")
+	}
+	if f.Cursor != nil {
+		conds = append(conds, "("+sortKey+", r.id) < ("+arg(f.Cursor.Key)+", "+arg(f.Cursor.ID)+")")
+	}
+
+	query := `
+		SELECT r.id, r.title, COALESCE(r.description, ''), r.status, r.created_by, r.created_at, r.scheduled_start_at, r.started_at,
+		       (SELECT COUNT(*) FROM participants p WHERE p.room_id = r.id AND p.left_at IS NULL),
+		       COALESCE((SELECT string_agg(t.tag, ',' ORDER BY t.tag) FROM room_tags t WHERE t.room_id = r.id), ''),
+		       COALESCE((SELECT MAX(l.created_at) FROM chat_logs l WHERE l.room_id = r.id), r.started_at, r.created_at),
+		       ` + sortKey + `
+		FROM rooms r`
+	if len(conds) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conds, " AND ")
+	}
+	// 次のページがあるかを知るために一件多く取得する
+	query += "\n\t\tORDER BY " + sortKey + " DESC, r.id DESC\n\t\tLIMIT " + arg(f.Limit+1)
+	return query, args
+}
+
+// escapeLike は LIKE のパターンで特別な意味を持つ文字をエスケープします。
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// GetRooms godoc
+// @Summary      会議室一覧を取得
+// @Description  会議室を新しい順にカーソルでページ分けして返します。ステータス・ホスト・参加者・タグ・日付の範囲・タイトルと説明のキーワードで絞り込めます
+// @Tags         rooms
+// @Produce      json
+// @Param        status       query     string  false  "ステータス（カンマ区切りで複数指定可）"
+// @Param        created_by   query     string  false  "ホストのユーザーID"
+// @Param        participant  query     string  false  "参加したことのあるユーザーのID（me で認証情報のユーザー）"
+// @Param        tag          query     string  false  "タグ（複数指定した場合はすべてを持つ会議室）"
+// @Param        from         query     string  false  "会議の日時の下限（RFC 3339）"
+// @Param        to           query     string  false  "会議の日時の上限（RFC 3339、この日時を含まない）"
+// @Param        q            query     string  false  "タイトルと説明に含まれるキーワード"
+// @Param        sort         query     string  false  "並び順（created（既定）・started・activity）"
+// @Param        limit        query     int     false  "件数（1〜100、既定 20）"
+// @Param        cursor       query     string  false  "前のページの next_cursor"
+// @Success      200          {object}  models.RoomListResponse
+// @Failure      400          {object}  map[string]interface{}
+// @Failure      500          {object}  map[string]interface{}
+// @Router       /rooms [get]
+func (h *RoomHandler) GetRooms(c *gin.Context) {
+	filter, msg := parseRoomListFilter(c)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	query, args := buildRoomListQuery(filter)
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer rows.Close()
+
+	response := models.RoomListResponse{Rooms: []models.RoomListItem{}}
+	var lastKey time.Time
+	for rows.Next() {
+		var room models.RoomListItem
+		var createdBy sql.NullString
+		var scheduledStartAt, startedAt sql.NullTime
+		var tags string
+		var sortKey time.Time
+		if err := rows.Scan(&room.ID, &room.Title, &room.Description, &room.Status, &createdBy, &room.CreatedAt, &scheduledStartAt, &startedAt, &room.ParticipantCount, &tags, &room.LastActivityAt, &sortKey); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		if len(response.Rooms) == filter.Limit {
+			last := response.Rooms[len(response.Rooms)-1]
+			response.NextCursor = roomCursor{Sort: filter.Sort, Key: lastKey, ID: last.ID}.encode()
+			break
+		}
+		if createdBy.Valid {
+			room.CreatedBy = &createdBy.String
+		}
+		if scheduledStartAt.Valid {
+			room.ScheduledStartAt = &scheduledStartAt.Time
+		}
+		if startedAt.Valid {
+			room.StartedAt = &startedAt.Time
+		}
+		room.Tags = splitQueryList([]string{tags})
+		if room.Tags == nil {
+			room.Tags = []string{}
+		}
+		lastKey = sortKey
+		response.Rooms = append(response.Rooms, room)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// normalizeTags はタグの前後の空白と重複を取り除き、不正な場合はエラーメッセージを返します。
+func normalizeTags(tags []string) ([]string, string) {
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxRoomTagLength || strings.Contains(tag, ",") {
+			return nil, fmt.Sprintf("タグは%d文字以内で、カンマを含めずに指定してください", maxRoomTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxRoomTags {
+		return nil, fmt.Sprintf("タグは%d個までです", maxRoomTags)
+	}
+	return normalized, ""
+}
+
+// replaceRoomTags は会議室のタグを置き換えます。
+func replaceRoomTags(ctx context.Context, tx *sql.Tx, roomID string, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM room_tags WHERE room_id = $1`, roomID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO room_tags (room_id, tag) VALUES ($1, $2)`, roomID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roomTags は会議室のタグを名前順に返します。
+func roomTags(ctx context.Context, db *sql.DB, roomID string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT tag FROM room_tags WHERE room_id = $1 ORDER BY tag`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// SetRoomTags godoc
+// @Summary      会議室のタグを設定
+// @Description  会議室のホストがタグを置き換えます。空のリストですべてのタグを外します
+// @Tags         rooms
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                  true  "会議室ID"
+// @Param        tags  body      models.RoomTagsRequest  true  "タグ"
+// @Success      200   {object}  models.Room
+// @Failure      400   {object}  map[string]interface{}
+// @Failure      403   {object}  map[string]interface{}
+// @Failure      404   {object}  map[string]interface{}
+// @Failure      500   {object}  map[string]interface{}
+// @Router       /rooms/{id}/tags [put]
+func (h *RoomHandler) SetRoomTags(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req models.RoomTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	tags, msg := normalizeTags(req.Tags)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+	if err := replaceRoomTags(ctx, tx, roomID, tags); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "タグの設定に失敗しました"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "タグの設定に失敗しました"})
+		return
+	}
+
+	room, err := loadRoom(ctx, h.db, roomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新後の部屋情報の取得に失敗しました"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, room)
+}
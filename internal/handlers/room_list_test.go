@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var roomListColumns = []string{"id", "title", "description", "status", "created_by", "created_at", "scheduled_start_at", "started_at", "participant_count", "tags", "last_activity_at", "sort_key"}
+
+func TestGetRooms_FiltersAndPaginates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`WHERE r.status IN \(\$1, \$2\) AND EXISTS \(SELECT 1 FROM room_tags t WHERE t.room_id = r.id AND t.tag = \$3\)\s+ORDER BY r.created_at DESC, r.id DESC\s+LIMIT \$4`).
+		WithArgs("inprogress", "done", "定例", 3).
+		WillReturnRows(sqlmock.NewRows(roomListColumns).
+			AddRow("r003", "週次 3", "", "inprogress", "u001", now, nil, now, 4, "定例,開発", now, now).
+			AddRow("r002", "週次 2", "", "done", "u001", now.Add(-time.Hour), nil, nil, 0, "定例", now.Add(-time.Hour), now.Add(-time.Hour)).
+			AddRow("r001", "週次 1", "", "done", nil, now.Add(-2*time.Hour), nil, nil, 0, "定例", now, now.Add(-2*time.Hour)))
+
+	c, w := newJSONContext(http.MethodGet, "/rooms?status=inprogress,done&tag=定例&limit=2", "")
+	NewRoomHandler(db, nil).GetRooms(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response models.RoomListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Rooms, 2)
+	assert.Equal(t, "r003", response.Rooms[0].ID)
+	assert.Equal(t, 4, response.Rooms[0].ParticipantCount)
+	assert.Equal(t, []string{"定例", "開発"}, response.Rooms[0].Tags)
+	require.NotEmpty(t, response.NextCursor)
+
+	cursor, err := decodeRoomCursor(response.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, "r002", cursor.ID)
+	assert.True(t, cursor.Key.Equal(now.Add(-time.Hour)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRooms_RejectsInvalidFilters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	startedCursor := roomCursor{Sort: "started", ID: "r001"}.encode()
+	for _, target := range []string{
+		"/rooms?status=archived",
+		"/rooms?sort=title",
+		"/rooms?limit=0",
+		"/rooms?from=yesterday",
+		"/rooms?cursor=" + startedCursor,
+		"/rooms?participant=me",
+	} {
+		c, w := newJSONContext(http.MethodGet, target, "")
+		NewRoomHandler(db, nil).GetRooms(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuildRoomListQuery_CursorAndSearch(t *testing.T) {
+	key := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query, args := buildRoomListQuery(roomListFilter{
+		Participant: "u001",
+		Query:       "100%",
+		Sort:        "activity",
+		Limit:       20,
+		Cursor:      &roomCursor{Sort: "activity", Key: key, ID: "r001"},
+	})
+
+	assert.Contains(t, query, "p.user_id = $1")
+	assert.Contains(t, query, "r.title ILIKE $2 OR r.description ILIKE $2")
+	assert.Contains(t, query, "r.started_at, r.created_at), r.id) < ($3, $4)")
+	assert.Contains(t, query, "LIMIT $5")
+	assert.Equal(t, []interface{}{"u001", `%100\%%`, key, "r001", 21}, args)
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, msg := normalizeTags([]string{" 開発 ", "定例", "開発", ""})
+	assert.Empty(t, msg)
+	assert.Equal(t, []string{"開発", "定例"}, tags)
+
+	_, msg = normalizeTags([]string{"a,b"})
+	assert.NotEmpty(t, msg)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// 会議室の既定のタイムゾーンと会議の長さの上限（分）
+const (
+	defaultRoomTimeZone = "Asia/Tokyo"
+	maxDurationMinutes  = 24 * 60
+)
+
+// validateSchedule は予定の設定を検証し、不正な場合はエラーメッセージを返します。
+// 開始予定日時は時差付きの絶対時刻として扱うため、夏時間の切り替えなどの影響を受けません。
+func validateSchedule(startAt *time.Time, durationMinutes *int, timeZone string, now time.Time) string {
+	if _, err := time.LoadLocation(timeZone); err != nil || timeZone == "" {
+		return "time_zoneにはIANAのタイムゾーン名（例: Asia/Tokyo）を指定してください"
+	}
+	if startAt != nil && !startAt.After(now) {
+		return "scheduled_start_atには未来の日時を指定してください"
+	}
+	if durationMinutes != nil && (*durationMinutes <= 0 || *durationMinutes > maxDurationMinutes) {
+		return "duration_minutesは1〜1440で指定してください"
+	}
+	return ""
+}
+
+// applyRoomTimeZone は予定日時を会議室のタイムゾーンの時差で表すように変換します。
+func applyRoomTimeZone(room *models.Room) {
+	if room.ScheduledStartAt == nil {
+		return
+	}
+	loc, err := time.LoadLocation(room.TimeZone)
+	if err != nil {
+		return
+	}
+	t := room.ScheduledStartAt.In(loc)
+	room.ScheduledStartAt = &t
+}
+
+// SetSchedule godoc
+// @Summary      会議室の予定を設定
+// @Description  開始予定日時・会議の長さ・タイムゾーンを設定します。開始予定日時になると自動で開始し、終了前に通知して、会議の長さが経つと自動で終了します。開始後は開始予定日時を変更できません
+// @Tags         rooms
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string                  true  "会議室ID"
+// @Param        schedule  body      models.ScheduleRequest  true  "予定"
+// @Success      200       {object}  models.Room
+// @Failure      400       {object}  map[string]interface{}
+// @Failure      403       {object}  map[string]interface{}
+// @Failure      404       {object}  map[string]interface{}
+// @Failure      409       {object}  map[string]interface{}
+// @Failure      500       {object}  map[string]interface{}
+// @Router       /rooms/{id}/schedule [put]
+func (h *RoomHandler) SetSchedule(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req models.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	userID, ok := actingUser(c, h.db, roomID, req.UserID)
+	if !ok {
+		return
+	}
+	if !requireHost(c, h.db, roomID, userID) {
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	// スケジューラーが同時に開始しないよう、部屋の行をロックする
+	var status, timeZone string
+	var current sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, time_zone, scheduled_start_at FROM rooms WHERE id = $1 FOR UPDATE`, roomID).Scan(&status, &timeZone, &current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	if req.TimeZone != "" {
+		timeZone = req.TimeZone
+	}
+
+	startChanged := current.Valid != (req.ScheduledStartAt != nil) ||
+		(current.Valid && !current.Time.Equal(*req.ScheduledStartAt))
+	if status != "not started" && startChanged {
+		c.JSON(http.StatusConflict, gin.H{"error": "開始済みの会議室の開始予定日時は変更できません"})
+		return
+	}
+	startAt := req.ScheduledStartAt
+	if !startChanged {
+		startAt = nil // 変更しない場合は過去の日時でも検証しない
+	}
+	if msg := validateSchedule(startAt, req.DurationMinutes, timeZone, time.Now()); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	// 会議の長さを変えた場合は終了前の通知をやり直す。予定が変わった場合はカレンダーの SEQUENCE を増やす
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rooms SET
+			calendar_sequence = calendar_sequence + CASE
+				WHEN scheduled_start_at IS DISTINCT FROM $1 OR duration_minutes IS DISTINCT FROM $2 OR time_zone IS DISTINCT FROM $3 THEN 1
+				ELSE 0 END,
+			reminder_sent_at = CASE WHEN scheduled_start_at IS DISTINCT FROM $1 THEN NULL ELSE reminder_sent_at END,
+			scheduled_start_at = $1,
+			end_warned_at = CASE WHEN duration_minutes IS DISTINCT FROM $2 THEN NULL ELSE end_warned_at END,
+			duration_minutes = $2,
+			time_zone = $3
+		WHERE id = $4`, req.ScheduledStartAt, req.DurationMinutes, timeZone, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "予定の設定に失敗しました"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "予定の設定に失敗しました"})
+		return
+	}
+
+	room, err := loadRoom(ctx, h.db, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新後の部屋情報の取得に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusOK, room)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSchedule(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Minute)
+	duration := func(m int) *int { return &m }
+
+	assert.Empty(t, validateSchedule(nil, nil, "Asia/Tokyo", now))
+	assert.Empty(t, validateSchedule(&future, duration(60), "America/New_York", now))
+	assert.NotEmpty(t, validateSchedule(&past, nil, "Asia/Tokyo", now))
+	assert.NotEmpty(t, validateSchedule(&future, duration(0), "Asia/Tokyo", now))
+	assert.NotEmpty(t, validateSchedule(&future, duration(maxDurationMinutes+1), "Asia/Tokyo", now))
+	assert.NotEmpty(t, validateSchedule(&future, nil, "JST", now))
+	assert.NotEmpty(t, validateSchedule(&future, nil, "", now))
+}
+
+func TestApplyRoomTimeZone(t *testing.T) {
+	start := time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC)
+	room := models.Room{ScheduledStartAt: &start, TimeZone: "Asia/Tokyo"}
+
+	applyRoomTimeZone(&room)
+
+	assert.True(t, room.ScheduledStartAt.Equal(start))
+	assert.Equal(t, "2024-03-01T10:00:00+09:00", room.ScheduledStartAt.Format(time.RFC3339))
+}
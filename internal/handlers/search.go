@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// 検索のキーワードと件数の上限、抜粋の長さ
+const (
+	maxSearchTerms     = 5
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	snippetRadius      = 40
+)
+
+var searchKinds = map[string]bool{
+	models.SearchKindTitle:       true,
+	models.SearchKindDescription: true,
+	models.SearchKindConclusion:  true,
+	models.SearchKindMessage:     true,
+	models.SearchKindSummary:     true,
+}
+
+type SearchHandler struct {
+	db *sql.DB
+}
+
+func NewSearchHandler(db *sql.DB) *SearchHandler {
+	return &SearchHandler{db: db}
+}
+
+// roomScope は呼び出し元が検索・書き出しできる会議室の範囲です。
+type roomScope struct {
+	allRooms bool   // 結果の閲覧権限を持つサービスアカウント
+	roomID   string // ゲストはトークンの会議室のみ
+	userID   string // ユーザーはホストか参加したことのある会議室のみ
+}
+
+// resolveRoomScope は呼び出し元から検索・書き出しできる会議室の範囲を決めます。
+// 拒否した場合はレスポンスを書き込んで false を返します。
+func resolveRoomScope(c *gin.Context, db *sql.DB) (roomScope, bool) {
+	if principal, ok := auth.PrincipalFrom(c); ok {
+		switch principal.Kind {
+		case auth.KindGuest:
+			return roomScope{roomID: principal.RoomID}, true
+		case auth.KindServiceAccount:
+			return roomScope{allRooms: true}, true
+		}
+	}
+	userID, ok := actingUser(c, db, "", c.Query("user_id"))
+	if !ok {
+		return roomScope{}, false
+	}
+	return roomScope{userID: userID}, true
+}
+
+// condition は rooms r を範囲内の会議室に絞り込む条件です。arg は値をプレースホルダーに置き換えます。
+func (s roomScope) condition(arg func(interface{}) string) string {
+	switch {
+	case s.allRooms:
+		return "TRUE"
+	case s.roomID != "":
+		return "r.id = " + arg(s.roomID)
+	default:
+		user := arg(s.userID)
+		return "(r.created_by = " + user + " OR EXISTS (SELECT 1 FROM participants p WHERE p.room_id = r.id AND p.user_id = " + user + "))"
+	}
+}
+
+// includes は会議室が範囲内にあるかどうかを返します。
+func (s roomScope) includes(ctx context.Context, db *sql.DB, roomID string) (bool, error) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	query := "SELECT r.id FROM rooms r WHERE r.id = " + arg(roomID) + " AND " + s.condition(arg)
+	var found string
+	err := db.QueryRowContext(ctx, query, args...).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// buildSearchQuery は会議室のタイトル・説明・結論とチャットログを横断する検索クエリを組み立てます。
+// キーワードはすべてを含むものに一致します（部分一致。pg_trgm のインデックスで速くなります）。
+func buildSearchQuery(scope roomScope, terms, kinds []string, limit, offset int) (string, []interface{}) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	patterns := make([]string, len(terms))
+	for i, term := range terms {
+		patterns[i] = arg("%" + escapeLike(term) + "%")
+	}
+	match := func(column string) string {
+		conds := make([]string, len(patterns))
+		for i, p := range patterns {
+			conds[i] = column + " ILIKE " + p
+		}
+		return strings.Join(conds, " AND ")
+	}
+
+	query := `
+		WITH accessible AS (SELECT r.id, r.title, r.description, r.conclusion, r.created_at FROM rooms r WHERE ` + scope.condition(arg) + `)
+		SELECT kind, room_id, room_title, message_id, body, created_at FROM (
+			SELECT 'title' AS kind, a.id AS room_id, a.title AS room_title, NULL AS message_id, a.title AS body, a.created_at
+			FROM accessible a WHERE ` + match("a.title") + `
+			UNION ALL
+			SELECT 'description', a.id, a.title, NULL, a.description, a.created_at
+			FROM accessible a WHERE ` + match("a.description") + `
+			UNION ALL
+			SELECT 'conclusion', a.id, a.title, NULL, a.conclusion, a.created_at
+			FROM accessible a WHERE ` + match("a.conclusion") + `
+			UNION ALL
+			SELECT CASE WHEN l.is_summary THEN 'summary' ELSE 'message' END, a.id, a.title, l.id, l.message, l.created_at
+			FROM chat_logs l JOIN accessible a ON a.id = l.room_id WHERE ` + match("l.message") + `
+		) hits`
+	if len(kinds) > 0 {
+		placeholders := make([]string, len(kinds))
+		for i, kind := range kinds {
+			placeholders[i] = arg(kind)
+		}
+		query += "\n\t\tWHERE kind IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	// 次のページがあるかを知るために一件多く取得する
+	query += "\n\t\tORDER BY created_at DESC, room_id, message_id\n\t\tLIMIT " + arg(limit+1) + " OFFSET " + arg(offset)
+	return query, args
+}
+
+// highlightSnippet は最初に一致した箇所の前後を抜き出し、HTMLエスケープして一致した部分を <mark> で囲みます。
+// 大文字と小文字は区別しません。
+func highlightSnippet(text string, terms []string, radius int) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// 一致した位置に印を付ける
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		t := []rune(strings.ToLower(term))
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != string(t) {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		first = 0
+	}
+
+	start := first - radius
+	if start < 0 {
+		start = 0
+	}
+	end := first + radius
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			segment = "<mark>" + segment + "</mark>"
+		}
+		b.WriteString(segment)
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// Search godoc
+// @Summary      会議を検索
+// @Description  会議室のタイトル・説明・結論、チャットメッセージ、AIの要約を横断して検索し、一致した箇所の抜粋を新しい順に返します。ユーザーはホストか参加したことのある会議室、ゲストはトークンの会議室、サービスアカウントはすべての会議室を検索できます
+// @Tags         search
+// @Produce      json
+// @Param        q        query     string  true   "キーワード（空白区切りですべてを含むものに一致）"
+// @Param        user_id  query     string  false  "検索するユーザーのID（認証情報がない場合は必須）"
+// @Param        type     query     string  false  "一致した箇所の種類（title / description / conclusion / message / summary。カンマ区切りで複数指定可）"
+// @Param        limit    query     int     false  "件数（1〜50、既定 20）"
+// @Param        offset   query     int     false  "読み飛ばす件数"
+// @Success      200      {object}  models.SearchResponse
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /search [get]
+func (h *SearchHandler) Search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	terms := strings.Fields(q)
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "qは必須です"})
+		return
+	}
+	if len(terms) > maxSearchTerms || utf8.RuneCountInString(q) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "キーワードは5個・100文字までです"})
+		return
+	}
+	kinds := splitQueryList(c.QueryArray("type"))
+	for _, kind := range kinds {
+		if !searchKinds[kind] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "typeが不正です: " + kind})
+			return
+		}
+	}
+	limit, offset := defaultSearchLimit, 0
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limitは1〜50で指定してください"})
+			return
+		}
+		limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offsetは0以上で指定してください"})
+			return
+		}
+		offset = n
+	}
+	scope, ok := resolveRoomScope(c, h.db)
+	if !ok {
+		return
+	}
+
+	query, args := buildSearchQuery(scope, terms, kinds, limit, offset)
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer rows.Close()
+
+	response := models.SearchResponse{Query: q, Results: []models.SearchResult{}}
+	for rows.Next() {
+		var result models.SearchResult
+		var messageID sql.NullString
+		var body string
+		if err := rows.Scan(&result.Kind, &result.RoomID, &result.RoomTitle, &messageID, &body, &result.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		if len(response.Results) == limit {
+			next := offset + limit
+			response.NextOffset = &next
+			break
+		}
+		if messageID.Valid {
+			result.MessageID = &messageID.String
+		}
+		result.Snippet = highlightSnippet(body, terms, snippetRadius)
+		response.Results = append(response.Results, result)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch_GuestOnlySearchesOwnRoom(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	tokens := auth.NewGuestTokens([]byte("secret"), time.Hour)
+	token, _, err := tokens.Issue("g0000001", "r001")
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`WITH accessible AS \(SELECT .* FROM rooms r WHERE r.id = \$3\)`).
+		WithArgs("%リリース%", "%延期%", "r001", "message", "summary", 2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "room_id", "room_title", "message_id", "body", "created_at"}).
+			AddRow("summary", "r001", "週次", "log2", "議論の結果、リリースを延期することにした", now).
+			AddRow("message", "r001", "週次", "log1", "リリースは延期しましょう", now.Add(-time.Minute)))
+
+	c, w := newJSONContext(http.MethodGet, "/search?q=リリース　延期&type=message,summary&limit=1", "")
+	c.Request.Header.Set("Authorization", "Bearer "+token)
+	auth.Middleware(tokens, nil, nil, nil)(c)
+	NewSearchHandler(db).Search(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response models.SearchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Results, 1)
+	assert.Equal(t, "summary", response.Results[0].Kind)
+	assert.Equal(t, "議論の結果、<mark>リリース</mark>を<mark>延期</mark>することにした", response.Results[0].Snippet)
+	require.NotNil(t, response.NextOffset)
+	assert.Equal(t, 1, *response.NextOffset)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearch_RequiresQueryAndUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, target := range []string{"/search", "/search?q=リリース", "/search?q=リリース&user_id=u001&type=poll"} {
+		c, w := newJSONContext(http.MethodGet, target, "")
+		NewSearchHandler(db).Search(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	text := "前置きがとても長い文章です。" + "あいうえおかきくけこさしすせそ" + "Go の<テスト>を書く"
+
+	snippet := highlightSnippet(text, []string{"go", "テスト"}, 10)
+
+	assert.Equal(t, "…かきくけこさしすせそ<mark>Go</mark> の&lt;<mark>テスト</mark>&gt;を…", snippet)
+	assert.Equal(t, "短い", highlightSnippet("短い", []string{"なし"}, 10))
+}
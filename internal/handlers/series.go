@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/shuto.sawaki/elmo-project/internal/series"
+)
+
+type SeriesHandler struct {
+	db *sql.DB
+}
+
+func NewSeriesHandler(db *sql.DB) *SeriesHandler {
+	return &SeriesHandler{db: db}
+}
+
+// CreateSeries godoc
+// @Summary      定例会議のシリーズを作成
+// @Description  繰り返しのルールに従って各回の会議室を開始の1日前に自動で作成します。各回は前回の会議室とつながり、前回の結論と未完了のアクションを最初の問いかけに引き継ぎます
+// @Tags         series
+// @Accept       json
+// @Produce      json
+// @Param        series  body      models.SeriesRequest  true  "シリーズ"
+// @Success      201     {object}  models.Series
+// @Failure      400     {object}  map[string]interface{}
+// @Failure      403     {object}  map[string]interface{}
+// @Failure      500     {object}  map[string]interface{}
+// @Router       /series [post]
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	var req models.SeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	if req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "タイトルは必須です"})
+		return
+	}
+	if req.StartAt.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_atは必須です"})
+		return
+	}
+	if req.MaxParticipants != nil && *req.MaxParticipants <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_participantsは1以上を指定してください"})
+		return
+	}
+	if req.TimeZone == "" {
+		req.TimeZone = defaultRoomTimeZone
+	}
+	if msg := validateSchedule(nil, req.DurationMinutes, req.TimeZone, time.Now()); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	rule, err := series.ParseRule(req.RRule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rruleが不正です: " + err.Error()})
+		return
+	}
+	loc, _ := time.LoadLocation(req.TimeZone)
+	next, ok := rule.Next(req.StartAt, time.Now(), loc)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "これから開催される回がありません"})
+		return
+	}
+
+	// APIキーで作成した場合はサービスアカウントをホストにする
+	if principal, ok := auth.PrincipalFrom(c); ok {
+		if principal.Kind != auth.KindServiceAccount {
+			c.JSON(http.StatusForbidden, gin.H{"error": "この認証情報はこの操作に使用できません"})
+			return
+		}
+		req.CreatedBy = &principal.UserID
+	}
+
+	seriesID, err := gonanoid.New()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	_, err = h.db.ExecContext(c.Request.Context(), `
+		INSERT INTO room_series (id, title, description, rrule, start_at, duration_minutes, time_zone, max_participants, allow_guests, anonymous, created_by, next_start_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		seriesID, req.Title, req.Description, req.RRule, req.StartAt, req.DurationMinutes, req.TimeZone, req.MaxParticipants, req.AllowGuests, req.Anonymous, req.CreatedBy, next)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "created_byのユーザーが見つかりません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "シリーズの作成に失敗しました"})
+		return
+	}
+	h.respondSeries(c, http.StatusCreated, seriesID)
+}
+
+// GetSeries godoc
+// @Summary      定例会議のシリーズを取得
+// @Description  シリーズの設定と、作成済みの各回の会議室を開始日時順に返します
+// @Tags         series
+// @Produce      json
+// @Param        id   path      string  true  "シリーズID"
+// @Success      200  {object}  models.Series
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /series/{id} [get]
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	h.respondSeries(c, http.StatusOK, c.Param("id"))
+}
+
+// StopSeries godoc
+// @Summary      定例会議のシリーズを停止
+// @Description  以降の会議室の自動作成を止めます。作成済みの会議室はそのまま残ります。ホストが記録されているシリーズはホストのみ停止できます
+// @Tags         series
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                    true  "シリーズID"
+// @Param        request  body      models.StopSeriesRequest  true  "停止するユーザー"
+// @Success      200      {object}  models.Series
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /series/{id}/stop [post]
+func (h *SeriesHandler) StopSeries(c *gin.Context) {
+	seriesID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req models.StopSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	// シリーズはどの会議室にも属さないため、ゲストトークンでは操作できない
+	userID, ok := actingUser(c, h.db, "", req.UserID)
+	if !ok {
+		return
+	}
+
+	var createdBy sql.NullString
+	err := h.db.QueryRowContext(ctx, `SELECT created_by FROM room_series WHERE id = $1`, seriesID).Scan(&createdBy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定されたシリーズは見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	if createdBy.Valid && createdBy.String != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "この操作はシリーズのホストのみ実行できます"})
+		return
+	}
+
+	if _, err := h.db.ExecContext(ctx, `UPDATE room_series SET active = FALSE WHERE id = $1`, seriesID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "シリーズの停止に失敗しました"})
+		return
+	}
+	h.respondSeries(c, http.StatusOK, seriesID)
+}
+
+func (h *SeriesHandler) respondSeries(c *gin.Context, status int, seriesID string) {
+	s, err := loadSeries(c.Request.Context(), h.db, seriesID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定されたシリーズは見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+	c.JSON(status, s)
+}
+
+// loadSeries はシリーズと作成済みの会議室を読み込みます。日時はシリーズのタイムゾーンで返します。
+func loadSeries(ctx context.Context, db *sql.DB, seriesID string) (models.Series, error) {
+	var s models.Series
+	var durationMinutes, maxParticipants sql.NullInt64
+	var createdBy sql.NullString
+	var nextStartAt sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT id, title, description, rrule, start_at, duration_minutes, time_zone, max_participants, allow_guests, anonymous, created_by, next_start_at, active
+		FROM room_series WHERE id = $1`, seriesID).
+		Scan(&s.ID, &s.Title, &s.Description, &s.RRule, &s.StartAt, &durationMinutes, &s.TimeZone, &maxParticipants, &s.AllowGuests, &s.Anonymous, &createdBy, &nextStartAt, &s.Active)
+	if err != nil {
+		return s, err
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	s.StartAt = s.StartAt.In(loc)
+	if durationMinutes.Valid {
+		minutes := int(durationMinutes.Int64)
+		s.DurationMinutes = &minutes
+	}
+	if maxParticipants.Valid {
+		max := int(maxParticipants.Int64)
+		s.MaxParticipants = &max
+	}
+	if createdBy.Valid {
+		s.CreatedBy = &createdBy.String
+	}
+	if nextStartAt.Valid && s.Active {
+		next := nextStartAt.Time.In(loc)
+		s.NextStartAt = &next
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, title, status, scheduled_start_at, previous_room_id FROM rooms
+		WHERE series_id = $1 ORDER BY scheduled_start_at`, seriesID)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var room models.SeriesRoom
+		var startAt sql.NullTime
+		var previousRoomID sql.NullString
+		if err := rows.Scan(&room.ID, &room.Title, &room.Status, &startAt, &previousRoomID); err != nil {
+			return s, err
+		}
+		if startAt.Valid {
+			t := startAt.Time.In(loc)
+			room.ScheduledStartAt = &t
+		}
+		if previousRoomID.Valid {
+			room.PreviousRoomID = &previousRoomID.String
+		}
+		s.Rooms = append(s.Rooms, room)
+	}
+	return s, rows.Err()
+}
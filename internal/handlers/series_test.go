@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSeries_SchedulesFirstUpcomingOccurrence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	start := time.Now().In(loc).AddDate(0, 0, -7).Truncate(time.Hour)
+	next := start.AddDate(0, 0, 7)
+	if !next.After(time.Now()) {
+		next = next.AddDate(0, 0, 7)
+	}
+
+	mock.ExpectExec(`INSERT INTO room_series`).
+		WithArgs(sqlmock.AnyArg(), "週次定例", "", "FREQ=WEEKLY", sqlmock.AnyArg(), nil, "Asia/Tokyo", nil, false, false, nil, next).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`FROM room_series WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "rrule", "start_at", "duration_minutes", "time_zone", "max_participants", "allow_guests", "anonymous", "created_by", "next_start_at", "active"}).
+			AddRow("s1", "週次定例", "", "FREQ=WEEKLY", start, nil, "Asia/Tokyo", nil, false, false, nil, next, true))
+	mock.ExpectQuery(`FROM rooms\s+WHERE series_id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "status", "scheduled_start_at", "previous_room_id"}))
+
+	body := `{"title":"週次定例","rrule":"FREQ=WEEKLY","start_at":"` + start.Format(time.RFC3339) + `"}`
+	c, w := newJSONContext(http.MethodPost, "/series", body)
+	NewSeriesHandler(db).CreateSeries(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var response models.Series
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Active)
+	require.NotNil(t, response.NextStartAt)
+	assert.True(t, response.NextStartAt.Equal(next))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateSeries_RejectsInvalidRule(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	c, w := newJSONContext(http.MethodPost, "/series", `{"title":"週次定例","rrule":"FREQ=YEARLY","start_at":"2030-01-01T10:00:00+09:00"}`)
+	NewSeriesHandler(db).CreateSeries(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+type ServiceAccountHandler struct {
+	db *sql.DB
+}
+
+func NewServiceAccountHandler(db *sql.DB) *ServiceAccountHandler {
+	return &ServiceAccountHandler{db: db}
+}
+
+// CreateServiceAccount godoc
+// @Summary      サービスアカウントを作成
+// @Description  ボットなどの連携用に、ログインせずAPIキーで操作するユーザーを作成します。管理者トークン（ADMIN_API_TOKEN）か service-accounts:manage スコープのAPIキーが必要です
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        account  body      models.ServiceAccountRequest  true  "サービスアカウント情報"
+// @Success      201      {object}  models.User
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      401      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /service-accounts [post]
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	if !requireServiceAccountAdmin(c) {
+		return
+	}
+	var req models.ServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "名前は必須です"})
+		return
+	}
+
+	const maxRetries = 10
+	for i := 0; i < maxRetries; i++ {
+		newID, err := gonanoid.Generate("0123456789abcdefghijklmnopqrstuvwxyz", 8)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		result, err := h.db.ExecContext(c.Request.Context(), `
+			INSERT INTO users (id, user_name, is_service_account) VALUES ($1, $2, TRUE)
+			ON CONFLICT (id) DO NOTHING`, newID, req.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			c.JSON(http.StatusCreated, models.User{ID: newID, UserName: req.Name, IsServiceAccount: true})
+			return
+		}
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部で問題が発生しました。"})
+}
+
+// CreateAPIKey godoc
+// @Summary      APIキーを発行
+// @Description  サービスアカウントにスコープ付きのAPIキーを発行します。平文のキーはこのレスポンスでのみ返されます。管理者トークン（ADMIN_API_TOKEN）か service-accounts:manage スコープのAPIキーが必要です
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string                true  "サービスアカウントのユーザーID"
+// @Param        key  body      models.APIKeyRequest  true  "キー情報"
+// @Success      201  {object}  models.APIKeyCreatedResponse
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /service-accounts/{id}/keys [post]
+func (h *ServiceAccountHandler) CreateAPIKey(c *gin.Context) {
+	if !requireServiceAccountAdmin(c) {
+		return
+	}
+	accountID := c.Param("id")
+
+	var req models.APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "名前は必須です"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "スコープを1つ以上指定してください"})
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不明なスコープです: " + scope})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if ok, err := h.isServiceAccount(ctx, accountID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	} else if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定されたサービスアカウントは見つかりません"})
+		return
+	}
+
+	created, err := h.insertKey(ctx, h.db, accountID, req.Name, req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "APIキーの発行に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// ListAPIKeys godoc
+// @Summary      APIキー一覧を取得
+// @Description  サービスアカウントのAPIキーを失効済みのものも含めて取得します。管理者トークン（ADMIN_API_TOKEN）か service-accounts:manage スコープのAPIキーが必要です
+// @Tags         service-accounts
+// @Produce      json
+// @Param        id   path      string  true  "サービスアカウントのユーザーID"
+// @Success      200  {array}   models.APIKey
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /service-accounts/{id}/keys [get]
+func (h *ServiceAccountHandler) ListAPIKeys(c *gin.Context) {
+	if !requireServiceAccountAdmin(c) {
+		return
+	}
+	rows, err := h.db.QueryContext(c.Request.Context(), `
+		SELECT id, user_id, name, scopes, created_at, last_used_at, revoked_at
+		FROM api_keys WHERE user_id = $1
+		ORDER BY created_at DESC`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		var scopes string
+		var lastUsedAt, revokedAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &scopes, &key.CreatedAt, &lastUsedAt, &revokedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		key.Scopes = strings.Fields(scopes)
+		if lastUsedAt.Valid {
+			key.LastUsedAt = &lastUsedAt.Time
+		}
+		if revokedAt.Valid {
+			key.RevokedAt = &revokedAt.Time
+		}
+		keys = append(keys, key)
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RotateAPIKey godoc
+// @Summary      APIキーをローテーション
+// @Description  既存のキーを失効させ、同じ名前とスコープで新しいキーを発行します。管理者トークン（ADMIN_API_TOKEN）か service-accounts:manage スコープのAPIキーが必要です
+// @Tags         service-accounts
+// @Produce      json
+// @Param        id     path      string  true  "サービスアカウントのユーザーID"
+// @Param        keyId  path      string  true  "キーのID"
+// @Success      201    {object}  models.APIKeyCreatedResponse
+// @Failure      401    {object}  map[string]interface{}
+// @Failure      403    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]interface{}
+// @Failure      500    {object}  map[string]interface{}
+// @Router       /service-accounts/{id}/keys/{keyId}/rotate [post]
+func (h *ServiceAccountHandler) RotateAPIKey(c *gin.Context) {
+	if !requireServiceAccountAdmin(c) {
+		return
+	}
+	accountID, keyID := c.Param("id"), c.Param("keyId")
+	ctx := c.Request.Context()
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	var name, scopes string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+		RETURNING name, scopes`, keyID, accountID).Scan(&name, &scopes)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "有効なAPIキーが見つかりません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+
+	created, err := h.insertKey(ctx, tx, accountID, name, strings.Fields(scopes))
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "APIキーの発行に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// RevokeAPIKey godoc
+// @Summary      APIキーを失効
+// @Description  APIキーを失効させます。失効したキーは以降の認証に使用できません。管理者トークン（ADMIN_API_TOKEN）か service-accounts:manage スコープのAPIキーが必要です
+// @Tags         service-accounts
+// @Param        id     path  string  true  "サービスアカウントのユーザーID"
+// @Param        keyId  path  string  true  "キーのID"
+// @Success      204    "No Content"
+// @Failure      401    {object}  map[string]interface{}
+// @Failure      403    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]interface{}
+// @Failure      500    {object}  map[string]interface{}
+// @Router       /service-accounts/{id}/keys/{keyId} [delete]
+func (h *ServiceAccountHandler) RevokeAPIKey(c *gin.Context) {
+	if !requireServiceAccountAdmin(c) {
+		return
+	}
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, c.Param("keyId"), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "有効なAPIキーが見つかりません"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// requireServiceAccountAdmin は呼び出し元が管理者トークンか service-accounts:manage スコープの API キーで
+// 認証されているかどうかを確かめます。拒否した場合はレスポンスを書き込んで false を返します。
+// 誰でもキーを発行できるとスコープで権限を分ける意味がなくなるため、すべての管理操作で確かめます。
+func requireServiceAccountAdmin(c *gin.Context) bool {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "管理者トークンか service-accounts:manage スコープのAPIキーが必要です"})
+		return false
+	}
+	if principal.Kind == auth.KindAdmin ||
+		(principal.Kind == auth.KindServiceAccount && principal.HasScope(auth.ScopeServiceAccountsManage)) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "サービスアカウントを管理する権限がありません"})
+	return false
+}
+
+func (h *ServiceAccountHandler) isServiceAccount(ctx context.Context, userID string) (bool, error) {
+	var ok bool
+	err := h.db.QueryRowContext(ctx, `SELECT is_service_account FROM users WHERE id = $1`, userID).Scan(&ok)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return ok, err
+}
+
+// rowQueryer は *sql.DB と *sql.Tx の共通部分です。
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (h *ServiceAccountHandler) insertKey(ctx context.Context, q rowQueryer, accountID, name string, scopes []string) (models.APIKeyCreatedResponse, error) {
+	keyID, plaintext, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return models.APIKeyCreatedResponse{}, err
+	}
+	var createdAt time.Time
+	err = q.QueryRowContext(ctx, `
+		INSERT INTO api_keys (id, user_id, name, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`, keyID, accountID, name, hash, strings.Join(scopes, " ")).Scan(&createdAt)
+	if err != nil {
+		return models.APIKeyCreatedResponse{}, err
+	}
+	return models.APIKeyCreatedResponse{
+		APIKey: models.APIKey{
+			ID:        keyID,
+			UserID:    accountID,
+			Name:      name,
+			Scopes:    scopes,
+			CreatedAt: createdAt,
+		},
+		Key: plaintext,
+	}, nil
+}
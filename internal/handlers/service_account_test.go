@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminToken = "admin-token-0123456789abcdefghijklmnop"
+
+// newServiceAccountRouter は認証のミドルウェアを通してサービスアカウントの管理ルートを呼び出すルーターを返します。
+func newServiceAccountRouter(db *sql.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	policy := auth.ScopePolicy{
+		"POST /service-accounts":                        auth.ScopeServiceAccountsManage,
+		"GET /service-accounts/:id/keys":                auth.ScopeServiceAccountsManage,
+		"POST /service-accounts/:id/keys":               auth.ScopeServiceAccountsManage,
+		"POST /service-accounts/:id/keys/:keyId/rotate": auth.ScopeServiceAccountsManage,
+		"DELETE /service-accounts/:id/keys/:keyId":      auth.ScopeServiceAccountsManage,
+		"GET /search": auth.ScopeResultsRead,
+	}
+	router.Use(auth.Middleware(nil, auth.NewAPIKeys(db), auth.NewAdminToken(testAdminToken), policy))
+	h := NewServiceAccountHandler(db)
+	router.POST("/service-accounts", h.CreateServiceAccount)
+	router.GET("/service-accounts/:id/keys", h.ListAPIKeys)
+	router.POST("/service-accounts/:id/keys", h.CreateAPIKey)
+	router.POST("/service-accounts/:id/keys/:keyId/rotate", h.RotateAPIKey)
+	router.DELETE("/service-accounts/:id/keys/:keyId", h.RevokeAPIKey)
+	router.GET("/search", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func serveServiceAccount(router *gin.Engine, method, target, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// expectAPIKey は API キーでの認証のクエリを登録し、平文のキーを返します。
+func expectAPIKey(t *testing.T, mock sqlmock.Sqlmock, scopes string) string {
+	keyID, plaintext, hash, err := auth.NewAPIKey()
+	require.NoError(t, err)
+	mock.ExpectQuery(`FROM api_keys k`).WithArgs(keyID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "key_hash", "scopes", "revoked"}).AddRow("bot00001", hash, scopes, false))
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at`).WithArgs(keyID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	return plaintext
+}
+
+func TestServiceAccountRoutes_RequireCredential(t *testing.T) {
+	tests := []struct{ method, target, body string }{
+		{http.MethodPost, "/service-accounts", `{"name":"bot"}`},
+		{http.MethodGet, "/service-accounts/bot00001/keys", ""},
+		{http.MethodPost, "/service-accounts/bot00001/keys", `{"name":"ci","scopes":["rooms:write"]}`},
+		{http.MethodPost, "/service-accounts/bot00001/keys/k001/rotate", ""},
+		{http.MethodDelete, "/service-accounts/bot00001/keys/k001", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			w := serveServiceAccount(newServiceAccountRouter(db), tt.method, tt.target, "", tt.body)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code, w.Body.String())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCreateServiceAccount_WithAdminToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO users \(id, user_name, is_service_account\)`).WithArgs(sqlmock.AnyArg(), "bot").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	w := serveServiceAccount(newServiceAccountRouter(db), http.MethodPost, "/service-accounts", testAdminToken, `{"name":" bot "}`)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var user models.User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &user))
+	assert.Equal(t, "bot", user.UserName)
+	assert.True(t, user.IsServiceAccount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminToken_OnlyForServiceAccountRoutes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	w := serveServiceAccount(newServiceAccountRouter(db), http.MethodGet, "/search", testAdminToken, "")
+
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAPIKey_WithManageScope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	key := expectAPIKey(t, mock, "service-accounts:manage")
+	mock.ExpectQuery(`SELECT is_service_account FROM users WHERE id = \$1`).WithArgs("bot00002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_service_account"}).AddRow(true))
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs(sqlmock.AnyArg(), "bot00002", "ci", sqlmock.AnyArg(), "rooms:read results:read").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)))
+
+	w := serveServiceAccount(newServiceAccountRouter(db), http.MethodPost, "/service-accounts/bot00002/keys", key,
+		`{"name":"ci","scopes":["rooms:read","results:read"]}`)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created models.APIKeyCreatedResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, auth.IsAPIKey(created.Key))
+	assert.Equal(t, []string{"rooms:read", "results:read"}, created.Scopes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAPIKey_WithoutManageScopeIsForbidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	key := expectAPIKey(t, mock, "rooms:write results:read webhooks:manage")
+
+	w := serveServiceAccount(newServiceAccountRouter(db), http.MethodPost, "/service-accounts/bot00001/keys", key,
+		`{"name":"escalate","scopes":["service-accounts:manage"]}`)
+
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAPIKey_UnknownScope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	w := serveServiceAccount(newServiceAccountRouter(db), http.MethodPost, "/service-accounts/bot00001/keys", testAdminToken,
+		`{"name":"ci","scopes":["admin"]}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListAPIKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM api_keys WHERE user_id = \$1`).WithArgs("bot00001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "scopes", "created_at", "last_used_at", "revoked_at"}).
+			AddRow("k002", "bot00001", "ci", "rooms:read results:read", at, at, nil).
+			AddRow("k001", "bot00001", "ci", "rooms:read", at, nil, at))
+
+	w := serveServiceAccount(newServiceAccountRouter(db), http.MethodGet, "/service-accounts/bot00001/keys", testAdminToken, "")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var keys []models.APIKey
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+	require.Len(t, keys, 2)
+	assert.Equal(t, []string{"rooms:read", "results:read"}, keys[0].Scopes)
+	assert.Nil(t, keys[0].RevokedAt)
+	assert.NotNil(t, keys[1].RevokedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE api_keys SET revoked_at = NOW\(\)`).WithArgs("k001", "bot00001").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "scopes"}).AddRow("ci", "rooms:read"))
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs(sqlmock.AnyArg(), "bot00001", "ci", sqlmock.AnyArg(), "rooms:read").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)))
+	mock.ExpectCommit()
+
+	w := serveServiceAccount(newServiceAccountRouter(db), http.MethodPost, "/service-accounts/bot00001/keys/k001/rotate", testAdminToken, "")
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at = NOW\(\)`).WithArgs("k009", "bot00001").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	w := serveServiceAccount(newServiceAccountRouter(db), http.MethodDelete, "/service-accounts/bot00001/keys/k009", testAdminToken, "")
+
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
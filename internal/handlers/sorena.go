@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// 「それな」の各エンドポイントは汎用のリアクションAPIで reaction_type = "sorena" を扱うのと同じです。
+// 既存クライアントとの互換性のために残しています。
+
+// PUT /rooms/:id/messages/:messageId/sorena
+func (h *RoomHandler) AddMessageSorena(c *gin.Context) {
+	h.toggleMessageSorena(c, true)
+}
+
+// DELETE /rooms/:id/messages/:messageId/sorena
+func (h *RoomHandler) RemoveMessageSorena(c *gin.Context) {
+	h.toggleMessageSorena(c, false)
+}
+
+func (h *RoomHandler) toggleMessageSorena(c *gin.Context, on bool) {
+	messageID := c.Param("messageId")
+	count, ok := h.toggleReaction(c, models.ReactionSorena, on)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, models.SorenaToggleResponse{MessageID: messageID, Sorena: on, Count: count})
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin" // ★ Ginをインポート
 	"github.com/joho/godotenv"
 	"github.com/shuto.sawaki/elmo-project/internal/ai"
+	"github.com/shuto.sawaki/elmo-project/internal/events"
 	"github.com/shuto.sawaki/elmo-project/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,11 +31,11 @@ func TestStartRoomHandler_Integration(t *testing.T) {
 
 	// --- SQLモックの設定 (ここまでは同じ) ---
 	roomID := "r001"
-	rows := sqlmock.NewRows([]string{"id", "title", "description", "status"}).
-		AddRow(roomID, "Go言語のテスト", "テストコードの書き方について議論する部屋", "not started")
-	mock.ExpectQuery(`SELECT id, title, description, status FROM rooms WHERE id = \$1`).WithArgs(roomID).WillReturnRows(rows)
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "status", "initial_question", "previous_room_id", "prompt_variant"}).
+		AddRow(roomID, "Go言語のテスト", "テストコードの書き方について議論する部屋", "not started", nil, nil, "default")
+	mock.ExpectQuery(`SELECT id, title, description, status, initial_question, previous_room_id, prompt_variant FROM rooms WHERE id = \$1`).WithArgs(roomID).WillReturnRows(rows)
 
-	mock.ExpectExec(`UPDATE rooms SET status = \$1, initial_question = \$2 WHERE id = \$3`).
+	mock.ExpectExec(`UPDATE rooms SET status = \$1, initial_question = \$2, started_at = NOW\(\) WHERE id = \$3 AND status = 'not started'`).
 		WithArgs("inprogress", sqlmock.AnyArg(), roomID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -42,6 +43,9 @@ func TestStartRoomHandler_Integration(t *testing.T) {
 	mock.ExpectQuery(`SELECT u.id, u.user_name FROM participants p JOIN users u ON p.user_id = u.id WHERE p.room_id = \$1`).
 		WithArgs(roomID).
 		WillReturnRows(participantRows)
+	mock.ExpectQuery(`FROM action_items a`).
+		WithArgs(roomID).
+		WillReturnRows(sqlmock.NewRows(nil))
 
 	// ★★★ ここからGinのテスト形式に変更 ★★★
 	// 1. レスポンスを記録するためのRecorderを作成
@@ -78,4 +82,69 @@ func TestStartRoomHandler_Integration(t *testing.T) {
 	assert.Len(t, response.Participants, 0)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
-}
\ No newline at end of file
+}
+
+func TestStartRoom_StartedByScheduler(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT id, title, description, status, initial_question, previous_room_id, prompt_variant FROM rooms WHERE id = \$1`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "initial_question", "previous_room_id", "prompt_variant"}).
+			AddRow("r001", "週次定例", "", "not started", nil, nil, "default"))
+	// 問いかけの生成中にスケジューラーが開始したため、更新する行がない
+	mock.ExpectExec(`UPDATE rooms SET status = \$1, initial_question = \$2, started_at = NOW\(\) WHERE id = \$3 AND status = 'not started'`).
+		WithArgs("inprogress", "今日の議題は？", "r001").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/rooms/r001/start", nil)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	NewRoomHandler(db, &stubAIGenerator{question: "今日の議題は？"}).StartRoom(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStartRoom_PublishesToHub(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// スケジューラーが事前に用意した問いかけを使う
+	mock.ExpectQuery(`SELECT id, title, description, status, initial_question, previous_room_id, prompt_variant FROM rooms WHERE id = \$1`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "initial_question", "previous_room_id", "prompt_variant"}).
+			AddRow("r001", "週次定例", "", "not started", "今日の議題は？", nil, "default"))
+	mock.ExpectExec(`UPDATE rooms SET status = \$1, initial_question = \$2, started_at = NOW\(\) WHERE id = \$3 AND status = 'not started'`).
+		WithArgs("inprogress", "今日の議題は？", "r001").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT u.id, u.user_name FROM participants p JOIN users u ON p.user_id = u.id WHERE p.room_id = \$1`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_name"}))
+	mock.ExpectQuery(`FROM action_items a`).
+		WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows(nil))
+
+	hub := events.NewHub()
+	sub, unsubscribe := hub.Subscribe("r001")
+	defer unsubscribe()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/rooms/r001/start", nil)
+	c.Params = gin.Params{{Key: "id", Value: "r001"}}
+	h := NewRoomHandler(db, nil)
+	h.SetHub(hub)
+	h.StartRoom(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	select {
+	case e := <-sub:
+		assert.Equal(t, events.TypeRoomStarted, e.Type)
+		assert.Equal(t, map[string]string{"room_id": "r001", "initial_question": "今日の議題は？"}, e.Data)
+	default:
+		t.Fatal("room.started が配信されていません")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,496 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/ai"
+	"github.com/shuto.sawaki/elmo-project/internal/auth"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+// テンプレートのタイトルのパターンで開始予定日に置き換える文字列
+const titleDatePlaceholder = "{date}"
+
+var (
+	errBuiltInTemplate    = errors.New("built-in template")
+	errNotTemplateCreator = errors.New("not template creator")
+)
+
+type TemplateHandler struct {
+	db *sql.DB
+}
+
+func NewTemplateHandler(db *sql.DB) *TemplateHandler {
+	return &TemplateHandler{db: db}
+}
+
+// loadTemplates はテンプレートを議題・リアクションのセットとともに返します。
+// templateID を指定した場合はそのテンプレートのみを返します。
+func loadTemplates(ctx context.Context, db *sql.DB, templateID string) ([]models.RoomTemplate, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, name, title_pattern, description, prompt_variant, duration_minutes, max_participants, allow_guests, anonymous, built_in, created_by
+		FROM room_templates
+		WHERE $1 = '' OR id = $1
+		ORDER BY built_in DESC, created_at, id`, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.RoomTemplate{}
+	index := make(map[string]int)
+	for rows.Next() {
+		t := models.RoomTemplate{Agenda: []models.TemplateAgendaItem{}, ReactionTypes: []string{}}
+		var durationMinutes, maxParticipants sql.NullInt64
+		var createdBy sql.NullString
+		if err := rows.Scan(&t.ID, &t.Name, &t.TitlePattern, &t.Description, &t.PromptVariant, &durationMinutes, &maxParticipants, &t.AllowGuests, &t.Anonymous, &t.BuiltIn, &createdBy); err != nil {
+			return nil, err
+		}
+		if createdBy.Valid {
+			t.CreatedBy = &createdBy.String
+		}
+		if durationMinutes.Valid {
+			minutes := int(durationMinutes.Int64)
+			t.DurationMinutes = &minutes
+		}
+		if maxParticipants.Valid {
+			max := int(maxParticipants.Int64)
+			t.MaxParticipants = &max
+		}
+		index[t.ID] = len(templates)
+		templates = append(templates, t)
+	}
+	if err := rows.Err(); err != nil || len(templates) == 0 {
+		return templates, err
+	}
+
+	agendaRows, err := db.QueryContext(ctx, `
+		SELECT template_id, title, description, timebox_minutes FROM room_template_agenda_items
+		WHERE $1 = '' OR template_id = $1
+		ORDER BY template_id, position`, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer agendaRows.Close()
+	for agendaRows.Next() {
+		var id string
+		var item models.TemplateAgendaItem
+		var timebox sql.NullInt64
+		if err := agendaRows.Scan(&id, &item.Title, &item.Description, &timebox); err != nil {
+			return nil, err
+		}
+		if timebox.Valid {
+			minutes := int(timebox.Int64)
+			item.TimeboxMinutes = &minutes
+		}
+		if i, ok := index[id]; ok {
+			templates[i].Agenda = append(templates[i].Agenda, item)
+		}
+	}
+	if err := agendaRows.Err(); err != nil {
+		return nil, err
+	}
+
+	reactionRows, err := db.QueryContext(ctx, `
+		SELECT template_id, reaction_key FROM room_template_reaction_types
+		WHERE $1 = '' OR template_id = $1
+		ORDER BY template_id, sort_order`, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer reactionRows.Close()
+	for reactionRows.Next() {
+		var id, key string
+		if err := reactionRows.Scan(&id, &key); err != nil {
+			return nil, err
+		}
+		if i, ok := index[id]; ok {
+			templates[i].ReactionTypes = append(templates[i].ReactionTypes, key)
+		}
+	}
+	return templates, reactionRows.Err()
+}
+
+// loadTemplate はテンプレートを一件読み込みます。見つからない場合は sql.ErrNoRows を返します。
+func loadTemplate(ctx context.Context, db *sql.DB, templateID string) (models.RoomTemplate, error) {
+	templates, err := loadTemplates(ctx, db, templateID)
+	if err != nil {
+		return models.RoomTemplate{}, err
+	}
+	if len(templates) == 0 {
+		return models.RoomTemplate{}, sql.ErrNoRows
+	}
+	return templates[0], nil
+}
+
+// validateTemplate はテンプレートを検証して正規化し、不正な場合はエラーメッセージを返します。
+func validateTemplate(t *models.RoomTemplate) string {
+	t.Name = strings.TrimSpace(t.Name)
+	t.TitlePattern = strings.TrimSpace(t.TitlePattern)
+	if t.Name == "" || utf8.RuneCountInString(t.Name) > 100 {
+		return "nameは1〜100文字で指定してください"
+	}
+	if t.TitlePattern == "" {
+		t.TitlePattern = t.Name
+	}
+	if utf8.RuneCountInString(t.TitlePattern) > 255 {
+		return "title_patternは255文字以内で指定してください"
+	}
+	if t.PromptVariant == "" {
+		t.PromptVariant = ai.PromptDefault
+	}
+	if !ai.IsPromptVariant(t.PromptVariant) {
+		return "prompt_variantが不正です"
+	}
+	if msg := validateSchedule(nil, t.DurationMinutes, defaultRoomTimeZone, time.Now()); msg != "" {
+		return msg
+	}
+	if t.MaxParticipants != nil && *t.MaxParticipants <= 0 {
+		return "max_participantsは1以上を指定してください"
+	}
+	for i := range t.Agenda {
+		t.Agenda[i].Title = strings.TrimSpace(t.Agenda[i].Title)
+		if t.Agenda[i].Title == "" {
+			return "議題のタイトルは必須です"
+		}
+		if t.Agenda[i].TimeboxMinutes != nil && *t.Agenda[i].TimeboxMinutes <= 0 {
+			return "timebox_minutesは1以上を指定してください"
+		}
+	}
+	seen := make(map[string]bool, len(t.ReactionTypes))
+	for _, key := range t.ReactionTypes {
+		if seen[key] {
+			return "reaction_typesに重複があります"
+		}
+		seen[key] = true
+	}
+	if t.Agenda == nil {
+		t.Agenda = []models.TemplateAgendaItem{}
+	}
+	if t.ReactionTypes == nil {
+		t.ReactionTypes = []string{}
+	}
+	return ""
+}
+
+// writeTemplateContents はテンプレートの議題とリアクションのセットを置き換えます。
+func writeTemplateContents(ctx context.Context, tx *sql.Tx, t models.RoomTemplate) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM room_template_agenda_items WHERE template_id = $1`, t.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM room_template_reaction_types WHERE template_id = $1`, t.ID); err != nil {
+		return err
+	}
+	for i, item := range t.Agenda {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO room_template_agenda_items (template_id, position, title, description, timebox_minutes)
+			VALUES ($1, $2, $3, $4, $5)`, t.ID, i+1, item.Title, item.Description, item.TimeboxMinutes)
+		if err != nil {
+			return err
+		}
+	}
+	for i, key := range t.ReactionTypes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO room_template_reaction_types (template_id, reaction_key, sort_order) VALUES ($1, $2, $3)`,
+			t.ID, key, (i+1)*10)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTemplate はリクエストで指定されなかった会議室の設定をテンプレートで補います。
+func applyTemplate(room *models.Room, t models.RoomTemplate, now time.Time) {
+	if room.Title == "" {
+		date := now
+		if room.ScheduledStartAt != nil {
+			date = *room.ScheduledStartAt
+		}
+		if loc, err := time.LoadLocation(room.TimeZone); err == nil {
+			date = date.In(loc)
+		}
+		room.Title = strings.ReplaceAll(t.TitlePattern, titleDatePlaceholder, date.Format("2006/01/02"))
+	}
+	if room.Description == "" {
+		room.Description = t.Description
+	}
+	if room.PromptVariant == "" {
+		room.PromptVariant = t.PromptVariant
+	}
+	if room.DurationMinutes == nil {
+		room.DurationMinutes = t.DurationMinutes
+	}
+	if room.MaxParticipants == nil {
+		room.MaxParticipants = t.MaxParticipants
+	}
+	room.AllowGuests = room.AllowGuests || t.AllowGuests
+	room.Anonymous = room.Anonymous || t.Anonymous
+}
+
+// insertTemplateRoomContents はテンプレートの議題とリアクションのセットを会議室に登録します。
+func insertTemplateRoomContents(ctx context.Context, tx *sql.Tx, roomID string, t models.RoomTemplate) error {
+	for i, item := range t.Agenda {
+		itemID, err := gonanoid.New()
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO agenda_items (id, room_id, position, title, description, timebox_minutes)
+			VALUES ($1, $2, $3, $4, $5, $6)`, itemID, roomID, i+1, item.Title, item.Description, item.TimeboxMinutes)
+		if err != nil {
+			return err
+		}
+	}
+	for i, key := range t.ReactionTypes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO room_reaction_types (room_id, reaction_key, sort_order) VALUES ($1, $2, $3)`,
+			roomID, key, (i+1)*10)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListTemplates godoc
+// @Summary      テンプレート一覧を取得
+// @Description  組み込みのテンプレートと追加されたテンプレートを取得します
+// @Tags         templates
+// @Produce      json
+// @Success      200  {array}   models.RoomTemplate
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /templates [get]
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	templates, err := loadTemplates(c.Request.Context(), h.db, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetTemplate godoc
+// @Summary      テンプレートを取得
+// @Tags         templates
+// @Produce      json
+// @Param        id   path      string  true  "テンプレートID"
+// @Success      200  {object}  models.RoomTemplate
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /templates/{id} [get]
+func (h *TemplateHandler) GetTemplate(c *gin.Context) {
+	h.respondTemplate(c, http.StatusOK, c.Param("id"))
+}
+
+// CreateTemplate godoc
+// @Summary      テンプレートを追加
+// @Description  会議室のタイトルのパターン・説明・議題・リアクションのセット・プロンプトの種類・設定をまとめたテンプレートを追加します。ゲストは実行できません
+// @Tags         templates
+// @Accept       json
+// @Produce      json
+// @Param        template  body      models.RoomTemplate  true  "テンプレート"
+// @Success      201       {object}  models.RoomTemplate
+// @Failure      400       {object}  map[string]interface{}
+// @Failure      403       {object}  map[string]interface{}
+// @Failure      500       {object}  map[string]interface{}
+// @Router       /templates [post]
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	var req models.RoomTemplate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	editor, ok := h.resolveTemplateEditor(c, req.UserID)
+	if !ok {
+		return
+	}
+	id, err := gonanoid.New()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "IDの生成に失敗しました"})
+		return
+	}
+	req.ID = id
+	h.saveTemplate(c, req, editor, true)
+}
+
+// UpdateTemplate godoc
+// @Summary      テンプレートを更新
+// @Description  追加したテンプレートを置き換えます。テンプレートを追加したユーザーか templates:manage スコープのサービスアカウントのみ実行できます。組み込みのテンプレートは変更できません。作成済みの会議室には影響しません
+// @Tags         templates
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string               true  "テンプレートID"
+// @Param        template  body      models.RoomTemplate  true  "テンプレート"
+// @Success      200       {object}  models.RoomTemplate
+// @Failure      400       {object}  map[string]interface{}
+// @Failure      403       {object}  map[string]interface{}
+// @Failure      404       {object}  map[string]interface{}
+// @Failure      500       {object}  map[string]interface{}
+// @Router       /templates/{id} [put]
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	var req models.RoomTemplate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	editor, ok := h.resolveTemplateEditor(c, req.UserID)
+	if !ok {
+		return
+	}
+	req.ID = c.Param("id")
+	h.saveTemplate(c, req, editor, false)
+}
+
+// templateEditor はテンプレートを変更するユーザーです。
+// manageAll は templates:manage スコープのサービスアカウントで、他のユーザーが追加したテンプレートも変更できます。
+type templateEditor struct {
+	userID    string
+	manageAll bool
+}
+
+// resolveTemplateEditor はテンプレートを追加・変更するユーザーを返します。
+// テンプレートはどの会議室にも属さないため、ゲストトークンでは操作できない
+func (h *TemplateHandler) resolveTemplateEditor(c *gin.Context, requestedUserID string) (templateEditor, bool) {
+	if principal, ok := auth.PrincipalFrom(c); ok && principal.HasScope(auth.ScopeTemplatesManage) {
+		return templateEditor{userID: principal.UserID, manageAll: true}, true
+	}
+	userID, ok := actingUser(c, h.db, "", requestedUserID)
+	return templateEditor{userID: userID}, ok
+}
+
+func (h *TemplateHandler) saveTemplate(c *gin.Context, t models.RoomTemplate, editor templateEditor, create bool) {
+	ctx := c.Request.Context()
+	if msg := validateTemplate(&t); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	if create {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO room_templates (id, name, title_pattern, description, prompt_variant, duration_minutes, max_participants, allow_guests, anonymous, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			t.ID, t.Name, t.TitlePattern, t.Description, t.PromptVariant, t.DurationMinutes, t.MaxParticipants, t.AllowGuests, t.Anonymous, editor.userID)
+	} else {
+		err = lockCustomTemplate(ctx, tx, t.ID, editor)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE room_templates SET name = $2, title_pattern = $3, description = $4, prompt_variant = $5,
+					duration_minutes = $6, max_participants = $7, allow_guests = $8, anonymous = $9
+				WHERE id = $1`,
+				t.ID, t.Name, t.TitlePattern, t.Description, t.PromptVariant, t.DurationMinutes, t.MaxParticipants, t.AllowGuests, t.Anonymous)
+		}
+	}
+	if err == nil {
+		err = writeTemplateContents(ctx, tx, t)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if create {
+		status = http.StatusCreated
+	}
+	h.respondTemplate(c, status, t.ID)
+}
+
+// DeleteTemplate godoc
+// @Summary      テンプレートを削除
+// @Description  追加したテンプレートを削除します。テンプレートを追加したユーザーか templates:manage スコープのサービスアカウントのみ実行できます。組み込みのテンプレートは削除できません。作成済みの会議室には影響しません
+// @Tags         templates
+// @Param        id       path   string  true   "テンプレートID"
+// @Param        user_id  query  string  false  "削除するユーザーのID（認証情報がない場合は必須）"
+// @Success      204
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /templates/{id} [delete]
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	ctx := c.Request.Context()
+	editor, ok := h.resolveTemplateEditor(c, c.Query("user_id"))
+	if !ok {
+		return
+	}
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer tx.Rollback()
+
+	err = lockCustomTemplate(ctx, tx, c.Param("id"), editor)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `DELETE FROM room_templates WHERE id = $1`, c.Param("id"))
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// lockCustomTemplate は追加されたテンプレートの行をロックします。
+// 組み込みのテンプレートの場合は errBuiltInTemplate を、editor が追加したものでない場合は errNotTemplateCreator を返します。
+// 作成者のわからないテンプレートは templates:manage スコープのサービスアカウントのみ変更できます。
+func lockCustomTemplate(ctx context.Context, tx *sql.Tx, templateID string, editor templateEditor) error {
+	var builtIn bool
+	var createdBy sql.NullString
+	err := tx.QueryRowContext(ctx, `SELECT built_in, created_by FROM room_templates WHERE id = $1 FOR UPDATE`, templateID).Scan(&builtIn, &createdBy)
+	if err != nil {
+		return err
+	}
+	if builtIn {
+		return errBuiltInTemplate
+	}
+	if !editor.manageAll && (!createdBy.Valid || createdBy.String != editor.userID) {
+		return errNotTemplateCreator
+	}
+	return nil
+}
+
+func respondTemplateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定されたテンプレートは見つかりません"})
+	case errors.Is(err, errBuiltInTemplate):
+		c.JSON(http.StatusForbidden, gin.H{"error": "組み込みのテンプレートは変更・削除できません"})
+	case errors.Is(err, errNotTemplateCreator):
+		c.JSON(http.StatusForbidden, gin.H{"error": "このテンプレートは追加したユーザーのみ変更・削除できます"})
+	case isForeignKeyViolation(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "存在しないリアクションが指定されています"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+	}
+}
+
+func (h *TemplateHandler) respondTemplate(c *gin.Context, status int, templateID string) {
+	t, err := loadTemplate(c.Request.Context(), h.db, templateID)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(status, t)
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRoom_AppliesTemplate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM room_templates`).
+		WithArgs("retrospective").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "title_pattern", "description", "prompt_variant", "duration_minutes", "max_participants", "allow_guests", "anonymous", "built_in", "created_by"}).
+			AddRow("retrospective", "ふりかえり", "ふりかえり {date}", "次に試すことを決めます", "retrospective", 30, nil, false, false, true, nil))
+	mock.ExpectQuery(`FROM room_template_agenda_items`).
+		WithArgs("retrospective").
+		WillReturnRows(sqlmock.NewRows([]string{"template_id", "title", "description", "timebox_minutes"}).
+			AddRow("retrospective", "うまくいったこと", "", 10).
+			AddRow("retrospective", "次に試すこと", "", nil))
+	mock.ExpectQuery(`FROM room_template_reaction_types`).
+		WithArgs("retrospective").
+		WillReturnRows(sqlmock.NewRows([]string{"template_id", "reaction_key"}).
+			AddRow("retrospective", "sorena").
+			AddRow("retrospective", "action"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO rooms`).
+		WithArgs(sqlmock.AnyArg(), "ふりかえり 2030/01/07", "次に試すことを決めます", nil, false, true, nil, sqlmock.AnyArg(), 30, "Asia/Tokyo", "retrospective", "retrospective").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO agenda_items`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1, "うまくいったこと", "", 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO agenda_items`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2, "次に試すこと", "", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO room_reaction_types`).
+		WithArgs(sqlmock.AnyArg(), "sorena", 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO room_reaction_types`).
+		WithArgs(sqlmock.AnyArg(), "action", 20).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 開始予定日は会議室のタイムゾーン（Asia/Tokyo）で数える
+	body := `{"template_id":"retrospective","anonymous":true,"scheduled_start_at":"2030-01-06T16:00:00Z"}`
+	c, w := newJSONContext(http.MethodPost, "/rooms", body)
+	NewRoomHandler(db, nil).CreateRoom(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var room models.Room
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &room))
+	assert.Equal(t, "ふりかえり 2030/01/07", room.Title)
+	assert.Equal(t, "retrospective", room.PromptVariant)
+	assert.True(t, room.Anonymous)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateRoom_UnknownTemplate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM room_templates`).
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "title_pattern", "description", "prompt_variant", "duration_minutes", "max_participants", "allow_guests", "anonymous", "built_in", "created_by"}))
+
+	c, w := newJSONContext(http.MethodPost, "/rooms", `{"template_id":"missing"}`)
+	NewRoomHandler(db, nil).CreateRoom(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidateTemplate(t *testing.T) {
+	template := models.RoomTemplate{Name: " 定例 ", Agenda: []models.TemplateAgendaItem{{Title: " 報告 "}}}
+	assert.Empty(t, validateTemplate(&template))
+	assert.Equal(t, "定例", template.TitlePattern)
+	assert.Equal(t, "default", template.PromptVariant)
+	assert.Equal(t, "報告", template.Agenda[0].Title)
+	assert.NotNil(t, template.ReactionTypes)
+
+	assert.NotEmpty(t, validateTemplate(&models.RoomTemplate{}))
+	assert.NotEmpty(t, validateTemplate(&models.RoomTemplate{Name: "定例", PromptVariant: "unknown"}))
+	assert.NotEmpty(t, validateTemplate(&models.RoomTemplate{Name: "定例", ReactionTypes: []string{"agree", "agree"}}))
+	assert.NotEmpty(t, validateTemplate(&models.RoomTemplate{Name: "定例", Agenda: []models.TemplateAgendaItem{{Title: ""}}}))
+	zero := 0
+	assert.NotEmpty(t, validateTemplate(&models.RoomTemplate{Name: "定例", DurationMinutes: &zero}))
+}
+
+func TestApplyTemplate_KeepsRequestedValues(t *testing.T) {
+	max := 5
+	room := models.Room{Title: "特別回", MaxParticipants: &max, TimeZone: "Asia/Tokyo"}
+	duration := 30
+	applyTemplate(&room, models.RoomTemplate{TitlePattern: "定例 {date}", Description: "説明", DurationMinutes: &duration, PromptVariant: "decision"}, time.Now())
+
+	assert.Equal(t, "特別回", room.Title)
+	assert.Equal(t, 5, *room.MaxParticipants)
+	assert.Equal(t, "説明", room.Description)
+	assert.Equal(t, 30, *room.DurationMinutes)
+	assert.Equal(t, "decision", room.PromptVariant)
+}
+
+func TestUpdateTemplate_NonCreatorIsForbidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT built_in, created_by FROM room_templates WHERE id = \$1 FOR UPDATE`).WithArgs("tpl1").
+		WillReturnRows(sqlmock.NewRows([]string{"built_in", "created_by"}).AddRow(false, "u001"))
+	mock.ExpectRollback()
+
+	c, w := newJSONContext(http.MethodPut, "/templates/tpl1", `{"user_id":"u002","name":"定例","title_pattern":"定例 {date}"}`)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "tpl1"}}
+	NewTemplateHandler(db).UpdateTemplate(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteTemplate_NonCreatorIsForbidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// 作成者のわからないテンプレートはユーザーからは削除できない
+	for _, createdBy := range []interface{}{"u001", nil} {
+		mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+			WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT built_in, created_by FROM room_templates WHERE id = \$1 FOR UPDATE`).WithArgs("tpl1").
+			WillReturnRows(sqlmock.NewRows([]string{"built_in", "created_by"}).AddRow(false, createdBy))
+		mock.ExpectRollback()
+
+		c, w := newJSONContext(http.MethodDelete, "/templates/tpl1?user_id=u002", "")
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "tpl1"}}
+		NewTemplateHandler(db).DeleteTemplate(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteTemplate_ByCreator(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT built_in, created_by FROM room_templates WHERE id = \$1 FOR UPDATE`).WithArgs("tpl1").
+		WillReturnRows(sqlmock.NewRows([]string{"built_in", "created_by"}).AddRow(false, "u001"))
+	mock.ExpectExec(`DELETE FROM room_templates WHERE id = \$1`).WithArgs("tpl1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	c, _ := newJSONContext(http.MethodDelete, "/templates/tpl1?user_id=u001", "")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "tpl1"}}
+	NewTemplateHandler(db).DeleteTemplate(c)
+
+	assert.Equal(t, http.StatusNoContent, c.Writer.Status())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateTemplate_GuestIsForbidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	c, w := newJSONContext(http.MethodPost, "/templates", `{"name":"定例","title_pattern":"定例 {date}"}`)
+	authenticateGuest(t, c, "g0000001", "r001")
+	NewTemplateHandler(db).CreateTemplate(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
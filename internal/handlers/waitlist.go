@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+)
+
+var errRoomNotFound = errors.New("room not found")
+
+// joinResult は joinRoom の結果を表します。
+type joinResult struct {
+	Status   string // models.JoinStatusJoined / models.JoinStatusWaitlisted
+	Position int    // キャンセル待ちの順番（Status が waitlisted のときのみ）
+	Created  bool   // 新たに参加した（または再参加した）場合に true
+}
+
+// lockRoomCapacity は部屋の行をロックして参加者上限を返します。
+// 参加・退出の処理は必ず最初にこれを呼び、同時実行時に上限を超えないようにします。
+func lockRoomCapacity(ctx context.Context, tx *sql.Tx, roomID string) (sql.NullInt64, error) {
+	var maxParticipants sql.NullInt64
+	err := tx.QueryRowContext(ctx, `SELECT max_participants FROM rooms WHERE id = $1 FOR UPDATE`, roomID).Scan(&maxParticipants)
+	if errors.Is(err, sql.ErrNoRows) {
+		return maxParticipants, errRoomNotFound
+	}
+	return maxParticipants, err
+}
+
+func countActiveParticipants(ctx context.Context, tx *sql.Tx, roomID string) (int, error) {
+	var n int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM participants WHERE room_id = $1 AND left_at IS NULL`, roomID).Scan(&n)
+	return n, err
+}
+
+// waitlistPosition はキャンセル待ちの順番を返します。
+// promoteWaitlist と同じ (created_at, user_id) の順で数え、登録日時が同じユーザーが同じ順番にならないようにします。
+func waitlistPosition(ctx context.Context, tx *sql.Tx, roomID, userID string) (int, error) {
+	var pos int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM room_waitlist w, room_waitlist me
+		WHERE w.room_id = $1 AND me.room_id = $1 AND me.user_id = $2
+		  AND (w.created_at, w.user_id) <= (me.created_at, me.user_id)`,
+		roomID, userID).Scan(&pos)
+	return pos, err
+}
+
+func activateParticipant(ctx context.Context, tx *sql.Tx, roomID, userID string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO participants (room_id, user_id, joined_at, last_seen_at)
+		VALUES ($1, $2, NOW(), NOW())
+		ON CONFLICT (room_id, user_id)
+		DO UPDATE SET left_at = NULL, joined_at = NOW(), last_seen_at = NOW()`, roomID, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM room_waitlist WHERE room_id = $1 AND user_id = $2`, roomID, userID)
+	return err
+}
+
+// joinRoom はユーザーを部屋に参加させます。上限に達している場合はキャンセル待ちに登録します。
+// 既に参加中の場合は何もしません。
+func joinRoom(ctx context.Context, tx *sql.Tx, roomID, userID string) (joinResult, error) {
+	maxParticipants, err := lockRoomCapacity(ctx, tx, roomID)
+	if err != nil {
+		return joinResult{}, err
+	}
+
+	var leftAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT left_at FROM participants WHERE room_id = $1 AND user_id = $2`, roomID, userID).Scan(&leftAt)
+	if err == nil && !leftAt.Valid {
+		return joinResult{Status: models.JoinStatusJoined}, nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return joinResult{}, err
+	}
+
+	if maxParticipants.Valid {
+		active, err := countActiveParticipants(ctx, tx, roomID)
+		if err != nil {
+			return joinResult{}, err
+		}
+		if int64(active) >= maxParticipants.Int64 {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO room_waitlist (room_id, user_id) VALUES ($1, $2)
+				ON CONFLICT (room_id, user_id) DO NOTHING`, roomID, userID)
+			if err != nil {
+				return joinResult{}, err
+			}
+			pos, err := waitlistPosition(ctx, tx, roomID, userID)
+			if err != nil {
+				return joinResult{}, err
+			}
+			return joinResult{Status: models.JoinStatusWaitlisted, Position: pos}, nil
+		}
+	}
+
+	if err := activateParticipant(ctx, tx, roomID, userID); err != nil {
+		return joinResult{}, err
+	}
+	return joinResult{Status: models.JoinStatusJoined, Created: true}, nil
+}
+
+// promoteWaitlist は空きがある限り、キャンセル待ちの先頭から参加者に繰り上げます。
+// 呼び出し前に lockRoomCapacity で部屋をロックしておく必要があります。
+func promoteWaitlist(ctx context.Context, tx *sql.Tx, roomID string, maxParticipants sql.NullInt64) ([]string, error) {
+	var promoted []string
+	for {
+		if maxParticipants.Valid {
+			active, err := countActiveParticipants(ctx, tx, roomID)
+			if err != nil {
+				return promoted, err
+			}
+			if int64(active) >= maxParticipants.Int64 {
+				return promoted, nil
+			}
+		}
+
+		var userID string
+		err := tx.QueryRowContext(ctx, `
+			SELECT user_id FROM room_waitlist
+			WHERE room_id = $1
+			ORDER BY created_at ASC, user_id ASC
+			LIMIT 1`, roomID).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return promoted, nil
+		}
+		if err != nil {
+			return promoted, err
+		}
+		if err := activateParticipant(ctx, tx, roomID, userID); err != nil {
+			return promoted, err
+		}
+		promoted = append(promoted, userID)
+	}
+}
+
+// leaveRoom は参加者を退出させ、空いた枠にキャンセル待ちを繰り上げます。
+// キャンセル待ち中のユーザーの場合はキャンセル待ちから外します。
+// 対象が見つからなかった場合は false を返します。
+func leaveRoom(ctx context.Context, tx *sql.Tx, roomID, userID string) (bool, []string, error) {
+	maxParticipants, err := lockRoomCapacity(ctx, tx, roomID)
+	if err != nil {
+		if errors.Is(err, errRoomNotFound) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE participants SET left_at = NOW()
+		WHERE room_id = $1 AND user_id = $2 AND left_at IS NULL`, roomID, userID)
+	if err != nil {
+		return false, nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return false, nil, err
+	} else if n > 0 {
+		promoted, err := promoteWaitlist(ctx, tx, roomID, maxParticipants)
+		return true, promoted, err
+	}
+
+	result, err = tx.ExecContext(ctx, `DELETE FROM room_waitlist WHERE room_id = $1 AND user_id = $2`, roomID, userID)
+	if err != nil {
+		return false, nil, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, nil, err
+}
+
+// GetWaitlist godoc
+// @Summary      キャンセル待ち一覧を取得
+// @Description  指定された会議室のキャンセル待ちを順番順に取得します
+// @Tags         participants
+// @Produce      json
+// @Param        id   path      string  true  "会議室ID"
+// @Success      200  {object}  models.WaitlistResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /rooms/{id}/waitlist [get]
+func (h *ParticipantHandler) GetWaitlist(c *gin.Context) {
+	roomID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var maxParticipants sql.NullInt64
+	var active int
+	err := h.db.QueryRowContext(ctx, `
+		SELECT r.max_participants,
+		       (SELECT COUNT(*) FROM participants p WHERE p.room_id = r.id AND p.left_at IS NULL)
+		FROM rooms r WHERE r.id = $1`, roomID).Scan(&maxParticipants, &active)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された部屋は見つかりません"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		}
+		return
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT u.id, u.user_name, w.created_at
+		FROM room_waitlist w
+		JOIN users u ON w.user_id = u.id
+		WHERE w.room_id = $1
+		ORDER BY w.created_at ASC, w.user_id ASC`, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.WaitlistEntry{}
+	for rows.Next() {
+		var e models.WaitlistEntry
+		if err := rows.Scan(&e.UserID, &e.Name, &e.QueuedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		e.Position = len(entries) + 1
+		entries = append(entries, e)
+	}
+
+	response := models.WaitlistResponse{
+		RoomID:       roomID,
+		Participants: active,
+		Entries:      entries,
+	}
+	if maxParticipants.Valid {
+		max := int(maxParticipants.Int64)
+		response.MaxParticipants = &max
+	}
+	c.JSON(http.StatusOK, response)
+}
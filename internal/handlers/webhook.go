@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
+	"github.com/shuto.sawaki/elmo-project/internal/webhooks"
+)
+
+// 配信の記録の既定の件数と上限
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+type WebhookHandler struct {
+	db *sql.DB
+}
+
+func NewWebhookHandler(db *sql.DB) *WebhookHandler {
+	return &WebhookHandler{db: db}
+}
+
+// CreateWebhook godoc
+// @Summary      Webhookを購読
+// @Description  会議室の出来事（作成・開始・要約・結論・終了）を指定したURLにPOSTで送る購読を作成します。本文はHMAC-SHA256で署名し、X-Elmo-Signature に "sha256=<16進数>"（"<X-Elmo-Timestamp>.<本文>" の署名）を付けます。2xx以外の応答は間隔を空けて再送します。署名用のシークレットはこのレスポンスでのみ返します
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        webhook  body      models.WebhookRequest  true  "購読の内容"
+// @Success      201      {object}  models.WebhookCreatedResponse
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      403      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストボディが不正です"})
+		return
+	}
+	dest, ok := bindDestination(c, h.db, destinationRequest{URL: req.URL, Events: req.Events, Scope: req.Scope, RoomID: req.RoomID, UserID: req.UserID},
+		nil, []string{webhooks.ScopeRoom, webhooks.ScopeCreator, webhooks.ScopeGlobal})
+	if !ok {
+		return
+	}
+	webhook := models.Webhook{URL: req.URL, Scope: req.Scope, Events: dest.events, RoomID: dest.roomID, CreatorID: dest.creatorID, CreatedBy: dest.userID}
+
+	id, err := gonanoid.New()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	webhook.ID = id
+	err = h.db.QueryRowContext(c.Request.Context(), `
+		INSERT INTO webhook_subscriptions (id, url, secret, scope, room_id, creator_id, events, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at`,
+		webhook.ID, webhook.URL, secret, webhook.Scope, webhook.RoomID, webhook.CreatorID, strings.Join(webhook.Events, " "), webhook.CreatedBy).
+		Scan(&webhook.CreatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーが見つかりません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "購読の作成に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusCreated, models.WebhookCreatedResponse{Webhook: webhook, Secret: secret})
+}
+
+// ListWebhooks godoc
+// @Summary      Webhookの購読一覧
+// @Description  自分が作成したWebhookの購読を返します（シークレットは含みません）
+// @Tags         webhooks
+// @Produce      json
+// @Param        user_id  query     string  false  "ユーザーID（認証情報がない場合は必須）"
+// @Success      200      {array}   models.Webhook
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	userID, ok := actingUser(c, h.db, "", c.Query("user_id"))
+	if !ok {
+		return
+	}
+	rows, err := h.db.QueryContext(c.Request.Context(), `
+		SELECT id, url, scope, room_id, creator_id, events, created_by, created_at
+		FROM webhook_subscriptions WHERE created_by = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer rows.Close()
+
+	list := []models.Webhook{}
+	for rows.Next() {
+		var w models.Webhook
+		var roomID, creatorID sql.NullString
+		var events string
+		if err := rows.Scan(&w.ID, &w.URL, &w.Scope, &roomID, &creatorID, &events, &w.CreatedBy, &w.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		if roomID.Valid {
+			w.RoomID = &roomID.String
+		}
+		if creatorID.Valid {
+			w.CreatorID = &creatorID.String
+		}
+		w.Events = strings.Fields(events)
+		list = append(list, w)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteWebhook godoc
+// @Summary      Webhookの購読を削除
+// @Description  購読と配信の記録を削除します。送信待ちの配信も送りません
+// @Tags         webhooks
+// @Param        id       path  string  true   "購読のID"
+// @Param        user_id  query string  false  "ユーザーID（認証情報がない場合は必須）"
+// @Success      204
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userID, ok := actingUser(c, h.db, "", c.Query("user_id"))
+	if !ok {
+		return
+	}
+	result, err := h.db.ExecContext(c.Request.Context(),
+		`DELETE FROM webhook_subscriptions WHERE id = $1 AND created_by = $2`, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "購読の削除に失敗しました"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された購読は見つかりません"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ownWebhook は購読が呼び出し元のものかどうかを確かめます。拒否した場合はレスポンスを書き込んで false を返します。
+func ownWebhook(c *gin.Context, db *sql.DB, webhookID string) bool {
+	userID, ok := actingUser(c, db, "", c.Query("user_id"))
+	if !ok {
+		return false
+	}
+	var owner string
+	err := db.QueryRowContext(c.Request.Context(), `SELECT created_by FROM webhook_subscriptions WHERE id = $1`, webhookID).Scan(&owner)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return false
+	}
+	// 他人の購読は存在を知られないよう見つからない扱いにする
+	if errors.Is(err, sql.ErrNoRows) || owner != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定された購読は見つかりません"})
+		return false
+	}
+	return true
+}
+
+const webhookDeliveryColumns = `id, event_id, event_type, room_id, status, attempts, last_status_code, last_error,
+	CASE WHEN status = 'pending' THEN next_attempt_at END, created_at, delivered_at`
+
+func scanWebhookDelivery(scan func(dest ...any) error) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var roomID, lastError sql.NullString
+	var lastStatusCode sql.NullInt64
+	var nextAttemptAt, deliveredAt sql.NullTime
+	if err := scan(&d.ID, &d.EventID, &d.EventType, &roomID, &d.Status, &d.Attempts, &lastStatusCode, &lastError,
+		&nextAttemptAt, &d.CreatedAt, &deliveredAt); err != nil {
+		return d, err
+	}
+	if roomID.Valid {
+		d.RoomID = &roomID.String
+	}
+	if lastStatusCode.Valid {
+		code := int(lastStatusCode.Int64)
+		d.LastStatusCode = &code
+	}
+	if lastError.Valid {
+		d.LastError = &lastError.String
+	}
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return d, nil
+}
+
+// ListWebhookDeliveries godoc
+// @Summary      Webhookの配信の記録
+// @Description  購読の配信の記録を新しい順に返します。記録は30日間残します
+// @Tags         webhooks
+// @Produce      json
+// @Param        id       path      string  true   "購読のID"
+// @Param        status   query     string  false  "状態で絞り込み（pending / succeeded / failed）"
+// @Param        limit    query     int     false  "件数（1〜200、既定 50）"
+// @Param        user_id  query     string  false  "ユーザーID（認証情報がない場合は必須）"
+// @Success      200      {array}   models.WebhookDelivery
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	webhookID := c.Param("id")
+	status := c.Query("status")
+	if status != "" && status != webhooks.StatusPending && status != webhooks.StatusSucceeded && status != webhooks.StatusFailed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "statusは pending / succeeded / failed のいずれかを指定してください"})
+		return
+	}
+	limit := defaultDeliveryLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxDeliveryLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limitは1〜200で指定してください"})
+			return
+		}
+		limit = n
+	}
+	if !ownWebhook(c, h.db, webhookID) {
+		return
+	}
+
+	rows, err := h.db.QueryContext(c.Request.Context(), `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY id DESC
+		LIMIT $3`, webhookID, status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	defer rows.Close()
+
+	list := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows.Scan)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+			return
+		}
+		list = append(list, d)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "サーバー内部エラーです"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// replayDelivery は配信と同じ本文の配信を新しく積みます。
+func replayDelivery(ctx context.Context, db *sql.DB, webhookID string, deliveryID int64) (models.WebhookDelivery, error) {
+	row := db.QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, room_id, payload)
+		SELECT subscription_id, event_id, event_type, room_id, payload
+		FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2
+		RETURNING `+webhookDeliveryColumns, deliveryID, webhookID)
+	return scanWebhookDelivery(row.Scan)
+}
+
+// ReplayWebhookDelivery godoc
+// @Summary      Webhookの配信を再送
+// @Description  過去の配信と同じ本文（同じ出来事のID）を新しい配信として積み直します。受信側の障害から復旧した後などに使います
+// @Tags         webhooks
+// @Produce      json
+// @Param        id          path      string  true   "購読のID"
+// @Param        deliveryId  path      int     true   "配信のID"
+// @Param        user_id     query     string  false  "ユーザーID（認証情報がない場合は必須）"
+// @Success      202         {object}  models.WebhookDelivery
+// @Failure      400         {object}  map[string]interface{}
+// @Failure      404         {object}  map[string]interface{}
+// @Failure      500         {object}  map[string]interface{}
+// @Router       /webhooks/{id}/deliveries/{deliveryId}/replay [post]
+func (h *WebhookHandler) ReplayWebhookDelivery(c *gin.Context) {
+	webhookID := c.Param("id")
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "配信のIDが不正です"})
+		return
+	}
+	if !ownWebhook(c, h.db, webhookID) {
+		return
+	}
+
+	delivery, err := replayDelivery(c.Request.Context(), h.db, webhookID, deliveryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定された配信は見つかりません"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "再送に失敗しました"})
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
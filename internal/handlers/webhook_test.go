@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWebhook_Room(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	mock.ExpectQuery(`INSERT INTO webhook_subscriptions`).
+		WithArgs(sqlmock.AnyArg(), "https://example.com/hooks", sqlmock.AnyArg(), "room", "r001", nil, "room.started conclusion.saved", "u001").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+
+	body := `{"user_id":"u001","url":"https://example.com/hooks","scope":"room","room_id":"r001","events":["room.started","conclusion.saved","room.started"]}`
+	c, w := newJSONContext(http.MethodPost, "/webhooks", body)
+	NewWebhookHandler(db).CreateWebhook(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"secret":"whsec_`)
+	assert.Contains(t, w.Body.String(), `"events":["room.started","conclusion.saved"]`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateWebhook_Rejects(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := map[string]struct {
+		body   string
+		status int
+	}{
+		"URLが不正":        {`{"user_id":"u001","url":"ftp://example.com","scope":"creator"}`, http.StatusBadRequest},
+		"メタデータのアドレス":    {`{"user_id":"u001","url":"http://169.254.169.254/latest/meta-data/","scope":"creator"}`, http.StatusBadRequest},
+		"プライベートのアドレス":   {`{"user_id":"u001","url":"http://10.0.0.5:8080/hook","scope":"creator"}`, http.StatusBadRequest},
+		"出来事の種類が不正":     {`{"user_id":"u001","url":"https://example.com","scope":"creator","events":["room.deleted"]}`, http.StatusBadRequest},
+		"範囲が不正":         {`{"user_id":"u001","url":"https://example.com","scope":"team"}`, http.StatusBadRequest},
+		"globalはユーザー不可": {`{"user_id":"u001","url":"https://example.com","scope":"global"}`, http.StatusForbidden},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tt.status != http.StatusBadRequest {
+				mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+					WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+			}
+			c, w := newJSONContext(http.MethodPost, "/webhooks", tt.body)
+			NewWebhookHandler(db).CreateWebhook(c)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u001").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM webhook_subscriptions`).WithArgs("wh1").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+	mock.ExpectQuery(`INSERT INTO webhook_deliveries .+ FROM webhook_deliveries WHERE id = \$1 AND subscription_id = \$2`).
+		WithArgs(int64(7), "wh1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "event_type", "room_id", "status", "attempts", "last_status_code", "last_error", "next_attempt_at", "created_at", "delivered_at"}).
+			AddRow(8, "evt_1", "room.done", "r001", "pending", 0, nil, nil, now, now, nil))
+
+	c, w := newJSONContext(http.MethodPost, "/webhooks/wh1/deliveries/7/replay?user_id=u001", "")
+	c.Params = gin.Params{{Key: "id", Value: "wh1"}, {Key: "deliveryId", Value: "7"}}
+	NewWebhookHandler(db).ReplayWebhookDelivery(c)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"event_id":"evt_1"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListWebhookDeliveries_OtherUsersWebhook(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT is_guest FROM users`).WithArgs("u002").
+		WillReturnRows(sqlmock.NewRows([]string{"is_guest"}).AddRow(false))
+	mock.ExpectQuery(`SELECT created_by FROM webhook_subscriptions`).WithArgs("wh1").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow("u001"))
+
+	c, w := newJSONContext(http.MethodGet, "/webhooks/wh1/deliveries?user_id=u002", "")
+	c.Params = gin.Params{{Key: "id", Value: "wh1"}}
+	NewWebhookHandler(db).ListWebhookDeliveries(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
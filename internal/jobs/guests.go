@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// ゲストの既定の保持期間
+const DefaultGuestRetention = 30 * 24 * time.Hour
+
+// anonymizedGuestName は保持期間を過ぎたゲストに付ける表示名です。
+const anonymizedGuestName = "ゲスト"
+
+// GuestRetention は保持期間を過ぎたゲストユーザーを定期的に削除・匿名化します。
+// 発言が残っているゲストは議事録の整合性を保つため削除せず、表示名を匿名化します。
+type GuestRetention struct {
+	db        *sql.DB
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewGuestRetention(db *sql.DB, retention time.Duration) *GuestRetention {
+	return &GuestRetention{db: db, retention: retention, interval: time.Hour}
+}
+
+// NewGuestRetentionFromEnv は GUEST_RETENTION（例: 720h）から保持期間を読み込みます。
+func NewGuestRetentionFromEnv(db *sql.DB) (*GuestRetention, error) {
+	retention := DefaultGuestRetention
+	if v := os.Getenv("GUEST_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GUEST_RETENTION: %w", err)
+		}
+		retention = d
+	}
+	return NewGuestRetention(db, retention), nil
+}
+
+// Run は ctx がキャンセルされるまで定期的に Purge を実行します。
+func (j *GuestRetention) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		deleted, anonymized, err := j.Purge(ctx)
+		if err != nil {
+			log.Printf("ゲストの整理に失敗しました: %v", err)
+		} else if deleted > 0 || anonymized > 0 {
+			log.Printf("保持期間を過ぎたゲストを整理しました: 削除=%d, 匿名化=%d", deleted, anonymized)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge は保持期間を過ぎたゲストのうち、発言のないものを削除し、残りを匿名化します。
+func (j *GuestRetention) Purge(ctx context.Context) (deleted, anonymized int64, err error) {
+	cutoff := time.Now().Add(-j.retention)
+
+	result, err := j.db.ExecContext(ctx, `
+		DELETE FROM users u
+		WHERE u.is_guest AND u.created_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM chat_logs l WHERE l.user_id = u.id)`, cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete expired guests: %w", err)
+	}
+	deleted, _ = result.RowsAffected()
+
+	result, err = j.db.ExecContext(ctx, `
+		UPDATE users SET user_name = $2, anonymized_at = NOW()
+		WHERE is_guest AND anonymized_at IS NULL AND created_at < $1`, cutoff, anonymizedGuestName)
+	if err != nil {
+		return deleted, 0, fmt.Errorf("failed to anonymize expired guests: %w", err)
+	}
+	anonymized, _ = result.RowsAffected()
+	return deleted, anonymized, nil
+}
@@ -0,0 +1,268 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/ai"
+	"github.com/shuto.sawaki/elmo-project/internal/conclusions"
+	"github.com/shuto.sawaki/elmo-project/internal/events"
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+	"github.com/shuto.sawaki/elmo-project/internal/series"
+)
+
+// スケジューラーの既定の設定
+const (
+	// 開始予定日時のどれだけ前に問いかけを用意しておくか
+	DefaultPrepareAhead = 10 * time.Minute
+	// 終了のどれだけ前に参加者に知らせるか
+	DefaultEndWarning = 5 * time.Minute
+	// 定例会議の各回の会議室を開始のどれだけ前に作成するか
+	DefaultSeriesLookahead = 24 * time.Hour
+)
+
+// RoomEnding は終了前の通知の内容です。
+type RoomEnding struct {
+	RoomID string    `json:"room_id"`
+	EndsAt time.Time `json:"ends_at"`
+}
+
+// Scheduler は予定された会議室を自動で開始・終了します。
+// 時刻の比較はすべてデータベースの NOW() で行うため、会議室のタイムゾーンに関係なく動作します。
+type Scheduler struct {
+	db           *sql.DB
+	aiGenerator  ai.AIGenerator
+	hub          *events.Hub
+	notifiers    notify.Notifiers
+	interval     time.Duration
+	prepareAhead time.Duration
+	endWarning   time.Duration
+	lookahead    time.Duration
+}
+
+func NewScheduler(db *sql.DB, aiGen ai.AIGenerator, hub *events.Hub) *Scheduler {
+	return &Scheduler{
+		db:           db,
+		aiGenerator:  aiGen,
+		hub:          hub,
+		interval:     15 * time.Second,
+		prepareAhead: DefaultPrepareAhead,
+		endWarning:   DefaultEndWarning,
+		lookahead:    DefaultSeriesLookahead,
+	}
+}
+
+// AddNotifier は自動で開始・終了したことを知らせる通知先を追加します。
+func (s *Scheduler) AddNotifier(n notify.Notifier) {
+	s.notifiers = append(s.notifiers, n)
+}
+
+// Run は ctx がキャンセルされるまで定期的に Tick を実行します。
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Tick(ctx); err != nil {
+			log.Printf("会議室のスケジュール処理に失敗しました: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick は定例会議の会議室の作成、問いかけの準備、開始、終了前の通知、終了を一回ずつ行います。
+// 一つが失敗しても残りは実行します。
+func (s *Scheduler) Tick(ctx context.Context) error {
+	return errors.Join(
+		s.spawn(ctx),
+		s.prepare(ctx),
+		s.start(ctx),
+		s.warn(ctx),
+		s.end(ctx),
+	)
+}
+
+// prepare は開始予定日時が近い会議室の最初の問いかけを事前に生成し、開始時に待たせないようにします。
+func (s *Scheduler) prepare(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, title, description, previous_room_id, prompt_variant FROM rooms
+		WHERE status = 'not started' AND initial_question IS NULL
+		  AND scheduled_start_at <= NOW() + make_interval(secs => $1)`, s.prepareAhead.Seconds())
+	if err != nil {
+		return err
+	}
+	_, err = s.generateQuestions(ctx, rows)
+	return err
+}
+
+// generateQuestions は問いかけのない会議室（id, title, description, previous_room_id, prompt_variant）の問いかけを生成し、
+// 保存できた会議室の ID と問いかけを返します。
+// 定例会議では前回の結論と未完了のアクションを引き継ぎます。
+func (s *Scheduler) generateQuestions(ctx context.Context, rows *sql.Rows) (map[string]string, error) {
+	type pending struct {
+		id, title, description, promptVariant string
+		previousRoomID                        sql.NullString
+	}
+	var rooms []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.title, &p.description, &p.previousRoomID, &p.promptVariant); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rooms = append(rooms, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	saved := make(map[string]string)
+	var errs []error
+	for _, r := range rooms {
+		topic, err := series.QuestionTopic(ctx, s.db, r.previousRoomID, r.description)
+		if err != nil {
+			log.Printf("前回の会議からの引き継ぎの読み込みに失敗しました: room=%s, err=%v", r.id, err)
+		}
+		question, err := s.aiGenerator.GenerateInitialQuestion(ctx, r.title, ai.WithPromptVariant(topic, r.promptVariant))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("room %s: %w", r.id, err))
+			continue
+		}
+		result, err := s.db.ExecContext(ctx, `
+			UPDATE rooms SET initial_question = $1 WHERE id = $2 AND initial_question IS NULL`, question, r.id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// 生成している間に手動で開始された会議室は、開始した側の問いかけを残す
+		if n, err := result.RowsAffected(); err != nil {
+			errs = append(errs, err)
+		} else if n == 1 {
+			saved[r.id] = question
+		}
+	}
+	return saved, errors.Join(errs...)
+}
+
+// start は開始予定日時を過ぎた会議室を開始します。
+// 問いかけの準備が間に合わなかった会議室も開始し、続けて問いかけを生成します。
+// 通知先への開始の通知は、問いかけを添えられるよう問いかけができてから送ります。
+func (s *Scheduler) start(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE rooms SET status = 'inprogress', started_at = NOW()
+		WHERE status = 'not started' AND scheduled_start_at <= NOW()
+		RETURNING id, COALESCE(initial_question, '')`)
+	if err != nil {
+		return err
+	}
+	type startedRoom struct{ id, question string }
+	var started []startedRoom
+	for rows.Next() {
+		var r startedRoom
+		if err := rows.Scan(&r.id, &r.question); err != nil {
+			rows.Close()
+			return err
+		}
+		started = append(started, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, r := range started {
+		log.Printf("予定どおり会議室を開始しました: room=%s", r.id)
+		// 問いかけの準備が間に合わなかった会議室では initial_question は空になり、用意できたら room.question_ready で配信する
+		s.hub.Publish(r.id, events.Event{Type: events.TypeRoomStarted, Data: map[string]string{"room_id": r.id, "initial_question": r.question}})
+		if r.question != "" {
+			s.notifiers.Publish(ctx, s.db, notify.TypeRoomStarted, r.id, notify.RoomStarted{InitialQuestion: r.question})
+		}
+	}
+	return s.prepareStarted(ctx)
+}
+
+// prepareStarted は問いかけのないまま自動で開始された会議室の問いかけを生成し、
+// 生成できた会議室の購読者に問いかけを配信して、見送っていた開始の通知を送ります。
+// 生成に失敗した会議室は次回に再び生成します。
+func (s *Scheduler) prepareStarted(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, title, description, previous_room_id, prompt_variant FROM rooms
+		WHERE status = 'inprogress' AND initial_question IS NULL AND scheduled_start_at IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	saved, err := s.generateQuestions(ctx, rows)
+	for id, question := range saved {
+		s.hub.Publish(id, events.Event{Type: events.TypeRoomQuestionReady, Data: map[string]string{"room_id": id, "initial_question": question}})
+		s.notifiers.Publish(ctx, s.db, notify.TypeRoomStarted, id, notify.RoomStarted{InitialQuestion: question})
+	}
+	return err
+}
+
+// warn は終了が近い会議室の参加者に一度だけ知らせます。
+func (s *Scheduler) warn(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE rooms SET end_warned_at = NOW()
+		WHERE status IN ('inprogress', 'concluded') AND end_warned_at IS NULL
+		  AND duration_minutes IS NOT NULL AND started_at IS NOT NULL
+		  AND started_at + make_interval(mins => duration_minutes) - make_interval(secs => $1) <= NOW()
+		RETURNING id, started_at + make_interval(mins => duration_minutes)`, s.endWarning.Seconds())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ending RoomEnding
+		if err := rows.Scan(&ending.RoomID, &ending.EndsAt); err != nil {
+			return err
+		}
+		s.hub.Publish(ending.RoomID, events.Event{Type: events.TypeRoomEnding, Data: ending})
+	}
+	return rows.Err()
+}
+
+// end は会議の長さが経った会議室を終了します。
+// 結論の承認者がいる会議室は、全員が最新の版を承認するまで終了しません（承認されたら次の確認で終了します）。
+func (s *Scheduler) end(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE rooms SET status = 'done'
+		WHERE status IN ('inprogress', 'concluded')
+		  AND duration_minutes IS NOT NULL AND started_at IS NOT NULL
+		  AND started_at + make_interval(mins => duration_minutes) <= NOW()
+		  AND NOT `+conclusions.PendingApprovals+`
+		RETURNING id`)
+	if err != nil {
+		return err
+	}
+	ids, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		log.Printf("会議の時間が終わったため会議室を終了しました: room=%s", id)
+		s.hub.Publish(id, events.Event{Type: events.TypeRoomEnded, Data: map[string]string{"room_id": id}})
+		s.notifiers.Publish(ctx, s.db, notify.TypeRoomDone, id, nil)
+	}
+	return nil
+}
+
+func scanIDs(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
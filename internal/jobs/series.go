@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/series"
+)
+
+// spawn は次回の開始が近い定例会議のシリーズから会議室を作成します。
+func (s *Scheduler) spawn(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id FROM room_series
+		WHERE active AND next_start_at <= NOW() + make_interval(secs => $1)`, s.lookahead.Seconds())
+	if err != nil {
+		return err
+	}
+	ids, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if err := s.spawnSeries(ctx, id, time.Now()); err != nil {
+			errs = append(errs, fmt.Errorf("series %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// spawnSeries はシリーズの次回の会議室を作成し、前回の会議室とつなげます。
+// サーバーが止まっていた間に開始時刻を過ぎた回は作成せずに飛ばします。
+func (s *Scheduler) spawnSeries(ctx context.Context, seriesID string, now time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var title, description, rrule, timeZone string
+	var startAt, nextStartAt time.Time
+	var durationMinutes, maxParticipants sql.NullInt64
+	var allowGuests, anonymous bool
+	var createdBy sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT title, description, rrule, start_at, duration_minutes, time_zone, max_participants, allow_guests, anonymous, created_by, next_start_at
+		FROM room_series WHERE id = $1 AND active AND next_start_at IS NOT NULL
+		FOR UPDATE SKIP LOCKED`, seriesID).
+		Scan(&title, &description, &rrule, &startAt, &durationMinutes, &timeZone, &maxParticipants, &allowGuests, &anonymous, &createdBy, &nextStartAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 別のインスタンスが処理中か、すでに停止された
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	rule, err := series.ParseRule(rrule)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return err
+	}
+
+	roomStart := nextStartAt
+	hasRoom := !roomStart.Before(now)
+	if hasRoom {
+		var previousRoomID sql.NullString
+		err := tx.QueryRowContext(ctx, `
+			SELECT id FROM rooms WHERE series_id = $1 ORDER BY scheduled_start_at DESC LIMIT 1`, seriesID).Scan(&previousRoomID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		roomID, err := gonanoid.Generate("0123456789abcdefghijklmnopqrstuvwxyz", 6)
+		if err != nil {
+			return err
+		}
+		roomTitle := fmt.Sprintf("%s (%s)", title, roomStart.In(loc).Format("2006/01/02"))
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO rooms (id, title, description, max_participants, allow_guests, anonymous, created_by, scheduled_start_at, duration_minutes, time_zone, series_id, previous_room_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			roomID, roomTitle, description, maxParticipants, allowGuests, anonymous, createdBy, roomStart, durationMinutes, timeZone, seriesID, previousRoomID)
+		if err != nil {
+			return err
+		}
+		log.Printf("定例会議の会議室を作成しました: series=%s, room=%s, start=%s", seriesID, roomID, roomStart.Format(time.RFC3339))
+	}
+
+	// 次回の開始日時。飛ばした回も含めて現在より後の回まで進める
+	after := roomStart
+	if !hasRoom {
+		after = now
+	}
+	var next *time.Time
+	if t, ok := rule.Next(startAt, after, loc); ok {
+		next = &t
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE room_series SET next_start_at = $1 WHERE id = $2`, next, seriesID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
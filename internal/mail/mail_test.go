@@ -0,0 +1,333 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	raw, err := Build(Message{
+		From:           "Elmo <noreply@elmo.example.com>",
+		To:             "tanaka@example.com",
+		Subject:        "【Elmo】会議のまとめ: 週次定例",
+		Text:           "結論\n" + strings.Repeat("長い行", 40),
+		HTML:           "<p>結論</p>",
+		UnsubscribeURL: "https://elmo.example.com/unsubscribe?token=abc&type=digests",
+	}, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	msg, err := netmail.ReadMessage(strings.NewReader(string(raw)))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "【Elmo】会議のまとめ: 週次定例", subject)
+	assert.Equal(t, "<https://elmo.example.com/unsubscribe?token=abc&type=digests>", msg.Header.Get("List-Unsubscribe"))
+	assert.Equal(t, "List-Unsubscribe=One-Click", msg.Header.Get("List-Unsubscribe-Post"))
+	assert.True(t, strings.HasSuffix(msg.Header.Get("Message-ID"), "@elmo.example.com>"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, "quoted-printable", part.Header.Get("Content-Transfer-Encoding"))
+		b, err := io.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+		bodies = append(bodies, string(b))
+	}
+	require.Len(t, bodies, 2)
+	assert.Equal(t, "結論\r\n"+strings.Repeat("長い行", 40), bodies[0])
+	assert.Equal(t, "<p>結論</p>", bodies[1])
+
+	// 行の長さは RFC 5322 の上限を超えない
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		assert.LessOrEqual(t, len(line), 998)
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := Data{
+		RecipientName: "田中",
+		Room: Room{
+			ID:              "r001",
+			Title:           "週次定例 <全体>",
+			StartAt:         time.Date(2024, 1, 1, 10, 0, 0, 0, time.FixedZone("Asia/Tokyo", 9*60*60)),
+			DurationMinutes: 30,
+			URL:             "https://elmo.example.com/rooms/r001",
+		},
+		InvitedBy:      "佐藤",
+		UnsubscribeURL: "https://elmo.example.com/unsubscribe?token=abc&type=invitations",
+	}
+	subject, text, html, err := Render(KindInvitation, data)
+	require.NoError(t, err)
+	assert.Equal(t, "【Elmo】「週次定例 <全体>」への招待", subject)
+	assert.Equal(t, `田中 さん
+
+佐藤 さんから会議「週次定例 <全体>」に招待されました。
+
+日時: 2024年1月1日 10:00（Asia/Tokyo）（30 分）
+
+参加する: https://elmo.example.com/rooms/r001
+
+--
+Elmo
+このお知らせが不要な場合は、次のURLから配信を停止できます。
+https://elmo.example.com/unsubscribe?token=abc&type=invitations
+`, text)
+	assert.Contains(t, html, "週次定例 &lt;全体&gt;")
+	assert.Contains(t, html, `href="https://elmo.example.com/rooms/r001"`)
+	assert.Contains(t, html, `href="https://elmo.example.com/unsubscribe?token=abc&amp;type=invitations"`)
+
+	_, text, _, err = Render(KindDigest, Data{RecipientName: "田中", Room: Room{Title: "週次定例"}})
+	require.NoError(t, err)
+	assert.Contains(t, text, "（結論は記録されていません）")
+	assert.NotContains(t, text, "AI の要約")
+
+	_, _, _, err = Render("unknown", data)
+	assert.Error(t, err)
+}
+
+func TestFileSender(t *testing.T) {
+	dir := t.TempDir()
+	s := &FileSender{Dir: filepath.Join(dir, "mail")}
+	require.NoError(t, s.Send(context.Background(), "a@example.com", "b@example.com", []byte("Subject: test\r\n\r\nbody")))
+
+	files, err := os.ReadDir(s.Dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), ".eml"))
+}
+
+// fakeSMTP は STARTTLS に対応していない、一通だけ受け取る SMTP サーバーです。
+func fakeSMTP(t *testing.T) (addr string, received <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				ch <- data.String()
+				reply("250 ok")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+func TestSMTPSender(t *testing.T) {
+	t.Run("STARTTLSが必須なら送らない", func(t *testing.T) {
+		addr, _ := fakeSMTP(t)
+		s := &SMTPSender{Addr: addr, RequireTLS: true}
+		err := s.Send(context.Background(), "a@example.com", "b@example.com", []byte("Subject: test\r\n\r\nbody\r\n"))
+		assert.ErrorIs(t, err, ErrNoStartTLS)
+	})
+	t.Run("任意なら平文で送る", func(t *testing.T) {
+		addr, received := fakeSMTP(t)
+		s := &SMTPSender{Addr: addr}
+		require.NoError(t, s.Send(context.Background(), "a@example.com", "b@example.com", []byte("Subject: test\r\n\r\nbody\r\n")))
+		select {
+		case data := <-received:
+			assert.Equal(t, "Subject: test\r\n\r\nbody\r\n", data)
+		case <-time.After(5 * time.Second):
+			t.Fatal("メールが届きませんでした")
+		}
+	})
+}
+
+type recordingSender struct {
+	to  []string
+	raw []string
+}
+
+func (s *recordingSender) Send(ctx context.Context, from, to string, raw []byte) error {
+	s.to = append(s.to, to)
+	s.raw = append(s.raw, string(raw))
+	return nil
+}
+
+func TestMailer_Digest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT r.title, r.description, r.scheduled_start_at`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "description", "scheduled_start_at", "duration_minutes", "time_zone", "conclusion"}).
+			AddRow("週次定例", "", nil, nil, "Asia/Tokyo", "来週リリースする"))
+	mock.ExpectQuery(`SELECT message FROM chat_logs WHERE room_id = \$1 AND is_summary`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"message"}).AddRow("リリース日を議論した"))
+	mock.ExpectQuery(`SELECT u.user_name, p.email, p.unsubscribe_token FROM notification_preferences p`).
+		WithArgs("r001", KindDigest, "").
+		WillReturnRows(sqlmock.NewRows([]string{"user_name", "email", "unsubscribe_token"}).
+			AddRow("田中", "tanaka@example.com", "tok1").
+			AddRow("佐藤", "sato@example.com", "tok2"))
+
+	sender := &recordingSender{}
+	m, err := NewMailer(db, sender, "Elmo <noreply@elmo.example.com>", "https://elmo.example.com/")
+	require.NoError(t, err)
+	require.NoError(t, m.Notify(context.Background(), notify.Event{Type: notify.TypeRoomDone, Room: notify.Room{ID: "r001"}}))
+	// 終了以外の出来事ではまとめを送らない
+	require.NoError(t, m.Notify(context.Background(), notify.Event{Type: notify.TypeRoomStarted, Room: notify.Room{ID: "r001"}}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, m.queue, 2)
+	for len(m.queue) > 0 {
+		require.NoError(t, m.send(context.Background(), <-m.queue))
+	}
+	assert.Equal(t, []string{"tanaka@example.com", "sato@example.com"}, sender.to)
+	assert.Contains(t, sender.raw[0], "List-Unsubscribe: <https://elmo.example.com/unsubscribe?token=tok1&type=digests>")
+}
+
+func TestMailer_Disabled(t *testing.T) {
+	m, err := NewMailer(nil, nil, "", "")
+	require.NoError(t, err)
+	assert.False(t, m.Enabled())
+	n, err := m.Invite(context.Background(), "r001", "u001", []string{"u002"})
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestMailer_OverdueActionItems(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`UPDATE action_items a SET reminded_at = NOW\(\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"assignee_id", "user_name", "email", "unsubscribe_token", "title", "due_date", "room_id", "room_title", "id"}).
+			AddRow("u001", "田中", "tanaka@example.com", "tok1", "議事録を共有する", "2024-01-31", "r001", "週次定例", "ai001").
+			AddRow("u002", "佐藤", "sato@example.com", "tok2", "見積もりを出す", "2024-01-20", nil, nil, "ai002").
+			AddRow("u001", "田中", "tanaka@example.com", "tok1", "テスト計画を共有する", "2024-01-10", "r001", "週次定例", "ai003"))
+
+	sender := &recordingSender{}
+	m, err := NewMailer(db, sender, "Elmo <noreply@elmo.example.com>", "https://elmo.example.com")
+	require.NoError(t, err)
+	require.NoError(t, m.SendOverdueActionItems(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// 担当者ごとに一通にまとめ、期限の古い順に並べる
+	require.Len(t, m.queue, 2)
+	msg := <-m.queue
+	assert.Equal(t, "【Elmo】期限を過ぎたアクションアイテムが 2 件あります", msg.Subject)
+	assert.Contains(t, msg.Text, `次の 2 件が期限を過ぎています。
+
+- テスト計画を共有する（期限: 2024-01-10・会議「週次定例」）
+  https://elmo.example.com/rooms/r001/result
+- 議事録を共有する（期限: 2024-01-31・会議「週次定例」）
+  https://elmo.example.com/rooms/r001/result
+
+終わったものは Elmo で完了にしてください。`)
+	assert.Equal(t, "https://elmo.example.com/unsubscribe?token=tok1&type=reminders", msg.UnsubscribeURL)
+	msg = <-m.queue
+	assert.Contains(t, msg.Text, "- 見積もりを出す（期限: 2024-01-20）\n")
+	assert.Contains(t, msg.HTML, "<li>見積もりを出す（期限: 2024-01-20）</li>")
+}
+
+func TestMailer_QueueFullUnmarksReminders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := NewMailer(db, &recordingSender{}, "Elmo <noreply@elmo.example.com>", "")
+	require.NoError(t, err)
+	// 空きが一通分しかないキュー
+	m.queue = make(chan Message, 1)
+
+	startAt := time.Now().Add(10 * time.Minute)
+	mock.ExpectQuery(`UPDATE rooms SET reminder_sent_at = NOW\(\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "scheduled_start_at"}).AddRow("r001", startAt))
+	mock.ExpectQuery(`SELECT r.title, r.description, r.scheduled_start_at`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "description", "scheduled_start_at", "duration_minutes", "time_zone", "conclusion"}).
+			AddRow("週次定例", "", startAt, 30, "Asia/Tokyo", ""))
+	mock.ExpectQuery(`SELECT u.user_name, p.email, p.unsubscribe_token FROM notification_preferences p`).
+		WithArgs("r001", KindReminder, "").
+		WillReturnRows(sqlmock.NewRows([]string{"user_name", "email", "unsubscribe_token"}).
+			AddRow("田中", "tanaka@example.com", "tok1").
+			AddRow("佐藤", "sato@example.com", "tok2"))
+	// 全員分を積めないため一通も積まず、次回に送り直す
+	mock.ExpectExec(`UPDATE rooms SET reminder_sent_at = NULL WHERE id = \$1`).WithArgs("r001").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = m.SendReminders(context.Background())
+	assert.ErrorIs(t, err, errQueueFull)
+	assert.Empty(t, m.queue)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMailer_QueueFullUnmarksOverdueActionItems(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := NewMailer(db, &recordingSender{}, "Elmo <noreply@elmo.example.com>", "")
+	require.NoError(t, err)
+	m.queue = make(chan Message, 1)
+
+	mock.ExpectQuery(`UPDATE action_items a SET reminded_at = NOW\(\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"assignee_id", "user_name", "email", "unsubscribe_token", "title", "due_date", "room_id", "room_title", "id"}).
+			AddRow("u001", "田中", "tanaka@example.com", "tok1", "議事録を共有する", "2024-01-31", nil, nil, "ai001").
+			AddRow("u002", "佐藤", "sato@example.com", "tok2", "見積もりを出す", "2024-01-20", nil, nil, "ai002").
+			AddRow("u002", "佐藤", "sato@example.com", "tok2", "テスト計画を共有する", "2024-01-10", nil, nil, "ai003"))
+	// 二人目の担当者の分は積めないため、そのアイテムだけ次回に送り直す
+	mock.ExpectExec(`UPDATE action_items SET reminded_at = NULL WHERE id = \$1`).WithArgs("ai002").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE action_items SET reminded_at = NULL WHERE id = \$1`).WithArgs("ai003").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = m.SendOverdueActionItems(context.Background())
+	assert.ErrorIs(t, err, errQueueFull)
+	require.Len(t, m.queue, 1)
+	assert.Contains(t, (<-m.queue).To, "<tanaka@example.com>")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,458 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+)
+
+// メールの既定の設定
+const (
+	// 開始予定日時のどれだけ前にリマインダーを送るか
+	DefaultReminderBefore = 15 * time.Minute
+	// 送信待ちのメールの上限。超えた分は捨てる
+	queueSize = 1024
+)
+
+// errQueueFull は送信待ちのメールが多すぎて積めなかった場合のエラーです。
+var errQueueFull = errors.New("送信待ちのメールが多すぎます")
+
+// Mailer は通知の設定でメールを受け取るユーザーに、招待・リマインダー・会議後のまとめと、
+// 期限を過ぎたアクションアイテムのリマインダーを送ります。
+// 送信はメモリ上のキューから Run が順に行うため、リクエストを待たせません。
+// Sender が設定されていない場合は何も送りません。
+type Mailer struct {
+	db             *sql.DB
+	sender         Sender
+	from           *netmail.Address
+	baseURL        string
+	reminderBefore time.Duration
+	interval       time.Duration
+	queue          chan Message
+	// 一件の通知で送るメールをまとめて積むため、空きの確認と積む処理を排他にする
+	queueMu sync.Mutex
+	now     func() time.Time
+}
+
+// NewMailer は from（例: "Elmo <noreply@example.com>"）を送信元にした Mailer を作ります。
+// baseURL は会議室へのリンクと配信停止のURLに使います。sender が nil の場合はメールを送りません。
+func NewMailer(db *sql.DB, sender Sender, from, baseURL string) (*Mailer, error) {
+	m := &Mailer{
+		db:             db,
+		sender:         sender,
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		reminderBefore: DefaultReminderBefore,
+		interval:       time.Minute,
+		queue:          make(chan Message, queueSize),
+		now:            time.Now,
+	}
+	if sender != nil {
+		addr, err := netmail.ParseAddress(from)
+		if err != nil {
+			return nil, fmt.Errorf("送信元のアドレスが不正です: %w", err)
+		}
+		m.from = addr
+	}
+	return m, nil
+}
+
+// NewMailerFromEnv は環境変数から Mailer を作ります。
+//   - MAIL_FILE_DIR: 送らずに .eml ファイルを書き出すディレクトリ（開発用。SMTP_ADDR より優先）
+//   - SMTP_ADDR / SMTP_USERNAME / SMTP_PASSWORD: SMTP サーバー（host:port）と認証情報
+//   - SMTP_STARTTLS: required（既定）なら STARTTLS に対応していないサーバーには送らない。optional なら平文でも送る
+//   - MAIL_FROM: 送信元のアドレス（送信する場合は必須）
+//   - MAIL_REMINDER_BEFORE: 開始予定日時のどれだけ前にリマインダーを送るか（既定 15m）
+//
+// MAIL_FILE_DIR と SMTP_ADDR がどちらも未設定の場合はメールを送りません。
+func NewMailerFromEnv(db *sql.DB, baseURL string) (*Mailer, error) {
+	var sender Sender
+	if dir := os.Getenv("MAIL_FILE_DIR"); dir != "" {
+		sender = &FileSender{Dir: dir}
+	} else if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		starttls := os.Getenv("SMTP_STARTTLS")
+		if starttls != "" && starttls != "required" && starttls != "optional" {
+			return nil, fmt.Errorf("SMTP_STARTTLS は required か optional で指定してください: %q", starttls)
+		}
+		sender = &SMTPSender{
+			Addr:       addr,
+			Username:   os.Getenv("SMTP_USERNAME"),
+			Password:   os.Getenv("SMTP_PASSWORD"),
+			RequireTLS: starttls != "optional",
+		}
+	}
+	m, err := NewMailer(db, sender, os.Getenv("MAIL_FROM"), baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if v := os.Getenv("MAIL_REMINDER_BEFORE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("MAIL_REMINDER_BEFORE が不正です: %q", v)
+		}
+		m.reminderBefore = d
+	}
+	return m, nil
+}
+
+// Enabled はメールを送るかどうかを返します。
+func (m *Mailer) Enabled() bool {
+	return m.sender != nil
+}
+
+// NewUnsubscribeToken は配信停止のURLに含めるトークンを生成します。
+func NewUnsubscribeToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Run は ctx がキャンセルされるまで、積まれたメールを送り、定期的にリマインダーとアクションアイテムのリマインダーを積みます。
+func (m *Mailer) Run(ctx context.Context) {
+	if !m.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-m.queue:
+			if err := m.send(ctx, msg); err != nil {
+				log.Printf("メールの送信に失敗しました: to=%s, subject=%s, err=%v", msg.To, msg.Subject, err)
+			}
+		case <-ticker.C:
+			if err := m.SendReminders(ctx); err != nil {
+				log.Printf("リマインダーの送信に失敗しました: %v", err)
+			}
+			if err := m.SendOverdueActionItems(ctx); err != nil {
+				log.Printf("アクションアイテムのリマインダーの送信に失敗しました: %v", err)
+			}
+		}
+	}
+}
+
+func (m *Mailer) send(ctx context.Context, msg Message) error {
+	raw, err := Build(msg, m.now())
+	if err != nil {
+		return err
+	}
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+	return m.sender.Send(ctx, m.from.Address, to.Address, raw)
+}
+
+type recipient struct {
+	name, email, token string
+}
+
+// recipients は会議室のホスト・参加者・招待されたユーザーのうち、kind のメールを受け取る設定のユーザーを返します。
+// userID を指定した場合はそのユーザーだけに絞ります。
+func (m *Mailer) recipients(ctx context.Context, roomID, kind, userID string) ([]recipient, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT u.user_name, p.email, p.unsubscribe_token
+		FROM notification_preferences p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.email IS NOT NULL
+		  AND CASE $2 WHEN 'invitations' THEN p.invitations WHEN 'reminders' THEN p.reminders ELSE p.digests END
+		  AND ($3 = '' OR u.id = $3)
+		  AND u.id IN (
+			SELECT created_by FROM rooms WHERE id = $1
+			UNION SELECT user_id FROM participants WHERE room_id = $1
+			UNION SELECT user_id FROM room_invitations WHERE room_id = $1)
+		ORDER BY u.id`, roomID, kind, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []recipient
+	for rows.Next() {
+		var r recipient
+		if err := rows.Scan(&r.name, &r.email, &r.token); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+type roomDetail struct {
+	room       Room
+	conclusion string
+}
+
+// loadRoom はメールに載せる会議室の情報を読み込みます。
+func (m *Mailer) loadRoom(ctx context.Context, roomID string) (roomDetail, error) {
+	var d roomDetail
+	var startAt sql.NullTime
+	var duration sql.NullInt64
+	var timeZone string
+	err := m.db.QueryRowContext(ctx, `
+		SELECT r.title, r.description, r.scheduled_start_at, r.duration_minutes, r.time_zone,
+			COALESCE(r.conclusion, '')
+		FROM rooms r WHERE r.id = $1`, roomID).
+		Scan(&d.room.Title, &d.room.Description, &startAt, &duration, &timeZone, &d.conclusion)
+	if err != nil {
+		return d, err
+	}
+	d.room.ID = roomID
+	if startAt.Valid {
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			loc = time.UTC
+		}
+		d.room.StartAt = startAt.Time.In(loc)
+	}
+	if duration.Valid {
+		d.room.DurationMinutes = int(duration.Int64)
+	}
+	if m.baseURL != "" {
+		d.room.URL = m.baseURL + "/rooms/" + url.PathEscape(roomID)
+	}
+	return d, nil
+}
+
+// enqueue は受け取る設定のユーザーそれぞれにメールを積み、積んだ数を返します。
+// 全員分を積めない場合は一通も積まずに errQueueFull を返します。
+func (m *Mailer) enqueue(ctx context.Context, roomID, kind, userID string, data Data) (int, error) {
+	list, err := m.recipients(ctx, roomID, kind, userID)
+	if err != nil {
+		return 0, err
+	}
+	msgs := make([]Message, 0, len(list))
+	for _, r := range list {
+		msg, err := m.message(r, kind, kind, data)
+		if err != nil {
+			return 0, err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := m.push(msgs...); err != nil {
+		return 0, err
+	}
+	return len(msgs), nil
+}
+
+// message は name のテンプレートで一人宛てのメールを作ります。配信停止のリンクは kind の種類を止めます。
+func (m *Mailer) message(r recipient, kind, name string, data Data) (Message, error) {
+	data.RecipientName = r.name
+	data.UnsubscribeURL = ""
+	if m.baseURL != "" {
+		data.UnsubscribeURL = m.baseURL + "/unsubscribe?" + url.Values{"token": {r.token}, "type": {kind}}.Encode()
+	}
+	subject, text, html, err := Render(name, data)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		From:           m.from.String(),
+		To:             (&netmail.Address{Name: r.name, Address: r.email}).String(),
+		Subject:        subject,
+		Text:           text,
+		HTML:           html,
+		UnsubscribeURL: data.UnsubscribeURL,
+	}, nil
+}
+
+// push はメールをまとめてキューに積みます。キューに全件分の空きがない場合は一通も積まずに errQueueFull を返します。
+func (m *Mailer) push(msgs ...Message) error {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	if cap(m.queue)-len(m.queue) < len(msgs) {
+		return errQueueFull
+	}
+	for _, msg := range msgs {
+		m.queue <- msg
+	}
+	return nil
+}
+
+// Invite は招待したユーザーのうち、招待のメールを受け取る設定のユーザーにメールを積み、積んだ数を返します。
+// 招待（room_invitations）は呼び出し元で記録しておいてください。
+func (m *Mailer) Invite(ctx context.Context, roomID, invitedBy string, userIDs []string) (int, error) {
+	if !m.Enabled() {
+		return 0, nil
+	}
+	d, err := m.loadRoom(ctx, roomID)
+	if err != nil {
+		return 0, err
+	}
+	var inviterName string
+	err = m.db.QueryRowContext(ctx, `SELECT user_name FROM users WHERE id = $1`, invitedBy).Scan(&inviterName)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	total := 0
+	for _, userID := range userIDs {
+		n, err := m.enqueue(ctx, roomID, KindInvitation, userID, Data{Room: d.room, InvitedBy: inviterName})
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// SendReminders は開始予定日時が近い会議室のリマインダーを積みます。会議室ごとに一度だけ送ります。
+// 積めなかった会議室は送信済みの印を戻し、次回に送り直します。
+func (m *Mailer) SendReminders(ctx context.Context) error {
+	rows, err := m.db.QueryContext(ctx, `
+		UPDATE rooms SET reminder_sent_at = NOW()
+		WHERE status = 'not started' AND reminder_sent_at IS NULL
+		  AND scheduled_start_at > NOW() AND scheduled_start_at <= NOW() + make_interval(secs => $1)
+		RETURNING id, scheduled_start_at`, m.reminderBefore.Seconds())
+	if err != nil {
+		return err
+	}
+	type upcoming struct {
+		id      string
+		startAt time.Time
+	}
+	var rooms []upcoming
+	for rows.Next() {
+		var u upcoming
+		if err := rows.Scan(&u.id, &u.startAt); err != nil {
+			rows.Close()
+			return err
+		}
+		rooms = append(rooms, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, r := range rooms {
+		if err := m.remind(ctx, r.id, r.startAt); err != nil {
+			errs = append(errs, fmt.Errorf("room %s: %w", r.id, err))
+			// 積めなかった会議室は送信済みの印を戻し、次回に送り直す
+			if _, err := m.db.ExecContext(ctx, `UPDATE rooms SET reminder_sent_at = NULL WHERE id = $1`, r.id); err != nil {
+				errs = append(errs, fmt.Errorf("room %s: %w", r.id, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// remind は一つの会議室のリマインダーを積みます。
+func (m *Mailer) remind(ctx context.Context, roomID string, startAt time.Time) error {
+	d, err := m.loadRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	minutes := int((startAt.Sub(m.now()) + time.Minute - 1) / time.Minute)
+	_, err = m.enqueue(ctx, roomID, KindReminder, "", Data{Room: d.room, MinutesBefore: max(minutes, 1)})
+	return err
+}
+
+// SendOverdueActionItems は期限を過ぎて未完了のアクションアイテムを、担当者ごとに一通にまとめて積みます。
+// 開始前のリマインダーを受け取る設定の担当者に、同じアイテムについては一日に一度だけ送ります。
+// 積めなかった担当者のアイテムは送信済みの印を戻し、次回に送り直します。
+func (m *Mailer) SendOverdueActionItems(ctx context.Context) error {
+	rows, err := m.db.QueryContext(ctx, `
+		UPDATE action_items a SET reminded_at = NOW()
+		FROM notification_preferences p JOIN users u ON u.id = p.user_id
+		WHERE p.user_id = a.assignee_id AND p.email IS NOT NULL AND p.reminders
+		  AND a.status = 'open'
+		  AND a.due_date < (NOW() AT TIME ZONE COALESCE((SELECT time_zone FROM rooms WHERE id = a.room_id), 'UTC'))::date
+		  AND (a.reminded_at IS NULL OR a.reminded_at <= NOW() - INTERVAL '1 day')
+		RETURNING a.assignee_id, u.user_name, p.email, p.unsubscribe_token,
+			a.title, to_char(a.due_date, 'YYYY-MM-DD'), a.room_id, (SELECT title FROM rooms WHERE id = a.room_id), a.id`)
+	if err != nil {
+		return err
+	}
+	type assignee struct {
+		recipient
+		items   []ActionItem
+		itemIDs []string
+	}
+	var order []string
+	assignees := make(map[string]*assignee)
+	for rows.Next() {
+		var userID, itemID string
+		var r recipient
+		var item ActionItem
+		var roomID, roomTitle sql.NullString
+		if err := rows.Scan(&userID, &r.name, &r.email, &r.token, &item.Title, &item.DueDate, &roomID, &roomTitle, &itemID); err != nil {
+			rows.Close()
+			return err
+		}
+		item.RoomTitle = roomTitle.String
+		if roomID.Valid && m.baseURL != "" {
+			item.URL = m.baseURL + "/rooms/" + url.PathEscape(roomID.String) + "/result"
+		}
+		a, ok := assignees[userID]
+		if !ok {
+			a = &assignee{recipient: r}
+			assignees[userID] = a
+			order = append(order, userID)
+		}
+		a.items = append(a.items, item)
+		a.itemIDs = append(a.itemIDs, itemID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, userID := range order {
+		a := assignees[userID]
+		sort.SliceStable(a.items, func(i, j int) bool { return a.items[i].DueDate < a.items[j].DueDate })
+		data := Data{Room: Room{Title: "期限を過ぎたアクションアイテム"}, ActionItems: a.items}
+		msg, err := m.message(a.recipient, KindReminder, templateOverdueActionItems, data)
+		if err == nil {
+			err = m.push(msg)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", userID, err))
+			for _, id := range a.itemIDs {
+				if _, err := m.db.ExecContext(ctx, `UPDATE action_items SET reminded_at = NULL WHERE id = $1`, id); err != nil {
+					errs = append(errs, fmt.Errorf("action item %s: %w", id, err))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Notify は会議室が終了したときに、結論と最後の AI の要約をまとめたメールを積みます。
+func (m *Mailer) Notify(ctx context.Context, e notify.Event) error {
+	if !m.Enabled() || e.Type != notify.TypeRoomDone {
+		return nil
+	}
+	d, err := m.loadRoom(ctx, e.Room.ID)
+	if err != nil {
+		return err
+	}
+	var summary string
+	err = m.db.QueryRowContext(ctx, `
+		SELECT message FROM chat_logs WHERE room_id = $1 AND is_summary ORDER BY created_at DESC LIMIT 1`, e.Room.ID).Scan(&summary)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	data := Data{Room: d.room, Conclusion: d.conclusion, Summary: summary}
+	if d.room.URL != "" {
+		data.ResultURL = d.room.URL + "/result"
+	}
+	_, err = m.enqueue(ctx, e.Room.ID, KindDigest, "", data)
+	return err
+}
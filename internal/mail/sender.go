@@ -0,0 +1,104 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNoStartTLS は STARTTLS が必須なのにサーバーが対応していない場合のエラーです。
+var ErrNoStartTLS = errors.New("smtp server does not support STARTTLS")
+
+// SMTPSender は SMTP サーバーにメールを渡します。
+// サーバーが STARTTLS に対応していれば必ず使い、RequireTLS の場合は対応していないサーバーには送りません。
+type SMTPSender struct {
+	Addr       string // host:port
+	Username   string
+	Password   string
+	RequireTLS bool
+	Timeout    time.Duration
+}
+
+func (s *SMTPSender) Send(ctx context.Context, from, to string, raw []byte) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	} else if s.RequireTLS {
+		return ErrNoStartTLS
+	}
+	if s.Username != "" {
+		// PlainAuth は TLS のない接続では localhost 以外に認証情報を送らない
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// FileSender はメールを送らずに、一通ずつ .eml ファイルとして Dir に書き出します。開発用です。
+type FileSender struct {
+	Dir string
+}
+
+func (s *FileSender) Send(ctx context.Context, from, to string, raw []byte) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(b))
+	return os.WriteFile(filepath.Join(s.Dir, name), raw, 0o644)
+}
@@ -0,0 +1,117 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+)
+
+// メールの種類。通知の設定の項目と同じです
+const (
+	KindInvitation = "invitations"
+	KindReminder   = "reminders"
+	KindDigest     = "digests"
+)
+
+// Kinds はメールの種類の一覧です。
+var Kinds = []string{KindInvitation, KindReminder, KindDigest}
+
+// 期限を過ぎたアクションアイテムのリマインダーのテンプレート。通知の設定は reminders に従います
+const templateOverdueActionItems = "overdue_action_items"
+
+// Room はメールに載せる会議室の情報です。
+type Room struct {
+	ID          string
+	Title       string
+	Description string
+	// 開始予定日時（会議室のタイムゾーン）。予定がない場合はゼロ値
+	StartAt         time.Time
+	DurationMinutes int
+	URL             string
+}
+
+// StartText は開始予定日時を会議室のタイムゾーンで表示します。
+func (r Room) StartText() string {
+	if r.StartAt.IsZero() {
+		return ""
+	}
+	return r.StartAt.Format("2006年1月2日 15:04") + "（" + r.StartAt.Location().String() + "）"
+}
+
+// ActionItem はメールに載せるアクションアイテムです。
+type ActionItem struct {
+	Title     string
+	DueDate   string
+	RoomTitle string
+	// 作成元の会議室のリザルトのURL
+	URL string
+}
+
+// Data はメールのテンプレートに渡す内容です。種類によって使う項目が異なります。
+type Data struct {
+	RecipientName string
+	Room          Room
+	// 招待したユーザーの名前（招待）
+	InvitedBy string
+	// 開始までの分数（リマインダー）
+	MinutesBefore int
+	// 結論と最後の AI の要約（まとめ）
+	Conclusion string
+	Summary    string
+	ResultURL  string
+	// 期限を過ぎたアクションアイテム（アクションアイテムのリマインダー）
+	ActionItems []ActionItem
+	// 配信停止のURL
+	UnsubscribeURL string
+}
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+type template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// button は HTML のテンプレートでボタンのリンクを作ります。
+func button(label, url string) map[string]string {
+	return map[string]string{"Label": label, "URL": url}
+}
+
+// templates は種類ごとのテンプレートに、共通のヘッダーとフッター（footer.*.tmpl）を加えたものです。
+var templates = func() map[string]template {
+	m := make(map[string]template)
+	for _, kind := range append(Kinds, templateOverdueActionItems) {
+		m[kind] = template{
+			text: texttemplate.Must(texttemplate.ParseFS(templateFS,
+				"templates/"+kind+".txt.tmpl", "templates/footer.txt.tmpl")),
+			html: htmltemplate.Must(htmltemplate.New(kind+".html.tmpl").
+				Funcs(htmltemplate.FuncMap{"button": button}).
+				ParseFS(templateFS, "templates/"+kind+".html.tmpl", "templates/footer.html.tmpl")),
+		}
+	}
+	return m
+}()
+
+// Render は種類ごとのテンプレートで件名と本文（テキストと HTML）を作ります。
+// 件名はテキストのテンプレートの "subject" です。
+func Render(kind string, data Data) (subject, text, html string, err error) {
+	t, ok := templates[kind]
+	if !ok {
+		return "", "", "", fmt.Errorf("unknown mail kind: %s", kind)
+	}
+	var s, tb, hb bytes.Buffer
+	if err := t.text.ExecuteTemplate(&s, "subject", data); err != nil {
+		return "", "", "", err
+	}
+	if err := t.text.Execute(&tb, data); err != nil {
+		return "", "", "", err
+	}
+	if err := t.html.Execute(&hb, data); err != nil {
+		return "", "", "", err
+	}
+	return s.String(), tb.String(), hb.String(), nil
+}
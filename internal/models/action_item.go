@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// ActionItem 会議で決まったアクションアイテム。会議室が終わっても追跡できます
+type ActionItem struct {
+	ID           string     `json:"id" example:"V1StGXR8_Z5jdHi6B-myT" description:"アクションアイテムのID"`
+	RoomID       *string    `json:"room_id,omitempty" example:"abc123" description:"作成元の会議室ID（会議室が削除された場合は省略）"`
+	RoomTitle    *string    `json:"room_title,omitempty" example:"週次ミーティング" description:"作成元の会議室のタイトル"`
+	MessageID    *string    `json:"message_id,omitempty" example:"V1StGXR8_Z5jdHi6B-myT" description:"作成元のメッセージID"`
+	Title        string     `json:"title" example:"テスト計画を共有する" description:"やること"`
+	AssigneeID   *string    `json:"assignee_id,omitempty" example:"user123" description:"担当者のユーザーID"`
+	AssigneeName *string    `json:"assignee_name,omitempty" example:"田中" description:"担当者の名前"`
+	DueDate      *string    `json:"due_date,omitempty" example:"2024-01-31" description:"期限（YYYY-MM-DD）"`
+	Status       string     `json:"status" example:"open" description:"状態（open / done / canceled）"`
+	Source       string     `json:"source" example:"manual" description:"作成の方法（manual: 手動 / ai: AI の要約から抽出）"`
+	Overdue      bool       `json:"overdue" example:"false" description:"期限を過ぎて未完了か（会議室のタイムゾーンの日付で判定）"`
+	CreatedBy    *string    `json:"created_by,omitempty" example:"user123" description:"作成したユーザーのID"`
+	CreatedAt    time.Time  `json:"created_at" example:"2024-01-01T10:00:00Z" description:"作成日時"`
+	UpdatedAt    time.Time  `json:"updated_at" example:"2024-01-01T10:00:00Z" description:"更新日時"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty" example:"2024-01-05T10:00:00Z" description:"完了・取り消しの日時"`
+}
+
+// ActionItemRequest アクションアイテムの作成リクエスト
+type ActionItemRequest struct {
+	UserID     string  `json:"user_id" example:"user123" description:"作成するユーザーのID（認証情報がない場合は必須）"`
+	Title      string  `json:"title" example:"テスト計画を共有する" description:"やること（最大200文字。message_id を指定した場合は省略するとメッセージの本文）"`
+	AssigneeID *string `json:"assignee_id,omitempty" example:"user456" description:"担当者のユーザーID"`
+	DueDate    *string `json:"due_date,omitempty" example:"2024-01-31" description:"期限（YYYY-MM-DD）"`
+	MessageID  *string `json:"message_id,omitempty" example:"V1StGXR8_Z5jdHi6B-myT" description:"作成元のメッセージID（会議室のメッセージのみ）"`
+}
+
+// ActionItemUpdateRequest アクションアイテムの更新リクエスト。省略した項目は変更しません
+type ActionItemUpdateRequest struct {
+	UserID     string  `json:"user_id" example:"user123" description:"更新するユーザーのID（認証情報がない場合は必須）"`
+	Title      *string `json:"title,omitempty" example:"テスト計画をレビューする" description:"やること"`
+	AssigneeID *string `json:"assignee_id,omitempty" example:"user456" description:"担当者のユーザーID（空文字で担当者なし）"`
+	DueDate    *string `json:"due_date,omitempty" example:"2024-02-07" description:"期限（YYYY-MM-DD。空文字で期限なし）"`
+	Status     *string `json:"status,omitempty" example:"done" description:"状態（open / done / canceled）"`
+}
+
+// ExtractActionItemsRequest AI の要約からのアクションアイテムの抽出リクエスト
+type ExtractActionItemsRequest struct {
+	UserID    string  `json:"user_id" example:"user123" description:"抽出するホストのユーザーID（認証情報がない場合は必須）"`
+	MessageID *string `json:"message_id,omitempty" example:"V1StGXR8_Z5jdHi6B-myT" description:"抽出元の AI の要約のメッセージID（省略すると最新の要約）"`
+}
+
+// ActionItemCandidate AI が要約から抽出したアクションアイテムの候補
+type ActionItemCandidate struct {
+	Title    string `json:"title"`
+	Assignee string `json:"assignee"`
+	Due      string `json:"due"`
+}
+
+// ActionItemList アクションアイテムの一覧
+type ActionItemList struct {
+	Items []ActionItem `json:"items" description:"アクションアイテム（期限の近い順。期限なしは最後）"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// AgendaItemRequest 議題の追加リクエスト
+type AgendaItemRequest struct {
+	UserID         string `json:"user_id" example:"user123" description:"追加するユーザー（ホスト）のID"`
+	Title          string `json:"title" example:"来期の採用計画" description:"議題のタイトル"`
+	Description    string `json:"description" example:"エンジニア採用の人数と時期を決める" description:"議題の説明"`
+	TimeboxMinutes *int   `json:"timebox_minutes,omitempty" example:"15" description:"割り当て時間（分）。省略時は時間制限なし"`
+}
+
+// AgendaOrderRequest 議題の並べ替えリクエスト
+type AgendaOrderRequest struct {
+	UserID  string   `json:"user_id" example:"user123" description:"並べ替えるユーザー（ホスト）のID"`
+	ItemIDs []string `json:"item_ids" description:"すべての議題のIDを新しい順番で並べたもの"`
+}
+
+// AdvanceAgendaRequest 議題を進めるリクエスト
+type AdvanceAgendaRequest struct {
+	UserID string `json:"user_id" example:"user123" description:"進行するユーザー（ホスト）のID"`
+	ItemID string `json:"item_id,omitempty" example:"V1StGXR8_Z5jdHi6B-myT" description:"移る議題のID。省略時は次の議題に進みます"`
+}
+
+// AgendaConclusionRequest 議題ごとの結論の保存リクエスト
+type AgendaConclusionRequest struct {
+	UserID     string `json:"user_id" example:"user123" description:"保存するユーザー（ホスト）のID"`
+	Conclusion string `json:"conclusion" example:"4月に2名採用する" description:"議題の結論"`
+}
+
+// AgendaItem 会議室の議題一件
+type AgendaItem struct {
+	ID              string     `json:"id" example:"V1StGXR8_Z5jdHi6B-myT" description:"議題のID"`
+	Position        int        `json:"position" example:"1" description:"順番（1始まり）"`
+	Title           string     `json:"title" example:"来期の採用計画" description:"議題のタイトル"`
+	Description     string     `json:"description" example:"エンジニア採用の人数と時期を決める" description:"議題の説明"`
+	TimeboxMinutes  *int       `json:"timebox_minutes,omitempty" example:"15" description:"割り当て時間（分）"`
+	InitialQuestion string     `json:"initial_question,omitempty" example:"採用で一番の課題は何ですか？" description:"AIが生成した議題の最初の問いかけ"`
+	Conclusion      string     `json:"conclusion,omitempty" example:"4月に2名採用する" description:"議題の結論"`
+	Current         bool       `json:"current" example:"true" description:"現在の議題かどうか"`
+	StartedAt       *time.Time `json:"started_at,omitempty" example:"2024-01-01T10:00:00Z" description:"議題を始めた日時"`
+	EndedAt         *time.Time `json:"ended_at,omitempty" example:"2024-01-01T10:15:00Z" description:"議題を終えた日時"`
+	DeadlineAt      *time.Time `json:"deadline_at,omitempty" example:"2024-01-01T10:15:00Z" description:"割り当て時間の終了予定日時（開始済みで時間制限がある場合のみ）"`
+	Overtime        bool       `json:"overtime" example:"false" description:"割り当て時間を超過しているか（超過したか）"`
+}
+
+// AgendaResponse 会議室の議題一覧
+type AgendaResponse struct {
+	RoomID        string       `json:"room_id" example:"room123" description:"会議室のID"`
+	CurrentItemID *string      `json:"current_item_id" example:"V1StGXR8_Z5jdHi6B-myT" description:"現在の議題のID（Null許容）"`
+	Items         []AgendaItem `json:"items" description:"議題の一覧（順番順）"`
+}
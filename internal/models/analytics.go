@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// RoomAnalytics 会議室の分析。発言の偏り・結論が出るまでの時間・発言の頻度を返します
+type RoomAnalytics struct {
+	RoomID                  string                `json:"room_id" example:"abc123" description:"会議室ID"`
+	Status                  string                `json:"status" example:"done" description:"会議室のステータス"`
+	StartedAt               *time.Time            `json:"started_at,omitempty" example:"2024-01-01T10:00:00Z" description:"開始日時"`
+	ConcludedAt             *time.Time            `json:"concluded_at,omitempty" example:"2024-01-01T10:40:00Z" description:"結論が初めて保存された日時"`
+	EndedAt                 *time.Time            `json:"ended_at,omitempty" example:"2024-01-01T11:00:00Z" description:"終了（done）した日時"`
+	DurationMinutes         *float64              `json:"duration_minutes,omitempty" example:"60" description:"開始から終了までの時間（分）"`
+	TimeToConclusionMinutes *float64              `json:"time_to_conclusion_minutes,omitempty" example:"40" description:"開始から結論が出るまでの時間（分）"`
+	MessageCount            int                   `json:"message_count" example:"120" description:"メッセージ数（AIの要約を除く）"`
+	SorenaCount             int                   `json:"sorena_count" example:"35" description:"それなの数"`
+	Gini                    float64               `json:"gini" example:"0.32" description:"参加者ごとのメッセージ数のジニ係数（0: 均等、1に近いほど一部の参加者に偏っている）"`
+	Participants            []ParticipantActivity `json:"participants,omitempty" description:"参加者ごとの発言（匿名モードの会議室では省略）"`
+	MessageRate             []MessageRateBucket   `json:"message_rate" description:"一定の間隔ごとのメッセージ数（開始から最後のメッセージまで）"`
+}
+
+// ParticipantActivity 参加者ごとの発言
+type ParticipantActivity struct {
+	UserID         string  `json:"user_id" example:"user123" description:"ユーザーID"`
+	UserName       string  `json:"user_name" example:"田中" description:"ユーザー名"`
+	MessageCount   int     `json:"message_count" example:"30" description:"メッセージ数"`
+	CharacterCount int     `json:"character_count" example:"1200" description:"メッセージの文字数"`
+	SorenaReceived int     `json:"sorena_received" example:"12" description:"メッセージに付いたそれなの数"`
+	SpeakingShare  float64 `json:"speaking_share" example:"0.25" description:"会議室のメッセージに占める割合"`
+}
+
+// MessageRateBucket 一定の間隔のメッセージ数
+type MessageRateBucket struct {
+	Start        time.Time `json:"start" example:"2024-01-01T10:00:00Z" description:"間隔の開始日時"`
+	MessageCount int       `json:"message_count" example:"8" description:"メッセージ数"`
+}
+
+// WeeklyTrend 週ごとの会議の傾向
+type WeeklyTrend struct {
+	WeekStart               string   `json:"week_start" example:"2024-01-01" description:"週の初め（月曜日。YYYY-MM-DD）"`
+	RoomsHeld               int      `json:"rooms_held" example:"5" description:"開始した会議の数"`
+	AvgDurationMinutes      *float64 `json:"avg_duration_minutes,omitempty" example:"45.5" description:"終了した会議の平均の長さ（分）"`
+	ConclusionRate          *float64 `json:"conclusion_rate,omitempty" example:"0.8" description:"結論が出た会議の割合"`
+	AvgTimeToConclusionMins *float64 `json:"avg_time_to_conclusion_minutes,omitempty" example:"30" description:"結論が出るまでの平均時間（分）"`
+	MessageCount            int      `json:"message_count" example:"420" description:"メッセージ数（AIの要約を除く）"`
+}
+
+// AnalyticsTrends 週ごとの会議の傾向
+type AnalyticsTrends struct {
+	TimeZone string        `json:"time_zone" example:"Asia/Tokyo" description:"週の区切りに使ったタイムゾーン"`
+	Weeks    []WeeklyTrend `json:"weeks" description:"週ごとの傾向（古い順。会議がない週も含みます）"`
+}
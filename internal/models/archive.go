@@ -0,0 +1,114 @@
+package models
+
+import "time"
+
+// ArchiveVersion 会議室アーカイブの形式のバージョン
+const ArchiveVersion = 1
+
+// 取り込み時に会議室IDが使われていた場合の扱い
+const (
+	ArchiveConflictRemap   = "remap"
+	ArchiveConflictFail    = "fail"
+	ArchiveConflictReplace = "replace"
+)
+
+// RoomArchive 会議室アーカイブ。会議室と参加者・議題・チャットログ（要約を含む）・リアクション・結論をまとめたもの
+type RoomArchive struct {
+	Version       int                   `json:"version" example:"1" description:"アーカイブの形式のバージョン"`
+	ExportedAt    time.Time             `json:"exported_at" example:"2024-01-01T12:00:00Z" description:"書き出した日時"`
+	Room          ArchiveRoom           `json:"room" description:"会議室"`
+	Users         []ArchiveUser         `json:"users" description:"参加者・発言者・リアクションしたユーザー・ホスト"`
+	Participants  []ArchiveParticipant  `json:"participants" description:"参加者（退出した人を含む）"`
+	ReactionTypes []ArchiveReactionType `json:"reaction_types" description:"使われたリアクションと会議室で使うリアクションの定義"`
+	Agenda        []ArchiveAgendaItem   `json:"agenda" description:"議題（順番順）"`
+	Messages      []ArchiveMessage      `json:"messages" description:"チャットログとAIの要約（投稿順）"`
+	Reactions     []ArchiveReaction     `json:"reactions" description:"メッセージへのリアクション"`
+}
+
+// ArchiveRoom アーカイブの会議室
+type ArchiveRoom struct {
+	ID                  string     `json:"id" example:"abc123" description:"書き出し元の会議室ID"`
+	Title               string     `json:"title" example:"週次ミーティング" description:"タイトル"`
+	Description         string     `json:"description" example:"今週の進捗確認と来週の計画" description:"説明"`
+	Conclusion          string     `json:"conclusion,omitempty" example:"来週までにプロトタイプを完成させる" description:"結論"`
+	Status              string     `json:"status" example:"done" description:"ステータス"`
+	InitialQuestion     string     `json:"initial_question,omitempty" example:"今日の議題について何か質問はありますか？" description:"最初の問いかけ"`
+	MaxParticipants     *int       `json:"max_participants,omitempty" example:"10" description:"参加者の上限"`
+	AllowGuests         bool       `json:"allow_guests" example:"false" description:"ゲストの参加を許可するか"`
+	Anonymous           bool       `json:"anonymous" example:"false" description:"匿名モードかどうか"`
+	CreatedBy           *string    `json:"created_by,omitempty" example:"user123" description:"ホストのユーザーID（users に含まれます）"`
+	ScheduledStartAt    *time.Time `json:"scheduled_start_at,omitempty" example:"2024-01-01T10:00:00+09:00" description:"開始予定日時"`
+	DurationMinutes     *int       `json:"duration_minutes,omitempty" example:"60" description:"会議の長さ（分）"`
+	TimeZone            string     `json:"time_zone" example:"Asia/Tokyo" description:"タイムゾーン"`
+	PromptVariant       string     `json:"prompt_variant" example:"default" description:"最初の問いかけのプロンプトの種類"`
+	Tags                []string   `json:"tags,omitempty" example:"開発,定例" description:"タグ"`
+	ReactionTypes       []string   `json:"reaction_types,omitempty" example:"sorena,agree" description:"会議室で使うリアクション（省略時は既定のリアクション）"`
+	CurrentAgendaItemID *string    `json:"current_agenda_item_id,omitempty" example:"V1StGXR8_Z5jdHi6B-myT" description:"現在の議題のID"`
+	StartedAt           *time.Time `json:"started_at,omitempty" example:"2024-01-01T10:00:00Z" description:"開始した日時"`
+	CreatedAt           time.Time  `json:"created_at" example:"2024-01-01T09:00:00Z" description:"作成日時"`
+}
+
+// ArchiveUser アーカイブのユーザー
+type ArchiveUser struct {
+	ID      string `json:"id" example:"user123" description:"書き出し元のユーザーID"`
+	Name    string `json:"name" example:"田中太郎" description:"表示名"`
+	IsGuest bool   `json:"is_guest" example:"false" description:"ゲストかどうか"`
+}
+
+// ArchiveParticipant アーカイブの参加者
+type ArchiveParticipant struct {
+	UserID     string     `json:"user_id" example:"user123" description:"ユーザーID"`
+	JoinedAt   time.Time  `json:"joined_at" example:"2024-01-01T10:00:00Z" description:"参加した日時"`
+	LeftAt     *time.Time `json:"left_at,omitempty" example:"2024-01-01T11:00:00Z" description:"退出した日時"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty" example:"2024-01-01T10:59:00Z" description:"最後に応答した日時"`
+}
+
+// ArchiveReactionType アーカイブのリアクションの定義
+type ArchiveReactionType struct {
+	Key   string `json:"key" example:"sorena" description:"リアクションのキー"`
+	Label string `json:"label" example:"それな" description:"表示名"`
+	Emoji string `json:"emoji" example:"🙌" description:"絵文字"`
+}
+
+// ArchiveAgendaItem アーカイブの議題
+type ArchiveAgendaItem struct {
+	ID              string     `json:"id" example:"V1StGXR8_Z5jdHi6B-myT" description:"書き出し元の議題ID"`
+	Position        int        `json:"position" example:"1" description:"順番"`
+	Title           string     `json:"title" example:"来期の採用計画" description:"タイトル"`
+	Description     string     `json:"description" example:"エンジニア採用の人数と時期を決める" description:"説明"`
+	TimeboxMinutes  *int       `json:"timebox_minutes,omitempty" example:"15" description:"割り当て時間（分）"`
+	InitialQuestion string     `json:"initial_question,omitempty" example:"採用で一番の課題は何ですか？" description:"問いかけ"`
+	Conclusion      string     `json:"conclusion,omitempty" example:"4月に2名採用する" description:"結論"`
+	StartedAt       *time.Time `json:"started_at,omitempty" example:"2024-01-01T10:00:00Z" description:"始めた日時"`
+	EndedAt         *time.Time `json:"ended_at,omitempty" example:"2024-01-01T10:15:00Z" description:"終えた日時"`
+}
+
+// ArchiveMessage アーカイブのチャットログ・AIの要約
+type ArchiveMessage struct {
+	ID           string    `json:"id" example:"V1StGXR8_Z5jdHi6B-myT" description:"書き出し元のメッセージID"`
+	UserID       *string   `json:"user_id,omitempty" example:"user123" description:"発言者のID（要約では省略）"`
+	Message      string    `json:"message" example:"良いアイデアですね" description:"メッセージ"`
+	IsSummary    bool      `json:"is_summary" example:"false" description:"AIの要約かどうか"`
+	AgendaItemID *string   `json:"agenda_item_id,omitempty" example:"V1StGXR8_Z5jdHi6B-myT" description:"投稿時の議題のID"`
+	CreatedAt    time.Time `json:"created_at" example:"2024-01-01T10:00:00Z" description:"投稿日時"`
+}
+
+// ArchiveReaction アーカイブのリアクション
+type ArchiveReaction struct {
+	MessageID string    `json:"message_id" example:"V1StGXR8_Z5jdHi6B-myT" description:"メッセージID"`
+	UserID    string    `json:"user_id" example:"user123" description:"リアクションしたユーザーのID"`
+	Type      string    `json:"type" example:"sorena" description:"リアクションのキー"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T10:01:00Z" description:"リアクションした日時"`
+}
+
+// ArchiveImportResult 会議室アーカイブの取り込み結果
+type ArchiveImportResult struct {
+	RoomID         string            `json:"room_id" example:"xyz789" description:"取り込んだ会議室のID"`
+	OriginalRoomID string            `json:"original_room_id" example:"abc123" description:"アーカイブの会議室ID"`
+	Remapped       bool              `json:"remapped" example:"true" description:"会議室IDが使われていたため、議題・メッセージを含めて新しいIDを割り当てたか"`
+	Replaced       bool              `json:"replaced" example:"false" description:"同じIDの会議室を置き換えたか"`
+	UserIDs        map[string]string `json:"user_ids" description:"アーカイブのユーザーIDから取り込み先のユーザーIDへの対応"`
+	Participants   int               `json:"participants" example:"5" description:"取り込んだ参加者の数"`
+	Messages       int               `json:"messages" example:"120" description:"取り込んだメッセージ（要約を含む）の数"`
+	Reactions      int               `json:"reactions" example:"48" description:"取り込んだリアクションの数"`
+}
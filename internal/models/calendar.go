@@ -0,0 +1,7 @@
+package models
+
+// CalendarTokenResponse カレンダーの購読用トークンの発行結果
+type CalendarTokenResponse struct {
+	Token string `json:"token" example:"cal_Zm9vYmFy..." description:"購読用のトークン（一度だけ返します。再発行すると以前のトークンは使えなくなります）"`
+	URL   string `json:"url" example:"/users/user123/calendar.ics?token=cal_Zm9vYmFy..." description:"カレンダーアプリに登録するURL"`
+}
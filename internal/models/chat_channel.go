@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ChatChannelRequest チャットツールへの投稿先の登録リクエスト
+type ChatChannelRequest struct {
+	UserID string   `json:"user_id" example:"user123" description:"登録するユーザーのID（認証情報がない場合は必須）"`
+	Kind   string   `json:"kind" example:"slack" description:"投稿先の種類（slack / mattermost）"`
+	URL    string   `json:"url" example:"https://hooks.slack.com/services/T000/B000/XXXX" description:"Incoming Webhook のURL"`
+	Scope  string   `json:"scope" example:"creator" description:"投稿する会議室の範囲（room: 一つの会議室 / creator: 自分がホストの会議室すべて）"`
+	RoomID *string  `json:"room_id,omitempty" example:"abc123" description:"scope が room の場合の会議室ID（ホストのみ）"`
+	Events []string `json:"events,omitempty" example:"conclusion.saved" description:"投稿する出来事の種類（room.started / summary.created / conclusion.saved。省略するとすべて）"`
+}
+
+// ChatChannel チャットツールへの投稿先。URL は秘密の情報のため、ホスト名までしか返しません
+type ChatChannel struct {
+	ID           string     `json:"id" example:"V1StGXR8_Z5jdHi6B-myT" description:"投稿先のID"`
+	Kind         string     `json:"kind" example:"slack" description:"投稿先の種類"`
+	URLHost      string     `json:"url_host" example:"hooks.slack.com" description:"Incoming Webhook のURLのホスト名"`
+	Scope        string     `json:"scope" example:"creator" description:"投稿する会議室の範囲（room / creator）"`
+	RoomID       *string    `json:"room_id,omitempty" example:"abc123" description:"scope が room の場合の会議室ID"`
+	CreatorID    *string    `json:"creator_id,omitempty" example:"user123" description:"scope が creator の場合のホストのユーザーID"`
+	Events       []string   `json:"events" example:"conclusion.saved" description:"投稿する出来事の種類（空の場合はすべて）"`
+	CreatedBy    string     `json:"created_by" example:"user123" description:"登録したユーザーのID"`
+	CreatedAt    time.Time  `json:"created_at" example:"2024-01-01T10:00:00Z" description:"登録日時"`
+	LastPostedAt *time.Time `json:"last_posted_at,omitempty" example:"2024-01-01T11:00:00Z" description:"最後に投稿に成功した日時"`
+	LastError    *string    `json:"last_error,omitempty" example:"unexpected status 404: no_service" description:"最後に投稿に失敗した理由（その後成功した場合は空）"`
+	LastErrorAt  *time.Time `json:"last_error_at,omitempty" example:"2024-01-01T11:00:00Z" description:"最後に投稿に失敗した日時"`
+}
@@ -1,5 +1,62 @@
 package models
 
-type ConclusionRequest struct {
-	Conclusion string `json:"conclusion"`
-}
\ No newline at end of file
+import "time"
+
+// DiffLine 結論の版の差分の一行
+type DiffLine struct {
+	Op   string `json:"op" example:"insert" description:"差分の種類（equal: 変更なし / insert: 追加 / delete: 削除）"`
+	Text string `json:"text" example:"来週までにプロトタイプを完成させる" description:"行の内容"`
+}
+
+// ConclusionVersion 会議の結論の版
+type ConclusionVersion struct {
+	Version      int        `json:"version" example:"2" description:"版（1 から順に増えます）"`
+	Conclusion   string     `json:"conclusion" example:"来週までにプロトタイプを完成させる" description:"結論"`
+	AuthorID     *string    `json:"author_id,omitempty" example:"user123" description:"保存したユーザーのID（匿名モードの会議室と、記録されていない場合は省略）"`
+	AuthorName   *string    `json:"author_name,omitempty" example:"田中" description:"保存したユーザーの名前"`
+	RevertedFrom *int       `json:"reverted_from,omitempty" example:"1" description:"以前の版に戻した場合の戻し元の版"`
+	CreatedAt    time.Time  `json:"created_at" example:"2024-01-01T10:30:00Z" description:"保存日時"`
+	Diff         []DiffLine `json:"diff" description:"一つ前の版からの行ごとの差分"`
+}
+
+// ConclusionApprover 結論の承認者
+type ConclusionApprover struct {
+	UserID          string     `json:"user_id" example:"user456" description:"承認者のユーザーID"`
+	UserName        string     `json:"user_name" example:"佐藤" description:"承認者の名前"`
+	Approved        bool       `json:"approved" example:"true" description:"最新の版を承認したか"`
+	ApprovedVersion *int       `json:"approved_version,omitempty" example:"2" description:"最後に承認した版"`
+	ApprovedAt      *time.Time `json:"approved_at,omitempty" example:"2024-01-01T10:40:00Z" description:"最後に承認した日時"`
+}
+
+// ConclusionApproval 結論の承認の状況
+type ConclusionApproval struct {
+	Required  bool                 `json:"required" example:"true" description:"承認者が登録されているか（登録されている場合は全員が最新の版を承認するまで done にできません）"`
+	Approved  bool                 `json:"approved" example:"false" description:"承認者全員が最新の版を承認したか（承認者がいない場合は true）"`
+	Approvers []ConclusionApprover `json:"approvers" description:"承認者"`
+}
+
+// ConclusionHistory 会議の結論の版の履歴
+type ConclusionHistory struct {
+	RoomID         string              `json:"room_id" example:"abc123" description:"会議室ID"`
+	CurrentVersion int                 `json:"current_version" example:"2" description:"最新の版（結論がない場合は 0）"`
+	Versions       []ConclusionVersion `json:"versions" description:"結論の版（新しい順）"`
+	Approval       ConclusionApproval  `json:"approval" description:"結論の承認の状況"`
+}
+
+// ConclusionRevertRequest 結論を以前の版に戻すリクエスト
+type ConclusionRevertRequest struct {
+	UserID  string `json:"user_id" example:"user123" description:"戻すユーザー（ホスト）のID（認証情報がない場合は必須）"`
+	Version int    `json:"version" example:"1" description:"戻す版"`
+}
+
+// ConclusionApproversRequest 結論の承認者の設定リクエスト
+type ConclusionApproversRequest struct {
+	UserID      string   `json:"user_id" example:"user123" description:"設定するユーザー（ホスト）のID（認証情報がない場合は必須）"`
+	ApproverIDs []string `json:"approver_ids" example:"user456,user789" description:"承認者のユーザーID（会議室のホスト・参加者・招待されたユーザー。最大20人。空にすると承認なしで done にできます）"`
+}
+
+// ConclusionApproveRequest 結論の承認リクエスト
+type ConclusionApproveRequest struct {
+	UserID  string `json:"user_id" example:"user456" description:"承認するユーザーのID（認証情報がない場合は必須）"`
+	Version int    `json:"version" example:"2" description:"承認する版。最新の版でない場合は承認できません"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// GuestJoinRequest ゲスト参加リクエスト
+type GuestJoinRequest struct {
+	Nickname string `json:"nickname" example:"ゲストA" description:"表示名（20文字以内）"`
+}
+
+// GuestJoinResponse ゲスト参加レスポンス
+type GuestJoinResponse struct {
+	RoomID           string    `json:"room_id" example:"room123" description:"会議室のID"`
+	UserID           string    `json:"user_id" example:"g7k2m9qx" description:"ゲストユーザーのID"`
+	Nickname         string    `json:"nickname" example:"ゲストA" description:"表示名"`
+	Token            string    `json:"token" description:"この会議室でのみ有効なゲストトークン（Authorization: Bearer で送信）"`
+	ExpiresAt        time.Time `json:"expires_at" example:"2024-01-01T22:00:00Z" description:"トークンの有効期限"`
+	Status           string    `json:"status" example:"joined" description:"参加結果（joined / waitlisted）"`
+	WaitlistPosition int       `json:"waitlist_position,omitempty" example:"2" description:"キャンセル待ちの順番（1始まり）"`
+}
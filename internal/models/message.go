@@ -0,0 +1,7 @@
+package models
+
+// MessageRequest チャットメッセージ投稿リクエスト
+type MessageRequest struct {
+	UserID  string `json:"user_id" example:"user123" description:"投稿するユーザーのID（ゲストの場合は省略可）"`
+	Message string `json:"message" example:"良いアイデアですね" description:"メッセージ本文"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// NotificationPreferences メールの通知の設定
+type NotificationPreferences struct {
+	Email       *string `json:"email" example:"tanaka@example.com" description:"通知を送るメールアドレス（未設定の場合はメールを送りません）"`
+	Invitations bool    `json:"invitations" example:"true" description:"会議室への招待を受け取るか"`
+	Reminders   bool    `json:"reminders" example:"true" description:"開始前のリマインダーを受け取るか"`
+	Digests     bool    `json:"digests" example:"true" description:"会議後のまとめ（結論と AI の要約）を受け取るか"`
+}
+
+// NotificationPreferencesRequest メールの通知の設定の更新リクエスト。省略した項目は変更しません
+type NotificationPreferencesRequest struct {
+	Email       *string `json:"email,omitempty" example:"tanaka@example.com" description:"通知を送るメールアドレス（空文字で削除）"`
+	Invitations *bool   `json:"invitations,omitempty" example:"true" description:"会議室への招待を受け取るか"`
+	Reminders   *bool   `json:"reminders,omitempty" example:"true" description:"開始前のリマインダーを受け取るか"`
+	Digests     *bool   `json:"digests,omitempty" example:"false" description:"会議後のまとめを受け取るか"`
+}
+
+// InvitationRequest 会議室への招待のリクエスト
+type InvitationRequest struct {
+	UserID  string   `json:"user_id" example:"user123" description:"招待するホストのユーザーID（認証情報がない場合は必須）"`
+	UserIDs []string `json:"user_ids" example:"user456,user789" description:"招待するユーザーのID（最大50人）"`
+}
+
+// InvitationResponse 会議室への招待の結果
+type InvitationResponse struct {
+	Invited []string `json:"invited" example:"user456,user789" description:"招待したユーザーのID（招待済みのユーザーを含む）"`
+	Emailed int      `json:"emailed" example:"1" description:"招待のメールを送ったユーザーの数（メールアドレスがない・受け取らない設定のユーザーには送りません）"`
+}
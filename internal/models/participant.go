@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Participant 参加者情報を表す構造体
 type Participant struct {
 	RoomID string `json:"room_id" example:"room123" description:"会議室のID"`
@@ -12,14 +14,60 @@ type ParticipantRequest struct {
 	UserID string `json:"user_id" example:"user123" description:"ユーザーのID"`
 }
 
+// LeaveRoomRequest 会議室からの退出リクエスト
+type LeaveRoomRequest struct {
+	UserID string `json:"user_id" example:"user123" description:"退出するユーザーのID（ゲストの場合は省略可）"`
+}
+
+// 参加者のプレゼンス状態
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
 // ParticipantUser 参加者ユーザー情報
 type ParticipantUser struct {
-	ID           string `json:"id" example:"user123" description:"ユーザーの一意のID"`
-	Name         string `json:"name" example:"田中太郎" description:"ユーザーの名前"`
+	ID       string     `json:"id" example:"user123" description:"ユーザーの一意のID"`
+	Name     string     `json:"name" example:"田中太郎" description:"ユーザーの名前"`
+	JoinedAt *time.Time `json:"joined_at,omitempty" example:"2024-01-01T10:00:00Z" description:"参加日時"`
+	LeftAt   *time.Time `json:"left_at,omitempty" example:"2024-01-01T11:00:00Z" description:"退出日時（退出済みの場合のみ）"`
+	Presence string     `json:"presence,omitempty" example:"online" description:"プレゼンス状態（online / away / offline）"`
+	IsGuest  bool       `json:"is_guest" example:"false" description:"ゲスト参加者かどうか"`
 }
 
 // ParticipantsResponse 参加者一覧レスポンス
 type ParticipantsResponse struct {
-	RoomID string           `json:"room_id" example:"room123" description:"会議室のID"`
+	RoomID string            `json:"room_id" example:"room123" description:"会議室のID"`
 	Users  []ParticipantUser `json:"users" description:"参加者ユーザーの一覧"`
 }
+
+// 参加リクエストの結果
+const (
+	JoinStatusJoined     = "joined"
+	JoinStatusWaitlisted = "waitlisted"
+)
+
+// JoinResponse 参加リクエストのレスポンス
+type JoinResponse struct {
+	RoomID           string `json:"room_id" example:"room123" description:"会議室のID"`
+	UserID           string `json:"user_id" example:"user123" description:"ユーザーのID"`
+	Status           string `json:"status" example:"waitlisted" description:"参加結果（joined / waitlisted）"`
+	WaitlistPosition int    `json:"waitlist_position,omitempty" example:"2" description:"キャンセル待ちの順番（1始まり）"`
+}
+
+// WaitlistEntry キャンセル待ち一件を表します
+type WaitlistEntry struct {
+	UserID   string    `json:"user_id" example:"user123" description:"ユーザーのID"`
+	Name     string    `json:"name" example:"田中太郎" description:"ユーザーの名前"`
+	Position int       `json:"position" example:"1" description:"キャンセル待ちの順番（1始まり）"`
+	QueuedAt time.Time `json:"queued_at" example:"2024-01-01T10:00:00Z" description:"キャンセル待ちに登録された日時"`
+}
+
+// WaitlistResponse キャンセル待ち一覧レスポンス
+type WaitlistResponse struct {
+	RoomID          string          `json:"room_id" example:"room123" description:"会議室のID"`
+	MaxParticipants *int            `json:"max_participants,omitempty" example:"10" description:"参加者の上限"`
+	Participants    int             `json:"participants" example:"10" description:"現在の参加者数"`
+	Entries         []WaitlistEntry `json:"entries" description:"キャンセル待ちの一覧（順番順）"`
+}
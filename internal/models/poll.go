@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// PollRequest 投票の作成リクエスト
+type PollRequest struct {
+	UserID    string     `json:"user_id" example:"user123" description:"作成するユーザー（ホスト）のID"`
+	Question  string     `json:"question" example:"次回の開催日はどれが良いですか？" description:"質問"`
+	Options   []string   `json:"options" example:"月曜,水曜,金曜" description:"選択肢（2〜10個）"`
+	Multiple  bool       `json:"multiple" example:"false" description:"複数選択を許可するか"`
+	Anonymous bool       `json:"anonymous" example:"false" description:"匿名投票にするか（誰がどれに投票したかを返しません）"`
+	ClosesAt  *time.Time `json:"closes_at,omitempty" example:"2024-01-01T10:30:00Z" description:"締め切り日時（省略時はホストが締め切るまで）"`
+}
+
+// VoteRequest 投票リクエスト。同じユーザーが再度投票すると前回の投票を置き換えます
+type VoteRequest struct {
+	UserID    string  `json:"user_id" example:"user123" description:"投票するユーザーのID（ゲストの場合は省略可）"`
+	OptionIDs []int64 `json:"option_ids" example:"1" description:"選ぶ選択肢のID（単一選択の場合は1つ）"`
+}
+
+// ClosePollRequest 投票の締め切りリクエスト
+type ClosePollRequest struct {
+	UserID string `json:"user_id" example:"user123" description:"締め切るユーザー（ホスト）のID"`
+}
+
+// PollOption 投票の選択肢と集計
+type PollOption struct {
+	ID     int64    `json:"id" example:"1" description:"選択肢のID"`
+	Label  string   `json:"label" example:"水曜" description:"選択肢"`
+	Votes  int      `json:"votes" example:"3" description:"得票数"`
+	Voters []string `json:"voters,omitempty" description:"投票したユーザーのID（匿名投票・匿名モードでは省略）"`
+}
+
+// Poll 会議室内の投票
+type Poll struct {
+	ID          string       `json:"id" example:"V1StGXR8_Z5jdHi6B-myT" description:"投票のID"`
+	RoomID      string       `json:"room_id" example:"room123" description:"会議室のID"`
+	Question    string       `json:"question" example:"次回の開催日はどれが良いですか？" description:"質問"`
+	Multiple    bool         `json:"multiple" example:"false" description:"複数選択を許可するか"`
+	Anonymous   bool         `json:"anonymous" example:"false" description:"匿名投票かどうか"`
+	CreatedBy   *string      `json:"created_by" example:"user123" description:"作成したユーザーのID（Null許容）"`
+	ClosesAt    *time.Time   `json:"closes_at,omitempty" example:"2024-01-01T10:30:00Z" description:"締め切り日時"`
+	Closed      bool         `json:"closed" example:"false" description:"締め切られているか"`
+	TotalVoters int          `json:"total_voters" example:"5" description:"投票した人数"`
+	Options     []PollOption `json:"options" description:"選択肢と集計"`
+	CreatedAt   time.Time    `json:"created_at" example:"2024-01-01T10:00:00Z" description:"作成日時"`
+}
@@ -0,0 +1,41 @@
+package models
+
+// 「それな」のリアクション種別キー
+const ReactionSorena = "sorena"
+
+// ReactionType リアクションの種類
+type ReactionType struct {
+	Key     string `json:"key" example:"agree" description:"リアクションの種類を表すキー（英小文字・数字・_）"`
+	Label   string `json:"label" example:"賛成" description:"表示名"`
+	Emoji   string `json:"emoji" example:"👍" description:"表示に使う絵文字"`
+	BuiltIn bool   `json:"built_in,omitempty" example:"true" description:"組み込みの種類かどうか"`
+}
+
+// RoomReactionTypesRequest 会議室で使うリアクションの種類の設定リクエスト
+type RoomReactionTypesRequest struct {
+	UserID string   `json:"user_id" example:"user123" description:"ホストのユーザーID（認証情報がない場合は必須）"`
+	Keys   []string `json:"keys" example:"sorena,agree,question" description:"使用するリアクションのキー（表示順）。空の場合は既定のセットに戻します"`
+}
+
+// ReactionToggleRequest メッセージへのリアクションの付け外しリクエスト
+type ReactionToggleRequest struct {
+	UserID string `json:"user_id" example:"user123" description:"ユーザーのID（ゲストの場合は省略可）"`
+}
+
+// ReactionToggleResponse メッセージへのリアクションの付け外しレスポンス
+type ReactionToggleResponse struct {
+	MessageID string `json:"message_id" example:"V1StGXR8_Z5jdHi6B-myT" description:"チャットメッセージのID"`
+	Type      string `json:"type" example:"agree" description:"リアクションの種類"`
+	Active    bool   `json:"active" example:"true" description:"リクエストしたユーザーがこのリアクションをしているか"`
+	Count     int    `json:"count" example:"4" description:"このメッセージのこの種類のリアクションの総数"`
+}
+
+// ReactionSummary リザルト画面のリアクション種類ごとの集計情報を表します
+type ReactionSummary struct {
+	Type         string              `json:"type" example:"agree" description:"リアクションの種類"`
+	Label        string              `json:"label" example:"賛成" description:"表示名"`
+	Emoji        string              `json:"emoji" example:"👍" description:"絵文字"`
+	TotalCount   int                 `json:"total_count" example:"15" description:"総数"`
+	Participants []SorenaParticipant `json:"participants,omitempty" description:"発言者ごとの集計（匿名モードでは省略）"`
+	TopMessages  []SorenaMessage     `json:"top_messages" description:"このリアクションが多かったメッセージのランキング"`
+}
@@ -4,35 +4,53 @@ import "time"
 
 // ResultRoomInfo リザルト画面の部屋情報を表します
 type ResultRoomInfo struct {
-	RoomID string `json:"room_id" example:"room123" description:"会議室のID"`
-	Title  string `json:"title" example:"週次ミーティング" description:"会議室のタイトル"`
+	RoomID    string `json:"room_id" example:"room123" description:"会議室のID"`
+	Title     string `json:"title" example:"週次ミーティング" description:"会議室のタイトル"`
+	Anonymous bool   `json:"anonymous" example:"false" description:"匿名モードかどうか（匿名モードでは発言者と個人別の集計を含みません）"`
 }
 
-// SorenaParticipant リザルト画面の参加者ごとの「それな」数を表します
+// SorenaParticipant リザルト画面の発言者ごとの、発言に付いた「それな」などのリアクション数を表します
 type SorenaParticipant struct {
 	UserID   string `json:"user_id" example:"user123" description:"ユーザーのID"`
 	UserName string `json:"user_name" example:"田中太郎" description:"ユーザーの名前"`
-	Count    int    `json:"count" example:"5" description:"「それな」の数"`
+	Count    int    `json:"count" example:"5" description:"このユーザーの発言に付いた「それな」の数"`
+	IsGuest  bool   `json:"is_guest" example:"false" description:"ゲスト参加者かどうか"`
+}
+
+// SorenaMessage リザルト画面の「それな」などのリアクションが多かったメッセージを表します
+type SorenaMessage struct {
+	LogID   string  `json:"log_id" example:"V1StGXR8_Z5jdHi6B-myT" description:"ログの一意のID"`
+	UserID  *string `json:"user_id" example:"user123" description:"発言者のID（Null許容）"`
+	Message string  `json:"message" example:"良いアイデアですね" description:"チャットメッセージ"`
+	Count   int     `json:"count" example:"4" description:"「それな」の数"`
 }
 
 // SorenaSummary リザルト画面の「それな」集計情報を表します
 type SorenaSummary struct {
 	TotalCount   int                 `json:"total_count" example:"15" description:"「それな」の総数"`
-	Participants []SorenaParticipant `json:"participants" description:"参加者ごとの「それな」集計"`
+	Participants []SorenaParticipant `json:"participants,omitempty" description:"発言者ごとの「それな」集計（匿名モードでは省略）"`
+	TopMessages  []SorenaMessage     `json:"top_messages" description:"「それな」が多かったメッセージのランキング"`
 }
 
 // ChatLog リザルト画面のチャットログ一件を表します
 type ChatLog struct {
-	LogID     int        `json:"log_id" example:"1" description:"ログの一意のID"`
-	UserID    *string    `json:"user_id" example:"user123" description:"ユーザーのID（Null許容）"`
-	Message   string     `json:"message" example:"良いアイデアですね" description:"チャットメッセージ"`
-	IsSummary bool       `json:"is_summary" example:"false" description:"要約メッセージかどうか"`
-	Timestamp time.Time  `json:"timestamp" example:"2024-01-01T10:00:00Z" description:"タイムスタンプ"`
+	LogID        string         `json:"log_id" example:"V1StGXR8_Z5jdHi6B-myT" description:"ログの一意のID"`
+	UserID       *string        `json:"user_id" example:"user123" description:"ユーザーのID（Null許容）"`
+	Message      string         `json:"message" example:"良いアイデアですね" description:"チャットメッセージ"`
+	IsSummary    bool           `json:"is_summary" example:"false" description:"要約メッセージかどうか"`
+	Timestamp    time.Time      `json:"timestamp" example:"2024-01-01T10:00:00Z" description:"タイムスタンプ"`
+	IsGuest      bool           `json:"is_guest" example:"false" description:"ゲスト参加者の発言かどうか"`
+	SorenaCount  int            `json:"sorena_count" example:"3" description:"このメッセージの「それな」の数"`
+	Reactions    map[string]int `json:"reactions,omitempty" description:"このメッセージのリアクションの種類ごとの数"`
+	AgendaItemID *string        `json:"agenda_item_id,omitempty" example:"V1StGXR8_Z5jdHi6B-myT" description:"投稿時の議題のID"`
 }
 
 // RoomResultResponse リザルト画面APIの完全なレスポンスボディを表します
 type RoomResultResponse struct {
-	RoomInfo      ResultRoomInfo  `json:"room_info" description:"会議室の基本情報"`
-	SorenaSummary SorenaSummary   `json:"sorena_summary" description:"「それな」の集計情報"`
-	ChatLogs      []ChatLog       `json:"chat_logs" description:"チャットログの一覧"`
-}
\ No newline at end of file
+	RoomInfo      ResultRoomInfo    `json:"room_info" description:"会議室の基本情報"`
+	SorenaSummary SorenaSummary     `json:"sorena_summary" description:"「それな」の集計情報（reactions の sorena と同じ内容）"`
+	Reactions     []ReactionSummary `json:"reactions" description:"リアクションの種類ごとの集計情報（表示順）"`
+	ChatLogs      []ChatLog         `json:"chat_logs" description:"チャットログの一覧"`
+	Polls         []Poll            `json:"polls" description:"締め切られた投票の結果（作成順）"`
+	Agenda        []AgendaItem      `json:"agenda" description:"議題ごとの問いかけ・結論・時間（順番順）"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Room 会議室の情報を表す構造体
 type Room struct {
 	ID          string `json:"id" example:"abc123" description:"会議室の一意のID"`
@@ -8,6 +10,53 @@ type Room struct {
 	Conclusion  string `json:"conclusion,omitempty" example:"来週までにプロトタイプを完成させる" description:"会議の結論（オプション）"`
 	Status      string `json:"status,omitempty" example:"inprogress" description:"会議室のステータス（オプション）"`
 	InitialQuestion  string `json:"initial_question,omitempty" example:"今日の議題について何か質問はありますか？" description:"AIが生成した初期質問（オプション）"`
+	MaxParticipants *int `json:"max_participants,omitempty" example:"10" description:"参加者の上限（未指定の場合は無制限）"`
+	AllowGuests bool `json:"allow_guests,omitempty" example:"true" description:"アカウントを持たないゲストの参加を許可するか"`
+	Anonymous bool `json:"anonymous,omitempty" example:"false" description:"匿名モード（結果で発言者や「それな」の内訳を表示しない）。作成時のみ指定可能"`
+	CreatedBy *string `json:"created_by,omitempty" example:"user123" description:"会議室のホスト（作成したユーザー）のID。投票の作成などはホストのみ実行できます"`
+	ScheduledStartAt *time.Time `json:"scheduled_start_at,omitempty" example:"2024-01-01T10:00:00+09:00" description:"開始予定日時。この時刻に自動で開始されます（レスポンスは time_zone の時差で返します）"`
+	DurationMinutes *int `json:"duration_minutes,omitempty" example:"60" description:"会議の長さ（分）。開始からこの時間が経つと自動で終了します"`
+	TimeZone string `json:"time_zone,omitempty" example:"Asia/Tokyo" description:"会議室のタイムゾーン（IANA名。省略時は Asia/Tokyo）"`
+	SeriesID *string `json:"series_id,omitempty" example:"V1StGXR8_Z5jdHi6B-myT" description:"定例会議のシリーズから作成された場合のシリーズID"`
+	PreviousRoomID *string `json:"previous_room_id,omitempty" example:"abc122" description:"同じシリーズの前回の会議室ID。前回の結論と未完了のアクションを最初の問いかけに引き継ぎます"`
+	TemplateID *string `json:"template_id,omitempty" example:"retrospective" description:"作成に使うテンプレートのID。指定しなかった項目にテンプレートの設定を使い、議題とリアクションのセットを登録します"`
+	PromptVariant string `json:"prompt_variant,omitempty" example:"retrospective" description:"最初の問いかけのプロンプトの種類（default / retrospective / brainstorming / decision / one_on_one）"`
+	Tags []string `json:"tags,omitempty" example:"開発,定例" description:"会議室のタグ（一覧の絞り込みに使います）"`
+}
+
+// RoomTagsRequest 会議室のタグの設定リクエスト
+type RoomTagsRequest struct {
+	UserID string   `json:"user_id" example:"user123" description:"設定するユーザー（ホスト）のID"`
+	Tags   []string `json:"tags" example:"開発,定例" description:"設定するタグ（既存のタグを置き換えます）"`
+}
+
+// RoomListItem 会議室一覧の各会議室
+type RoomListItem struct {
+	ID               string     `json:"id" example:"abc123" description:"会議室ID"`
+	Title            string     `json:"title" example:"週次ミーティング" description:"会議室のタイトル"`
+	Description      string     `json:"description" example:"今週の進捗確認と来週の計画" description:"会議室の説明"`
+	Status           string     `json:"status" example:"inprogress" description:"会議室のステータス"`
+	CreatedBy        *string    `json:"created_by,omitempty" example:"user123" description:"会議室のホストのID"`
+	Tags             []string   `json:"tags" example:"開発,定例" description:"会議室のタグ"`
+	ParticipantCount int        `json:"participant_count" example:"5" description:"参加中の人数"`
+	CreatedAt        time.Time  `json:"created_at" example:"2024-01-01T09:00:00+09:00" description:"作成日時"`
+	ScheduledStartAt *time.Time `json:"scheduled_start_at,omitempty" example:"2024-01-01T10:00:00+09:00" description:"開始予定日時"`
+	StartedAt        *time.Time `json:"started_at,omitempty" example:"2024-01-01T10:00:00+09:00" description:"開始日時"`
+	LastActivityAt   time.Time  `json:"last_activity_at" example:"2024-01-01T10:30:00+09:00" description:"最後に発言・要約があった日時（ない場合は開始日時か作成日時）"`
+}
+
+// RoomListResponse 会議室一覧のレスポンス
+type RoomListResponse struct {
+	Rooms      []RoomListItem `json:"rooms" description:"会議室の一覧"`
+	NextCursor string         `json:"next_cursor,omitempty" example:"eyJpZCI6ImFiYzEyMyJ9" description:"次のページを取得するカーソル。最後のページでは省略されます"`
+}
+
+// ScheduleRequest 会議室の予定の設定リクエスト
+type ScheduleRequest struct {
+	UserID           string     `json:"user_id" example:"user123" description:"設定するユーザー（ホスト）のID"`
+	ScheduledStartAt *time.Time `json:"scheduled_start_at" example:"2024-01-01T10:00:00+09:00" description:"開始予定日時（時差付き）。null で予定を取り消します"`
+	DurationMinutes  *int       `json:"duration_minutes" example:"60" description:"会議の長さ（分）。null で自動終了しません"`
+	TimeZone         string     `json:"time_zone" example:"Asia/Tokyo" description:"会議室のタイムゾーン（IANA名）。省略時は変更しません"`
 }
 
 // UpdateRoomStatusRequest 会議室のステータス更新リクエスト
@@ -17,5 +66,6 @@ type UpdateRoomStatusRequest struct {
 
 // ConclusionRequest 会議の結論保存リクエスト
 type ConclusionRequest struct {
+	UserID     string `json:"user_id" example:"user123" description:"保存するユーザー（ホスト・参加者）のID（認証情報がない場合は必須）。版の作成者として記録します"`
 	Conclusion string `json:"conclusion" example:"来週までにプロトタイプを完成させる" description:"保存する結論"`
 }
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// 検索結果の種類
+const (
+	SearchKindTitle       = "title"
+	SearchKindDescription = "description"
+	SearchKindConclusion  = "conclusion"
+	SearchKindMessage     = "message"
+	SearchKindSummary     = "summary"
+)
+
+// SearchResult 検索結果の一件
+type SearchResult struct {
+	Kind      string    `json:"kind" example:"conclusion" description:"一致した箇所（title / description / conclusion / message / summary）"`
+	RoomID    string    `json:"room_id" example:"abc123" description:"会議室ID"`
+	RoomTitle string    `json:"room_title" example:"週次ミーティング" description:"会議室のタイトル"`
+	MessageID *string   `json:"message_id,omitempty" example:"V1StGXR8_Z5jdHi6B-myT" description:"一致したメッセージ・要約のID"`
+	Snippet   string    `json:"snippet" example:"…来週までに<mark>プロトタイプ</mark>を完成させる" description:"一致した前後の抜粋。HTMLエスケープ済みで、一致した部分を <mark> で囲みます"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T10:00:00+09:00" description:"会議室の作成日時、またはメッセージの投稿日時"`
+}
+
+// SearchResponse 検索のレスポンス
+type SearchResponse struct {
+	Query      string         `json:"query" example:"プロトタイプ" description:"検索したキーワード"`
+	Results    []SearchResult `json:"results" description:"新しい順の検索結果"`
+	NextOffset *int           `json:"next_offset,omitempty" example:"20" description:"次のページの offset。最後のページでは省略されます"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// SeriesRequest 定例会議のシリーズの作成リクエスト
+type SeriesRequest struct {
+	Title           string    `json:"title" example:"週次ミーティング" description:"会議室のタイトル（各回のタイトルには開催日が付きます）"`
+	Description     string    `json:"description" example:"今週の進捗確認と来週の計画" description:"会議室の説明"`
+	RRule           string    `json:"rrule" example:"FREQ=WEEKLY;BYDAY=MO" description:"繰り返しのルール（RFC 5545 の RRULE。FREQ は DAILY / WEEKLY / MONTHLY、INTERVAL・BYDAY・COUNT・UNTIL に対応）"`
+	StartAt         time.Time `json:"start_at" example:"2024-01-01T10:00:00+09:00" description:"初回の開始日時（時差付き）。各回はこの時刻に開始します"`
+	DurationMinutes *int      `json:"duration_minutes,omitempty" example:"60" description:"各回の会議の長さ（分）"`
+	TimeZone        string    `json:"time_zone,omitempty" example:"Asia/Tokyo" description:"繰り返しを数えるタイムゾーン（IANA名。省略時は Asia/Tokyo）"`
+	MaxParticipants *int      `json:"max_participants,omitempty" example:"10" description:"各回の参加者の上限"`
+	AllowGuests     bool      `json:"allow_guests,omitempty" example:"false" description:"各回でゲストの参加を許可するか"`
+	Anonymous       bool      `json:"anonymous,omitempty" example:"false" description:"各回を匿名モードにするか"`
+	CreatedBy       *string   `json:"created_by,omitempty" example:"user123" description:"シリーズと各回のホストのID"`
+}
+
+// Series 定例会議のシリーズ
+type Series struct {
+	ID              string       `json:"id" example:"V1StGXR8_Z5jdHi6B-myT" description:"シリーズID"`
+	Title           string       `json:"title" example:"週次ミーティング" description:"会議室のタイトル"`
+	Description     string       `json:"description" example:"今週の進捗確認と来週の計画" description:"会議室の説明"`
+	RRule           string       `json:"rrule" example:"FREQ=WEEKLY;BYDAY=MO" description:"繰り返しのルール"`
+	StartAt         time.Time    `json:"start_at" example:"2024-01-01T10:00:00+09:00" description:"初回の開始日時"`
+	DurationMinutes *int         `json:"duration_minutes,omitempty" example:"60" description:"各回の会議の長さ（分）"`
+	TimeZone        string       `json:"time_zone" example:"Asia/Tokyo" description:"タイムゾーン"`
+	MaxParticipants *int         `json:"max_participants,omitempty" example:"10" description:"各回の参加者の上限"`
+	AllowGuests     bool         `json:"allow_guests,omitempty" example:"false" description:"各回でゲストの参加を許可するか"`
+	Anonymous       bool         `json:"anonymous,omitempty" example:"false" description:"各回を匿名モードにするか"`
+	CreatedBy       *string      `json:"created_by,omitempty" example:"user123" description:"シリーズのホストのID"`
+	NextStartAt     *time.Time   `json:"next_start_at,omitempty" example:"2024-01-08T10:00:00+09:00" description:"まだ会議室を作成していない次回の開始日時。これ以上開催しない場合は省略されます"`
+	Active          bool         `json:"active" example:"true" description:"会議室の自動作成を続けているか"`
+	Rooms           []SeriesRoom `json:"rooms,omitempty" description:"作成済みの各回の会議室（開始日時順）"`
+}
+
+// SeriesRoom シリーズから作成された会議室
+type SeriesRoom struct {
+	ID               string     `json:"id" example:"abc123" description:"会議室ID"`
+	Title            string     `json:"title" example:"週次ミーティング (2024/01/08)" description:"会議室のタイトル"`
+	Status           string     `json:"status" example:"done" description:"会議室のステータス"`
+	ScheduledStartAt *time.Time `json:"scheduled_start_at,omitempty" example:"2024-01-08T10:00:00+09:00" description:"開始予定日時"`
+	PreviousRoomID   *string    `json:"previous_room_id,omitempty" example:"abc122" description:"前回の会議室ID"`
+}
+
+// StopSeriesRequest シリーズの停止リクエスト
+type StopSeriesRequest struct {
+	UserID string `json:"user_id" example:"user123" description:"停止するユーザー（ホスト）のID"`
+}
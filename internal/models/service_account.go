@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ServiceAccountRequest サービスアカウント作成リクエスト
+type ServiceAccountRequest struct {
+	Name string `json:"name" example:"議事録bot" description:"サービスアカウントの名前"`
+}
+
+// APIKeyRequest APIキー発行リクエスト
+type APIKeyRequest struct {
+	Name   string   `json:"name" example:"本番環境" description:"キーの用途を表す名前"`
+	Scopes []string `json:"scopes" example:"rooms:read,results:read" description:"付与するスコープ（rooms:read / rooms:write / results:read / webhooks:manage / service-accounts:manage）"`
+}
+
+// APIKey APIキーの情報（平文のキーは含みません）
+type APIKey struct {
+	ID         string     `json:"id" example:"k3v9x0a1b2c3" description:"キーのID"`
+	UserID     string     `json:"user_id" example:"bot12345" description:"サービスアカウントのユーザーID"`
+	Name       string     `json:"name" example:"本番環境" description:"キーの名前"`
+	Scopes     []string   `json:"scopes" example:"rooms:read,results:read" description:"スコープ"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-01-01T10:00:00Z" description:"発行日時"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2024-01-02T10:00:00Z" description:"最終使用日時"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" example:"2024-01-03T10:00:00Z" description:"失効日時"`
+}
+
+// APIKeyCreatedResponse APIキー発行レスポンス。平文のキーはこのレスポンスでのみ返されます
+type APIKeyCreatedResponse struct {
+	APIKey
+	Key string `json:"key" example:"elmo_k3v9x0a1b2c3_..." description:"平文のAPIキー（Authorization: Bearer で送信）"`
+}
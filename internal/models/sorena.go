@@ -9,6 +9,19 @@ type SorenaCount struct {
 
 // SorenaRequest 「それな」処理リクエスト
 type SorenaRequest struct {
-	UserID string `json:"user_id" example:"user123" description:"ユーザーのID"`
-	Count  int    `json:"count" example:"1" description:"追加する「それな」の数"`
+	UserID    string `json:"user_id" example:"user123" description:"ユーザーのID"`
+	MessageID string `json:"message_id" example:"V1StGXR8_Z5jdHi6B-myT" description:"「それな」するチャットメッセージのID"`
+	Count     int    `json:"count" example:"1" description:"（非推奨）無視されます。「それな」は一人一メッセージにつき一回です"`
+}
+
+// SorenaToggleRequest メッセージへの「それな」の付け外しリクエスト
+type SorenaToggleRequest struct {
+	UserID string `json:"user_id" example:"user123" description:"ユーザーのID（ゲストの場合は省略可）"`
+}
+
+// SorenaToggleResponse メッセージへの「それな」の付け外しレスポンス
+type SorenaToggleResponse struct {
+	MessageID string `json:"message_id" example:"V1StGXR8_Z5jdHi6B-myT" description:"チャットメッセージのID"`
+	Sorena    bool   `json:"sorena" example:"true" description:"リクエストしたユーザーが「それな」しているか"`
+	Count     int    `json:"count" example:"4" description:"このメッセージの「それな」の総数"`
 }
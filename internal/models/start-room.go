@@ -5,6 +5,7 @@ type StartRoomResponse struct {
 	InitialQuestion string        `json:"initial_question" example:"今日の議題について何か質問はありますか？" description:"AIが生成した初期質問"`
 	RoomInfo        RoomInfo      `json:"room_info" description:"会議室の基本情報"`
 	Participants    []ParticipantUser `json:"participants" description:"参加者の一覧"`
+	OpenActionItems []ActionItem      `json:"open_action_items" description:"この会議室・前回の会議・同じ定例会議で作られた未完了のアクションアイテム（期限の近い順に最大20件）"`
 }
 
 // RoomInfo 会議室の情報
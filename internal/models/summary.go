@@ -2,5 +2,6 @@ package models
 
 // SummaryRequest /summary エンドポイントが受け取るリクエストボディの構造を表します
 type SummaryRequest struct {
-	Logs []LogEntry `json:"logs" description:"要約対象のログエントリの一覧"`
-}
\ No newline at end of file
+	Logs         []LogEntry `json:"logs" description:"要約対象のログエントリの一覧"`
+	IncludePolls bool       `json:"include_polls" example:"true" description:"締め切られた投票の結果を要約の材料に含めるか"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// TemplateAgendaItem テンプレートに含まれる議題
+type TemplateAgendaItem struct {
+	Title          string `json:"title" example:"うまくいったこと" description:"議題のタイトル"`
+	Description    string `json:"description,omitempty" example:"" description:"議題の説明"`
+	TimeboxMinutes *int   `json:"timebox_minutes,omitempty" example:"10" description:"割り当て時間（分）"`
+}
+
+// RoomTemplate 会議室のテンプレート
+type RoomTemplate struct {
+	ID              string               `json:"id" example:"retrospective" description:"テンプレートID"`
+	Name            string               `json:"name" example:"ふりかえり" description:"テンプレートの名前"`
+	TitlePattern    string               `json:"title_pattern" example:"ふりかえり {date}" description:"会議室のタイトルのパターン。{date} は開始予定日（未指定の場合は作成日）に置き換えます"`
+	Description     string               `json:"description,omitempty" example:"これまでの取り組みをふりかえり、次に試すことを決めます" description:"会議室の説明"`
+	Agenda          []TemplateAgendaItem `json:"agenda" description:"会議室に登録する議題"`
+	ReactionTypes   []string             `json:"reaction_types" example:"sorena,agree,action" description:"会議室で使うリアクションのキー（表示順）。空の場合は既定のセット"`
+	PromptVariant   string               `json:"prompt_variant,omitempty" example:"retrospective" description:"最初の問いかけのプロンプトの種類（default / retrospective / brainstorming / decision / one_on_one）"`
+	DurationMinutes *int                 `json:"duration_minutes,omitempty" example:"30" description:"会議の長さ（分）"`
+	MaxParticipants *int                 `json:"max_participants,omitempty" example:"10" description:"参加者の上限"`
+	AllowGuests     bool                 `json:"allow_guests,omitempty" example:"false" description:"ゲストの参加を許可するか"`
+	Anonymous       bool                 `json:"anonymous,omitempty" example:"false" description:"匿名モードにするか"`
+	BuiltIn         bool                 `json:"built_in,omitempty" example:"true" description:"組み込みのテンプレートかどうか（変更・削除できません）"`
+	CreatedBy       *string              `json:"created_by,omitempty" example:"user123" description:"テンプレートを追加したユーザーのID"`
+	UserID          string               `json:"user_id,omitempty" example:"user123" description:"追加・更新するユーザーのID（リクエストのみ。認証情報がない場合は必須）"`
+}
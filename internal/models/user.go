@@ -2,6 +2,7 @@ package models
 
 // User ユーザー情報を表す構造体
 type User struct {
-	ID       string `json:"id" example:"user123" description:"ユーザーの一意のID"`
-	UserName string `json:"user_name" example:"田中太郎" description:"ユーザーの名前"`
+	ID               string `json:"id" example:"user123" description:"ユーザーの一意のID"`
+	UserName         string `json:"user_name" example:"田中太郎" description:"ユーザーの名前"`
+	IsServiceAccount bool   `json:"is_service_account,omitempty" example:"false" description:"連携用のサービスアカウントかどうか"`
 }
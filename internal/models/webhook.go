@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// WebhookRequest Webhook の購読の作成リクエスト
+type WebhookRequest struct {
+	UserID string   `json:"user_id" example:"user123" description:"購読するユーザーのID（認証情報がない場合は必須）"`
+	URL    string   `json:"url" example:"https://example.com/hooks/elmo" description:"送信先のURL（http / https）"`
+	Scope  string   `json:"scope" example:"room" description:"購読の範囲（room: 一つの会議室 / creator: 自分がホストの会議室すべて / global: すべての会議室。global は webhooks:manage スコープのサービスアカウントのみ）"`
+	RoomID *string  `json:"room_id,omitempty" example:"abc123" description:"scope が room の場合の会議室ID（ホストのみ）"`
+	Events []string `json:"events,omitempty" example:"room.started,conclusion.saved" description:"送る出来事の種類（room.created / room.started / summary.created / conclusion.saved / room.done。省略するとすべて）"`
+}
+
+// Webhook Webhook の購読
+type Webhook struct {
+	ID        string    `json:"id" example:"V1StGXR8_Z5jdHi6B-myT" description:"購読のID"`
+	URL       string    `json:"url" example:"https://example.com/hooks/elmo" description:"送信先のURL"`
+	Scope     string    `json:"scope" example:"room" description:"購読の範囲（room / creator / global）"`
+	RoomID    *string   `json:"room_id,omitempty" example:"abc123" description:"scope が room の場合の会議室ID"`
+	CreatorID *string   `json:"creator_id,omitempty" example:"user123" description:"scope が creator の場合のホストのユーザーID"`
+	Events    []string  `json:"events" example:"room.started,conclusion.saved" description:"送る出来事の種類（空の場合はすべて）"`
+	CreatedBy string    `json:"created_by" example:"user123" description:"購読したユーザーのID"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T10:00:00Z" description:"作成日時"`
+}
+
+// WebhookCreatedResponse Webhook の購読の作成レスポンス。署名用のシークレットはこのレスポンスでのみ返されます
+type WebhookCreatedResponse struct {
+	Webhook
+	Secret string `json:"secret" example:"whsec_..." description:"X-Elmo-Signature の検証に使うシークレット"`
+}
+
+// WebhookDelivery Webhook の配信の記録
+type WebhookDelivery struct {
+	ID             int64      `json:"id" example:"42" description:"配信のID（X-Elmo-Delivery）"`
+	EventID        string     `json:"event_id" example:"evt_V1StGXR8_Z5jdHi6B-myT" description:"出来事のID。再送しても変わらないため、受信側で重複の判定に使えます"`
+	EventType      string     `json:"event_type" example:"conclusion.saved" description:"出来事の種類"`
+	RoomID         *string    `json:"room_id,omitempty" example:"abc123" description:"会議室ID"`
+	Status         string     `json:"status" example:"succeeded" description:"状態（pending: 送信待ち・再送待ち / succeeded / failed: 再送を諦めた）"`
+	Attempts       int        `json:"attempts" example:"1" description:"送信した回数"`
+	LastStatusCode *int       `json:"last_status_code,omitempty" example:"200" description:"最後の送信のHTTPステータス"`
+	LastError      *string    `json:"last_error,omitempty" example:"unexpected status 500" description:"最後の送信のエラー"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" example:"2024-01-01T10:01:00Z" description:"次に送信する日時（送信待ちの場合のみ）"`
+	CreatedAt      time.Time  `json:"created_at" example:"2024-01-01T10:00:00Z" description:"キューに積まれた日時"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" example:"2024-01-01T10:00:01Z" description:"送信に成功した日時"`
+}
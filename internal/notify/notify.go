@@ -0,0 +1,112 @@
+// Package notify は会議室のライフサイクルの出来事（作成・開始・要約・結論・終了）を外部に知らせる仕組みです。
+// Webhook やチャットツール、メールなどの通知先は Notifier を実装して登録します。
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+// 通知する出来事の種類
+const (
+	TypeRoomCreated     = "room.created"
+	TypeRoomStarted     = "room.started"
+	TypeSummaryCreated  = "summary.created"
+	TypeConclusionSaved = "conclusion.saved"
+	TypeRoomDone        = "room.done"
+)
+
+// Types は通知する出来事の種類の一覧です。
+var Types = []string{TypeRoomCreated, TypeRoomStarted, TypeSummaryCreated, TypeConclusionSaved, TypeRoomDone}
+
+// ValidType は通知する出来事の種類かどうかを返します。
+func ValidType(t string) bool {
+	for _, v := range Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Room は出来事が起きた会議室の、出来事の直後の状態です。
+type Room struct {
+	ID        string  `json:"id"`
+	Title     string  `json:"title"`
+	Status    string  `json:"status"`
+	CreatedBy *string `json:"created_by,omitempty"`
+	Anonymous bool    `json:"anonymous"`
+}
+
+// Event は会議室で起きた出来事一件です。
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Room       Room      `json:"room"`
+	Data       any       `json:"data,omitempty"`
+}
+
+// 出来事の種類ごとの Data
+type (
+	RoomStarted struct {
+		InitialQuestion string `json:"initial_question"`
+	}
+	SummaryCreated struct {
+		MessageID string `json:"message_id"`
+		Summary   string `json:"summary"`
+	}
+	ConclusionSaved struct {
+		Conclusion string `json:"conclusion"`
+	}
+)
+
+// Notifier は出来事を通知先に渡します。リクエストの処理中に呼ばれるため、
+// 時間のかかる送信はキューに積むなどしてすぐに戻ってください。
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// Notifiers は登録された通知先の一覧です。
+type Notifiers []Notifier
+
+// Publish は会議室を読み込んで出来事を作り、すべての通知先に渡します。
+// 通知の失敗で元の操作を失敗させないよう、エラーはログに出すだけにします。通知先がない場合は何もしません。
+func (ns Notifiers) Publish(ctx context.Context, db *sql.DB, eventType, roomID string, data any) {
+	if len(ns) == 0 {
+		return
+	}
+	e, err := NewEvent(ctx, db, eventType, roomID, data)
+	if err != nil {
+		log.Printf("通知する会議室の読み込みに失敗しました: type=%s, room=%s, err=%v", eventType, roomID, err)
+		return
+	}
+	for _, n := range ns {
+		if err := n.Notify(ctx, e); err != nil {
+			log.Printf("通知に失敗しました: type=%s, room=%s, err=%v", eventType, roomID, err)
+		}
+	}
+}
+
+// NewEvent は会議室の現在の状態から出来事を作ります。
+func NewEvent(ctx context.Context, db *sql.DB, eventType, roomID string, data any) (Event, error) {
+	id, err := gonanoid.New()
+	if err != nil {
+		return Event{}, err
+	}
+	e := Event{ID: "evt_" + id, Type: eventType, OccurredAt: time.Now().UTC().Truncate(time.Millisecond), Data: data}
+	var createdBy sql.NullString
+	err = db.QueryRowContext(ctx, `SELECT id, title, status, created_by, anonymous FROM rooms WHERE id = $1`, roomID).
+		Scan(&e.Room.ID, &e.Room.Title, &e.Room.Status, &createdBy, &e.Room.Anonymous)
+	if err != nil {
+		return Event{}, err
+	}
+	if createdBy.Valid {
+		e.Room.CreatedBy = &createdBy.String
+	}
+	return e, nil
+}
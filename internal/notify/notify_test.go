@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	events []Event
+	err    error
+}
+
+func (r *recorder) Notify(ctx context.Context, e Event) error {
+	r.events = append(r.events, e)
+	return r.err
+}
+
+func TestPublish(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT id, title, status, created_by, anonymous FROM rooms`).WithArgs("r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "status", "created_by", "anonymous"}).
+			AddRow("r001", "週次ふりかえり", "concluded", "u001", false))
+
+	// 一つの通知先が失敗しても残りには届ける
+	failing, ok := &recorder{err: errors.New("down")}, &recorder{}
+	Notifiers{failing, ok}.Publish(context.Background(), db, TypeConclusionSaved, "r001", ConclusionSaved{Conclusion: "隔週にする"})
+
+	require.Len(t, ok.events, 1)
+	e := ok.events[0]
+	assert.Equal(t, TypeConclusionSaved, e.Type)
+	assert.Contains(t, e.ID, "evt_")
+	assert.Equal(t, "週次ふりかえり", e.Room.Title)
+	assert.Equal(t, "u001", *e.Room.CreatedBy)
+	assert.Equal(t, ConclusionSaved{Conclusion: "隔週にする"}, e.Data)
+	assert.Len(t, failing.events, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublish_WithoutNotifiers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// 通知先がなければ会議室も読み込まない
+	Notifiers(nil).Publish(context.Background(), db, TypeRoomDone, "r001", nil)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidateURL(t *testing.T) {
+	for _, raw := range []string{"https://hooks.slack.com/services/T000/B000/XXX", "http://example.com:8080/hook"} {
+		assert.NoError(t, ValidateURL(raw), raw)
+	}
+	for _, raw := range []string{"ftp://example.com/", "/hooks", "https://", "https://example.com/" + strings.Repeat("a", maxURLLength)} {
+		assert.Error(t, ValidateURL(raw), raw)
+	}
+	for _, raw := range []string{
+		"http://localhost:8080/", "http://api.localhost/", "http://127.0.0.1/", "http://[::1]/",
+		"http://10.0.0.5/", "http://172.16.0.1/", "http://192.168.1.10/", "http://169.254.169.254/latest/meta-data/",
+		"http://0.0.0.0/", "http://[fe80::1]/", "http://[::ffff:127.0.0.1]/",
+	} {
+		assert.ErrorIs(t, ValidateURL(raw), ErrForbiddenAddress, raw)
+	}
+}
+
+func TestNewHTTPClient_RefusesPrivateAddresses(t *testing.T) {
+	var received bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer server.Close()
+
+	// URL を検査せずに送っても、接続する直前のアドレスで断る
+	_, err := NewHTTPClient(time.Second).Post(server.URL, "application/json", strings.NewReader("{}"))
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+	assert.False(t, received)
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 送信先の URL の長さの上限
+const maxURLLength = 2048
+
+// ErrForbiddenAddress は送信先が内部のネットワークを指している場合のエラーです。
+var ErrForbiddenAddress = errors.New("destination address is not allowed")
+
+// PublicIP は送信先にしてよいアドレスかどうかを返します。
+// 会議室のホストが指定した URL にサーバーから POST するため、サーバー自身や社内のネットワーク、
+// クラウドのメタデータ（169.254.169.254）を指すループバック・プライベート・リンクローカル・未指定のアドレスは断ります。
+func PublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
+
+// ValidateURL は送信先が http / https の絶対 URL で、ホストが内部のネットワークを指していないかを確かめます。
+// ホスト名の解決先は、送信のたびに NewHTTPClient のクライアントが接続の直前に確かめます。
+func ValidateURL(raw string) error {
+	if len(raw) > maxURLLength {
+		return fmt.Errorf("url is longer than %d bytes", maxURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https url")
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !PublicIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// NewHTTPClient は内部のネットワークに接続しない HTTP クライアントを返します。
+// 名前解決の後、接続する直前のアドレスを確かめるため、DNS の応答を後から内部のアドレスに変えられても
+// （DNS リバインディング）、リダイレクトで内部に向けられても接続しません。
+// 接続先を確かめられないため、環境変数のプロキシは使いません。
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second, Control: controlPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// controlPublic は接続するアドレスが PublicIP かどうかを確かめます。
+func controlPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 使われなくなったバケットを掃除する間隔
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	rule    Rule
+}
+
+// MemoryStore はプロセス内のメモリに状態を保持します。サーバーが一台の場合に使います。
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	seen      map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		seen:    make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, rule Rule) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.rule = rule
+	b.tokens = refill(b.tokens, now.Sub(b.updated), rule)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(rule.Interval)), nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+func (s *MemoryStore) Seen(_ context.Context, key string, window time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if expires, ok := s.seen[key]; ok && now.Before(expires) {
+		return true, nil
+	}
+	s.seen[key] = now.Add(window)
+	return false, nil
+}
+
+// sweep は満タンまで回復したバケットと期限切れの記録を削除します。呼び出し側でロックしてください。
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if refill(b.tokens, now.Sub(b.updated), b.rule) >= float64(b.rule.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, expires := range s.seen {
+		if !now.Before(expires) {
+			delete(s.seen, key)
+		}
+	}
+}
+
+// refill は経過時間に応じて回復した後のトークン数を返します。
+func refill(tokens float64, elapsed time.Duration, rule Rule) float64 {
+	if rule.Interval > 0 {
+		tokens += float64(elapsed) / float64(rule.Interval)
+	}
+	if tokens > float64(rule.Burst) {
+		tokens = float64(rule.Burst)
+	}
+	return tokens
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	limiter := New(store, map[string]Rule{ActionMessage: {Burst: 2, Interval: 10 * time.Second}})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := limiter.Allow(ctx, ActionMessage, "r001:u001")
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+
+	ok, retryAfter, err := limiter.Allow(ctx, ActionMessage, "r001:u001")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 10*time.Second, retryAfter)
+
+	// 別のユーザーは影響を受けない
+	ok, _, err = limiter.Allow(ctx, ActionMessage, "r001:u002")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	now = now.Add(4 * time.Second)
+	ok, retryAfter, err = limiter.Allow(ctx, ActionMessage, "r001:u001")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 6*time.Second, retryAfter)
+
+	now = now.Add(6 * time.Second)
+	ok, _, err = limiter.Allow(ctx, ActionMessage, "r001:u001")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestMemoryStore_Duplicate(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	limiter := New(store, DefaultRules)
+	ctx := context.Background()
+
+	dup, err := limiter.Duplicate(ctx, "r001:u001:hello", 10*time.Second)
+	require.NoError(t, err)
+	assert.False(t, dup)
+
+	dup, err = limiter.Duplicate(ctx, "r001:u001:hello", 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, dup)
+
+	now = now.Add(10 * time.Second)
+	dup, err = limiter.Duplicate(ctx, "r001:u001:hello", 10*time.Second)
+	require.NoError(t, err)
+	assert.False(t, dup)
+}
+
+func TestLimiter_NilAllowsEverything(t *testing.T) {
+	var limiter *Limiter
+	ok, _, err := limiter.Allow(context.Background(), ActionReaction, "r001:u001")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
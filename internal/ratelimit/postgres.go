@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// PostgresStore はデータベースに状態を保持し、複数のサーバーで制限を共有します。
+// 時刻はすべてデータベースの NOW() を使うため、サーバー間の時計のずれの影響を受けません。
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, rule Rule) (bool, time.Duration, error) {
+	burst := float64(rule.Burst)
+	interval := rule.Interval.Seconds()
+
+	// 回復後のトークンが一回分以上ある場合のみ更新する。行が返らなければ拒否
+	var tokens float64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at)
+		VALUES ($1, $2 - 1, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = LEAST($2, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) / $3) - 1,
+			updated_at = NOW()
+		WHERE LEAST($2, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) / $3) >= 1
+		RETURNING tokens`, key, burst, interval).Scan(&tokens)
+	if err == nil {
+		return true, 0, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, 0, err
+	}
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT LEAST($2, tokens + EXTRACT(EPOCH FROM NOW() - updated_at) / $3)
+		FROM rate_limit_buckets WHERE key = $1`, key, burst, interval).Scan(&tokens)
+	if err != nil {
+		return false, 0, err
+	}
+	return false, time.Duration((1 - tokens) * float64(rule.Interval)), nil
+}
+
+func (s *PostgresStore) Seen(ctx context.Context, key string, window time.Duration) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO rate_limit_seen AS s (key, expires_at)
+		VALUES ($1, NOW() + make_interval(secs => $2))
+		ON CONFLICT (key) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE s.expires_at <= NOW()`, key, window.Seconds())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 0, err
+}
+
+// Run は ctx がキャンセルされるまで、使われなくなった行を定期的に削除します。
+func (s *PostgresStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if err := s.Purge(ctx); err != nil {
+			log.Printf("レート制限の状態の整理に失敗しました: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge は一時間以上更新されていないバケットと期限切れの記録を削除します。
+// どの Rule でも一時間あれば満タンまで回復するため、削除しても制限の結果は変わりません。
+func (s *PostgresStore) Purge(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < NOW() - INTERVAL '1 hour'`); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_seen WHERE expires_at <= NOW()`)
+	return err
+}
@@ -0,0 +1,89 @@
+// Package ratelimit はユーザー・会議室ごとのトークンバケット方式のレート制限と、
+// 短時間の重複リクエストの抑止を提供します。
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+)
+
+// 制限をかける操作
+const (
+	ActionReaction = "reaction"
+	ActionMessage  = "message"
+	ActionSummary  = "summary"
+)
+
+// Rule はトークンバケットの設定です。Burst 回まで連続で許可し、Interval ごとに一回分回復します。
+type Rule struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// DefaultRules は操作ごとの既定の制限です。
+var DefaultRules = map[string]Rule{
+	ActionReaction: {Burst: 20, Interval: 2 * time.Second},
+	ActionMessage:  {Burst: 10, Interval: 3 * time.Second},
+	ActionSummary:  {Burst: 3, Interval: 30 * time.Second},
+}
+
+// Store はバケットと重複抑止の状態を保持します。
+type Store interface {
+	// Take はバケットから一回分を取り出します。取り出せない場合は再試行までの待ち時間を返します。
+	Take(ctx context.Context, key string, rule Rule) (bool, time.Duration, error)
+	// Seen は key が window 以内に既に記録されていれば true を返し、そうでなければ記録します。
+	Seen(ctx context.Context, key string, window time.Duration) (bool, error)
+}
+
+// Limiter は操作ごとの Rule に従ってリクエストを制限します。
+// nil の Limiter はすべてのリクエストを許可します。
+type Limiter struct {
+	store Store
+	rules map[string]Rule
+}
+
+func New(store Store, rules map[string]Rule) *Limiter {
+	return &Limiter{store: store, rules: rules}
+}
+
+// NewFromEnv は RATE_LIMIT_BACKEND から保存先を選んで Limiter を作成します。
+// memory（既定）はプロセスごと、postgres は複数のサーバーで状態を共有します。
+func NewFromEnv(db *sql.DB) (*Limiter, error) {
+	switch backend := os.Getenv("RATE_LIMIT_BACKEND"); backend {
+	case "", "memory":
+		return New(NewMemoryStore(), DefaultRules), nil
+	case "postgres":
+		return New(NewPostgresStore(db), DefaultRules), nil
+	default:
+		return nil, fmt.Errorf("invalid RATE_LIMIT_BACKEND: %q", backend)
+	}
+}
+
+// Store は状態の保存先を返します。
+func (l *Limiter) Store() Store {
+	return l.store
+}
+
+// Allow は action を key（通常は会議室とユーザー）で一回実行してよいかを返します。
+// 拒否した場合は再試行までの待ち時間を返します。Rule のない操作は常に許可します。
+func (l *Limiter) Allow(ctx context.Context, action, key string) (bool, time.Duration, error) {
+	if l == nil {
+		return true, 0, nil
+	}
+	rule, ok := l.rules[action]
+	if !ok {
+		return true, 0, nil
+	}
+	return l.store.Take(ctx, action+":"+key, rule)
+}
+
+// Duplicate は同じ key のリクエストが window 以内に既にあった場合に true を返します。
+func (l *Limiter) Duplicate(ctx context.Context, key string, window time.Duration) (bool, error) {
+	if l == nil {
+		return false, nil
+	}
+	return l.store.Seen(ctx, "dup:"+key, window)
+}
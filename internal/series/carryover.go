@@ -0,0 +1,109 @@
+package series
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/shuto.sawaki/elmo-project/internal/actionitems"
+)
+
+// 前回の会議から引き継ぐアクションの上限
+const maxCarriedActions = 5
+
+// CarryOver は前回の会議から次の会議に引き継ぐ内容です。
+type CarryOver struct {
+	Title             string
+	Conclusion        string
+	AgendaConclusions []string
+	OpenActions       []string
+}
+
+// Empty は引き継ぐ内容がないかどうかを返します。
+func (c CarryOver) Empty() bool {
+	return c.Conclusion == "" && len(c.AgendaConclusions) == 0 && len(c.OpenActions) == 0
+}
+
+// LoadCarryOver は前回の会議の結論・議題ごとの結論・未完了のアクションを読み込みます。
+// アクションは前回の会議とそれまでの同じ定例会議で作られ、まだ完了していないアクションアイテムです。
+// 担当者や発言者は含めないため、匿名モードの会議室でもそのまま使えます。
+func LoadCarryOver(ctx context.Context, db *sql.DB, roomID string) (CarryOver, error) {
+	var carry CarryOver
+	var conclusion sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT title, conclusion FROM rooms WHERE id = $1`, roomID).Scan(&carry.Title, &conclusion)
+	if err != nil {
+		return carry, err
+	}
+	carry.Conclusion = conclusion.String
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT title, conclusion FROM agenda_items
+		WHERE room_id = $1 AND conclusion IS NOT NULL AND conclusion <> ''
+		ORDER BY position`, roomID)
+	if err != nil {
+		return carry, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var title, itemConclusion string
+		if err := rows.Scan(&title, &itemConclusion); err != nil {
+			return carry, err
+		}
+		carry.AgendaConclusions = append(carry.AgendaConclusions, title+": "+itemConclusion)
+	}
+	if err := rows.Err(); err != nil {
+		return carry, err
+	}
+
+	actions, err := actionitems.OpenRelated(ctx, db, roomID, maxCarriedActions)
+	if err != nil {
+		return carry, err
+	}
+	for _, action := range actions {
+		if action.DueDate != nil {
+			carry.OpenActions = append(carry.OpenActions, action.Title+"（期限: "+*action.DueDate+"）")
+		} else {
+			carry.OpenActions = append(carry.OpenActions, action.Title)
+		}
+	}
+	return carry, nil
+}
+
+// Topic は会議の説明に前回からの引き継ぎを加え、最初の問いかけを生成するAIに渡す説明を作ります。
+func (c CarryOver) Topic(description string) string {
+	if c.Empty() {
+		return description
+	}
+	var b strings.Builder
+	b.WriteString(description)
+	b.WriteString("\n\nこの会議は定例会議です。前回の会議「" + c.Title + "」からの引き継ぎを踏まえ、フォローアップを促す問いかけにしてください。")
+	if c.Conclusion != "" {
+		b.WriteString("\n前回の結論: " + c.Conclusion)
+	}
+	if len(c.AgendaConclusions) > 0 {
+		b.WriteString("\n前回の議題ごとの結論:")
+		for _, conclusion := range c.AgendaConclusions {
+			b.WriteString("\n- " + conclusion)
+		}
+	}
+	if len(c.OpenActions) > 0 {
+		b.WriteString("\n未完了のアクション:")
+		for _, action := range c.OpenActions {
+			b.WriteString("\n- " + action)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// QuestionTopic は会議室の最初の問いかけに使う説明を返します。
+// 前回の会議がない場合や引き継ぎの読み込みに失敗した場合は、説明をそのまま返します。
+func QuestionTopic(ctx context.Context, db *sql.DB, previousRoomID sql.NullString, description string) (string, error) {
+	if !previousRoomID.Valid {
+		return description, nil
+	}
+	carry, err := LoadCarryOver(ctx, db, previousRoomID.String)
+	if err != nil {
+		return description, err
+	}
+	return carry.Topic(description), nil
+}
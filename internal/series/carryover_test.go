@@ -0,0 +1,23 @@
+package series
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCarryOverTopic(t *testing.T) {
+	assert.Equal(t, "進捗確認", CarryOver{Title: "週次 (2024/01/01)"}.Topic("進捗確認"))
+
+	topic := CarryOver{
+		Title:             "週次 (2024/01/01)",
+		Conclusion:        "リリースを1週間延期する",
+		AgendaConclusions: []string{"採用: 2名採用する"},
+		OpenActions:       []string{"田中さんがテスト計画を共有する"},
+	}.Topic("進捗確認")
+
+	assert.Contains(t, topic, "進捗確認\n\n")
+	assert.Contains(t, topic, "前回の結論: リリースを1週間延期する")
+	assert.Contains(t, topic, "- 採用: 2名採用する")
+	assert.Contains(t, topic, "未完了のアクション:\n- 田中さんがテスト計画を共有する")
+}
@@ -0,0 +1,168 @@
+package series
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 対応する繰り返しの単位
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+// 開催日を探すときに調べる期間（日・週・月）の上限
+const maxPeriods = 10000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Rule は RFC 5545 の RRULE のうち、定例会議に必要な部分を表します。
+// FREQ（DAILY / WEEKLY / MONTHLY）、INTERVAL、BYDAY（WEEKLY のみ）、COUNT、UNTIL に対応します。
+type Rule struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	Until    *time.Time
+}
+
+// ParseRule は "FREQ=WEEKLY;BYDAY=MO,TH" のような文字列を解析します。先頭の "RRULE:" は省略できます。
+func ParseRule(s string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return rule, errors.New("rrule is empty")
+	}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("invalid rrule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return rule, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				wd, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					return rule, fmt.Errorf("invalid BYDAY %q", day)
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return rule, fmt.Errorf("invalid COUNT %q", value)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return rule, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			rule.Until = &until
+		default:
+			return rule, fmt.Errorf("unsupported rrule part %q", key)
+		}
+	}
+	switch rule.Freq {
+	case FreqDaily, FreqMonthly:
+		if len(rule.ByDay) > 0 {
+			return rule, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+		}
+	case FreqWeekly:
+	default:
+		return rule, fmt.Errorf("unsupported FREQ %q", rule.Freq)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return rule, errors.New("COUNT and UNTIL cannot be used together")
+	}
+	// 週の中では月曜始まりの順に並べる
+	sort.Slice(rule.ByDay, func(i, j int) bool {
+		return mondayIndex(rule.ByDay[i]) < mondayIndex(rule.ByDay[j])
+	})
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unknown format")
+}
+
+func mondayIndex(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
+
+// Next は start を初回とする開催日時のうち、after より後の最初の日時を返します。
+// 開催日時は loc の壁時計の時刻で数えるため、夏時間をまたいでも同じ時刻に開催されます。
+// これ以上開催がない場合は false を返します。
+func (r Rule) Next(start, after time.Time, loc *time.Location) (time.Time, bool) {
+	start = start.In(loc)
+	n := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.occurrences(start, period, loc) {
+			if t.Before(start) {
+				continue
+			}
+			n++
+			if (r.Count > 0 && n > r.Count) || (r.Until != nil && t.After(*r.Until)) {
+				return time.Time{}, false
+			}
+			if t.After(after) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// occurrences は period 番目の期間（日・週・月）に含まれる開催日時を返します。
+func (r Rule) occurrences(start time.Time, period int, loc *time.Location) []time.Time {
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, 0, loc)
+	}
+
+	switch r.Freq {
+	case FreqDaily:
+		return []time.Time{at(y, m, d+period*r.Interval)}
+	case FreqWeekly:
+		// start を含む週の月曜日から数える
+		monday := d - mondayIndex(start.Weekday()) + period*r.Interval*7
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(y, m, monday+mondayIndex(start.Weekday()))}
+		}
+		times := make([]time.Time, 0, len(r.ByDay))
+		for _, wd := range r.ByDay {
+			times = append(times, at(y, m, monday+mondayIndex(wd)))
+		}
+		return times
+	case FreqMonthly:
+		// 31日のように存在しない日がある月は開催しない
+		t := at(y, m+time.Month(period*r.Interval), d)
+		if t.Day() != d {
+			return nil
+		}
+		return []time.Time{t}
+	}
+	return nil
+}
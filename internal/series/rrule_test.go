@@ -0,0 +1,76 @@
+package series
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TH,MO;COUNT=4")
+	require.NoError(t, err)
+	assert.Equal(t, FreqWeekly, rule.Freq)
+	assert.Equal(t, 2, rule.Interval)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Thursday}, rule.ByDay)
+	assert.Equal(t, 4, rule.Count)
+
+	for _, invalid := range []string{"", "FREQ=YEARLY", "FREQ=DAILY;BYDAY=MO", "FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;COUNT=2;UNTIL=20240101", "FREQ=DAILY;BYHOUR=9"} {
+		_, err := ParseRule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRuleNext_WeeklyByDay(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	rule, err := ParseRule("FREQ=WEEKLY;BYDAY=MO,TH;COUNT=3")
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 4, 10, 0, 0, 0, loc) // 木曜
+
+	first, ok := rule.Next(start, start.Add(-time.Second), loc)
+	require.True(t, ok)
+	assert.Equal(t, start, first)
+
+	second, ok := rule.Next(start, first, loc)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 8, 10, 0, 0, 0, loc), second)
+
+	third, ok := rule.Next(start, second, loc)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 11, 10, 0, 0, 0, loc), third)
+
+	_, ok = rule.Next(start, third, loc)
+	assert.False(t, ok)
+}
+
+func TestRuleNext_KeepsWallClockAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	rule, err := ParseRule("FREQ=WEEKLY")
+	require.NoError(t, err)
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, loc)
+
+	next, ok := rule.Next(start, start, loc)
+	require.True(t, ok)
+	assert.Equal(t, 9, next.Hour())
+	assert.Equal(t, 7*24*time.Hour-time.Hour, next.Sub(start))
+}
+
+func TestRuleNext_MonthlySkipsMissingDays(t *testing.T) {
+	rule, err := ParseRule("FREQ=MONTHLY;UNTIL=20240601")
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+
+	next, ok := rule.Next(start, start, time.UTC)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, 3, 31, 10, 0, 0, 0, time.UTC), next)
+
+	next, ok = rule.Next(start, next, time.UTC)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, 5, 31, 10, 0, 0, 0, time.UTC), next)
+
+	_, ok = rule.Next(start, next, time.UTC)
+	assert.False(t, ok)
+}
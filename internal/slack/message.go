@@ -0,0 +1,139 @@
+// Package slack は会議室の出来事を Slack 互換の Incoming Webhook（Slack / Mattermost）に投稿します。
+package slack
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+)
+
+// 投稿先の種類
+const (
+	KindSlack      = "slack"
+	KindMattermost = "mattermost"
+)
+
+// Types は投稿する出来事の種類です。notify.Types のうち、チャットに流して意味のあるものに絞っています。
+var Types = []string{notify.TypeRoomStarted, notify.TypeSummaryCreated, notify.TypeConclusionSaved}
+
+// 一つのテキストの長さの上限（Slack の section ブロックは3000文字まで）
+const maxTextLength = 2900
+
+// Message は Incoming Webhook に送る本文です。
+// Text は通知やブロックを表示できないクライアントのための代わりの文です。
+type Message struct {
+	Text   string  `json:"text"`
+	Blocks []Block `json:"blocks,omitempty"`
+}
+
+// Block は Block Kit のブロックです。使うのは header / section / context / actions のみです。
+type Block struct {
+	Type     string    `json:"type"`
+	Text     *Text     `json:"text,omitempty"`
+	Elements []Element `json:"elements,omitempty"`
+}
+
+// Text は Block Kit のテキストです。Type は plain_text か mrkdwn です。
+type Text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Element は context の中のテキストか、actions の中のボタンです。
+type Element struct {
+	Type string `json:"type"`
+	Text any    `json:"text,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+func plain(s string) *Text {
+	return &Text{Type: "plain_text", Text: truncate(s, 150)}
+}
+
+func mrkdwn(s string) *Text {
+	return &Text{Type: "mrkdwn", Text: s}
+}
+
+// Escape は Slack の mrkdwn で特別な意味を持つ &, <, > をエスケープします。
+func Escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// truncate は s を n 文字までに切り詰めます。
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+// quote は複数行の文を引用にします。
+func quote(s string) string {
+	return "> " + strings.ReplaceAll(strings.TrimSpace(s), "\n", "\n> ")
+}
+
+// RoomURL は会議室のページのURLです。baseURL が空の場合は空を返します。
+func RoomURL(baseURL, roomID string) string {
+	if baseURL == "" {
+		return ""
+	}
+	return strings.TrimRight(baseURL, "/") + "/rooms/" + url.PathEscape(roomID)
+}
+
+// Format は出来事を投稿先の種類に合わせたメッセージにします。投稿しない出来事の場合は false を返します。
+// Mattermost はブロックを表示しないため、Markdown の Text だけを送ります。
+func Format(kind string, e notify.Event, baseURL string) (Message, bool) {
+	var title, body, linkLabel, link string
+	roomURL := RoomURL(baseURL, e.Room.ID)
+	switch data := e.Data.(type) {
+	case notify.RoomStarted:
+		title = "会議が始まりました"
+		body = data.InitialQuestion
+		linkLabel, link = "会議室を開く", roomURL
+	case notify.SummaryCreated:
+		title = "AI の要約"
+		body = data.Summary
+		linkLabel, link = "会議室を開く", roomURL
+	case notify.ConclusionSaved:
+		title = "結論"
+		body = data.Conclusion
+		if roomURL != "" {
+			linkLabel, link = "結果を見る", roomURL+"/result"
+		}
+	default:
+		return Message{}, false
+	}
+	body = truncate(body, maxTextLength)
+
+	if kind == KindMattermost {
+		var b strings.Builder
+		b.WriteString("#### " + title + "：" + e.Room.Title)
+		if body != "" {
+			b.WriteString("\n" + quote(body))
+		}
+		if link != "" {
+			b.WriteString("\n[" + linkLabel + "](" + link + ")")
+		}
+		return Message{Text: b.String()}, true
+	}
+
+	msg := Message{
+		Text:   title + "：" + Escape(e.Room.Title),
+		Blocks: []Block{{Type: "header", Text: plain(title + "：" + e.Room.Title)}},
+	}
+	if body != "" {
+		msg.Blocks = append(msg.Blocks, Block{Type: "section", Text: mrkdwn(quote(Escape(body)))})
+	}
+	note := "会議室 `" + e.Room.ID + "`"
+	if e.Room.Anonymous {
+		note += "（匿名モード）"
+	}
+	msg.Blocks = append(msg.Blocks, Block{Type: "context", Elements: []Element{{Type: "mrkdwn", Text: note}}})
+	if link != "" {
+		msg.Blocks = append(msg.Blocks, Block{Type: "actions", Elements: []Element{{Type: "button", Text: plain(linkLabel), URL: link}}})
+	}
+	return msg, true
+}
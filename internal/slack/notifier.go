@@ -0,0 +1,190 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+)
+
+// 投稿の既定の設定
+const (
+	// 一回の送信の待ち時間
+	DefaultTimeout = 10 * time.Second
+	// 送信待ちの投稿の上限。超えた分は捨てる
+	queueSize = 256
+	// 一つの投稿を送る回数の上限（最初の送信を含む）
+	maxAttempts = 3
+	// Retry-After に従って待つ時間の上限
+	maxRetryAfter = 30 * time.Second
+	// 記録するレスポンスの本文の長さ
+	maxResponseExcerpt = 256
+)
+
+// Notifier は出来事に一致するチャンネルへの投稿をキューに積み、Run が順に送ります。
+// 投稿はメモリ上のキューで送るため、サーバーを止めると送信待ちの投稿は失われます。
+type Notifier struct {
+	db         *sql.DB
+	client     *http.Client
+	baseURL    string
+	queue      chan post
+	retryDelay time.Duration
+}
+
+type post struct {
+	channelID string
+	url       string
+	message   Message
+}
+
+// NewNotifier は baseURL（例: https://elmo.example.com）を会議室へのリンクに使います。空の場合はリンクを含めません。
+func NewNotifier(db *sql.DB, baseURL string) *Notifier {
+	return &Notifier{
+		db:         db,
+		client:     notify.NewHTTPClient(DefaultTimeout),
+		baseURL:    baseURL,
+		queue:      make(chan post, queueSize),
+		retryDelay: 2 * time.Second,
+	}
+}
+
+// WithClient は送信に使う HTTP クライアントを差し替えます。既定では内部のネットワークに接続しないクライアントを使います。
+func (n *Notifier) WithClient(client *http.Client) *Notifier {
+	n.client = client
+	return n
+}
+
+// Notify は出来事の種類と会議室に一致するチャンネルへの投稿を積みます。送信は待ちません。
+func (n *Notifier) Notify(ctx context.Context, e notify.Event) error {
+	if !slices.Contains(Types, e.Type) {
+		return nil
+	}
+	rows, err := n.db.QueryContext(ctx, `
+		SELECT c.id, c.kind, c.url
+		FROM chat_channels c
+		WHERE (c.events = '' OR $1 = ANY(string_to_array(c.events, ' ')))
+		  AND ((c.scope = 'room' AND c.room_id = $2)
+		    OR (c.scope = 'creator' AND c.creator_id = (SELECT created_by FROM rooms WHERE id = $2)))`,
+		e.Type, e.Room.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p post
+		var kind string
+		if err := rows.Scan(&p.channelID, &kind, &p.url); err != nil {
+			return err
+		}
+		msg, ok := Format(kind, e, n.baseURL)
+		if !ok {
+			continue
+		}
+		p.message = msg
+		select {
+		case n.queue <- p:
+		default:
+			log.Printf("チャットへの投稿が多すぎるため捨てました: channel=%s, event=%s", p.channelID, e.ID)
+		}
+	}
+	return rows.Err()
+}
+
+// Run は ctx がキャンセルされるまで、積まれた投稿を順に送ります。
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p := <-n.queue:
+			n.deliver(ctx, p)
+		}
+	}
+}
+
+// deliver は投稿を送り、送れなかった場合はチャンネルに最後のエラーを記録します。
+func (n *Notifier) deliver(ctx context.Context, p post) {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var wait time.Duration
+		wait, err = n.send(ctx, p.url, p.message)
+		if err == nil || wait < 0 || attempt == maxAttempts {
+			break
+		}
+		if wait == 0 {
+			wait = n.retryDelay * time.Duration(attempt)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+
+	if err == nil {
+		_, err = n.db.ExecContext(ctx, `
+			UPDATE chat_channels SET last_posted_at = NOW(), last_error = NULL WHERE id = $1`, p.channelID)
+		if err != nil {
+			log.Printf("チャンネルの更新に失敗しました: channel=%s, err=%v", p.channelID, err)
+		}
+		return
+	}
+	log.Printf("チャットへの投稿に失敗しました: channel=%s, err=%v", p.channelID, err)
+	if _, dbErr := n.db.ExecContext(ctx, `
+		UPDATE chat_channels SET last_error = $1, last_error_at = NOW() WHERE id = $2`, err.Error(), p.channelID); dbErr != nil {
+		log.Printf("チャンネルの更新に失敗しました: channel=%s, err=%v", p.channelID, dbErr)
+	}
+}
+
+// Send は投稿を一回だけ送ります。設定の確認のためのテスト投稿に使います。
+func (n *Notifier) Send(ctx context.Context, url string, msg Message) error {
+	_, err := n.send(ctx, url, msg)
+	return err
+}
+
+// send は投稿を送ります。失敗した場合は再送までの待ち時間（0 は既定の間隔、負の値は再送しない）を返します。
+func (n *Notifier) send(ctx context.Context, url string, msg Message) (time.Duration, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return -1, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Elmo-Chat/1.0")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseExcerpt))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+	err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(excerpt))
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		wait := time.Duration(0)
+		if sec, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && sec > 0 {
+			wait = min(time.Duration(sec)*time.Second, maxRetryAfter)
+		}
+		return wait, err
+	case resp.StatusCode >= 500:
+		return 0, err
+	default:
+		// URL の誤りや削除されたチャンネルなどは何度送っても同じ
+		return -1, err
+	}
+}
@@ -0,0 +1,144 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conclusionEvent() notify.Event {
+	return notify.Event{
+		ID:   "evt_1",
+		Type: notify.TypeConclusionSaved,
+		Room: notify.Room{ID: "r001", Title: "週次定例 <A&B>", Status: "concluded"},
+		Data: notify.ConclusionSaved{Conclusion: "来週リリースする\n<担当> は田中"},
+	}
+}
+
+func TestFormat_Slack(t *testing.T) {
+	msg, ok := Format(KindSlack, conclusionEvent(), "https://elmo.example.com/")
+	require.True(t, ok)
+
+	b, err := json.Marshal(msg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"text": "結論：週次定例 &lt;A&amp;B&gt;",
+		"blocks": [
+			{"type": "header", "text": {"type": "plain_text", "text": "結論：週次定例 <A&B>"}},
+			{"type": "section", "text": {"type": "mrkdwn", "text": "> 来週リリースする\n> &lt;担当&gt; は田中"}},
+			{"type": "context", "elements": [{"type": "mrkdwn", "text": "会議室 `+"`r001`"+`"}]},
+			{"type": "actions", "elements": [{"type": "button", "text": {"type": "plain_text", "text": "結果を見る"}, "url": "https://elmo.example.com/rooms/r001/result"}]}
+		]
+	}`, string(b))
+}
+
+func TestFormat_Mattermost(t *testing.T) {
+	e := notify.Event{
+		Type: notify.TypeRoomStarted,
+		Room: notify.Room{ID: "r001", Title: "週次定例"},
+		Data: notify.RoomStarted{InitialQuestion: "今週の課題は？"},
+	}
+	msg, ok := Format(KindMattermost, e, "https://elmo.example.com")
+	require.True(t, ok)
+	assert.Empty(t, msg.Blocks)
+	assert.Equal(t, "#### 会議が始まりました：週次定例\n> 今週の課題は？\n[会議室を開く](https://elmo.example.com/rooms/r001)", msg.Text)
+
+	// リンクのベースURLがない場合はリンクを含めない
+	msg, ok = Format(KindMattermost, e, "")
+	require.True(t, ok)
+	assert.NotContains(t, msg.Text, "](")
+}
+
+func TestFormat_NotPosted(t *testing.T) {
+	_, ok := Format(KindSlack, notify.Event{Type: notify.TypeRoomCreated, Room: notify.Room{ID: "r001"}}, "")
+	assert.False(t, ok)
+	assert.NotContains(t, Types, notify.TypeRoomDone)
+}
+
+func TestNotifier_PostsToChannel(t *testing.T) {
+	var attempts atomic.Int32
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 最初の一回は失敗させて再送を確かめる
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		// Webhook の配信と区別できるよう、チャットへの投稿は専用の User-Agent で送る
+		if r.Header.Get("User-Agent") != "Elmo-Chat/1.0" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`SELECT c.id, c.kind, c.url FROM chat_channels c`).
+		WithArgs(notify.TypeConclusionSaved, "r001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "url"}).AddRow("ch1", KindSlack, server.URL))
+	updated := make(chan struct{})
+	mock.ExpectExec(`UPDATE chat_channels SET last_posted_at = NOW\(\), last_error = NULL`).WithArgs("ch1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n := NewNotifier(db, "https://elmo.example.com").WithClient(server.Client())
+	n.retryDelay = time.Millisecond
+	require.NoError(t, n.Notify(context.Background(), conclusionEvent()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		p := <-n.queue
+		n.deliver(ctx, p)
+		close(updated)
+	}()
+
+	select {
+	case body := <-received:
+		assert.True(t, strings.HasPrefix(body, `{"text":"結論：`), body)
+		assert.Contains(t, body, `"url":"https://elmo.example.com/rooms/r001/result"`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("投稿が届きませんでした")
+	}
+	<-updated
+	assert.Equal(t, int32(2), attempts.Load())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotifier_DoesNotRetryClientErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, "no_service", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectExec(`UPDATE chat_channels SET last_error = \$1, last_error_at = NOW\(\)`).
+		WithArgs("unexpected status 404: no_service", "ch1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n := NewNotifier(db, "").WithClient(server.Client())
+	n.retryDelay = time.Millisecond
+	n.deliver(context.Background(), post{channelID: "ch1", url: server.URL, message: Message{Text: "test"}})
+
+	assert.Equal(t, int32(1), attempts.Load())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
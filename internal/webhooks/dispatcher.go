@@ -0,0 +1,193 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+)
+
+// 配信の既定の設定
+const (
+	// これだけ失敗したら諦める（最初の送信を含む）
+	DefaultMaxAttempts = 8
+	// 一回の送信の待ち時間
+	DefaultTimeout = 10 * time.Second
+	// 終わった配信の記録を残す期間
+	DefaultRetention = 30 * 24 * time.Hour
+	// 再送の間隔の上限
+	maxBackoff = 6 * time.Hour
+	// 一回の Tick で送る配信の数
+	batchSize = 50
+	// 送信中の配信を他のプロセスが取らないよう、送信の待ち時間にこれを足した時間だけ先送りする（結果を記録するまでの余裕）
+	leaseMargin = 30 * time.Second
+	// 記録するレスポンスの本文の長さ
+	maxResponseExcerpt = 512
+)
+
+// Dispatcher はキューに積まれた配信を送信し、失敗したものは間隔を空けて再送します。
+// 配信は FOR UPDATE SKIP LOCKED で一件ずつ取り出し、送信の待ち時間より長く先送りしてから送るため、
+// 複数のサーバーで動かしても二重には送りません。
+type Dispatcher struct {
+	db          *sql.DB
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int
+	retention   time.Duration
+	now         func() time.Time
+}
+
+func NewDispatcher(db *sql.DB) *Dispatcher {
+	return &Dispatcher{
+		db:          db,
+		client:      notify.NewHTTPClient(DefaultTimeout),
+		interval:    5 * time.Second,
+		maxAttempts: DefaultMaxAttempts,
+		retention:   DefaultRetention,
+		now:         time.Now,
+	}
+}
+
+// Backoff は attempts 回失敗した後、次に送るまでの間隔です（30秒から倍々に増やし、上限は6時間）。
+func Backoff(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// Run は ctx がキャンセルされるまで定期的に Tick を実行します。
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if err := d.Tick(ctx); err != nil {
+			log.Printf("Webhookの配信に失敗しました: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type pendingDelivery struct {
+	id        int64
+	eventID   string
+	eventType string
+	payload   string
+	attempts  int
+	url       string
+	secret    string
+}
+
+// Tick は送る時刻になった配信を一件ずつ取り出して送り（batchSize 件まで）、保持期間を過ぎた記録を削除します。
+// まとめて取り出すと後ろの配信ほど送るまでに時間がかかり、先送りした時間を過ぎて他のサーバーに取り出されるため、一件ずつ取り出します。
+func (d *Dispatcher) Tick(ctx context.Context) error {
+	var errs []error
+	for i := 0; i < batchSize; i++ {
+		p, ok, err := d.claim(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if !ok {
+			break
+		}
+		errs = append(errs, d.deliver(ctx, p))
+	}
+	_, err := d.db.ExecContext(ctx, `
+		DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < NOW() - make_interval(secs => $1)`, d.retention.Seconds())
+	return errors.Join(append(errs, err)...)
+}
+
+// claim は送る時刻になった配信を一件取り出し、送信が終わるまで他のサーバーが取り出さないように先送りします。
+// 送る配信がない場合は false を返します。
+func (d *Dispatcher) claim(ctx context.Context) (pendingDelivery, bool, error) {
+	var p pendingDelivery
+	lease := d.client.Timeout + leaseMargin
+	err := d.db.QueryRowContext(ctx, `
+		UPDATE webhook_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $1)
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id = (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING d.id, d.event_id, d.event_type, d.payload, d.attempts, s.url, s.secret`, lease.Seconds()).
+		Scan(&p.id, &p.eventID, &p.eventType, &p.payload, &p.attempts, &p.url, &p.secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, false, nil
+	}
+	return p, err == nil, err
+}
+
+// deliver は配信を一回送り、結果を記録します。
+func (d *Dispatcher) deliver(ctx context.Context, p pendingDelivery) error {
+	statusCode, sendErr := d.send(ctx, p)
+	attempts := p.attempts + 1
+
+	if sendErr == nil {
+		_, err := d.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET status = 'succeeded', attempts = $1, last_status_code = $2, last_error = NULL, delivered_at = NOW()
+			WHERE id = $3`, attempts, statusCode, p.id)
+		return err
+	}
+
+	var code sql.NullInt64
+	if statusCode != 0 {
+		code = sql.NullInt64{Int64: int64(statusCode), Valid: true}
+	}
+	status := StatusPending
+	if attempts >= d.maxAttempts {
+		status = StatusFailed
+		log.Printf("Webhookの配信を諦めました: delivery=%d, event=%s, err=%v", p.id, p.eventID, sendErr)
+	}
+	_, err := d.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = $1, attempts = $2, last_status_code = $3, last_error = $4,
+			next_attempt_at = NOW() + make_interval(secs => $5)
+		WHERE id = $6`, status, attempts, code, sendErr.Error(), Backoff(attempts).Seconds(), p.id)
+	return err
+}
+
+// send は署名付きで POST し、2xx 以外はエラーにします。
+func (d *Dispatcher) send(ctx context.Context, p pendingDelivery) (int, error) {
+	body := []byte(p.payload)
+	timestamp := d.now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Elmo-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, p.eventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(p.id, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(p.secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseExcerpt))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(excerpt))
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,84 @@
+// Package webhooks は会議室の出来事を購読先の URL に HMAC-SHA256 の署名付きで送ります。
+// 送信はデータベースをキューにしてバックグラウンドの Dispatcher が行うため、再起動しても失われません。
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+)
+
+// 購読の範囲
+const (
+	ScopeRoom    = "room"    // 一つの会議室
+	ScopeCreator = "creator" // あるユーザーがホストの会議室すべて
+	ScopeGlobal  = "global"  // すべての会議室
+)
+
+// 配信の状態
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// 送信するリクエストのヘッダー
+const (
+	HeaderEvent     = "X-Elmo-Event"
+	HeaderDelivery  = "X-Elmo-Delivery"
+	HeaderTimestamp = "X-Elmo-Timestamp"
+	HeaderSignature = "X-Elmo-Signature"
+)
+
+// NewSecret は署名用のシークレットを生成します。
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign は "<タイムスタンプ>.<本文>" の HMAC-SHA256 を "sha256=<16進数>" の形で返します。
+// 受信側は同じ計算をして X-Elmo-Signature と比べ、タイムスタンプが古すぎるものは捨ててください。
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notifier は出来事に一致する購読ごとに配信をキューに積みます。
+type Notifier struct {
+	db *sql.DB
+}
+
+func NewNotifier(db *sql.DB) *Notifier {
+	return &Notifier{db: db}
+}
+
+// Notify は出来事の種類と会議室に一致する購読すべてに配信を積みます。
+func (n *Notifier) Notify(ctx context.Context, e notify.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = n.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, room_id, payload)
+		SELECT s.id, $1, $2, $3, $4
+		FROM webhook_subscriptions s
+		WHERE (s.events = '' OR $2 = ANY(string_to_array(s.events, ' ')))
+		  AND (s.scope = 'global'
+		    OR (s.scope = 'room' AND s.room_id = $3)
+		    OR (s.scope = 'creator' AND s.creator_id = (SELECT created_by FROM rooms WHERE id = $3)))`,
+		e.ID, e.Type, e.Room.ID, string(payload))
+	return err
+}
@@ -0,0 +1,160 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shuto.sawaki/elmo-project/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pendingColumns = []string{"id", "event_id", "event_type", "payload", "attempts", "url", "secret"}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"a":1}' | openssl dgst -sha256 -hmac whsec_test
+	assert.Equal(t, "sha256=38877139021993b830af32feea6e18a8da83eb2f6e49ee50bd9e4cf4ca4d3789", Sign("whsec_test", 1700000000, []byte(`{"a":1}`)))
+	assert.NotEqual(t, Sign("whsec_test", 1700000000, []byte(`{"a":1}`)), Sign("whsec_test", 1700000001, []byte(`{"a":1}`)))
+	assert.NotEqual(t, Sign("whsec_test", 1700000000, []byte(`{"a":1}`)), Sign("whsec_other", 1700000000, []byte(`{"a":1}`)))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, maxBackoff, Backoff(20))
+}
+
+func TestNotifier_QueuesMatchingSubscriptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO webhook_deliveries .+ FROM webhook_subscriptions s`).
+		WithArgs("evt_1", notify.TypeConclusionSaved, "r001", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	e := notify.Event{ID: "evt_1", Type: notify.TypeConclusionSaved, Room: notify.Room{ID: "r001", Title: "週次"}}
+	require.NoError(t, NewNotifier(db).Notify(context.Background(), e))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatcher_SignsAndRecordsSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Unix(1700000000, 0)
+	payload := `{"id":"evt_1","type":"room.started"}`
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	mock.ExpectQuery(`UPDATE webhook_deliveries d SET next_attempt_at`).
+		WillReturnRows(sqlmock.NewRows(pendingColumns).AddRow(42, "evt_1", "room.started", payload, 0, server.URL, "whsec_test"))
+	mock.ExpectExec(`UPDATE webhook_deliveries SET status = 'succeeded'`).
+		WithArgs(1, http.StatusNoContent, int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE webhook_deliveries d SET next_attempt_at`).WillReturnRows(sqlmock.NewRows(pendingColumns))
+	mock.ExpectExec(`DELETE FROM webhook_deliveries`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	d := NewDispatcher(db)
+	d.client = server.Client()
+	d.now = func() time.Time { return now }
+	require.NoError(t, d.Tick(context.Background()))
+
+	require.NotNil(t, received)
+	assert.Equal(t, payload, string(body))
+	assert.Equal(t, "room.started", received.Header.Get(HeaderEvent))
+	assert.Equal(t, "42", received.Header.Get(HeaderDelivery))
+	assert.Equal(t, strconv.FormatInt(now.Unix(), 10), received.Header.Get(HeaderTimestamp))
+	assert.Equal(t, Sign("whsec_test", now.Unix(), []byte(payload)), received.Header.Get(HeaderSignature))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatcher_RetriesThenGivesUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// 1回目の失敗は再送待ちに、最後の失敗は failed にする
+	mock.ExpectQuery(`UPDATE webhook_deliveries d SET next_attempt_at`).
+		WillReturnRows(sqlmock.NewRows(pendingColumns).AddRow(1, "evt_1", "room.done", `{}`, 0, server.URL, "s"))
+	mock.ExpectExec(`UPDATE webhook_deliveries SET status = \$1`).
+		WithArgs(StatusPending, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), Backoff(1).Seconds(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE webhook_deliveries d SET next_attempt_at`).
+		WillReturnRows(sqlmock.NewRows(pendingColumns).AddRow(2, "evt_2", "room.done", `{}`, DefaultMaxAttempts-1, server.URL, "s"))
+	mock.ExpectExec(`UPDATE webhook_deliveries SET status = \$1`).
+		WithArgs(StatusFailed, DefaultMaxAttempts, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE webhook_deliveries d SET next_attempt_at`).WillReturnRows(sqlmock.NewRows(pendingColumns))
+	mock.ExpectExec(`DELETE FROM webhook_deliveries`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	d := NewDispatcher(db)
+	d.client = server.Client()
+	require.NoError(t, d.Tick(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatcher_LeasesDeliveryWhileSending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	var requests int32
+	sending, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			close(sending)
+		}
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	first, second := NewDispatcher(db), NewDispatcher(db)
+	first.client = &http.Client{Timeout: DefaultTimeout}
+	lease := first.client.Timeout + leaseMargin
+	// 送信が待ち時間いっぱいかかっても、結果を記録するまで他のサーバーには取り出されない
+	require.Greater(t, lease, first.client.Timeout)
+
+	mock.ExpectQuery(`UPDATE webhook_deliveries d SET next_attempt_at .+ LIMIT 1`).WithArgs(lease.Seconds()).
+		WillReturnRows(sqlmock.NewRows(pendingColumns).AddRow(42, "evt_1", "room.started", `{}`, 0, server.URL, "s"))
+	// 送信中に別のサーバーが取り出そうとしても、先送りされているので何も取り出されない
+	mock.ExpectQuery(`UPDATE webhook_deliveries d SET next_attempt_at .+ LIMIT 1`).WithArgs(lease.Seconds()).
+		WillReturnRows(sqlmock.NewRows(pendingColumns))
+	mock.ExpectExec(`DELETE FROM webhook_deliveries`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE webhook_deliveries SET status = 'succeeded'`).
+		WithArgs(1, http.StatusNoContent, int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE webhook_deliveries d SET next_attempt_at`).WillReturnRows(sqlmock.NewRows(pendingColumns))
+	mock.ExpectExec(`DELETE FROM webhook_deliveries`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done := make(chan error, 1)
+	go func() { done <- first.Tick(context.Background()) }()
+	<-sending
+	require.NoError(t, second.Tick(context.Background()))
+	close(release)
+	require.NoError(t, <-done)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
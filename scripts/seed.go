@@ -1,16 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
 	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/shuto.sawaki/elmo-project/internal/archive"
+	"github.com/shuto.sawaki/elmo-project/internal/models"
 )
 
 var (
@@ -65,6 +69,25 @@ var (
 		"仲間と一緒に頑張りましょう",
 		"楽しみながら学びましょう",
 	}
+
+	chatMessages = []string{
+		"いいですね、賛成です",
+		"もう少し具体的に聞きたいです",
+		"前回の話の続きですが",
+		"それは試してみる価値がありそう",
+		"来週までに調べておきます",
+		"別の案もあります",
+		"期限はいつにしますか？",
+		"まずは小さく始めましょう",
+		"担当を決めておきたいです",
+		"資料を共有します",
+	}
+
+	reactionTypes = []models.ArchiveReactionType{
+		{Key: "sorena", Label: "それな", Emoji: "🙌"},
+		{Key: "agree", Label: "賛成", Emoji: "👍"},
+		{Key: "question", Label: "質問", Emoji: "❓"},
+	}
 )
 
 func main() {
@@ -88,18 +111,24 @@ func main() {
 	}
 	defer db.Close()
 
-	// 乱数のシードを設定
-	rand.Seed(time.Now().UnixNano())
+	// 引数にアーカイブのファイルを指定した場合は、既存のデータを残したままそれを取り込む
+	if len(os.Args) > 1 {
+		for _, path := range os.Args[1:] {
+			importFile(db, path)
+		}
+		return
+	}
 
 	// 既存のデータを削除
 	clearTables(db)
 
 	// ランダムなデータを生成して挿入
-	insertRandomData(db, 50) // 各テーブルに50個のデータを挿入
+	insertRandomData(db, 50) // ユーザーと部屋を50個ずつ作成
 }
 
 func clearTables(db *sql.DB) {
-	tables := []string{"sorena_counts", "rooms", "users"}
+	// 参加者・チャットログ・リアクションなどは部屋とユーザーの削除で一緒に消える
+	tables := []string{"rooms", "users"}
 	for _, table := range tables {
 		_, err := db.Exec(fmt.Sprintf("DELETE FROM %s", table))
 		if err != nil {
@@ -114,69 +143,106 @@ func getRandomElement(slice []string) string {
 	return slice[rand.Intn(len(slice))]
 }
 
+// importFile は GET /rooms/:id/archive で書き出したアーカイブ（.json / .ndjson）を取り込みます。
+func importFile(db *sql.DB, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("%sを開けません: %v", path, err)
+	}
+	defer f.Close()
+
+	format := archive.FormatJSON
+	if filepath.Ext(path) == ".ndjson" {
+		format = archive.FormatNDJSON
+	}
+	a, err := archive.Decode(f, format)
+	if err != nil {
+		log.Fatalf("%sを読み込めません: %v", path, err)
+	}
+	result, err := archive.Import(context.Background(), db, a, models.ArchiveConflictRemap, archive.Importer{})
+	if err != nil {
+		log.Fatalf("%sの取り込みに失敗しました: %v", path, err)
+	}
+	log.Printf("%sを部屋%sとして取り込みました（メッセージ%d件）", path, result.RoomID, result.Messages)
+}
+
+// insertRandomData はランダムな部屋をアーカイブとして組み立て、APIの取り込みと同じ処理で挿入します。
 func insertRandomData(db *sql.DB, count int) {
-	// ユーザーを作成
-	userIDs := make([]string, count)
-	for i := 0; i < count; i++ {
+	// ユーザーを作成（取り込み時に作成される）
+	users := make([]models.ArchiveUser, count)
+	for i := range users {
 		id, err := gonanoid.Generate("0123456789abcdefghijklmnopqrstuvwxyz", 8)
 		if err != nil {
-			log.Printf("ユーザーID生成エラー: %v", err)
-			continue
-		}
-		userIDs[i] = id
-
-		userName := fmt.Sprintf("%s %s", getRandomElement(lastNames), getRandomElement(firstNames))
-		_, err = db.Exec(
-			"INSERT INTO users (id, user_name) VALUES ($1, $2)",
-			id,
-			userName,
-		)
-		if err != nil {
-			log.Printf("ユーザー作成エラー: %v", err)
+			log.Fatalf("ユーザーID生成エラー: %v", err)
 		}
+		users[i] = models.ArchiveUser{ID: id, Name: fmt.Sprintf("%s %s", getRandomElement(lastNames), getRandomElement(firstNames))}
 	}
-	log.Printf("%d人のユーザーを作成しました", count)
 
 	// 部屋を作成
-	roomIDs := make([]string, count)
+	messages := 0
 	for i := 0; i < count; i++ {
-		id, err := gonanoid.Generate("0123456789abcdefghijklmnopqrstuvwxyz", 6)
+		a, err := randomArchive(users)
 		if err != nil {
-			log.Printf("部屋ID生成エラー: %v", err)
+			log.Printf("部屋の生成エラー: %v", err)
 			continue
 		}
-		roomIDs[i] = id
-
-		roomType := getRandomElement(roomTypes)
-		description := getRandomElement(roomDescriptions)
-		_, err = db.Exec(
-			"INSERT INTO rooms (id, title, description, conclusion) VALUES ($1, $2, $3, $4)",
-			id,
-			fmt.Sprintf("%sの部屋", roomType),
-			description,
-			"", // 初期値は空文字列
-		)
+		result, err := archive.Import(context.Background(), db, a, models.ArchiveConflictRemap, archive.Importer{})
 		if err != nil {
 			log.Printf("部屋作成エラー: %v", err)
+			continue
 		}
+		messages += result.Messages
 	}
-	log.Printf("%d個の部屋を作成しました", count)
+	log.Printf("%d人のユーザーと%d個の部屋（メッセージ%d件）を作成しました", count, count, messages)
+}
 
-	// それなカウントを作成
-	for i := 0; i < count; i++ {
-		userID := userIDs[rand.Intn(len(userIDs))]
-		roomID := roomIDs[rand.Intn(len(roomIDs))]
-		count := rand.Intn(100) + 1 // 1から100のランダムな数
-
-		_, err := db.Exec(
-			"INSERT INTO sorena_counts (room_id, user_id, count) VALUES ($1, $2, $3)",
-			roomID,
-			userID,
-			count,
-		)
+// randomArchive は参加者・チャットログ・「それな」などのリアクションを持つランダムな部屋を組み立てます。
+func randomArchive(users []models.ArchiveUser) (models.RoomArchive, error) {
+	id, err := gonanoid.Generate("0123456789abcdefghijklmnopqrstuvwxyz", 6)
+	if err != nil {
+		return models.RoomArchive{}, err
+	}
+	now := time.Now()
+	createdAt := now.Add(-time.Duration(rand.Intn(30*24)) * time.Hour)
+	a := models.RoomArchive{
+		Version:    models.ArchiveVersion,
+		ExportedAt: now,
+		Room: models.ArchiveRoom{
+			ID:          id,
+			Title:       fmt.Sprintf("%sの部屋", getRandomElement(roomTypes)),
+			Description: getRandomElement(roomDescriptions),
+			Status:      "done",
+			TimeZone:    "Asia/Tokyo",
+			CreatedAt:   createdAt,
+			StartedAt:   &createdAt,
+		},
+		ReactionTypes: reactionTypes,
+	}
+
+	// 参加者を3〜8人選ぶ
+	members := rand.Perm(len(users))[:3+rand.Intn(6)]
+	for _, m := range members {
+		a.Users = append(a.Users, users[m])
+		a.Participants = append(a.Participants, models.ArchiveParticipant{UserID: users[m].ID, JoinedAt: createdAt})
+	}
+	a.Room.CreatedBy = &a.Users[0].ID
+
+	// チャットログとリアクション
+	at := createdAt
+	for i := 0; i < 5+rand.Intn(16); i++ {
+		messageID, err := gonanoid.New()
 		if err != nil {
-			log.Printf("それなカウント作成エラー: %v", err)
+			return a, err
+		}
+		at = at.Add(time.Duration(10+rand.Intn(120)) * time.Second)
+		author := a.Users[rand.Intn(len(a.Users))].ID
+		a.Messages = append(a.Messages, models.ArchiveMessage{ID: messageID, UserID: &author, Message: getRandomElement(chatMessages), CreatedAt: at})
+		for _, u := range a.Users {
+			if u.ID != author && rand.Intn(4) == 0 {
+				reaction := reactionTypes[rand.Intn(len(reactionTypes))].Key
+				a.Reactions = append(a.Reactions, models.ArchiveReaction{MessageID: messageID, UserID: u.ID, Type: reaction, CreatedAt: at})
+			}
 		}
 	}
-	log.Printf("%d個のそれなカウントを作成しました", count)
+	return a, nil
 }